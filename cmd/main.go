@@ -1,10 +1,38 @@
 package main
 
 import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
 	app "messenger/internal/app"
 )
 
-// main — это точка входа в приложение.
+// main — это точка входа в приложение: загружает конфигурацию, собирает приложение
+// и работает до сигнала завершения (SIGTERM или прерывания).
 func main() {
-	app.Run()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config, err := app.LoadConfig(app.DefaultConfigOptions())
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+	}
+
+	// Логгер приложения становится логгером по умолчанию, чтобы записи стандартного
+	// пакета log и компонентов без явно переданного логгера выводились в том же формате.
+	logger := app.LoadLogger(config.Log)
+	slog.SetDefault(logger)
+
+	application, err := app.New(app.Options{Config: config, Logger: logger})
+	if err != nil {
+		log.Fatalf("Ошибка сборки приложения: %v", err)
+	}
+
+	if err := application.Run(ctx); err != nil {
+		log.Fatalf("Ошибка работы приложения: %v", err)
+	}
 }
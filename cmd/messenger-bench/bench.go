@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/1ight181/messenger/pkg/client"
+)
+
+// payloadVariants — число заранее сгенерированных текстов сообщений. Тексты случайные,
+// чтобы сжатие permessage-deflate не искажало объем передаваемых данных.
+const payloadVariants = 64
+
+// benchConfig — параметры нагрузочного теста.
+type benchConfig struct {
+	url             string
+	restURL         string
+	tokens          []string
+	tlsConfig       *tls.Config
+	codec           string
+	connections     int
+	dialConcurrency int
+	ramp            time.Duration
+	rate            float64
+	mix             []mixEntry
+	size            int
+	rooms           int
+	inflight        int
+	duration        time.Duration
+	timeout         time.Duration
+	progress        time.Duration
+	logger          *slog.Logger
+}
+
+// mixEntry — тип сообщения в смеси и его вес.
+type mixEntry struct {
+	kind   string
+	weight int
+}
+
+// bench — состояние одного запуска теста.
+type bench struct {
+	cfg      benchConfig
+	stats    *stats
+	payloads []string
+	runID    string
+	mixTotal int
+}
+
+// runBench открывает соединения, отправляет сообщения в течение cfg.duration и
+// возвращает итоговый отчет. Отмена ctx досрочно завершает тест; отчет при этом
+// строится по собранным данным.
+func runBench(ctx context.Context, cfg benchConfig) (*report, error) {
+	b := &bench{
+		cfg:      cfg,
+		stats:    newStats(),
+		payloads: randomPayloads(cfg.size),
+		runID:    strconv.FormatInt(time.Now().Unix(), 36),
+	}
+	for _, entry := range cfg.mix {
+		b.mixTotal += entry.weight
+	}
+
+	if err := b.prepareRooms(ctx); err != nil {
+		return b.stats.report(0), err
+	}
+
+	stopProgress := b.startProgress()
+	defer stopProgress()
+
+	clients := b.connect(ctx)
+	defer func() {
+		var wg sync.WaitGroup
+		for _, c := range clients {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.Close()
+			}()
+		}
+		wg.Wait()
+	}()
+	if len(clients) == 0 {
+		return b.stats.report(0), errors.New("не удалось открыть ни одного соединения")
+	}
+	fmt.Fprintf(os.Stderr, "Открыто соединений: %d из %d\n", len(clients), cfg.connections)
+
+	sendCtx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+
+	started := time.Now()
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.sendLoop(sendCtx, c, i)
+		}()
+	}
+	wg.Wait()
+
+	return b.stats.report(time.Since(started)), nil
+}
+
+// connect открывает cfg.connections соединений не более чем по cfg.dialConcurrency
+// одновременно, равномерно распределяя начало подключений по cfg.ramp.
+// Возвращает успешно открытые соединения.
+func (b *bench) connect(ctx context.Context) []*client.Client {
+	var (
+		mu      sync.Mutex
+		clients []*client.Client
+		wg      sync.WaitGroup
+	)
+	limit := make(chan struct{}, b.cfg.dialConcurrency)
+	start := time.Now()
+
+	for i := range b.cfg.connections {
+		if b.cfg.ramp > 0 {
+			at := start.Add(b.cfg.ramp * time.Duration(i) / time.Duration(b.cfg.connections))
+			select {
+			case <-time.After(time.Until(at)):
+			case <-ctx.Done():
+			}
+		}
+		select {
+		case limit <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-limit }()
+
+			if c := b.dial(ctx, i); c != nil {
+				mu.Lock()
+				clients = append(clients, c)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return clients
+}
+
+// dial открывает соединение с номером i и учитывает задержку подключения или ошибку.
+func (b *bench) dial(ctx context.Context, i int) *client.Client {
+	options := client.Options{
+		URL:              b.cfg.url,
+		TLSConfig:        b.cfg.tlsConfig,
+		Codec:            b.cfg.codec,
+		HandshakeTimeout: b.cfg.timeout,
+		DisableReconnect: true,
+		OnMessage: func(client.Message) {
+			b.stats.received.Add(1)
+		},
+		OnDisconnect: func(err error) {
+			if !errors.Is(err, client.ErrClosed) {
+				b.stats.disconnects.Add(1)
+			}
+		},
+		Logger: b.cfg.logger,
+	}
+	if len(b.cfg.tokens) > 0 {
+		options.Token = b.cfg.tokens[i%len(b.cfg.tokens)]
+	}
+
+	started := time.Now()
+	c, err := client.Dial(ctx, options)
+	if err != nil {
+		b.stats.connectFailed(err)
+		return nil
+	}
+	b.stats.connected(time.Since(started))
+	return c
+}
+
+// sendLoop отправляет сообщения соединения с частотой cfg.rate/cfg.connections до
+// отмены ctx и ждет ответов на отправленные сообщения. Если ответа ждут cfg.inflight
+// сообщений, очередная отправка пропускается и учитывается как пропущенная.
+func (b *bench) sendLoop(ctx context.Context, c *client.Client, i int) {
+	if b.cfg.rate == 0 {
+		<-ctx.Done()
+		return
+	}
+
+	interval := max(time.Duration(float64(time.Second)*float64(b.cfg.connections)/b.cfg.rate), time.Microsecond)
+	room := b.room(i)
+	inflight := make(chan struct{}, b.cfg.inflight)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Случайный сдвиг первой отправки, чтобы соединения не отправляли сообщения одновременно.
+	select {
+	case <-time.After(rand.N(interval)):
+	case <-ctx.Done():
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case inflight <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-inflight }()
+				b.send(c, room)
+			}()
+		default:
+			b.stats.skipped.Add(1)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-c.Done():
+			return
+		}
+	}
+}
+
+// send отправляет одно сообщение случайного по смеси типа и учитывает время ответа или ошибку.
+func (b *bench) send(c *client.Client, room string) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.cfg.timeout)
+	defer cancel()
+
+	kind := b.pickKind()
+	text := b.payloads[rand.N(len(b.payloads))]
+
+	b.stats.sent.Add(1)
+	started := time.Now()
+	var err error
+	switch kind {
+	case "data":
+		_, err = c.SendData(ctx, room, text)
+	case "info":
+		_, err = c.SendInfo(ctx, text)
+	case "error":
+		_, err = c.SendError(ctx, text)
+	}
+	if err != nil {
+		b.stats.sendFailed(err)
+		return
+	}
+	b.stats.responded(kind, time.Since(started))
+}
+
+func (b *bench) pickKind() string {
+	n := rand.N(b.mixTotal)
+	for _, entry := range b.cfg.mix {
+		if n < entry.weight {
+			return entry.kind
+		}
+		n -= entry.weight
+	}
+	return b.cfg.mix[len(b.cfg.mix)-1].kind
+}
+
+// prepareRooms создает общие беседы cfg.rooms от имени первого токена и приглашает в них
+// пользователей остальных токенов: писать в беседу могут только ее участники. Своя беседа
+// каждого соединения и беседы одного пользователя создаются первым сообщением в них.
+func (b *bench) prepareRooms(ctx context.Context) error {
+	if b.cfg.rooms == 0 || len(b.cfg.tokens) < 2 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.timeout)
+	defer cancel()
+
+	options := client.Options{RESTURL: b.cfg.restURL, TLSConfig: b.cfg.tlsConfig}
+	var members []string
+	for _, token := range b.cfg.tokens[1:] {
+		options.Token = token
+		userID, err := client.NewREST(options).Me(ctx)
+		if err != nil {
+			return fmt.Errorf("ошибка получения пользователя токена: %w", err)
+		}
+		members = append(members, userID)
+	}
+
+	options.Token = b.cfg.tokens[0]
+	creator := client.NewREST(options)
+	for i := range b.cfg.rooms {
+		room := b.room(i)
+		if _, err := creator.SendRoom(ctx, room, "bench"); err != nil {
+			return fmt.Errorf("ошибка создания беседы %s: %w", room, err)
+		}
+		if _, err := creator.Invite(ctx, room, members...); err != nil {
+			return fmt.Errorf("ошибка приглашения в беседу %s: %w", room, err)
+		}
+	}
+	return nil
+}
+
+// room возвращает беседу для сообщений data соединения с номером i.
+func (b *bench) room(i int) string {
+	if b.cfg.rooms > 0 {
+		i %= b.cfg.rooms
+	}
+	return fmt.Sprintf("bench-%s-%d", b.runID, i)
+}
+
+// startProgress периодически выводит промежуточную статистику в stderr.
+// Возвращает функцию, останавливающую вывод.
+func (b *bench) startProgress() func() {
+	if b.cfg.progress <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(b.cfg.progress)
+		defer ticker.Stop()
+
+		var lastResponses uint64
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			progress := b.stats.progress()
+			rate := float64(progress.responses-lastResponses) / b.cfg.progress.Seconds()
+			lastResponses = progress.responses
+			fmt.Fprintf(os.Stderr, "соединений %d, отправлено %d, ответов %d (%.0f/с), ошибок %d, пропущено %d\n",
+				progress.connections, progress.sent, progress.responses, rate, progress.errors, progress.skipped)
+		}
+	}()
+	return func() { close(done) }
+}
+
+// randomPayloads генерирует payloadVariants случайных текстов длиной size байт.
+func randomPayloads(size int) []string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
+
+	payloads := make([]string, payloadVariants)
+	for i := range payloads {
+		text := make([]byte, size)
+		for j := range text {
+			text[j] = alphabet[rand.N(len(alphabet))]
+		}
+		payloads[i] = string(text)
+	}
+	return payloads
+}
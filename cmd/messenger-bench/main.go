@@ -0,0 +1,175 @@
+// Команда messenger-bench — нагрузочный тест сервера мессенджера.
+//
+// Открывает N TLS WebSocket-соединений, отправляет сообщения с заданной суммарной
+// частотой, смесью типов и размером текста и по завершении выводит задержку
+// подключения, перцентили времени ответа, пропускную способность и счетчики ошибок.
+//
+// Пример:
+//
+//	messenger-bench -url wss://127.0.0.1:8080/ws -token t1,t2 -insecure \
+//		-connections 1000 -rate 5000 -mix data=8,info=1,error=1 -size 256 -duration 1m
+//
+// Соединения распределяются по токенам из -token по кругу. Ограничения сервера на число
+// соединений одного пользователя и одного IP-адреса (admission) действуют и на тест:
+// для тысяч соединений нужны несколько токенов или доверенная сеть в admission.trusted_cidrs.
+//
+// Писать в беседу могут только ее участники, поэтому общие беседы -rooms перед тестом
+// создаются через REST API от имени первого токена, и в них приглашаются пользователи
+// остальных токенов.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/1ight181/messenger/pkg/client"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "Ошибка:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	var (
+		cfg      benchConfig
+		tokens   string
+		mix      string
+		caFile   string
+		insecure bool
+		verbose  bool
+	)
+	flags := flag.NewFlagSet("messenger-bench", flag.ContinueOnError)
+	flags.StringVar(&cfg.url, "url", "wss://127.0.0.1:8080/ws", "адрес WebSocket-эндпоинта сервера")
+	flags.StringVar(&cfg.restURL, "rest", "", "базовый адрес REST API для подготовки бесед (по умолчанию https://<хост из -url>/api)")
+	flags.StringVar(&tokens, "token", os.Getenv("MESSENGER_TOKEN"), "токены доступа через запятую; соединения распределяются по ним по кругу")
+	flags.StringVar(&caFile, "ca", "", "PEM-файл с корневым сертификатом сервера")
+	flags.BoolVar(&insecure, "insecure", false, "не проверять сертификат сервера")
+	flags.StringVar(&cfg.codec, "codec", client.CodecJSON, "подпротокол кодека: messenger.json, messenger.msgpack или messenger.proto")
+	flags.IntVar(&cfg.connections, "connections", 100, "число соединений")
+	flags.IntVar(&cfg.dialConcurrency, "dial-concurrency", 64, "число одновременных подключений")
+	flags.DurationVar(&cfg.ramp, "ramp", 0, "время, за которое открываются все соединения (0 — как можно быстрее)")
+	flags.Float64Var(&cfg.rate, "rate", 100, "суммарная частота отправки сообщений в секунду по всем соединениям (0 — без отправки)")
+	flags.StringVar(&mix, "mix", "data=1", "смесь типов сообщений в формате тип=вес через запятую; типы: data, info, error")
+	flags.IntVar(&cfg.size, "size", 64, "размер текста сообщения в байтах")
+	flags.IntVar(&cfg.rooms, "rooms", 0, "число бесед для сообщений data (0 — своя беседа у каждого соединения)")
+	flags.IntVar(&cfg.inflight, "inflight", 32, "максимум сообщений одного соединения, ожидающих ответа; сверх него отправка пропускается")
+	flags.DurationVar(&cfg.duration, "duration", 30*time.Second, "длительность отправки после открытия соединений")
+	flags.DurationVar(&cfg.timeout, "timeout", 10*time.Second, "таймаут подключения и ожидания ответа")
+	flags.DurationVar(&cfg.progress, "progress", 5*time.Second, "период вывода промежуточной статистики (0 — не выводить)")
+	flags.BoolVar(&verbose, "v", false, "выводить журнал клиентов")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Использование: messenger-bench [флаги]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if tokens != "" {
+		cfg.tokens = strings.Split(tokens, ",")
+	}
+	var err error
+	if cfg.restURL == "" {
+		if cfg.restURL, err = restBaseURL(cfg.url); err != nil {
+			return err
+		}
+	}
+	if cfg.mix, err = parseMix(mix); err != nil {
+		return err
+	}
+	if cfg.tlsConfig, err = tlsConfig(caFile, insecure); err != nil {
+		return err
+	}
+	if cfg.connections <= 0 || cfg.dialConcurrency <= 0 || cfg.inflight <= 0 || cfg.size < 0 || cfg.rate < 0 {
+		return errors.New("число соединений, одновременных подключений и ожидающих ответа сообщений должно быть положительным, размер и частота — неотрицательными")
+	}
+
+	cfg.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	if verbose {
+		cfg.logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	}
+
+	report, err := runBench(ctx, cfg)
+	report.print(os.Stdout)
+	return err
+}
+
+// parseMix разбирает смесь типов сообщений вида "data=8,info=1,error=1".
+func parseMix(value string) ([]mixEntry, error) {
+	var mix []mixEntry
+	for _, part := range strings.Split(value, ",") {
+		name, weightValue, found := strings.Cut(strings.TrimSpace(part), "=")
+		weight := 1
+		if found {
+			var err error
+			if weight, err = strconv.Atoi(weightValue); err != nil || weight < 0 {
+				return nil, fmt.Errorf("некорректный вес %q в -mix", part)
+			}
+		}
+		switch name {
+		case "data", "info", "error":
+		default:
+			return nil, fmt.Errorf("неизвестный тип сообщения %q в -mix", name)
+		}
+		if weight > 0 {
+			mix = append(mix, mixEntry{kind: name, weight: weight})
+		}
+	}
+	if len(mix) == 0 {
+		return nil, errors.New("в -mix нет типов с положительным весом")
+	}
+	return mix, nil
+}
+
+// restBaseURL выводит адрес REST API из адреса WebSocket-эндпоинта: тот же хост и путь /api.
+func restBaseURL(wsAddress string) (string, error) {
+	wsURL, err := url.Parse(wsAddress)
+	if err != nil {
+		return "", fmt.Errorf("некорректный адрес сервера %q: %w", wsAddress, err)
+	}
+	scheme := "https"
+	if wsURL.Scheme == "ws" {
+		scheme = "http"
+	}
+	return (&url.URL{Scheme: scheme, Host: wsURL.Host, Path: "/api"}).String(), nil
+}
+
+func tlsConfig(caFile string, insecure bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения сертификата %s: %w", caFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("файл %s не содержит сертификатов PEM", caFile)
+	}
+	config.RootCAs = pool
+	return config, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/1ight181/messenger/pkg/client"
+)
+
+// reportPercentiles — перцентили задержек в отчете.
+var reportPercentiles = []float64{50, 90, 99, 99.9}
+
+// stats собирает результаты теста из всех соединений.
+type stats struct {
+	sent        atomic.Uint64
+	skipped     atomic.Uint64
+	received    atomic.Uint64
+	disconnects atomic.Uint64
+
+	mu       sync.Mutex
+	connects []time.Duration
+	rtt      map[string][]time.Duration
+	errors   map[string]uint64
+}
+
+func newStats() *stats {
+	return &stats{
+		rtt:    make(map[string][]time.Duration),
+		errors: make(map[string]uint64),
+	}
+}
+
+func (s *stats) connected(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.connects = append(s.connects, latency)
+}
+
+func (s *stats) connectFailed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors["подключение: "+errorKind(err)]++
+}
+
+func (s *stats) responded(kind string, rtt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rtt[kind] = append(s.rtt[kind], rtt)
+}
+
+func (s *stats) sendFailed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors["отправка: "+errorKind(err)]++
+}
+
+// errorKind возвращает категорию ошибки для счетчиков отчета.
+func errorKind(err error) string {
+	var (
+		handshakeErr *client.HandshakeError
+		responseErr  *client.ResponseError
+	)
+	switch {
+	case errors.As(err, &handshakeErr):
+		return fmt.Sprintf("HTTP %d", handshakeErr.StatusCode)
+	case errors.As(err, &responseErr):
+		if len(responseErr.Response.Errors) > 0 {
+			return "отказ сервера: " + responseErr.Response.Errors[0].Code
+		}
+		return "отказ сервера: " + responseErr.Response.Type.String()
+	case errors.Is(err, client.ErrUnauthorized):
+		return "токен отклонен"
+	case errors.Is(err, client.ErrConnectionLost), errors.Is(err, client.ErrClosed):
+		return "соединение разорвано"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "таймаут"
+	default:
+		return err.Error()
+	}
+}
+
+// progressSnapshot — промежуточные значения счетчиков.
+type progressSnapshot struct {
+	connections uint64
+	sent        uint64
+	responses   uint64
+	errors      uint64
+	skipped     uint64
+}
+
+func (s *stats) progress() progressSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := progressSnapshot{
+		connections: uint64(len(s.connects)) - s.disconnects.Load(),
+		sent:        s.sent.Load(),
+		skipped:     s.skipped.Load(),
+	}
+	for _, samples := range s.rtt {
+		snapshot.responses += uint64(len(samples))
+	}
+	for _, count := range s.errors {
+		snapshot.errors += count
+	}
+	return snapshot
+}
+
+// report — итоговый отчет теста.
+type report struct {
+	elapsed     time.Duration
+	connected   int
+	connects    []time.Duration
+	sent        uint64
+	skipped     uint64
+	received    uint64
+	disconnects uint64
+	rtt         map[string][]time.Duration
+	errors      map[string]uint64
+}
+
+// report строит отчет по собранным данным; elapsed — длительность фазы отправки.
+func (s *stats) report(elapsed time.Duration) *report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := &report{
+		elapsed:     elapsed,
+		connected:   len(s.connects),
+		connects:    slices.Clone(s.connects),
+		sent:        s.sent.Load(),
+		skipped:     s.skipped.Load(),
+		received:    s.received.Load(),
+		disconnects: s.disconnects.Load(),
+		rtt:         make(map[string][]time.Duration, len(s.rtt)),
+		errors:      maps.Clone(s.errors),
+	}
+	slices.Sort(r.connects)
+	for kind, samples := range s.rtt {
+		sorted := slices.Clone(samples)
+		slices.Sort(sorted)
+		r.rtt[kind] = sorted
+	}
+	return r
+}
+
+// print выводит отчет в w.
+func (r *report) print(w io.Writer) {
+	fmt.Fprintf(w, "Соединения: открыто %d, разорвано сервером %d\n", r.connected, r.disconnects)
+	printLatency(w, "Задержка подключения", r.connects)
+
+	var all []time.Duration
+	for _, kind := range slices.Sorted(maps.Keys(r.rtt)) {
+		all = append(all, r.rtt[kind]...)
+	}
+	slices.Sort(all)
+
+	fmt.Fprintf(w, "Сообщения: отправлено %d, получено ответов %d, пропущено %d, получено доставок %d\n",
+		r.sent, len(all), r.skipped, r.received)
+	if seconds := r.elapsed.Seconds(); seconds > 0 {
+		fmt.Fprintf(w, "Пропускная способность: %.1f ответов/с за %s\n", float64(len(all))/seconds, r.elapsed.Round(time.Millisecond))
+	}
+	printLatency(w, "Время ответа", all)
+	for _, kind := range slices.Sorted(maps.Keys(r.rtt)) {
+		printLatency(w, "  "+kind, r.rtt[kind])
+	}
+
+	if len(r.errors) == 0 {
+		fmt.Fprintln(w, "Ошибок нет")
+		return
+	}
+	fmt.Fprintln(w, "Ошибки:")
+	for _, kind := range slices.Sorted(maps.Keys(r.errors)) {
+		fmt.Fprintf(w, "  %s: %d\n", kind, r.errors[kind])
+	}
+}
+
+// printLatency выводит перцентили и максимум отсортированных задержек.
+func printLatency(w io.Writer, title string, sorted []time.Duration) {
+	if len(sorted) == 0 {
+		fmt.Fprintf(w, "%s: нет данных\n", title)
+		return
+	}
+	fmt.Fprintf(w, "%s:", title)
+	for _, p := range reportPercentiles {
+		fmt.Fprintf(w, " p%g=%s", p, percentile(sorted, p).Round(time.Microsecond))
+	}
+	fmt.Fprintf(w, " max=%s (n=%d)\n", sorted[len(sorted)-1].Round(time.Microsecond), len(sorted))
+}
+
+// percentile возвращает p-й перцентиль отсортированных значений методом ближайшего ранга.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(float64(len(sorted))*p/100)) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/1ight181/messenger/pkg/client"
+)
+
+// presenceInterval — период опроса присутствия в режиме наблюдения.
+const presenceInterval = 2 * time.Second
+
+// runSend отправляет одно сообщение: в беседу через WebSocket или личное через REST API.
+func runSend(ctx context.Context, cfg config, args []string) error {
+	flags := flag.NewFlagSet("send", flag.ContinueOnError)
+	room := flags.String("room", "", "идентификатор беседы")
+	to := flags.String("to", "", "получатель личного сообщения")
+	text := flags.String("text", "", "текст сообщения (можно передать оставшимися аргументами)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *text == "" {
+		*text = strings.Join(flags.Args(), " ")
+	}
+	if *text == "" {
+		return errors.New("не задан текст сообщения")
+	}
+	if (*room == "") == (*to == "") {
+		return errors.New("нужно задать ровно один из флагов --room и --to")
+	}
+
+	if *to != "" {
+		rest, err := cfg.restClient()
+		if err != nil {
+			return err
+		}
+		online, err := rest.SendDirect(ctx, *to, *text)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Сообщение отправлено пользователю %s (%s)\n", *to, presenceLabel(online))
+		return nil
+	}
+
+	options, err := cfg.clientOptions()
+	if err != nil {
+		return err
+	}
+	options.DisableReconnect = true
+	c, err := client.Dial(ctx, options)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	response, err := c.SendData(ctx, *room, *text)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Сообщение %s отправлено в беседу %s\n", response.ID, *room)
+	return nil
+}
+
+// runHistory выводит историю беседы.
+func runHistory(ctx context.Context, cfg config, args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	room := flags.String("room", "", "идентификатор беседы")
+	limit := flags.Int("limit", 20, "число сообщений")
+	before := flags.String("before", "", "выводить сообщения до сообщения с этим идентификатором")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *room == "" {
+		return errors.New("не задан флаг --room")
+	}
+
+	rest, err := cfg.restClient()
+	if err != nil {
+		return err
+	}
+	history, err := rest.History(ctx, *room, *before, *limit)
+	if err != nil {
+		return err
+	}
+	for _, message := range history {
+		fmt.Println(formatMessage(message))
+	}
+	return nil
+}
+
+// runRooms выводит беседы пользователя.
+func runRooms(ctx context.Context, cfg config) error {
+	rest, err := cfg.restClient()
+	if err != nil {
+		return err
+	}
+	conversations, err := rest.Conversations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, conversation := range conversations {
+		fmt.Println(formatConversation(conversation))
+	}
+	return nil
+}
+
+// runInvite приглашает пользователей в беседу.
+func runInvite(ctx context.Context, cfg config, args []string) error {
+	flags := flag.NewFlagSet("invite", flag.ContinueOnError)
+	room := flags.String("room", "", "идентификатор беседы")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *room == "" {
+		return errors.New("не задан флаг --room")
+	}
+	userIDs := flags.Args()
+	if len(userIDs) == 0 {
+		return errors.New("не заданы пользователи")
+	}
+
+	rest, err := cfg.restClient()
+	if err != nil {
+		return err
+	}
+	conversation, err := rest.Invite(ctx, *room, userIDs...)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formatConversation(conversation))
+	return nil
+}
+
+// runPresence выводит, подключены ли пользователи. С флагом --watch опрашивает
+// присутствие до прерывания и выводит изменения.
+func runPresence(ctx context.Context, cfg config, args []string) error {
+	flags := flag.NewFlagSet("presence", flag.ContinueOnError)
+	watch := flags.Bool("watch", false, "следить за изменениями присутствия")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	userIDs := flags.Args()
+	if len(userIDs) == 0 {
+		return errors.New("не заданы пользователи")
+	}
+
+	rest, err := cfg.restClient()
+	if err != nil {
+		return err
+	}
+	presence, err := rest.Presence(ctx, userIDs...)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		fmt.Printf("%s: %s\n", userID, presenceLabel(presence[userID]))
+	}
+	if !*watch {
+		return nil
+	}
+
+	err = watchPresence(ctx, rest, userIDs, presence, func(userID string, online bool) {
+		fmt.Printf("%s %s: %s\n", time.Now().Format(time.TimeOnly), userID, presenceLabel(online))
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// watchPresence опрашивает присутствие пользователей каждые presenceInterval и вызывает
+// changed для пользователей, чье присутствие изменилось относительно last. Ошибки
+// опроса выводятся и не прерывают наблюдение. Возвращает ошибку отмены ctx.
+func watchPresence(ctx context.Context, rest *client.RESTClient, userIDs []string, last map[string]bool,
+	changed func(userID string, online bool)) error {
+	ticker := time.NewTicker(presenceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		presence, err := rest.Presence(ctx, userIDs...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Fprintln(os.Stderr, "Ошибка опроса присутствия:", err)
+			continue
+		}
+		for _, userID := range userIDs {
+			if presence[userID] != last[userID] {
+				changed(userID, presence[userID])
+			}
+		}
+		last = presence
+	}
+}
+
+// formatMessage форматирует сообщение для вывода: "[беседа] ЧЧ:ММ:СС отправитель: текст".
+func formatMessage(message client.Message) string {
+	place := "личное"
+	if message.Conversation != "" {
+		place = message.Conversation
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]", place)
+	if message.SentAt > 0 {
+		fmt.Fprintf(&b, " %s", time.UnixMilli(message.SentAt).Format(time.TimeOnly))
+	}
+	if message.From != "" {
+		fmt.Fprintf(&b, " %s:", message.From)
+	}
+	fmt.Fprintf(&b, " %s", message.Text)
+	return b.String()
+}
+
+// formatConversation форматирует беседу для вывода: идентификатор и участники.
+func formatConversation(conversation client.Conversation) string {
+	members := slices.Clone(conversation.Members)
+	slices.Sort(members)
+	return fmt.Sprintf("%s (%s)", conversation.ID, strings.Join(members, ", "))
+}
+
+func presenceLabel(online bool) string {
+	if online {
+		return "в сети"
+	}
+	return "не в сети"
+}
@@ -0,0 +1,189 @@
+// Команда messenger-cli — консольный клиент мессенджера для отладки сервера.
+//
+// Использование:
+//
+//	messenger-cli [флаги] [команда [флаги команды]]
+//
+// Команды:
+//
+//	repl                                  интерактивный режим (по умолчанию)
+//	send --room ID --text ТЕКСТ           отправить сообщение в беседу
+//	send --to ПОЛЬЗОВАТЕЛЬ --text ТЕКСТ   отправить личное сообщение
+//	history --room ID [--limit N]         показать историю беседы
+//	rooms                                 показать беседы пользователя
+//	invite --room ID ПОЛЬЗОВАТЕЛЬ...      пригласить пользователей в беседу
+//	presence [--watch] ПОЛЬЗОВАТЕЛЬ...    показать, подключены ли пользователи
+//
+// Первое сообщение в новую беседу создает ее, и отправитель становится ее участником;
+// писать в существующую беседу можно только после приглашения ее участника.
+//
+// Адрес сервера и токен можно задать переменными окружения MESSENGER_URL,
+// MESSENGER_REST_URL и MESSENGER_TOKEN.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/1ight181/messenger/pkg/client"
+)
+
+// defaultURL — адрес WebSocket-эндпоинта сервера по умолчанию.
+const defaultURL = "wss://127.0.0.1:8080/ws"
+
+// defaultRESTPath — путь REST API по умолчанию, если адрес REST API не задан.
+const defaultRESTPath = "/api"
+
+// config — общие флаги команд.
+type config struct {
+	url      string
+	restURL  string
+	token    string
+	caFile   string
+	insecure bool
+	codec    string
+	verbose  bool
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "Ошибка:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	var cfg config
+	flags := flag.NewFlagSet("messenger-cli", flag.ContinueOnError)
+	flags.StringVar(&cfg.url, "url", envOr("MESSENGER_URL", defaultURL), "адрес WebSocket-эндпоинта сервера")
+	flags.StringVar(&cfg.restURL, "rest", os.Getenv("MESSENGER_REST_URL"), "базовый адрес REST API (по умолчанию https://<хост из -url>/api)")
+	flags.StringVar(&cfg.token, "token", os.Getenv("MESSENGER_TOKEN"), "токен доступа")
+	flags.StringVar(&cfg.caFile, "ca", "", "PEM-файл с корневым сертификатом сервера")
+	flags.BoolVar(&cfg.insecure, "insecure", false, "не проверять сертификат сервера")
+	flags.StringVar(&cfg.codec, "codec", client.CodecJSON, "подпротокол кодека: messenger.json, messenger.msgpack или messenger.proto")
+	flags.BoolVar(&cfg.verbose, "v", false, "выводить журнал клиента")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Использование: messenger-cli [флаги] [repl|send|history|rooms|invite|presence] [флаги команды]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	command, commandArgs := "repl", flags.Args()
+	if len(commandArgs) > 0 {
+		command, commandArgs = commandArgs[0], commandArgs[1:]
+	}
+
+	switch command {
+	case "repl":
+		return runREPL(ctx, cfg)
+	case "send":
+		return runSend(ctx, cfg, commandArgs)
+	case "history":
+		return runHistory(ctx, cfg, commandArgs)
+	case "rooms":
+		return runRooms(ctx, cfg)
+	case "invite":
+		return runInvite(ctx, cfg, commandArgs)
+	case "presence":
+		return runPresence(ctx, cfg, commandArgs)
+	default:
+		flags.Usage()
+		return fmt.Errorf("неизвестная команда %q", command)
+	}
+}
+
+// clientOptions собирает настройки клиента из общих флагов.
+func (cfg config) clientOptions() (client.Options, error) {
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return client.Options{}, err
+	}
+	restURL, err := cfg.restBaseURL()
+	if err != nil {
+		return client.Options{}, err
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if cfg.verbose {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	}
+
+	return client.Options{
+		URL:       cfg.url,
+		Token:     cfg.token,
+		TLSConfig: tlsConfig,
+		Codec:     cfg.codec,
+		RESTURL:   restURL,
+		Logger:    logger,
+	}, nil
+}
+
+// restClient создает клиента для команд, которым нужен только REST API: соединение
+// WebSocket не открывается.
+func (cfg config) restClient() (*client.RESTClient, error) {
+	options, err := cfg.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	return client.NewREST(options), nil
+}
+
+func (cfg config) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.insecure}
+	if cfg.caFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(cfg.caFile)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения сертификата %s: %w", cfg.caFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("файл %s не содержит сертификатов PEM", cfg.caFile)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
+
+// restBaseURL возвращает адрес REST API: заданный флагом -rest или выведенный из -url.
+func (cfg config) restBaseURL() (string, error) {
+	if cfg.restURL != "" {
+		return cfg.restURL, nil
+	}
+
+	wsURL, err := url.Parse(cfg.url)
+	if err != nil {
+		return "", fmt.Errorf("некорректный адрес сервера %q: %w", cfg.url, err)
+	}
+	scheme := "https"
+	if wsURL.Scheme == "ws" {
+		scheme = "http"
+	}
+	return (&url.URL{Scheme: scheme, Host: wsURL.Host, Path: defaultRESTPath}).String(), nil
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/1ight181/messenger/pkg/client"
+)
+
+// replHistoryLimit — число сообщений истории, выводимых при входе в беседу.
+const replHistoryLimit = 10
+
+const replHelp = `Команды:
+  /join БЕСЕДА            выбрать текущую беседу и показать последние сообщения
+  /leave                  сбросить текущую беседу
+  /rooms                  показать беседы пользователя
+  /history [N]            показать последние N сообщений текущей беседы
+  /invite ПОЛЬЗОВАТЕЛЬ... пригласить пользователей в текущую беседу
+  /dm ПОЛЬЗОВАТЕЛЬ ТЕКСТ  отправить личное сообщение
+  /presence ПОЛЬЗОВАТЕЛЬ...  показать, подключены ли пользователи
+  /watch ПОЛЬЗОВАТЕЛЬ...  следить за присутствием пользователей
+  /unwatch                прекратить слежение
+  /info ТЕКСТ             отправить информационное сообщение
+  /help                   показать эту справку
+  /quit                   выйти
+Строка без "/" отправляется в текущую беседу. Первое сообщение в новую беседу создает
+ее; писать в существующую беседу можно только после приглашения ее участника.`
+
+// repl — состояние интерактивного режима.
+type repl struct {
+	client *client.Client
+	room   string
+
+	// outMu упорядочивает вывод входящих сообщений и ответов на команды.
+	outMu sync.Mutex
+	out   io.Writer
+
+	stopWatch context.CancelFunc
+}
+
+// runREPL подключается к серверу и читает команды со стандартного ввода до /quit,
+// конца ввода или прерывания.
+func runREPL(ctx context.Context, cfg config) error {
+	options, err := cfg.clientOptions()
+	if err != nil {
+		return err
+	}
+
+	r := &repl{out: os.Stdout}
+	options.OnMessage = func(message client.Message) {
+		r.println(formatMessage(message))
+	}
+	options.OnConnect = func() {
+		r.println("* подключено к серверу")
+	}
+	options.OnDisconnect = func(err error) {
+		r.println("* соединение разорвано:", err)
+	}
+
+	r.client, err = client.Dial(ctx, options)
+	if err != nil {
+		return err
+	}
+	defer r.client.Close()
+	defer r.unwatch()
+
+	r.println(`Введите /help для списка команд.`)
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	for {
+		r.prompt()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.client.Done():
+			return r.client.Err()
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			if quit := r.execute(ctx, strings.TrimSpace(line)); quit {
+				return nil
+			}
+		}
+	}
+}
+
+// execute выполняет строку ввода. Возвращает true, если нужно выйти.
+func (r *repl) execute(ctx context.Context, line string) bool {
+	if line == "" {
+		return false
+	}
+	if !strings.HasPrefix(line, "/") {
+		r.sendRoom(ctx, line)
+		return false
+	}
+
+	command, rest, _ := strings.Cut(line[1:], " ")
+	rest = strings.TrimSpace(rest)
+	args := strings.Fields(rest)
+
+	switch command {
+	case "quit", "exit":
+		return true
+	case "help":
+		r.println(replHelp)
+	case "join":
+		if len(args) != 1 {
+			r.println("Использование: /join БЕСЕДА")
+			return false
+		}
+		r.room = args[0]
+		r.history(ctx, replHistoryLimit)
+	case "leave":
+		r.room = ""
+	case "rooms":
+		r.rooms(ctx)
+	case "history":
+		limit := replHistoryLimit
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n <= 0 {
+				r.println("Использование: /history [N]")
+				return false
+			}
+			limit = n
+		}
+		r.history(ctx, limit)
+	case "invite":
+		if len(args) == 0 {
+			r.println("Использование: /invite ПОЛЬЗОВАТЕЛЬ...")
+			return false
+		}
+		r.invite(ctx, args)
+	case "dm":
+		userID, text, _ := strings.Cut(rest, " ")
+		if userID == "" || strings.TrimSpace(text) == "" {
+			r.println("Использование: /dm ПОЛЬЗОВАТЕЛЬ ТЕКСТ")
+			return false
+		}
+		r.sendDirect(ctx, userID, strings.TrimSpace(text))
+	case "presence":
+		if len(args) == 0 {
+			r.println("Использование: /presence ПОЛЬЗОВАТЕЛЬ...")
+			return false
+		}
+		r.presence(ctx, args)
+	case "watch":
+		if len(args) == 0 {
+			r.println("Использование: /watch ПОЛЬЗОВАТЕЛЬ...")
+			return false
+		}
+		r.watch(ctx, args)
+	case "unwatch":
+		r.unwatch()
+	case "info":
+		if rest == "" {
+			r.println("Использование: /info ТЕКСТ")
+			return false
+		}
+		r.sendInfo(ctx, rest)
+	default:
+		r.println("Неизвестная команда, введите /help")
+	}
+	return false
+}
+
+func (r *repl) sendRoom(ctx context.Context, text string) {
+	if r.room == "" {
+		r.println("Не выбрана беседа, введите /join БЕСЕДА или /dm ПОЛЬЗОВАТЕЛЬ ТЕКСТ")
+		return
+	}
+	if _, err := r.client.SendData(ctx, r.room, text); err != nil {
+		r.printError(err)
+	}
+}
+
+func (r *repl) sendInfo(ctx context.Context, text string) {
+	response, err := r.client.SendInfo(ctx, text)
+	if err != nil {
+		r.printError(err)
+		return
+	}
+	r.println("* ответ сервера:", response.Text)
+}
+
+func (r *repl) sendDirect(ctx context.Context, userID, text string) {
+	online, err := r.client.SendDirect(ctx, userID, text)
+	if err != nil {
+		r.printError(err)
+		return
+	}
+	r.println("* отправлено пользователю", userID+",", presenceLabel(online))
+}
+
+func (r *repl) history(ctx context.Context, limit int) {
+	if r.room == "" {
+		r.println("Не выбрана беседа, введите /join БЕСЕДА")
+		return
+	}
+	history, err := r.client.History(ctx, r.room, "", limit)
+	if err != nil {
+		r.printError(err)
+		return
+	}
+	for _, message := range history {
+		r.println(formatMessage(message))
+	}
+}
+
+func (r *repl) invite(ctx context.Context, userIDs []string) {
+	if r.room == "" {
+		r.println("Не выбрана беседа, введите /join БЕСЕДА")
+		return
+	}
+	conversation, err := r.client.Invite(ctx, r.room, userIDs...)
+	if err != nil {
+		r.printError(err)
+		return
+	}
+	r.println("*", formatConversation(conversation))
+}
+
+func (r *repl) rooms(ctx context.Context) {
+	conversations, err := r.client.Conversations(ctx)
+	if err != nil {
+		r.printError(err)
+		return
+	}
+	if len(conversations) == 0 {
+		r.println("* бесед нет")
+	}
+	for _, conversation := range conversations {
+		r.println(formatConversation(conversation))
+	}
+}
+
+func (r *repl) presence(ctx context.Context, userIDs []string) {
+	presence, err := r.client.Presence(ctx, userIDs...)
+	if err != nil {
+		r.printError(err)
+		return
+	}
+	for _, userID := range userIDs {
+		r.println(userID+":", presenceLabel(presence[userID]))
+	}
+}
+
+// watch запускает слежение за присутствием пользователей, заменяя предыдущее.
+func (r *repl) watch(ctx context.Context, userIDs []string) {
+	r.unwatch()
+
+	presence, err := r.client.Presence(ctx, userIDs...)
+	if err != nil {
+		r.printError(err)
+		return
+	}
+	for _, userID := range userIDs {
+		r.println(userID+":", presenceLabel(presence[userID]))
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	r.stopWatch = cancel
+	go watchPresence(watchCtx, r.client.RESTClient, userIDs, presence, func(userID string, online bool) {
+		r.println("*", userID+":", presenceLabel(online))
+	})
+}
+
+func (r *repl) unwatch() {
+	if r.stopWatch != nil {
+		r.stopWatch()
+		r.stopWatch = nil
+	}
+}
+
+func (r *repl) prompt() {
+	r.outMu.Lock()
+	defer r.outMu.Unlock()
+
+	if r.room != "" {
+		fmt.Fprintf(r.out, "%s> ", r.room)
+	} else {
+		fmt.Fprint(r.out, "> ")
+	}
+}
+
+func (r *repl) println(args ...any) {
+	r.outMu.Lock()
+	defer r.outMu.Unlock()
+
+	fmt.Fprintln(r.out, args...)
+}
+
+// printError выводит ошибку; для отказа сервера — текст ответа и ошибки полей.
+func (r *repl) printError(err error) {
+	var responseErr *client.ResponseError
+	if !errors.As(err, &responseErr) {
+		r.println("Ошибка:", err)
+		return
+	}
+	r.println("Сервер отклонил сообщение:", responseErr.Response.Text)
+	for _, fieldErr := range responseErr.Response.Errors {
+		if fieldErr.Field != "" {
+			r.println("  -", fieldErr.Field+":", fieldErr.Message)
+		} else {
+			r.println("  -", fieldErr.Message)
+		}
+	}
+}
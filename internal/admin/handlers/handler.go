@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"messenger/internal/cluster"
+	"messenger/internal/logging"
+	"messenger/internal/server/interfaces"
+	"messenger/internal/ws/traffic"
+	"net/http"
+)
+
+// ConnectionCounter сообщает число подключенных пользователей и их соединений.
+type ConnectionCounter interface {
+	Connections() (users int, connections int)
+}
+
+// ClusterDirectory сообщает состояние узлов кластера из каталога подключений.
+type ClusterDirectory interface {
+	Nodes() []cluster.NodeInfo
+}
+
+// AdminHandler обслуживает служебные эндпоинты для операторов:
+//   - GET {prefix}/stats — число подключений и статистика трафика.
+//   - GET {prefix}/cluster — узлы кластера, число их пользователей и сроки аренды.
+type AdminHandler struct {
+	connections ConnectionCounter
+	cluster     ClusterDirectory
+	logger      *slog.Logger
+}
+
+func New(connections ConnectionCounter, directory ClusterDirectory, logger *slog.Logger) *AdminHandler {
+	ah := &AdminHandler{
+		connections: connections,
+		cluster:     directory,
+	}
+	ah.logger = logging.Component(logger, ah.Tag())
+	return ah
+}
+
+// Tag возвращает строковый идентификатор для AdminHandler.
+func (*AdminHandler) Tag() string {
+	return "ADMIN_HANDLER"
+}
+
+// Register регистрирует обработчики в группе маршрутов администратора.
+func (ah *AdminHandler) Register(routes interfaces.Routes) {
+	routes.HandleFunc("GET /stats", ah.handleStats)
+	if ah.cluster != nil {
+		routes.HandleFunc("GET /cluster", ah.handleCluster)
+	}
+}
+
+type statsResponse struct {
+	Users       int              `json:"users"`
+	Connections int              `json:"connections"`
+	Traffic     traffic.Snapshot `json:"traffic"`
+}
+
+func (ah *AdminHandler) handleStats(w http.ResponseWriter, r *http.Request) {
+	users, connections := ah.connections.Connections()
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(statsResponse{
+		Users:       users,
+		Connections: connections,
+		Traffic:     traffic.Total.Snapshot(),
+	})
+	if err != nil {
+		ah.logger.Warn("Ошибка записи ответа", slog.Any("error", err))
+	}
+}
+
+type clusterResponse struct {
+	Nodes []cluster.NodeInfo `json:"nodes"`
+}
+
+func (ah *AdminHandler) handleCluster(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(clusterResponse{
+		Nodes: ah.cluster.Nodes(),
+	})
+	if err != nil {
+		ah.logger.Warn("Ошибка записи ответа", slog.Any("error", err))
+	}
+}
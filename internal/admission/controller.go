@@ -0,0 +1,132 @@
+package admission
+
+import (
+	"errors"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCapacity возвращается, если на сервере открыто максимальное число соединений.
+	ErrCapacity = errors.New("достигнуто максимальное число соединений сервера")
+	// ErrIPLimit возвращается, если с IP-адреса открыто максимальное число соединений.
+	ErrIPLimit = errors.New("достигнуто максимальное число соединений с IP-адреса")
+	// ErrUserLimit возвращается, если у пользователя открыто максимальное число соединений.
+	ErrUserLimit = errors.New("достигнуто максимальное число соединений пользователя")
+)
+
+type Options struct {
+	// MaxConnections — максимальное число соединений сервера. 0 — без ограничения.
+	MaxConnections int
+	// MaxPerIP — максимальное число соединений с одного IP-адреса. 0 — без ограничения.
+	MaxPerIP int
+	// MaxPerUser — максимальное число соединений одного пользователя. 0 — без ограничения.
+	MaxPerUser int
+	// Trusted — доверенные внутренние сети. На соединения с адресов из них не действуют
+	// ограничения по IP-адресу и пользователю, но действует общее ограничение сервера.
+	Trusted []netip.Prefix
+	// RetryAfter — время, через которое отклоненному клиенту предлагается повторить попытку.
+	RetryAfter time.Duration
+}
+
+// Controller ограничивает число одновременно открытых соединений сервера,
+// одного IP-адреса и одного пользователя.
+type Controller struct {
+	mu      sync.Mutex
+	options Options
+	total   int
+	perIP   map[string]int
+	perUser map[string]int
+}
+
+// New создает Controller с указанными ограничениями.
+func New(options Options) *Controller {
+	return &Controller{
+		options: options,
+		perIP:   make(map[string]int),
+		perUser: make(map[string]int),
+	}
+}
+
+// RetryAfter возвращает время, через которое отклоненному клиенту предлагается повторить попытку.
+func (c *Controller) RetryAfter() time.Duration {
+	return c.options.RetryAfter
+}
+
+// Admit резервирует место для соединения пользователя userID с адреса ip.
+// Проверяет общее ограничение сервера, затем, если адрес не доверенный, ограничения
+// IP-адреса и пользователя.
+//
+// Возвращает:
+//   - func(): Функция освобождения места, которую нужно вызвать при закрытии соединения.
+//   - error: ErrCapacity, ErrIPLimit или ErrUserLimit, если соединение не допущено.
+//
+// nil Controller допускает все соединения.
+func (c *Controller) Admit(ip, userID string) (func(), error) {
+	if c == nil {
+		return func() {}, nil
+	}
+	trusted := c.trusted(ip)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.options.MaxConnections > 0 && c.total >= c.options.MaxConnections {
+		return nil, ErrCapacity
+	}
+	if !trusted {
+		if c.options.MaxPerIP > 0 && c.perIP[ip] >= c.options.MaxPerIP {
+			return nil, ErrIPLimit
+		}
+		if c.options.MaxPerUser > 0 && c.perUser[userID] >= c.options.MaxPerUser {
+			return nil, ErrUserLimit
+		}
+	}
+
+	c.total++
+	c.perIP[ip]++
+	c.perUser[userID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { c.release(ip, userID) })
+	}, nil
+}
+
+func (c *Controller) release(ip, userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.total--
+	decrement(c.perIP, ip)
+	decrement(c.perUser, userID)
+}
+
+// trusted сообщает, входит ли ip в одну из доверенных сетей.
+func (c *Controller) trusted(ip string) bool {
+	if len(c.options.Trusted) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range c.options.Trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// decrement уменьшает счетчик key и удаляет его при обнулении,
+// чтобы карты не росли с числом когда-либо подключавшихся клиентов.
+func decrement(counts map[string]int, key string) {
+	if counts[key] <= 1 {
+		delete(counts, key)
+		return
+	}
+	counts[key]--
+}
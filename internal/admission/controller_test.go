@@ -0,0 +1,127 @@
+package admission
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// admit допускает соединение и завершает тест, если в нем отказано.
+func admit(t *testing.T, c *Controller, ip, userID string) func() {
+	t.Helper()
+
+	release, err := c.Admit(ip, userID)
+	if err != nil {
+		t.Fatalf("Соединение %s с %s не допущено: %v", userID, ip, err)
+	}
+	return release
+}
+
+// reject проверяет, что в соединении отказано с ошибкой want.
+func reject(t *testing.T, c *Controller, ip, userID string, want error) {
+	t.Helper()
+
+	if _, err := c.Admit(ip, userID); !errors.Is(err, want) {
+		t.Fatalf("Соединение %s с %s: получена ошибка %v, ожидалась %v", userID, ip, err, want)
+	}
+}
+
+func TestMaxConnections(t *testing.T) {
+	c := New(Options{MaxConnections: 2})
+
+	admit(t, c, "10.0.0.1", "alice")
+	release := admit(t, c, "10.0.0.2", "bob")
+	reject(t, c, "10.0.0.3", "carol", ErrCapacity)
+
+	release()
+	admit(t, c, "10.0.0.3", "carol")
+}
+
+func TestMaxPerIP(t *testing.T) {
+	c := New(Options{MaxPerIP: 2})
+
+	admit(t, c, "10.0.0.1", "alice")
+	release := admit(t, c, "10.0.0.1", "bob")
+	reject(t, c, "10.0.0.1", "carol", ErrIPLimit)
+	admit(t, c, "10.0.0.2", "carol")
+
+	release()
+	admit(t, c, "10.0.0.1", "carol")
+}
+
+func TestMaxPerUser(t *testing.T) {
+	c := New(Options{MaxPerUser: 1})
+
+	release := admit(t, c, "10.0.0.1", "alice")
+	reject(t, c, "10.0.0.2", "alice", ErrUserLimit)
+	admit(t, c, "10.0.0.1", "bob")
+
+	release()
+	admit(t, c, "10.0.0.2", "alice")
+}
+
+func TestTrustedNetworks(t *testing.T) {
+	c := New(Options{
+		MaxConnections: 3,
+		MaxPerIP:       1,
+		MaxPerUser:     1,
+		Trusted:        []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	})
+
+	// На доверенные адреса, в том числе в IPv4-отображенной форме, не действуют
+	// ограничения IP-адреса и пользователя.
+	admit(t, c, "10.0.0.1", "service")
+	admit(t, c, "10.0.0.1", "service")
+	admit(t, c, "::ffff:10.0.0.1", "service")
+
+	// Общее ограничение сервера действует и на них.
+	reject(t, c, "10.0.0.1", "service", ErrCapacity)
+}
+
+func TestTrustedConnectionsCountedForOthers(t *testing.T) {
+	c := New(Options{
+		MaxPerIP:   1,
+		MaxPerUser: 1,
+		Trusted:    []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	})
+
+	// Соединения с доверенных адресов учитываются в счетчиках пользователя:
+	// тот же пользователь с недоверенного адреса упирается в свое ограничение.
+	admit(t, c, "10.0.0.1", "alice")
+	reject(t, c, "192.0.2.1", "alice", ErrUserLimit)
+	admit(t, c, "192.0.2.1", "bob")
+	reject(t, c, "192.0.2.1", "carol", ErrIPLimit)
+}
+
+func TestReleaseIsIdempotent(t *testing.T) {
+	c := New(Options{MaxConnections: 1, MaxPerIP: 1, MaxPerUser: 1})
+
+	release := admit(t, c, "10.0.0.1", "alice")
+	release()
+	release()
+
+	if c.total != 0 || len(c.perIP) != 0 || len(c.perUser) != 0 {
+		t.Fatalf("После освобождения осталось соединений %d, адресов %d, пользователей %d",
+			c.total, len(c.perIP), len(c.perUser))
+	}
+
+	// Повторный вызов не освобождает место чужого соединения.
+	admit(t, c, "10.0.0.1", "alice")
+	release()
+	reject(t, c, "10.0.0.1", "alice", ErrCapacity)
+}
+
+func TestNilControllerAdmitsEverything(t *testing.T) {
+	var c *Controller
+	for i := 0; i < 10; i++ {
+		admit(t, c, "10.0.0.1", "alice")()
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	c := New(Options{RetryAfter: 3 * time.Second})
+	if got := c.RetryAfter(); got != 3*time.Second {
+		t.Fatalf("RetryAfter = %s, ожидалось 3s", got)
+	}
+}
@@ -1,73 +1,315 @@
 package app
 
 import (
-	"log"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
 
-	viperprov "messenger/internal/config/providers/viper"
+	"messenger/internal/config/models"
+	"messenger/internal/messaging/interfaces"
+	serverinterfaces "messenger/internal/server/interfaces"
+	ws "messenger/internal/ws"
+	wsinterfaces "messenger/internal/ws/interfaces"
 
 	processor "messenger/internal/messaging/processor"
 	"messenger/internal/messaging/receiver"
 	"messenger/internal/messaging/sender"
 )
 
-// Run инициализирует и запускает приложение WebSocket-сервера.
-// Выполняются следующие шаги:
-// 1. Загружается конфигурационный файл с использованием ViperConfigProvider.
-//   - Если переменная окружения CONFIG_PATH не задана, используется путь по умолчанию.
+// tracingShutdownTimeout — время на выгрузку накопленных спанов при остановке приложения.
+const tracingShutdownTimeout = 5 * time.Second
+
+// Тексты ответов обработчика сообщений по умолчанию.
+const (
+	defaultErrorResponseText = "Ошибка получена и обработана"
+	defaultInfoResponseText  = "Информационное собщение получено и обработано"
+	defaultDataResponseText  = "Сообщение с данными получено и обработано"
+)
+
+// Hook — обработчик этапа жизненного цикла приложения.
+type Hook func(ctx context.Context) error
+
+// Options — параметры сборки приложения. Обязательна только конфигурация,
+// остальные поля заменяют компоненты по умолчанию.
+type Options struct {
+	// Config — конфигурация приложения.
+	Config *models.Config
+	// Logger — логгер приложения. Если не задан, создается по config.Log (см. LoadLogger);
+	// логгер по умолчанию приложение не меняет.
+	Logger *slog.Logger
+	// TLSConfig — настройки TLS сервера. Если не заданы, загружается сертификат из config.Certificate.
+	TLSConfig *tls.Config
+	// Listener — слушатель сервера. Если не задан, Start слушает адрес из config.WebSocket.
+	// TLS применяется поверх слушателя.
+	Listener net.Listener
+
+	// ProcessorOptions — тексты ответов обработчика сообщений; пустые тексты заменяются
+	// текстами по умолчанию. Хранилище, маршрутизатор и логгер задает приложение.
+	ProcessorOptions processor.Options
+	// SenderOptions и ReceiverOptions — опции отправителя и получателя WebSocket-сообщений;
+	// сжатие, размер фрейма и правила проверки задает приложение по конфигурации.
+	SenderOptions   sender.Options
+	ReceiverOptions receiver.Options
+
+	// NewProcessor создает обработчик сообщений вместо processor.NewMessageProcessor
+	// для WebSocket-соединений, сессий резервных транспортов и REST API.
+	NewProcessor func(options processor.Options) interfaces.MessageProcessor
+	// NewSender создает отправителя сообщений WebSocket-соединения вместо sender.New.
+	NewSender func(options sender.Options) wsinterfaces.WebSocketSender
+	// NewReceiver создает получателя сообщений WebSocket-соединения вместо receiver.New.
+	NewReceiver func(options receiver.Options) wsinterfaces.WebSocketReceiver
+
+	// Routes регистрирует дополнительные HTTP-маршруты сервера.
+	Routes func(routes serverinterfaces.Routes)
+
+	// OnStart вызываются по порядку после запуска сервера. Ошибка останавливает
+	// приложение и возвращается из Start.
+	OnStart []Hook
+	// OnStop вызываются в обратном порядке после остановки сервера.
+	OnStop []Hook
+}
+
+// App — приложение мессенджера: WebSocket-сервер, резервные транспорты, REST API
+// и служебные эндпоинты, собранные по конфигурации.
 //
-// 2. Обрабатываются возможные ошибки при загрузке конфигурации, включая:
-//   - Ошибки пути
-//   - Отсутствие конфигурационного файла
-//   - Ошибки разбора конфигурации
+//	application, err := app.New(app.Options{Config: config})
+//	if err != nil { ... }
+//	if err := application.Start(ctx); err != nil { ... }
+//	defer application.Stop(context.Background())
+type App struct {
+	config          *models.Config
+	logger          *slog.Logger
+	service         *ws.WebsocketService
+	listener        net.Listener
+	onStart         []Hook
+	onStop          []Hook
+	shutdownTracing func(context.Context) error
+
+	mu       sync.Mutex
+	started  bool
+	stopped  bool
+	served   chan struct{}
+	serveErr error
+}
+
+// New собирает приложение:
+//  1. Создает логгер приложения с настроенными уровнем и форматом (текст или JSON),
+//     если он не передан. Логгер передается компонентам через фабрики и опции,
+//     глобальный логгер по умолчанию не меняется.
+//  2. Настраивает трассировку: спаны апгрейда, получения, обработки, сохранения, рассылки
+//     и отправки сообщений экспортируются в stdout, файл или коллектор OTLP.
+//  3. Загружает TLS-сертификат, если настройки TLS не переданы.
+//  4. Собирает HTTP-сервер с маршрутизатором: WebSocket-эндпоинт с апгрейдером
+//     и фабрикой обработчиков, проверки состояния и готовности (сертификат, хранилище бесед,
+//     шина, остановка сервера), метрики, служебные эндпоинты, дополнительные маршруты
+//     и, если включены, резервные транспорты SSE и long-polling, REST API и загрузка вложений.
 //
-// 3. Загружается TLS-сертификат для безопасной связи.
-// 4. Настраиваются параметры WebSocket, включая хост, порт, режим отладки и недопустимые источники.
-// 5. Создается и инициализируется обработчик WebSocket с необходимыми компонентами:
-//   - Upgrader для WebSocket-соединений
-//   - Отправитель, получатель и обработчик сообщений.
+// Все транспорты используют общие аутентификацию, хранилище бесед и маршрутизатор сообщений.
+// Маршрутизатор доставляет сообщения через шину (в памяти процесса или Redis Pub/Sub),
+// поэтому несколько экземпляров сервера доставляют сообщения пользователям друг друга,
+// а каталог подключений кластера знает, к каким узлам подключены пользователи.
 //
-// 6. Настраивается HTTP-сервер с конфигурацией TLS и обработчиком WebSocket.
-// 7. Запускается WebSocket-сервер.
+// Если сборка завершилась ошибкой, уже созданные компоненты (соединение с Redis,
+// каталог подключений, фоновые службы вложений и превью ссылок, трассировка) закрываются.
 //
-// Эта функция регистрирует фатальные ошибки и завершает приложение, если возникают
-// критические проблемы во время инициализации или запуска.
-func Run() {
-	appConfigOptions := AppConfigOptions{
-		Provider:    &viperprov.ViperConfigProvider{},
-		FileName:    "config",
-		FileType:    "yaml",
-		EnvVar:      "CONFIG_PATH",
-		DefaultPath: "../config",
-	}
-
-	config, err := loadAppConfig(appConfigOptions)
+// Сервер не запускается: его запускает Start.
+func New(options Options) (*App, error) {
+	if options.Config == nil {
+		return nil, errors.New("конфигурация приложения не задана")
+	}
+	config := options.Config
+
+	logger := options.Logger
+	if logger == nil {
+		logger = LoadLogger(config.Log)
+	}
+
+	shutdownTracing, err := loadAppTracing(config.Tracing, logger)
 	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+		return nil, fmt.Errorf("ошибка настройки трассировки: %w", err)
+	}
+
+	tlsConfig := options.TLSConfig
+	if tlsConfig == nil {
+		certificateConfig, err := loadAppCertificateConfig(config.Certificate)
+		if err != nil {
+			shutdownTracing(context.Background())
+			return nil, err
+		}
+		tlsConfig = &certificateConfig
+	}
+
+	processorOptions := options.ProcessorOptions
+	if processorOptions.ErrorResponseText == "" {
+		processorOptions.ErrorResponseText = defaultErrorResponseText
+	}
+	if processorOptions.InfoResponseText == "" {
+		processorOptions.InfoResponseText = defaultInfoResponseText
+	}
+	if processorOptions.DataResponseText == "" {
+		processorOptions.DataResponseText = defaultDataResponseText
 	}
 
-	tlsConfig, err := loadAppCertificateConfig(config.Certificate)
+	service, err := loadAppWebSocketService(WebSocketServiceOptions{
+		Config:            config.WebSocket,
+		FallbackConfig:    config.Fallback,
+		AuthConfig:        config.Auth,
+		RESTConfig:        config.REST,
+		StorageConfig:     config.Storage,
+		RoutesConfig:      config.Routes,
+		ShutdownConfig:    config.Shutdown,
+		RateLimitConfig:   config.RateLimit,
+		MessagesConfig:    config.Messages,
+		BusConfig:         config.Bus,
+		ClusterConfig:     config.Cluster,
+		AttachmentsConfig: config.Attachments,
+		LinkPreviewConfig: config.LinkPreview,
+		Logger:            logger,
+		TLSConfig:         tlsConfig,
+		SenderOptions:     options.SenderOptions,
+		ReceiverOptions:   options.ReceiverOptions,
+		ProcessorOptions:  processorOptions,
+		NewSender:         options.NewSender,
+		NewReceiver:       options.NewReceiver,
+		NewProcessor:      options.NewProcessor,
+		Routes:            options.Routes,
+	})
 	if err != nil {
-		log.Fatalf("Ошибка загрузки сертификата: %v", err)
+		shutdownTracing(context.Background())
+		return nil, fmt.Errorf("ошибка настройки WebSocket-сервиса: %w", err)
 	}
 
-	wsProcessorOptions :=
-		processor.Options{
-			ErrorResponseText: "Ошибка получена и обработана",
-			InfoResponseText:  "Информационное собщение получено и обработано",
-			DataResponseText:  "Сообщение с данными получено и обработано",
+	return &App{
+		config:          config,
+		logger:          logger,
+		service:         service,
+		listener:        options.Listener,
+		onStart:         options.OnStart,
+		onStop:          options.OnStop,
+		shutdownTracing: shutdownTracing,
+		served:          make(chan struct{}),
+	}, nil
+}
+
+// Server возвращает HTTP-сервер приложения. Позволяет обслуживать его на своем сервере
+// или слушателе, например в httptest; остановка в этом случае тоже выполняется через Stop.
+func (a *App) Server() *http.Server {
+	return a.service.Server()
+}
+
+// Addr возвращает адрес, на котором слушает запущенный сервер, или nil до вызова Start.
+func (a *App) Addr() net.Addr {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.listener == nil || !a.started {
+		return nil
+	}
+	return a.listener.Addr()
+}
+
+// Start запускает сервер в отдельной горутине и вызывает хуки OnStart.
+// Возвращает ошибку, если не удалось занять адрес или хук завершился с ошибкой;
+// в последнем случае приложение останавливается. Повторный запуск не поддерживается.
+func (a *App) Start(ctx context.Context) error {
+	a.mu.Lock()
+	if a.started || a.stopped {
+		a.mu.Unlock()
+		return errors.New("приложение уже запущено")
+	}
+	if a.listener == nil {
+		listener, err := net.Listen("tcp", a.service.Server().Addr)
+		if err != nil {
+			a.mu.Unlock()
+			return fmt.Errorf("ошибка запуска сервера: %w", err)
+		}
+		a.listener = listener
+	}
+	a.started = true
+	a.mu.Unlock()
+
+	go func() {
+		defer close(a.served)
+		if err := a.service.Serve(a.listener); err != nil {
+			a.logger.Error("Ошибка работы сервера", slog.Any("error", err))
+			a.serveErr = err
 		}
+	}()
 
-	wsSenderOptions := sender.Options{}
-	wsReceiverOptions := receiver.Options{}
+	for _, hook := range a.onStart {
+		if err := hook(ctx); err != nil {
+			return errors.Join(fmt.Errorf("ошибка хука запуска: %w", err), a.Stop(context.Background()))
+		}
+	}
+	return nil
+}
+
+// Stop останавливает приложение: сервер снимает готовность, чтобы балансировщик вывел
+// его из ротации, закрывает открытые WebSocket-соединения с подсказкой о переподключении
+// и завершает HTTP-запросы (см. ws.WebsocketService.Shutdown). Затем вызываются хуки OnStop
+// и выгружаются накопленные спаны. Отмена ctx сокращает ожидание клиентов.
+//
+// Возвращает объединенные ошибки остановки, хуков и работы сервера. Повторные вызовы
+// ничего не делают.
+func (a *App) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	if a.stopped {
+		a.mu.Unlock()
+		return nil
+	}
+	a.stopped = true
+	started := a.started
+	a.mu.Unlock()
+
+	var errs []error
+	if err := a.service.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("ошибка остановки сервера: %w", err))
+	}
+	if started {
+		select {
+		case <-a.served:
+			if a.serveErr != nil {
+				errs = append(errs, fmt.Errorf("ошибка работы сервера: %w", a.serveErr))
+			}
+		case <-ctx.Done():
+			errs = append(errs, ctx.Err())
+		}
+	}
+
+	for i := len(a.onStop) - 1; i >= 0; i-- {
+		if err := a.onStop[i](ctx); err != nil {
+			errs = append(errs, fmt.Errorf("ошибка хука остановки: %w", err))
+		}
+	}
+
+	tracingCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tracingShutdownTimeout)
+	defer cancel()
+	if err := a.shutdownTracing(tracingCtx); err != nil {
+		a.logger.Error("Ошибка выгрузки спанов трассировки", slog.Any("error", err))
+		errs = append(errs, fmt.Errorf("ошибка выгрузки спанов трассировки: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run запускает приложение и работает до отмены ctx (например, по сигналу завершения)
+// или до ошибки сервера, после чего останавливает приложение.
+func (a *App) Run(ctx context.Context) error {
+	if err := a.Start(ctx); err != nil {
+		return err
+	}
 
-	webSocketServiceOptions := WebSocketServiceOptions{
-		Config:           config.WebSocket,
-		TLSConfig:        &tlsConfig,
-		SenderOptions:    wsSenderOptions,
-		ReceiverOptions:  wsReceiverOptions,
-		ProcessorOptions: wsProcessorOptions,
+	select {
+	case <-ctx.Done():
+		a.logger.Info("Получен сигнал завершения")
+	case <-a.served:
 	}
-	wsService := loadAppWebSocketService(webSocketServiceOptions)
 
-	wsService.StartServer()
+	return a.Stop(context.WithoutCancel(ctx))
 }
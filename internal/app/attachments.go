@@ -0,0 +1,71 @@
+package app
+
+import (
+	"log/slog"
+	"messenger/internal/attachments"
+	"messenger/internal/attachments/blobstore"
+	athandlers "messenger/internal/attachments/handlers"
+	authinterfaces "messenger/internal/auth/interfaces"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+	"messenger/internal/messaging/interfaces"
+	"messenger/internal/server/middleware"
+	"messenger/internal/server/router"
+)
+
+type AttachmentsOptions struct {
+	Config        models.Attachments
+	Authenticator authinterfaces.Authenticator
+	Store         interfaces.ConversationStore
+	Logger        *slog.Logger
+}
+
+// loadAppAttachments создает службу вложений с хранилищем файлов на локальном диске,
+// если вложения включены в конфигурации. Служба прикрепляет вложения к сообщениям
+// всех транспортов, а скачать вложение могут участники бесед, в которые оно отправлено.
+// Из изображений удаляются метаданные, для них создаются миниатюры настроенных размеров.
+//
+// Возвращает службу, которую нужно закрыть при остановке сервера, или nil,
+// если вложения выключены.
+func loadAppAttachments(opts AttachmentsOptions) (*attachments.Service, error) {
+	enabled, prefix, dir, maxSize, _, userQuota, uploadTimeout, allowedTypes := loaders.LoadAttachments(opts.Config)
+	if !enabled {
+		return nil, nil
+	}
+
+	thumbnailSizes, maxPixels := loaders.LoadAttachmentImages(opts.Config.Images)
+
+	blobs, err := blobstore.NewLocal(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Logger.Info("Вложения включены",
+		slog.String("dir", dir), slog.Int64("max_size", maxSize), slog.Any("thumbnail_sizes", thumbnailSizes))
+	return attachments.New(attachments.Options{
+		Blobs:          blobs,
+		Store:          opts.Store,
+		URLPrefix:      prefix,
+		MaxSize:        maxSize,
+		UserQuota:      userQuota,
+		UploadTimeout:  uploadTimeout,
+		AllowedTypes:   allowedTypes,
+		ThumbnailSizes: thumbnailSizes,
+		MaxImagePixels: maxPixels,
+		Logger:         opts.Logger,
+	}), nil
+}
+
+// loadAppAttachmentRoutes регистрирует в маршрутизаторе обработчики загрузки
+// и скачивания вложений службы service.
+func loadAppAttachmentRoutes(httpRouter *router.Router, service *attachments.Service, opts AttachmentsOptions) {
+	_, prefix, _, _, maxChunkSize, _, _, _ := loaders.LoadAttachments(opts.Config)
+
+	attachmentHandler := athandlers.New(athandlers.Options{
+		Service:       service,
+		Authenticator: opts.Authenticator,
+		MaxChunkSize:  maxChunkSize,
+		Logger:        opts.Logger,
+	})
+	attachmentHandler.Register(httpRouter.Group(prefix, middleware.Recover(opts.Logger), middleware.AccessLog(opts.Logger)))
+}
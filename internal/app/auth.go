@@ -0,0 +1,21 @@
+package app
+
+import (
+	"log/slog"
+	"messenger/internal/auth/authenticators"
+	authinterfaces "messenger/internal/auth/interfaces"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+)
+
+// loadAppAuthenticator создает аутентификатор клиентов по конфигурации.
+// При выключенной аутентификации клиенты получают анонимные идентификаторы.
+func loadAppAuthenticator(authConfig models.Auth, logger *slog.Logger) authinterfaces.Authenticator {
+	enabled, users := loaders.LoadAuth(authConfig)
+	if !enabled {
+		logger.Warn("Аутентификация выключена, клиенты подключаются анонимно")
+		return authenticators.NewAnonymous()
+	}
+
+	return authenticators.NewToken(users)
+}
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+	"messenger/internal/messaging/bus"
+	"messenger/internal/messaging/interfaces"
+)
+
+// loadAppBus создает шину сообщений между узлами по конфигурации: шину в памяти
+// процесса для одного экземпляра сервера или Redis Pub/Sub для нескольких.
+func loadAppBus(busConfig models.Bus, logger *slog.Logger) (interfaces.Bus, error) {
+	busType, address, password, timeout := loaders.LoadBus(busConfig)
+
+	switch busType {
+	case "redis":
+		redisBus, err := bus.NewRedis(bus.RedisOptions{
+			Address:  address,
+			Password: password,
+			Timeout:  timeout,
+			Logger:   logger,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка подключения к Redis %s: %w", address, err)
+		}
+		logger.Info("Шина сообщений Redis подключена", slog.String("address", address))
+		return redisBus, nil
+	default:
+		return bus.NewMemory(), nil
+	}
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"messenger/internal/cluster"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+	"messenger/internal/messaging/interfaces"
+)
+
+// loadAppDirectory создает и запускает каталог подключений кластера поверх шины сообщений.
+func loadAppDirectory(clusterConfig models.Cluster, messageBus interfaces.Bus, logger *slog.Logger) (*cluster.Directory, error) {
+	nodeID, heartbeatInterval, leaseTTL := loaders.LoadCluster(clusterConfig)
+
+	directory := cluster.New(cluster.Options{
+		NodeID:            nodeID,
+		Bus:               messageBus,
+		HeartbeatInterval: heartbeatInterval,
+		LeaseTTL:          leaseTTL,
+		Logger:            logger,
+	})
+	if err := directory.Start(); err != nil {
+		return nil, fmt.Errorf("ошибка запуска каталога подключений кластера: %w", err)
+	}
+	return directory, nil
+}
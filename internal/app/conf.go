@@ -2,11 +2,13 @@ package app
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	models "messenger/internal/config/models"
 	confprov "messenger/internal/config/providers/interfaces"
 	"os"
 
+	viperprov "messenger/internal/config/providers/viper"
+
 	"github.com/spf13/viper"
 )
 
@@ -18,11 +20,28 @@ type AppConfigOptions struct {
 	DefaultPath string
 }
 
-func loadAppConfig(opts AppConfigOptions) (*models.Config, error) {
+// DefaultConfigOptions возвращает параметры загрузки конфигурации сервера:
+// файл config.yaml читается ViperConfigProvider из каталога CONFIG_PATH,
+// а если переменная окружения не задана — из ../config.
+func DefaultConfigOptions() AppConfigOptions {
+	return AppConfigOptions{
+		Provider:    &viperprov.ViperConfigProvider{},
+		FileName:    "config",
+		FileType:    "yaml",
+		EnvVar:      "CONFIG_PATH",
+		DefaultPath: "../config",
+	}
+}
+
+// LoadConfig загружает конфигурацию провайдером opts.Provider из каталога, заданного
+// переменной окружения opts.EnvVar или, если она не задана, opts.DefaultPath.
+// Ошибки пути, отсутствия файла и разбора конфигурации возвращаются с пояснением.
+func LoadConfig(opts AppConfigOptions) (*models.Config, error) {
 	path := os.Getenv(opts.EnvVar)
 	if path == "" {
 		path = opts.DefaultPath
-		log.Printf("Переменная окружения %s не задана, используется путь: %s", opts.EnvVar, path)
+		slog.Info("Переменная окружения не задана, используется путь по умолчанию",
+			slog.String("env", opts.EnvVar), slog.String("path", path))
 	}
 
 	config, err := opts.Provider.Load(
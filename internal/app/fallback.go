@@ -0,0 +1,71 @@
+package app
+
+import (
+	"log/slog"
+	authinterfaces "messenger/internal/auth/interfaces"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+	fbhandlers "messenger/internal/fallback/handlers"
+	"messenger/internal/fallback/sessions"
+	"messenger/internal/messaging/interfaces"
+	processor "messenger/internal/messaging/processor"
+	"messenger/internal/messaging/validation"
+	"messenger/internal/ratelimit"
+	"messenger/internal/server/middleware"
+	"messenger/internal/server/router"
+)
+
+type FallbackOptions struct {
+	Config           models.Fallback
+	Authenticator    authinterfaces.Authenticator
+	Router           interfaces.MessageRouter
+	Limiter          *ratelimit.Limiter
+	MaxMessageSize   int64
+	Validator        *validation.Validator
+	ProcessorOptions processor.Options
+	// NewProcessor создает обработчик сообщений сессии; nil — processor.NewMessageProcessor.
+	NewProcessor func(options processor.Options) interfaces.MessageProcessor
+	Logger       *slog.Logger
+}
+
+// loadAppFallback регистрирует в маршрутизаторе резервные транспорты (SSE и long-polling),
+// если они включены в конфигурации. Сессии обрабатываются тем же MessageProcessor,
+// что и WebSocket-соединения.
+//
+// Возвращает хранилище сессий, которое нужно закрыть при остановке сервера,
+// или nil, если резервные транспорты выключены.
+func loadAppFallback(httpRouter *router.Router, opts FallbackOptions) *sessions.Store {
+	enabled, prefix, sessionTimeout, pollTimeout, heartbeatInterval, bufferSize := loaders.LoadFallback(opts.Config)
+	if !enabled {
+		return nil
+	}
+
+	store := sessions.NewStore(sessions.Options{
+		NewProcessor: func(logger *slog.Logger) interfaces.MessageProcessor {
+			processorOptions := opts.ProcessorOptions
+			processorOptions.Logger = logger
+			if opts.NewProcessor != nil {
+				return opts.NewProcessor(processorOptions)
+			}
+			return processor.NewMessageProcessor(processorOptions)
+		},
+		Router:      opts.Router,
+		Limiter:     opts.Limiter,
+		BufferSize:  bufferSize,
+		IdleTimeout: sessionTimeout,
+		Logger:      opts.Logger,
+	})
+
+	fallbackHandler := fbhandlers.New(fbhandlers.Options{
+		Store:             store,
+		Authenticator:     opts.Authenticator,
+		PollTimeout:       pollTimeout,
+		HeartbeatInterval: heartbeatInterval,
+		MaxMessageSize:    opts.MaxMessageSize,
+		Validator:         opts.Validator,
+		Logger:            opts.Logger,
+	})
+	fallbackHandler.Register(httpRouter.Group(prefix, middleware.Recover(opts.Logger), middleware.AccessLog(opts.Logger)))
+
+	return store
+}
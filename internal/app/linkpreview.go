@@ -0,0 +1,48 @@
+package app
+
+import (
+	"log/slog"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+	"messenger/internal/linkpreview"
+	"messenger/internal/messaging/interfaces"
+)
+
+type LinkPreviewOptions struct {
+	Config models.LinkPreview
+	Store  interfaces.ConversationStore
+	Router interfaces.MessageRouter
+	Logger *slog.Logger
+}
+
+// loadAppLinkPreview создает службу превью ссылок, если превью включены в конфигурации.
+// Служба загружает превью ссылок из сообщений всех транспортов и рассылает их участникам
+// беседы событием message_updated.
+//
+// Возвращает службу, которую нужно закрыть при остановке сервера, или nil,
+// если превью выключены.
+func loadAppLinkPreview(opts LinkPreviewOptions) *linkpreview.Unfurler {
+	enabled, timeout, maxBodySize, maxURLs, cacheTTL, cacheSize, workers, allowPrivateNetworks :=
+		loaders.LoadLinkPreview(opts.Config)
+	if !enabled {
+		return nil
+	}
+
+	if allowPrivateNetworks {
+		opts.Logger.Warn("Превью ссылок загружаются в том числе из внутренних сетей")
+	}
+	opts.Logger.Info("Превью ссылок включены",
+		slog.Duration("timeout", timeout), slog.Int64("max_body_size", maxBodySize), slog.Int("workers", workers))
+	return linkpreview.New(linkpreview.Options{
+		Store:                opts.Store,
+		Router:               opts.Router,
+		Timeout:              timeout,
+		MaxBodySize:          maxBodySize,
+		MaxURLs:              maxURLs,
+		CacheTTL:             cacheTTL,
+		CacheSize:            cacheSize,
+		Workers:              workers,
+		AllowPrivateNetworks: allowPrivateNetworks,
+		Logger:               opts.Logger,
+	})
+}
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"log/slog"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+	"messenger/internal/logging"
+)
+
+// LoadLogger создает логгер приложения по конфигурации: с настроенными уровнем
+// и форматом (текст или JSON). Логгер по умолчанию (slog.SetDefault) не меняется —
+// это решает точка входа программы.
+func LoadLogger(logConfig models.Log) *slog.Logger {
+	level, json := loaders.LoadLog(logConfig)
+
+	logger := logging.New(logging.Options{
+		Level: level,
+		JSON:  json,
+	})
+	return logger
+}
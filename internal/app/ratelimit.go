@@ -0,0 +1,29 @@
+package app
+
+import (
+	"log/slog"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+	"messenger/internal/ratelimit"
+)
+
+// loadAppRateLimiter создает ограничитель частоты сообщений клиентов по конфигурации.
+// Возвращает nil, если ограничение выключено.
+func loadAppRateLimiter(rateLimitConfig models.RateLimit, logger *slog.Logger) *ratelimit.Limiter {
+	enabled, connectionRules, userRules, ipRules, maxViolations, violationWindow := loaders.LoadRateLimit(rateLimitConfig)
+	if !enabled {
+		return nil
+	}
+
+	logger.Info("Ограничение частоты сообщений включено",
+		slog.Int("max_violations", maxViolations),
+		slog.Duration("violation_window", violationWindow),
+	)
+	return ratelimit.New(ratelimit.Options{
+		Connection:      connectionRules,
+		User:            userRules,
+		IP:              ipRules,
+		MaxViolations:   maxViolations,
+		ViolationWindow: violationWindow,
+	})
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"log/slog"
+	authinterfaces "messenger/internal/auth/interfaces"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+	"messenger/internal/messaging/interfaces"
+	processor "messenger/internal/messaging/processor"
+	"messenger/internal/messaging/validation"
+	resthandlers "messenger/internal/rest/handlers"
+	"messenger/internal/server/middleware"
+	"messenger/internal/server/router"
+)
+
+type RESTOptions struct {
+	Config           models.REST
+	Authenticator    authinterfaces.Authenticator
+	Store            interfaces.ConversationStore
+	Router           interfaces.MessageRouter
+	Directory        interfaces.Directory
+	Validator        *validation.Validator
+	ProcessorOptions processor.Options
+	// NewProcessor создает обработчик сообщений REST API; nil — processor.NewMessageProcessor.
+	NewProcessor func(options processor.Options) interfaces.MessageProcessor
+	Logger       *slog.Logger
+}
+
+// loadAppREST регистрирует в маршрутизаторе обработчики REST API, если он включен в конфигурации.
+// Сообщения, отправленные через REST API, обрабатываются тем же MessageProcessor,
+// что и сообщения WebSocket-клиентов. Личные сообщения доставляются маршрутизатором,
+// а присутствие пользователей определяется по каталогу подключений кластера.
+func loadAppREST(httpRouter *router.Router, opts RESTOptions) {
+	enabled, prefix := loaders.LoadREST(opts.Config)
+	if !enabled {
+		return
+	}
+
+	var messageProcessor interfaces.MessageProcessor
+	if opts.NewProcessor != nil {
+		messageProcessor = opts.NewProcessor(opts.ProcessorOptions)
+	} else {
+		messageProcessor = processor.NewMessageProcessor(opts.ProcessorOptions)
+	}
+
+	restHandler := resthandlers.New(resthandlers.Options{
+		Authenticator:    opts.Authenticator,
+		MessageProcessor: messageProcessor,
+		Store:            opts.Store,
+		Router:           opts.Router,
+		Directory:        opts.Directory,
+		Validator:        opts.Validator,
+		Logger:           opts.Logger,
+	})
+	restHandler.Register(httpRouter.Group(prefix, middleware.Recover(opts.Logger), middleware.AccessLog(opts.Logger)))
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"log/slog"
+	adminhandlers "messenger/internal/admin/handlers"
+	authinterfaces "messenger/internal/auth/interfaces"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+	"messenger/internal/health"
+	"messenger/internal/metrics"
+	serverhandlers "messenger/internal/server/handlers"
+	serverinterfaces "messenger/internal/server/interfaces"
+	"messenger/internal/server/middleware"
+	"messenger/internal/server/router"
+	"net/http"
+)
+
+type RoutesOptions struct {
+	Config           models.Routes
+	WebSocketHandler http.Handler
+	Authenticator    authinterfaces.Authenticator
+	Connections      adminhandlers.ConnectionCounter
+	Cluster          adminhandlers.ClusterDirectory
+	Readiness        *health.Registry
+	// Routes регистрирует дополнительные маршруты приложения. nil — дополнительных маршрутов нет.
+	Routes func(routes serverinterfaces.Routes)
+	Logger *slog.Logger
+}
+
+// loadAppRouter создает маршрутизатор HTTP-сервера и регистрирует в нем
+// WebSocket-эндпоинт, проверки состояния, метрики и служебные эндпоинты администратора.
+// У каждой группы маршрутов свой набор промежуточных обработчиков:
+//   - проверки состояния и метрики не пишутся в журнал доступа, чтобы не засорять его
+//     запросами балансировщика и системы мониторинга;
+//   - служебные эндпоинты доступны только пользователям из routes.admin_users.
+//
+// Дополнительные маршруты приложения регистрируются с восстановлением после паники
+// и журналом доступа.
+//
+// Запросы к незарегистрированным путям получают ответ 404.
+func loadAppRouter(opts RoutesOptions) *router.Router {
+	webSocketPath, adminPrefix, adminUsers := loaders.LoadRoutes(opts.Config)
+
+	httpRouter := router.New(http.HandlerFunc(serverhandlers.NotFound))
+
+	recoverMiddleware := middleware.Recover(opts.Logger)
+	accessLogMiddleware := middleware.AccessLog(opts.Logger)
+
+	webSocketRoutes := httpRouter.Group("", recoverMiddleware, accessLogMiddleware)
+	webSocketRoutes.Handle("GET "+webSocketPath, opts.WebSocketHandler)
+
+	probeRoutes := httpRouter.Group("", recoverMiddleware)
+	probeRoutes.HandleFunc("GET /healthz", serverhandlers.Healthz)
+	probeRoutes.HandleFunc("GET /readyz", serverhandlers.Readyz(opts.Readiness))
+	probeRoutes.Handle("GET /metrics", metrics.Handler(opts.Logger))
+
+	if adminPrefix != "" {
+		adminRoutes := httpRouter.Group(
+			adminPrefix,
+			recoverMiddleware,
+			accessLogMiddleware,
+			middleware.RequireAdmin(opts.Authenticator, adminUsers),
+		)
+		adminhandlers.New(opts.Connections, opts.Cluster, opts.Logger).Register(adminRoutes)
+	}
+
+	if opts.Routes != nil {
+		opts.Routes(httpRouter.Group("", recoverMiddleware, accessLogMiddleware))
+	}
+
+	return httpRouter
+}
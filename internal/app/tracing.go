@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+	"messenger/internal/tracing"
+)
+
+// loadAppTracing настраивает трассировку по конфигурации. Возвращает функцию,
+// выгружающую накопленные спаны, которую нужно вызвать при остановке приложения.
+func loadAppTracing(tracingConfig models.Tracing, logger *slog.Logger) (func(context.Context) error, error) {
+	exporter, file, endpoint, serviceName, sampleRatio := loaders.LoadTracing(tracingConfig)
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    exporter,
+		File:        file,
+		Endpoint:    endpoint,
+		ServiceName: serviceName,
+		SampleRatio: sampleRatio,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if exporter != "" && exporter != tracing.ExporterNone {
+		logger.Info("Трассировка включена", slog.String("exporter", exporter), slog.Float64("sample_ratio", sampleRatio))
+	}
+	return shutdown, nil
+}
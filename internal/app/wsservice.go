@@ -3,38 +3,188 @@ package app
 import (
 	"crypto/tls"
 	"fmt"
+	"log"
+	"log/slog"
 
+	"messenger/internal/admission"
 	"messenger/internal/config/loaders"
 	"messenger/internal/config/models"
 	wshfac "messenger/internal/factories/wshandler"
+	"messenger/internal/health"
+	"messenger/internal/messaging/interfaces"
+	"messenger/internal/messaging/router"
+	"messenger/internal/messaging/store"
+	"messenger/internal/messaging/validation"
+	"messenger/internal/metrics"
+	serverinterfaces "messenger/internal/server/interfaces"
 
 	processor "messenger/internal/messaging/processor"
 	receiver "messenger/internal/messaging/receiver"
 	sender "messenger/internal/messaging/sender"
 
 	ws "messenger/internal/ws"
+	"messenger/internal/ws/connections"
+	wsinterfaces "messenger/internal/ws/interfaces"
 	wsupgr "messenger/internal/ws/upgraders"
 	"net/http"
 )
 
 type WebSocketServiceOptions struct {
-	Config           models.WebSocket
-	TLSConfig        *tls.Config
-	SenderOptions    sender.Options
-	ReceiverOptions  receiver.Options
-	ProcessorOptions processor.Options
+	Config            models.WebSocket
+	FallbackConfig    models.Fallback
+	AuthConfig        models.Auth
+	RESTConfig        models.REST
+	StorageConfig     models.Storage
+	RoutesConfig      models.Routes
+	ShutdownConfig    models.Shutdown
+	RateLimitConfig   models.RateLimit
+	MessagesConfig    models.Messages
+	BusConfig         models.Bus
+	ClusterConfig     models.Cluster
+	AttachmentsConfig models.Attachments
+	LinkPreviewConfig models.LinkPreview
+	TLSConfig         *tls.Config
+	SenderOptions     sender.Options
+	ReceiverOptions   receiver.Options
+	ProcessorOptions  processor.Options
+	NewSender         func(options sender.Options) wsinterfaces.WebSocketSender
+	NewReceiver       func(options receiver.Options) wsinterfaces.WebSocketReceiver
+	NewProcessor      func(options processor.Options) interfaces.MessageProcessor
+	Routes            func(routes serverinterfaces.Routes)
+	Logger            *slog.Logger
 }
 
-func loadAppWebSocketService(opts WebSocketServiceOptions) *ws.WebsocketService {
-	wsHost, wsPort, wsDebug, invalidOrigins := loaders.LoadWebsocket(opts.Config)
+// loadAppWebSocketService собирает WebSocket-сервис со всеми транспортами и общими
+// компонентами. Если сборка завершилась ошибкой, уже созданные компоненты закрываются
+// в обратном порядке: соединение с Redis, пульс каталога подключений и фоновые горутины
+// вложений, превью ссылок и резервных транспортов не переживают неудачный запуск.
+func loadAppWebSocketService(opts WebSocketServiceOptions) (_ *ws.WebsocketService, err error) {
+	var cleanup []func()
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(cleanup) - 1; i >= 0; i-- {
+			cleanup[i]()
+		}
+	}()
 
-	upgrager := wsupgr.NewUpgrader(wsDebug, invalidOrigins)
+	wsHost, wsPort, wsDebug, allowedOrigins, invalidOrigins, missingOrigin, writeTimeout := loaders.LoadWebsocket(opts.Config)
+	compressionEnabled, compressionLevel, compressionThreshold := loaders.LoadCompression(opts.Config.Compression)
+
+	originPolicy, err := wsupgr.NewOriginPolicy(wsupgr.OriginOptions{
+		Allowed:       allowedOrigins,
+		Blocked:       invalidOrigins,
+		MissingOrigin: missingOrigin,
+		Logger:        opts.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка настройки проверки Origin: %w", err)
+	}
+
+	upgrager := wsupgr.NewUpgrader(wsDebug, originPolicy, compressionEnabled)
+
+	maxConnections, maxPerIP, maxPerUser, trustedNetworks, retryAfter := loaders.LoadAdmission(opts.Config.Admission)
+	admissionController := admission.New(admission.Options{
+		MaxConnections: maxConnections,
+		MaxPerIP:       maxPerIP,
+		MaxPerUser:     maxPerUser,
+		Trusted:        trustedNetworks,
+		RetryAfter:     retryAfter,
+	})
+
+	authenticator := loadAppAuthenticator(opts.AuthConfig, opts.Logger)
+	conversationStore := store.NewMemory(store.Options{
+		HistoryLimit: loaders.LoadStorage(opts.StorageConfig),
+		ServiceUsers: loaders.LoadServiceUsers(opts.AuthConfig),
+	})
+	messageBus, err := loadAppBus(opts.BusConfig, opts.Logger)
+	if err != nil {
+		return nil, err
+	}
+	cleanup = append(cleanup, func() { messageBus.Close() })
+	directory, err := loadAppDirectory(opts.ClusterConfig, messageBus, opts.Logger)
+	if err != nil {
+		return nil, err
+	}
+	cleanup = append(cleanup, func() { directory.Close() })
+	messageRouter := router.New(router.Options{
+		Bus:       messageBus,
+		Store:     conversationStore,
+		Directory: directory,
+		Logger:    opts.Logger,
+	})
+
+	drainDelay, gracePeriod, reconnectDelay := loaders.LoadShutdown(opts.ShutdownConfig)
+	liveConnections := connections.NewRegistry(connections.Options{
+		ReconnectDelay: reconnectDelay,
+	})
+
+	rateLimiter := loadAppRateLimiter(opts.RateLimitConfig, opts.Logger)
+
+	readiness := health.NewRegistry()
+	readiness.Register("certificate", health.CertificateCheck(opts.TLSConfig))
+	readiness.Register("store", conversationStore.Ping)
+	readiness.Register("bus", messageBus.Ping)
+
+	attachmentsOptions := AttachmentsOptions{
+		Config:        opts.AttachmentsConfig,
+		Authenticator: authenticator,
+		Store:         conversationStore,
+		Logger:        opts.Logger,
+	}
+	attachmentService, err := loadAppAttachments(attachmentsOptions)
+	if err != nil {
+		return nil, err
+	}
+	if attachmentService != nil {
+		cleanup = append(cleanup, attachmentService.Close)
+	}
+
+	processorOptions := opts.ProcessorOptions
+	processorOptions.Store = conversationStore
+	processorOptions.Router = messageRouter
+	processorOptions.Logger = opts.Logger
+	if attachmentService != nil {
+		processorOptions.Attachments = attachmentService
+	}
+	unfurler := loadAppLinkPreview(LinkPreviewOptions{
+		Config: opts.LinkPreviewConfig,
+		Store:  conversationStore,
+		Router: messageRouter,
+		Logger: opts.Logger,
+	})
+	if unfurler != nil {
+		processorOptions.Unfurler = unfurler
+		cleanup = append(cleanup, unfurler.Close)
+	}
+
+	maxFrameSize, maxTextLength := loaders.LoadMessages(opts.MessagesConfig)
+	validator := validation.New(validation.Options{MaxTextLength: maxTextLength})
+
+	receiverOptions := opts.ReceiverOptions
+	receiverOptions.MaxFrameSize = maxFrameSize
+	receiverOptions.Validator = validator
+
+	senderOptions := opts.SenderOptions
+	senderOptions.CompressionLevel = compressionLevel
+	senderOptions.CompressionThreshold = compressionThreshold
+	senderOptions.WriteTimeout = writeTimeout
 
 	handlerFactoryOptions := wshfac.Options{
 		Upgrader:         upgrager,
-		SenderOptions:    opts.SenderOptions,
-		ReceiverOptions:  opts.ReceiverOptions,
-		ProcessorOptions: opts.ProcessorOptions,
+		SenderOptions:    senderOptions,
+		ReceiverOptions:  receiverOptions,
+		ProcessorOptions: processorOptions,
+		NewSender:        opts.NewSender,
+		NewReceiver:      opts.NewReceiver,
+		NewProcessor:     opts.NewProcessor,
+		Authenticator:    authenticator,
+		Router:           messageRouter,
+		Connections:      liveConnections,
+		Admission:        admissionController,
+		Limiter:          rateLimiter,
+		Logger:           opts.Logger,
 	}
 
 	webSocketHandlerFactory := wshfac.New(handlerFactoryOptions)
@@ -44,13 +194,86 @@ func loadAppWebSocketService(opts WebSocketServiceOptions) *ws.WebsocketService
 		handler.HandleWebSocket(w, r)
 	})
 
+	httpRouter := loadAppRouter(RoutesOptions{
+		Config:           opts.RoutesConfig,
+		WebSocketHandler: wsHandlerFunc,
+		Authenticator:    authenticator,
+		Connections:      messageRouter,
+		Cluster:          directory,
+		Readiness:        readiness,
+		Routes:           opts.Routes,
+		Logger:           opts.Logger,
+	})
+	fallbackStore := loadAppFallback(httpRouter, FallbackOptions{
+		Config:           opts.FallbackConfig,
+		Authenticator:    authenticator,
+		Router:           messageRouter,
+		Limiter:          rateLimiter,
+		MaxMessageSize:   maxFrameSize,
+		Validator:        validator,
+		ProcessorOptions: processorOptions,
+		NewProcessor:     opts.NewProcessor,
+		Logger:           opts.Logger,
+	})
+	if fallbackStore != nil {
+		cleanup = append(cleanup, fallbackStore.Close)
+	}
+	loadAppREST(httpRouter, RESTOptions{
+		Config:           opts.RESTConfig,
+		Authenticator:    authenticator,
+		Store:            conversationStore,
+		Router:           messageRouter,
+		Directory:        directory,
+		Validator:        validator,
+		ProcessorOptions: processorOptions,
+		NewProcessor:     opts.NewProcessor,
+		Logger:           opts.Logger,
+	})
+
+	if attachmentService != nil {
+		loadAppAttachmentRoutes(httpRouter, attachmentService, attachmentsOptions)
+	}
+
 	address := fmt.Sprintf("%s:%s", wsHost, wsPort)
 
+	// Ошибки http.Server (в том числе неудачные TLS-рукопожатия) пишутся в журнал
+	// приложения с уровнем warn.
+	serverErrorLog := slog.NewLogLogger(opts.Logger.Handler(), slog.LevelWarn)
+
 	httpServer := &http.Server{
 		Addr:      address,
-		Handler:   wsHandlerFunc,
+		Handler:   httpRouter,
 		TLSConfig: opts.TLSConfig,
+		ErrorLog:  log.New(metrics.NewTLSErrorWriter(serverErrorLog.Writer()), "", 0),
+	}
+	if fallbackStore != nil {
+		httpServer.RegisterOnShutdown(fallbackStore.Close)
+	}
+	if attachmentService != nil {
+		httpServer.RegisterOnShutdown(attachmentService.Close)
+	}
+	if unfurler != nil {
+		httpServer.RegisterOnShutdown(unfurler.Close)
+	}
+	// Каталог и шина закрываются после закрытия соединений: отключения пользователей
+	// еще публикуются в шину. Каталог закрывается до шины, чтобы успеть сообщить
+	// другим узлам об остановке узла.
+	closers := []func(){
+		func() { directory.Close() },
+		func() {
+			if err := messageBus.Close(); err != nil {
+				opts.Logger.Warn("Ошибка закрытия шины сообщений", slog.Any("error", err))
+			}
+		},
 	}
 
-	return ws.NewWebsocketService(httpServer)
+	return ws.NewWebsocketService(ws.Options{
+		Server:      httpServer,
+		Readiness:   readiness,
+		Connections: liveConnections,
+		DrainDelay:  drainDelay,
+		GracePeriod: gracePeriod,
+		Closers:     closers,
+		Logger:      opts.Logger,
+	}), nil
 }
@@ -0,0 +1,202 @@
+package apptest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/messaging/interfaces"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultTimeout — время ожидания сообщений и close-фреймов скриптовым клиентом.
+const DefaultTimeout = 5 * time.Second
+
+// NoOrigin — значение DialOptions.Origin, при котором заголовок Origin не передается.
+const NoOrigin = "-"
+
+// DialOptions — параметры подключения скриптового клиента.
+type DialOptions struct {
+	// Token — токен доступа; пустой — без заголовка Authorization.
+	Token string
+	// Origin — заголовок Origin. Пустой — Origin, разрешенный тестовой конфигурацией;
+	// NoOrigin — без заголовка.
+	Origin string
+	// Subprotocol — подпротокол кодека. Пустой — без согласования подпротокола
+	// (сервер использует кодек JSON).
+	Subprotocol string
+	// Header — дополнительные заголовки запроса на апгрейд.
+	Header http.Header
+	// Compression — предложить серверу сжатие permessage-deflate. Если сервер его
+	// принимает, клиент сжимает отправляемые сообщения.
+	Compression bool
+}
+
+// Client — скриптовый WebSocket-клиент: отправляет сообщения и проверяет ответы
+// сервера по шагам теста. Любая ошибка или превышение DefaultTimeout завершает тест.
+type Client struct {
+	t     testing.TB
+	conn  *websocket.Conn
+	codec interfaces.Codec
+}
+
+// Dial подключается к серверу и завершает тест, если подключение не удалось.
+// Соединение закрывается по завершении теста.
+func (s *Server) Dial(t testing.TB, options DialOptions) *Client {
+	t.Helper()
+
+	client, response, err := s.TryDial(t, options)
+	if err != nil {
+		status := 0
+		if response != nil {
+			status = response.StatusCode
+		}
+		t.Fatalf("Ошибка подключения (HTTP %d): %v", status, err)
+	}
+	return client
+}
+
+// TryDial подключается к серверу и возвращает ответ на рукопожатие и ошибку, не завершая
+// тест. Используется для проверки отказов в подключении.
+func (s *Server) TryDial(t testing.TB, options DialOptions) (*Client, *http.Response, error) {
+	t.Helper()
+
+	header := http.Header{}
+	for name, values := range options.Header {
+		header[name] = values
+	}
+	if options.Token != "" {
+		header.Set("Authorization", "Bearer "+options.Token)
+	}
+	switch options.Origin {
+	case "":
+		header.Set("Origin", Origin)
+	case NoOrigin:
+	default:
+		header.Set("Origin", options.Origin)
+	}
+
+	dialer := &websocket.Dialer{
+		TLSClientConfig:   s.TLSConfig(),
+		HandshakeTimeout:  DefaultTimeout,
+		EnableCompression: options.Compression,
+	}
+	if options.Subprotocol != "" {
+		dialer.Subprotocols = []string{options.Subprotocol}
+	}
+
+	conn, response, err := dialer.Dial(s.WebSocketURL, header)
+	if err != nil {
+		return nil, response, err
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &Client{
+		t:     t,
+		conn:  conn,
+		codec: codecs.BySubprotocol(conn.Subprotocol()),
+	}, response, nil
+}
+
+// Conn возвращает WebSocket-соединение клиента для проверок, которых нет в скриптовом API.
+func (c *Client) Conn() *websocket.Conn {
+	return c.conn
+}
+
+// Send кодирует сообщение согласованным кодеком и отправляет его.
+func (c *Client) Send(message msg.Message) {
+	c.t.Helper()
+
+	data, err := c.codec.Encode(message)
+	if err != nil {
+		c.t.Fatalf("Ошибка кодирования сообщения: %v", err)
+	}
+	c.SendRaw(c.codec.FrameType(), data)
+}
+
+// SendRaw отправляет фрейм с произвольным содержимым.
+func (c *Client) SendRaw(frameType int, data []byte) {
+	c.t.Helper()
+
+	c.conn.SetWriteDeadline(time.Now().Add(DefaultTimeout))
+	if err := c.conn.WriteMessage(frameType, data); err != nil {
+		c.t.Fatalf("Ошибка отправки фрейма: %v", err)
+	}
+}
+
+// Receive ждет следующее сообщение сервера и декодирует его.
+func (c *Client) Receive() msg.Message {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(DefaultTimeout))
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		c.t.Fatalf("Ошибка чтения сообщения: %v", err)
+	}
+	message, err := c.codec.Decode(data)
+	if err != nil {
+		c.t.Fatalf("Ошибка декодирования сообщения %q: %v", data, err)
+	}
+	return message
+}
+
+// Expect ждет следующее сообщение сервера и проверяет его тип.
+func (c *Client) Expect(messageType msg.MessageType) msg.Message {
+	c.t.Helper()
+
+	message := c.Receive()
+	if message.Type != messageType {
+		c.t.Fatalf("Получено сообщение типа %s (%q), ожидался %s", message.Type, message.Text, messageType)
+	}
+	return message
+}
+
+// Request отправляет сообщение и возвращает следующее сообщение сервера — ответ на него.
+func (c *Client) Request(message msg.Message) msg.Message {
+	c.t.Helper()
+
+	c.Send(message)
+	return c.Receive()
+}
+
+// SendClose отправляет close-фрейм с кодом code и причиной text.
+func (c *Client) SendClose(code int, text string) {
+	c.t.Helper()
+
+	err := c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text),
+		time.Now().Add(DefaultTimeout))
+	if err != nil {
+		c.t.Fatalf("Ошибка отправки close-фрейма: %v", err)
+	}
+}
+
+// ExpectClose читает соединение до close-фрейма сервера, пропуская сообщения,
+// и возвращает его код и причину. Завершает тест, если соединение оборвалось
+// без close-фрейма.
+func (c *Client) ExpectClose() *websocket.CloseError {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(DefaultTimeout))
+	// Ответный close-фрейм отправляет сам тест, чтобы проверить, что сервер получит его.
+	c.conn.SetCloseHandler(func(int, string) error { return nil })
+	for {
+		_, _, err := c.conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			c.t.Fatalf("Соединение закрыто без close-фрейма: %v", err)
+		}
+		return closeErr
+	}
+}
+
+// Close закрывает соединение без close-фрейма.
+func (c *Client) Close() {
+	c.conn.Close()
+}
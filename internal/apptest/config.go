@@ -0,0 +1,87 @@
+package apptest
+
+import (
+	"time"
+
+	"messenger/internal/config/models"
+)
+
+// Пользователи тестовой конфигурации и их токены доступа.
+const (
+	Alice      = "alice"
+	AliceToken = "alice-token"
+	Bob        = "bob"
+	BobToken   = "bob-token"
+	Admin      = "admin"
+	AdminToken = "admin-token"
+)
+
+// Origin — источник, разрешенный тестовой конфигурацией; клиент харнесса передает его по умолчанию.
+const Origin = "https://app.example.com"
+
+// Config возвращает конфигурацию сервера для интеграционных тестов:
+//   - аутентификация по токенам пользователей Alice, Bob и Admin;
+//   - разрешен только Origin, запросы без Origin отклоняются;
+//   - включены REST API (/api) и служебные эндпоинты (/admin, пользователь Admin);
+//   - шина сообщений в памяти, пульс каталога подключений раз в 50ms;
+//   - трассировка выключена, остановка без задержки снятия готовности;
+//   - включены вложения (/attachments) размером до 1 MiB частями до 64 KiB с квотой 2 MiB
+//     и миниатюрами изображений 320 и 64 пикселя.
+//
+// Сертификат и каталог вложений задавать не нужно: Start генерирует сертификат
+// и создает временный каталог сам. Тест может изменить
+// любое поле перед вызовом Start.
+func Config() *models.Config {
+	return &models.Config{
+		WebSocket: models.WebSocket{
+			// Адрес не используется: сервер слушает эфемерный порт.
+			Host:           "127.0.0.1",
+			Port:           "8080",
+			AllowedOrigins: []string{Origin},
+			MissingOrigin:  "reject",
+		},
+		Auth: models.Auth{
+			Enabled: true,
+			Users: []models.AuthUser{
+				{ID: Alice, Token: AliceToken},
+				{ID: Bob, Token: BobToken},
+				{ID: Admin, Token: AdminToken},
+			},
+		},
+		REST: models.REST{
+			Enabled: true,
+			Prefix:  "/api",
+		},
+		Routes: models.Routes{
+			WebSocketPath: "/ws",
+			AdminPrefix:   "/admin",
+			AdminUsers:    []string{Admin},
+		},
+		Log: models.Log{
+			Level:  "debug",
+			Format: "text",
+		},
+		Tracing: models.Tracing{
+			Exporter: "none",
+		},
+		Shutdown: models.Shutdown{
+			GracePeriod: 2 * time.Second,
+		},
+		Bus: models.Bus{
+			Type: "memory",
+		},
+		Cluster: models.Cluster{
+			HeartbeatInterval: 50 * time.Millisecond,
+		},
+		Attachments: models.Attachments{
+			Enabled:      true,
+			Prefix:       "/attachments",
+			MaxSize:      1 << 20,
+			MaxChunkSize: 64 << 10,
+			UserQuota:    2 << 20,
+			Images: models.AttachmentImages{
+				ThumbnailSizes: []int{320, 64},
+			},
+		},
+	}
+}
@@ -0,0 +1,38 @@
+package apptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// Invite приглашает пользователей users в беседу conversation через REST API от имени
+// владельца токена token и завершает тест, если сервер не ответил 200.
+func (s *Server) Invite(t testing.TB, token, conversation string, users ...string) {
+	t.Helper()
+
+	body, err := json.Marshal(map[string][]string{"users": users})
+	if err != nil {
+		t.Fatalf("Ошибка кодирования приглашения: %v", err)
+	}
+	target := s.URL + s.Config.REST.Prefix + "/conversations/" + url.PathEscape(conversation) + "/members"
+	request, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Ошибка создания запроса приглашения: %v", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := s.HTTPClient().Do(request)
+	if err != nil {
+		t.Fatalf("Ошибка запроса приглашения: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		text, _ := io.ReadAll(response.Body)
+		t.Fatalf("Приглашение в беседу %q: HTTP %d: %s", conversation, response.StatusCode, text)
+	}
+}
@@ -0,0 +1,220 @@
+// Package apptest — харнесс интеграционных тестов: запускает сервер мессенджера
+// с полной сборкой приложения (конфигурация, TLS со сгенерированным сертификатом,
+// апгрейдер, фабрика обработчиков, маршруты) через app.App на эфемерном порту
+// и предоставляет скриптовый WebSocket-клиент.
+//
+//	server := apptest.Start(t, nil)
+//	client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+//	response := client.Request(msg.NewInfoMessage("привет"))
+//
+// Сервер останавливается автоматически по завершении теста. Журнал сервера
+// собирается в буфер и выводится в лог теста, если тест не прошел.
+package apptest
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"messenger/internal/app"
+	"messenger/internal/config/loaders"
+	"messenger/internal/config/models"
+	"messenger/internal/logging"
+)
+
+// Server — запущенный в тесте сервер мессенджера.
+type Server struct {
+	// URL — базовый адрес сервера, например https://127.0.0.1:41234.
+	URL string
+	// WebSocketURL — адрес WebSocket-эндпоинта, например wss://127.0.0.1:41234/ws.
+	WebSocketURL string
+	// Config — конфигурация, с которой запущен сервер.
+	Config *models.Config
+	// App — запущенное приложение.
+	App *app.App
+
+	t         testing.TB
+	rootCAs   *x509.CertPool
+	logs      *syncBuffer
+	closeOnce sync.Once
+}
+
+// Start собирает приложение по конфигурации config (nil — Config()) и запускает его
+// на эфемерном порту с TLS. Для сервера генерируется самоподписанный сертификат,
+// который загружается так же, как сертификат из конфигурации. Сервер останавливается
+// через App.Stop по завершении теста.
+func Start(t testing.TB, config *models.Config) *Server {
+	t.Helper()
+
+	return StartApp(t, app.Options{Config: config})
+}
+
+// StartApp запускает приложение, как Start, с компонентами, маршрутами и хуками из options.
+// Хуки OnStart вызываются до возврата.
+func StartApp(t testing.TB, options app.Options) *Server {
+	t.Helper()
+
+	s := New(t, options)
+	if err := s.App.Start(context.Background()); err != nil {
+		t.Fatalf("Ошибка запуска приложения: %v", err)
+	}
+	return s
+}
+
+// New собирает приложение, как StartApp, но не запускает его: порт уже занят, а запуск
+// выполняет тест через App.Start. Конфигурация, TLS, логгер и слушатель задаются
+// харнессом: поля Config (nil — Config()), TLSConfig, Logger и Listener перезаписываются.
+func New(t testing.TB, options app.Options) *Server {
+	t.Helper()
+
+	config := options.Config
+	if config == nil {
+		config = Config()
+	}
+
+	dir := t.TempDir() + string(os.PathSeparator)
+	rootCAs, err := writeCertificate(dir)
+	if err != nil {
+		t.Fatalf("Ошибка генерации сертификата: %v", err)
+	}
+	config.Certificate = models.Certificate{
+		CertificateFileName: certificateFileName,
+		KeyFileName:         keyFileName,
+		CertificatePath:     dir,
+		KeyPath:             dir,
+	}
+	if config.Attachments.Enabled && config.Attachments.Dir == "" {
+		config.Attachments.Dir = t.TempDir()
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Некорректная тестовая конфигурация: %v", err)
+	}
+
+	certificate, err := loaders.LoadCertificate(config.Certificate)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки сертификата: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	logs := &syncBuffer{}
+	level, json := loaders.LoadLog(config.Log)
+	logger := logging.New(logging.Options{Level: level, JSON: json, Output: logs})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Ошибка открытия порта: %v", err)
+	}
+
+	options.Config = config
+	options.TLSConfig = tlsConfig
+	options.Logger = logger
+	options.Listener = listener
+	application, err := app.New(options)
+	if err != nil {
+		listener.Close()
+		t.Fatalf("Ошибка сборки приложения: %v", err)
+	}
+
+	address := listener.Addr().String()
+	s := &Server{
+		URL:          "https://" + address,
+		WebSocketURL: "wss://" + address + config.Routes.WebSocketPath,
+		Config:       config,
+		t:            t,
+		App:          application,
+		rootCAs:      rootCAs,
+		logs:         logs,
+	}
+	t.Cleanup(func() {
+		s.Close()
+		if t.Failed() {
+			t.Logf("Журнал сервера:\n%s", s.Logs())
+		}
+	})
+	return s
+}
+
+// Close останавливает приложение так же, как по сигналу завершения: снимает готовность,
+// закрывает WebSocket-соединения close-фреймом 1012 и останавливает HTTP-сервер.
+// Повторные вызовы безопасны.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		if err := s.App.Stop(context.Background()); err != nil {
+			s.t.Errorf("Ошибка остановки приложения: %v", err)
+		}
+	})
+}
+
+// TLSConfig возвращает настройки TLS клиента, доверяющего сертификату сервера.
+func (s *Server) TLSConfig() *tls.Config {
+	return &tls.Config{RootCAs: s.rootCAs}
+}
+
+// HTTPClient возвращает HTTP-клиент, доверяющий сертификату сервера.
+func (s *Server) HTTPClient() *http.Client {
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: s.TLSConfig()}}
+}
+
+// Logs возвращает накопленный журнал сервера.
+func (s *Server) Logs() string {
+	return s.logs.String()
+}
+
+// WaitForLog ждет появления в журнале сервера записи, содержащей все подстроки parts,
+// и завершает тест, если за DefaultTimeout она не появилась.
+func (s *Server) WaitForLog(t testing.TB, parts ...string) string {
+	t.Helper()
+
+	deadline := time.Now().Add(DefaultTimeout)
+	for {
+		for _, line := range strings.Split(s.Logs(), "\n") {
+			if containsAll(line, parts) {
+				return line
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("В журнале сервера нет записи с %q", parts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func containsAll(line string, parts []string) bool {
+	for _, part := range parts {
+		if !strings.Contains(line, part) {
+			return false
+		}
+	}
+	return true
+}
+
+// syncBuffer — буфер журнала, безопасный для записи из нескольких горутин.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
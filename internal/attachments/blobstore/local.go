@@ -0,0 +1,134 @@
+package blobstore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrNotFound — блоб не существует.
+	ErrNotFound = errors.New("блоб не найден")
+	// ErrOffsetMismatch — смещение части загрузки не совпадает с размером блоба.
+	ErrOffsetMismatch = errors.New("смещение не совпадает с размером блоба")
+	// ErrInvalidID — идентификатор блоба нельзя использовать как имя файла.
+	ErrInvalidID = errors.New("некорректный идентификатор блоба")
+)
+
+// LocalStore хранит блобы файлами в каталоге на локальном диске. Имя файла совпадает
+// с идентификатором блоба. Блобы видны только узлу, к диску которого подключен каталог.
+type LocalStore struct {
+	dir string
+}
+
+// NewLocal создает хранилище в каталоге dir, создавая каталог, если его нет.
+func NewLocal(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("ошибка создания каталога вложений: %w", err)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+// Create создает пустой файл блоба. Существующий блоб с тем же идентификатором не перезаписывается.
+func (ls *LocalStore) Create(id string) error {
+	path, err := ls.path(id)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Append дописывает данные в конец файла блоба, если его размер равен offset.
+func (ls *LocalStore) Append(id string, offset int64, r io.Reader) (int64, error) {
+	path, err := ls.path(id)
+	if err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() != offset {
+		return 0, ErrOffsetMismatch
+	}
+
+	written, err := io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return written, err
+}
+
+// Replace записывает данные во временный файл в каталоге хранилища и переименовывает его
+// в файл блоба, поэтому читатели видят либо прежнее, либо новое содержимое целиком.
+func (ls *LocalStore) Replace(id string, r io.Reader) (int64, error) {
+	path, err := ls.path(id)
+	if err != nil {
+		return 0, err
+	}
+	// Имена временных файлов начинаются с точки и не совпадают с идентификаторами блобов.
+	file, err := os.CreateTemp(ls.dir, ".replace-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+
+	written, err := io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return written, os.Rename(file.Name(), path)
+}
+
+// Open открывает файл блоба для чтения.
+func (ls *LocalStore) Open(id string) (io.ReadSeekCloser, error) {
+	path, err := ls.path(id)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete удаляет файл блоба.
+func (ls *LocalStore) Delete(id string) error {
+	path, err := ls.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path возвращает путь к файлу блоба. Идентификатор не может выходить за пределы каталога.
+func (ls *LocalStore) path(id string) (string, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return "", ErrInvalidID
+	}
+	return filepath.Join(ls.dir, id), nil
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"log/slog"
+	"messenger/internal/attachments"
+	authinterfaces "messenger/internal/auth/interfaces"
+	authmodels "messenger/internal/auth/models"
+	"messenger/internal/logging"
+	"messenger/internal/server/interfaces"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// defaultMaxChunkSize — максимальный размер части загрузки, если Options.MaxChunkSize не задан.
+	defaultMaxChunkSize = 1 << 20
+	// maxCreateBodySize — максимальный размер тела запроса на создание загрузки.
+	maxCreateBodySize = 4 << 10
+	// uploadOffsetHeader — заголовок со смещением части в запросе и числом загруженных байт в ответе.
+	uploadOffsetHeader = "Upload-Offset"
+)
+
+// AttachmentHandler обслуживает загрузку и скачивание вложений:
+//   - POST   {prefix}/uploads      — создание загрузки файла (name, size);
+//   - GET    {prefix}/{id}         — описание вложения и число загруженных байт;
+//   - PATCH  {prefix}/{id}         — загрузка части файла со смещения из заголовка Upload-Offset;
+//   - DELETE {prefix}/{id}         — удаление вложения владельцем;
+//   - GET    {prefix}/{id}/content — скачивание файла (поддерживаются запросы Range);
+//   - GET    {prefix}/{id}/thumbnails/{size} — скачивание миниатюры изображения.
+//
+// Части загружаются последовательно: смещение каждой части должно совпадать с числом
+// уже загруженных байт, которое сервер возвращает в заголовке Upload-Offset. После обрыва
+// загрузка продолжается с этого смещения. Клиенты аутентифицируются так же, как
+// WebSocket-клиенты, поэтому URL скачивания из сообщения открывается с токеном доступа.
+type AttachmentHandler struct {
+	service       *attachments.Service
+	authenticator authinterfaces.Authenticator
+	maxChunkSize  int64
+	logger        *slog.Logger
+}
+
+type Options struct {
+	Service       *attachments.Service
+	Authenticator authinterfaces.Authenticator
+	// MaxChunkSize — максимальный размер тела запроса с частью файла в байтах.
+	// Если не задан, используется 1 MiB.
+	MaxChunkSize int64
+	// Logger — логгер обработчика. Если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+func New(options Options) *AttachmentHandler {
+	maxChunkSize := options.MaxChunkSize
+	if maxChunkSize == 0 {
+		maxChunkSize = defaultMaxChunkSize
+	}
+	ah := &AttachmentHandler{
+		service:       options.Service,
+		authenticator: options.Authenticator,
+		maxChunkSize:  maxChunkSize,
+	}
+	ah.logger = logging.Component(options.Logger, ah.Tag())
+	return ah
+}
+
+// Tag возвращает строковый идентификатор для AttachmentHandler.
+func (*AttachmentHandler) Tag() string {
+	return "ATTACHMENT_HANDLER"
+}
+
+// Register регистрирует обработчики вложений в группе маршрутов.
+func (ah *AttachmentHandler) Register(routes interfaces.Routes) {
+	routes.HandleFunc("POST /uploads", ah.authenticated(ah.handleCreate))
+	routes.HandleFunc("GET /{id}", ah.authenticated(ah.handleInfo))
+	routes.HandleFunc("PATCH /{id}", ah.authenticated(ah.handleWrite))
+	routes.HandleFunc("DELETE /{id}", ah.authenticated(ah.handleDelete))
+	routes.HandleFunc("GET /{id}/content", ah.authenticated(ah.handleContent))
+	routes.HandleFunc("GET /{id}/thumbnails/{size}", ah.authenticated(ah.handleThumbnail))
+}
+
+type createRequest struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+type attachmentResponse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Offset   int64  `json:"offset"`
+	Complete bool   `json:"complete"`
+	// ContentType и URL заполняются после завершения загрузки.
+	ContentType string `json:"content_type,omitempty"`
+	URL         string `json:"url,omitempty"`
+	// Размеры, заглушка и миниатюры заполняются после завершения загрузки изображения.
+	Width       int             `json:"width,omitempty"`
+	Height      int             `json:"height,omitempty"`
+	Placeholder string          `json:"placeholder,omitempty"`
+	Thumbnails  []msg.Thumbnail `json:"thumbnails,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// authenticated аутентифицирует запрос и передает личность клиента обработчику.
+// Неаутентифицированным клиентам отвечает 401.
+func (ah *AttachmentHandler) authenticated(
+	next func(w http.ResponseWriter, r *http.Request, identity authmodels.Identity),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, err := ah.authenticator.Authenticate(r)
+		if err != nil {
+			ah.writeError(w, http.StatusUnauthorized, "Требуется аутентификация")
+			return
+		}
+		next(w, r, identity)
+	}
+}
+
+// requestLogger возвращает логгер запроса с адресом клиента, пользователем и вложением.
+func (ah *AttachmentHandler) requestLogger(r *http.Request, identity authmodels.Identity) *slog.Logger {
+	return ah.logger.With(
+		slog.String(logging.KeyRemoteAddr, r.RemoteAddr),
+		slog.String(logging.KeyUserID, identity.UserID),
+		slog.String("attachment", r.PathValue("id")),
+	)
+}
+
+// handleCreate начинает загрузку файла и возвращает идентификатор вложения.
+// Размер файла проверяется по ограничению размера и квоте пользователя до загрузки данных.
+func (ah *AttachmentHandler) handleCreate(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	var request createRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCreateBodySize))
+	if err := decoder.Decode(&request); err != nil {
+		ah.writeError(w, http.StatusBadRequest, "Некорректное тело запроса")
+		return
+	}
+
+	info, err := ah.service.Create(identity.UserID, request.Name, request.Size)
+	switch {
+	case errors.Is(err, attachments.ErrInvalidName), errors.Is(err, attachments.ErrInvalidSize):
+		ah.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, attachments.ErrTooLarge):
+		ah.writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case errors.Is(err, attachments.ErrQuotaExceeded):
+		ah.writeError(w, http.StatusInsufficientStorage, err.Error())
+		return
+	case err != nil:
+		ah.requestLogger(r, identity).Error("Ошибка создания загрузки", slog.Any("error", err))
+		ah.writeError(w, http.StatusInternalServerError, "Не удалось начать загрузку")
+		return
+	}
+
+	ah.writeUpload(w, http.StatusCreated, info)
+}
+
+// handleInfo возвращает описание вложения. По нему клиент узнает смещение,
+// с которого нужно продолжить прерванную загрузку.
+func (ah *AttachmentHandler) handleInfo(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	info, err := ah.service.Info(identity.UserID, r.PathValue("id"))
+	if err != nil {
+		ah.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	ah.writeUpload(w, http.StatusOK, info)
+}
+
+// handleWrite записывает часть файла, начинающуюся со смещения из заголовка Upload-Offset.
+// В ответе, в том числе с ошибкой, заголовок Upload-Offset содержит число загруженных байт.
+func (ah *AttachmentHandler) handleWrite(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	offset, err := strconv.ParseInt(r.Header.Get(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		ah.writeError(w, http.StatusBadRequest, "Заголовок Upload-Offset должен содержать неотрицательное число")
+		return
+	}
+
+	info, err := ah.service.Write(identity.UserID, r.PathValue("id"), offset, http.MaxBytesReader(w, r.Body, ah.maxChunkSize))
+	if info.ID != "" {
+		w.Header().Set(uploadOffsetHeader, strconv.FormatInt(info.Offset, 10))
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case err == nil:
+		ah.writeUpload(w, http.StatusOK, info)
+	case errors.Is(err, attachments.ErrNotFound):
+		ah.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, attachments.ErrOffsetMismatch), errors.Is(err, attachments.ErrBusy):
+		ah.writeError(w, http.StatusConflict, err.Error())
+	case errors.As(err, &maxBytesErr):
+		ah.writeError(w, http.StatusRequestEntityTooLarge, "Часть файла больше допустимого размера")
+	case errors.Is(err, attachments.ErrTooMuchData):
+		ah.writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, attachments.ErrTypeNotAllowed):
+		ah.writeError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, attachments.ErrInvalidImage):
+		ah.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case info.ID != "":
+		// Тело запроса оборвалось: записанная часть сохранена, загрузку можно продолжить.
+		ah.requestLogger(r, identity).Debug("Загрузка части прервана",
+			slog.Int64("offset", info.Offset), slog.Any("error", err))
+		ah.writeError(w, http.StatusBadRequest, "Загрузка части прервана")
+	default:
+		ah.requestLogger(r, identity).Error("Ошибка загрузки части", slog.Any("error", err))
+		ah.writeError(w, http.StatusInternalServerError, "Не удалось загрузить часть файла")
+	}
+}
+
+// handleDelete удаляет вложение владельца и освобождает его квоту.
+func (ah *AttachmentHandler) handleDelete(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	err := ah.service.Delete(identity.UserID, r.PathValue("id"))
+	switch {
+	case errors.Is(err, attachments.ErrNotFound):
+		ah.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, attachments.ErrBusy):
+		ah.writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		ah.requestLogger(r, identity).Error("Ошибка удаления вложения", slog.Any("error", err))
+		ah.writeError(w, http.StatusInternalServerError, "Не удалось удалить вложение")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleContent отдает файл вложения владельцу и участникам бесед, в которые оно отправлено.
+// Файл всегда отдается для сохранения (Content-Disposition: attachment) и с типом,
+// определенным сервером, чтобы браузер не исполнял загруженное содержимое.
+func (ah *AttachmentHandler) handleContent(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	content, info, err := ah.service.Open(identity.UserID, r.PathValue("id"))
+	switch {
+	case errors.Is(err, attachments.ErrNotFound):
+		ah.writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, attachments.ErrIncomplete):
+		ah.writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		ah.requestLogger(r, identity).Error("Ошибка открытия вложения", slog.Any("error", err))
+		ah.writeError(w, http.StatusInternalServerError, "Не удалось открыть вложение")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, info.Name, info.CompletedAt, content)
+}
+
+// handleThumbnail отдает миниатюру изображения тем же пользователям, что и handleContent.
+// Миниатюры создает сервер, поэтому они отдаются для показа в браузере.
+func (ah *AttachmentHandler) handleThumbnail(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	size, err := strconv.Atoi(r.PathValue("size"))
+	if err != nil {
+		ah.writeError(w, http.StatusNotFound, attachments.ErrNotFound.Error())
+		return
+	}
+
+	content, info, thumbnail, err := ah.service.OpenThumbnail(identity.UserID, r.PathValue("id"), size)
+	switch {
+	case errors.Is(err, attachments.ErrNotFound):
+		ah.writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, attachments.ErrIncomplete):
+		ah.writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		ah.requestLogger(r, identity).Error("Ошибка открытия миниатюры", slog.Any("error", err))
+		ah.writeError(w, http.StatusInternalServerError, "Не удалось открыть миниатюру")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", thumbnail.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, "", info.CompletedAt, content)
+}
+
+// writeUpload отвечает описанием вложения; URL скачивания передается после завершения загрузки.
+func (ah *AttachmentHandler) writeUpload(w http.ResponseWriter, status int, info attachments.Info) {
+	response := attachmentResponse{
+		ID:          info.ID,
+		Name:        info.Name,
+		Size:        info.Size,
+		Offset:      info.Offset,
+		Complete:    info.Complete,
+		ContentType: info.ContentType,
+	}
+	if info.Complete {
+		reference := ah.service.Reference(info)
+		response.URL = reference.URL
+		response.Width, response.Height = reference.Width, reference.Height
+		response.Placeholder = reference.Placeholder
+		response.Thumbnails = reference.Thumbnails
+	}
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(info.Offset, 10))
+	ah.writeJSON(w, status, response)
+}
+
+func (ah *AttachmentHandler) writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		ah.logger.Warn("Ошибка записи ответа", slog.Any("error", err))
+	}
+}
+
+func (ah *AttachmentHandler) writeError(w http.ResponseWriter, status int, message string) {
+	ah.writeJSON(w, status, errorResponse{Error: message})
+}
@@ -0,0 +1,112 @@
+package images
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+// base83 — алфавит кодирования BlurHash.
+const base83 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Placeholder вычисляет BlurHash изображения — строку из 20–30 символов, по которой клиент
+// рисует размытую заглушку до загрузки миниатюры. xComponents и yComponents (от 1 до 9)
+// задают число гармоник по горизонтали и вертикали; чем их больше, тем детальнее заглушка.
+//
+// Вычисление проходит по всем пикселям для каждой гармоники, поэтому изображение
+// стоит заранее уменьшить до нескольких десятков пикселей.
+func Placeholder(img *image.RGBA, xComponents, yComponents int) string {
+	xComponents = min(max(xComponents, 1), 9)
+	yComponents = min(max(yComponents, 1), 9)
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+
+	// Цвета пикселей в линейном пространстве.
+	linear := make([][3]float64, width*height)
+	for y := range height {
+		for x := range width {
+			offset := img.PixOffset(img.Rect.Min.X+x, img.Rect.Min.Y+y)
+			pixel := img.Pix[offset : offset+3]
+			linear[y*width+x] = [3]float64{sRGBToLinear(pixel[0]), sRGBToLinear(pixel[1]), sRGBToLinear(pixel[2])}
+		}
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := range yComponents {
+		for i := range xComponents {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := range height {
+				basisY := math.Cos(math.Pi * float64(j) * float64(y) / float64(height))
+				for x := range width {
+					basis := basisY * math.Cos(math.Pi*float64(i)*float64(x)/float64(width))
+					pixel := linear[y*width+x]
+					factor[0] += basis * pixel[0]
+					factor[1] += basis * pixel[1]
+					factor[2] += basis * pixel[2]
+				}
+			}
+			scale := normalisation / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	encode83(&hash, (xComponents-1)+(yComponents-1)*9, 1)
+
+	maximumValue := 1.0
+	if len(factors) > 1 {
+		actualMaximum := 0.0
+		for _, factor := range factors[1:] {
+			actualMaximum = max(actualMaximum, math.Abs(factor[0]), math.Abs(factor[1]), math.Abs(factor[2]))
+		}
+		quantisedMaximum := min(max(int(math.Floor(actualMaximum*166-0.5)), 0), 82)
+		maximumValue = float64(quantisedMaximum+1) / 166
+		encode83(&hash, quantisedMaximum, 1)
+	} else {
+		encode83(&hash, 0, 1)
+	}
+
+	dc := factors[0]
+	encode83(&hash, linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4)
+	for _, factor := range factors[1:] {
+		quantise := func(value float64) int {
+			return min(max(int(math.Floor(signPow(value/maximumValue, 0.5)*9+9.5)), 0), 18)
+		}
+		encode83(&hash, quantise(factor[0])*19*19+quantise(factor[1])*19+quantise(factor[2]), 2)
+	}
+	return hash.String()
+}
+
+// encode83 дописывает value в кодировке base83 ровно length символами.
+func encode83(hash *strings.Builder, value, length int) {
+	divisor := 1
+	for range length - 1 {
+		divisor *= 83
+	}
+	for ; divisor > 0; divisor /= 83 {
+		hash.WriteByte(base83[value/divisor%83])
+	}
+}
+
+func sRGBToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := min(max(value, 0), 1)
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
@@ -0,0 +1,184 @@
+// Package images обрабатывает изображения вложений без внешних зависимостей:
+// удаляет метаданные (EXIF, XMP, IPTC, комментарии) из JPEG, PNG и GIF, декодирует
+// изображения с ограничением числа пикселей, уменьшает их, поворачивает по ориентации
+// из EXIF и вычисляет заглушку BlurHash для показа до загрузки миниатюры.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	// Декодер GIF регистрируется для image.Decode.
+	_ "image/gif"
+)
+
+// thumbnailQuality — качество JPEG-миниатюр.
+const thumbnailQuality = 85
+
+var (
+	// ErrUnsupported — формат изображения не поддерживается.
+	ErrUnsupported = errors.New("формат изображения не поддерживается")
+	// ErrMalformed — структура файла изображения повреждена.
+	ErrMalformed = errors.New("файл изображения поврежден")
+	// ErrTooManyPixels — изображение больше допустимого числа пикселей.
+	ErrTooManyPixels = errors.New("изображение больше допустимого числа пикселей")
+)
+
+// Supported сообщает, поддерживается ли тип содержимого, определенный
+// http.DetectContentType.
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// Decode декодирует изображение, предварительно проверив по заголовку, что в нем не больше
+// maxPixels пикселей (0 — без ограничения). Из анимированного GIF декодируется первый кадр.
+func Decode(r io.ReadSeeker, maxPixels int64) (image.Image, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrMalformed
+	}
+	if maxPixels > 0 && int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	return img, nil
+}
+
+// Fit возвращает размеры изображения width×height, уменьшенного с сохранением пропорций
+// так, чтобы большая сторона не превышала maxSide. Изображение не увеличивается.
+func Fit(width, height, maxSide int) (int, int) {
+	if width <= maxSide && height <= maxSide {
+		return width, height
+	}
+	if width >= height {
+		return maxSide, max(1, height*maxSide/width)
+	}
+	return max(1, width*maxSide/height), maxSide
+}
+
+// Resize уменьшает изображение до размеров width×height усреднением пикселей
+// (box-фильтр). Размеры больше исходных уменьшаются до исходных.
+//
+// Исходное изображение читается построчно, поэтому дополнительная память
+// не зависит от его высоты.
+func Resize(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	width, height = min(max(width, 1), srcWidth), min(max(height, 1), srcHeight)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	row := image.NewRGBA(image.Rect(0, 0, srcWidth, 1))
+
+	// columns[x] — столбец результата, в который попадает столбец x исходного изображения.
+	columns := make([]int, srcWidth)
+	for x := range columns {
+		columns[x] = x * width / srcWidth
+	}
+	sums := make([]uint64, width*4)
+	counts := make([]uint64, width)
+
+	dstY := 0
+	for y := 0; y < srcHeight; y++ {
+		draw.Draw(row, row.Rect, src, image.Pt(bounds.Min.X, bounds.Min.Y+y), draw.Src)
+		for x, dstX := range columns {
+			pixel := row.Pix[x*4 : x*4+4 : x*4+4]
+			sum := sums[dstX*4 : dstX*4+4 : dstX*4+4]
+			sum[0] += uint64(pixel[0])
+			sum[1] += uint64(pixel[1])
+			sum[2] += uint64(pixel[2])
+			sum[3] += uint64(pixel[3])
+			counts[dstX]++
+		}
+
+		if y+1 < srcHeight && (y+1)*height/srcHeight == dstY {
+			continue
+		}
+		// Строка результата собрана: записываем средние значения и начинаем следующую.
+		out := dst.Pix[dst.PixOffset(0, dstY):]
+		for dstX, count := range counts {
+			for c := range 4 {
+				out[dstX*4+c] = uint8((sums[dstX*4+c] + count/2) / count)
+				sums[dstX*4+c] = 0
+			}
+			counts[dstX] = 0
+		}
+		dstY++
+	}
+	return dst
+}
+
+// Orient поворачивает и отражает изображение так, как его нужно показывать
+// при ориентации orientation из EXIF.
+func Orient(src *image.RGBA, orientation Orientation) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := range dstHeight {
+		for x := range dstWidth {
+			var srcX, srcY int
+			switch orientation {
+			case 2: // отражение по горизонтали
+				srcX, srcY = width-1-x, y
+			case 3: // поворот на 180°
+				srcX, srcY = width-1-x, height-1-y
+			case 4: // отражение по вертикали
+				srcX, srcY = x, height-1-y
+			case 5: // транспонирование
+				srcX, srcY = y, x
+			case 6: // поворот на 90° по часовой стрелке
+				srcX, srcY = y, height-1-x
+			case 7: // поперечное транспонирование
+				srcX, srcY = width-1-y, height-1-x
+			case 8: // поворот на 90° против часовой стрелки
+				srcX, srcY = width-1-y, x
+			}
+			from := src.PixOffset(src.Rect.Min.X+srcX, src.Rect.Min.Y+srcY)
+			copy(dst.Pix[dst.PixOffset(x, y):], src.Pix[from:from+4])
+		}
+	}
+	return dst
+}
+
+// Encode кодирует миниатюру: непрозрачную — в JPEG, с прозрачностью — в PNG.
+// Возвращает закодированные данные и их тип содержимого.
+func Encode(img *image.RGBA) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if img.Opaque() {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
@@ -0,0 +1,438 @@
+package images
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Orientation — ориентация изображения из тега EXIF Orientation: 1 — показывать как есть,
+// 2–8 — отражения и повороты на 90°, 180° и 270°.
+type Orientation int
+
+// Размеры, после которых сегмент метаданных считается поврежденным.
+const (
+	// maxEXIFSize — максимальный размер блока EXIF, читаемого в память.
+	maxEXIFSize = 1 << 20
+	// maxPNGChunkSize — максимальная длина чанка PNG по спецификации.
+	maxPNGChunkSize = 1<<31 - 1
+)
+
+// Маркеры JPEG.
+const (
+	jpegSOI   = 0xD8
+	jpegEOI   = 0xD9
+	jpegSOS   = 0xDA
+	jpegRST0  = 0xD0
+	jpegRST7  = 0xD7
+	jpegTEM   = 0x01
+	jpegAPP0  = 0xE0
+	jpegAPP1  = 0xE1
+	jpegAPP2  = 0xE2
+	jpegAPP14 = 0xEE
+	jpegAPP15 = 0xEF
+	jpegCOM   = 0xFE
+)
+
+var (
+	exifHeader   = []byte("Exif\x00\x00")
+	jfifHeader   = []byte("JFIF\x00")
+	iccHeader    = []byte("ICC_PROFILE\x00")
+	adobeHeader  = []byte("Adobe")
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// StripMetadata копирует изображение из r в w без метаданных: EXIF (в том числе координат
+// съемки и сведений о камере), XMP, IPTC, текстовых комментариев и данных после конца
+// изображения. Пиксели не перекодируются. Цветовой профиль сохраняется, а ориентация
+// из EXIF, если она отличается от обычной, записывается в новый EXIF без других тегов,
+// чтобы изображение показывалось так же, как до удаления метаданных.
+//
+// Возвращает ориентацию из EXIF (1, если ее нет). Поддерживаются JPEG, PNG и GIF;
+// для других типов возвращается ErrUnsupported, для поврежденных файлов — ErrMalformed.
+func StripMetadata(w io.Writer, r io.Reader, contentType string) (Orientation, error) {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	var orientation Orientation
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		orientation, err = stripJPEG(bw, br)
+	case "image/png":
+		orientation, err = stripPNG(bw, br)
+	case "image/gif":
+		orientation, err = 1, stripGIF(bw, br)
+	default:
+		return 0, ErrUnsupported
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = fmt.Errorf("%w: неожиданный конец файла", ErrMalformed)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return orientation, bw.Flush()
+}
+
+// stripJPEG разбирает JPEG по сегментам и пропускает сегменты метаданных:
+// APP1 (EXIF, XMP), APP3–APP13 и APP15 (в том числе IPTC), комментарии, а также APP0, APP2
+// и APP14, кроме JFIF, ICC-профиля и Adobe. Данные после маркера конца изображения
+// (дополнительные изображения, трейлеры камер) отбрасываются.
+func stripJPEG(w *bufio.Writer, r *bufio.Reader) (Orientation, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil {
+		return 0, err
+	}
+	if soi[0] != 0xFF || soi[1] != jpegSOI {
+		return 0, fmt.Errorf("%w: нет маркера начала JPEG", ErrMalformed)
+	}
+	w.Write(soi[:])
+
+	orientation := Orientation(1)
+	for {
+		marker, err := readJPEGMarker(r)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case marker == jpegEOI:
+			w.Write([]byte{0xFF, marker})
+			return orientation, nil
+		case marker == jpegTEM || marker >= jpegRST0 && marker <= jpegRST7:
+			w.Write([]byte{0xFF, marker})
+			continue
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return 0, err
+		}
+		size := int(binary.BigEndian.Uint16(length[:]))
+		if size < 2 {
+			return 0, fmt.Errorf("%w: некорректная длина сегмента JPEG", ErrMalformed)
+		}
+		payload := make([]byte, size-2)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return 0, err
+		}
+
+		if marker == jpegAPP1 && bytes.HasPrefix(payload, exifHeader) {
+			orientation = exifOrientation(payload[len(exifHeader):])
+			if orientation > 1 {
+				writeJPEGSegment(w, jpegAPP1, append(bytes.Clone(exifHeader), orientationEXIF(orientation)...))
+			}
+			continue
+		}
+		if !keepJPEGSegment(marker, payload) {
+			continue
+		}
+		writeJPEGSegment(w, marker, payload)
+
+		if marker == jpegSOS {
+			if err := copyJPEGScan(w, r); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// keepJPEGSegment сообщает, нужен ли сегмент для показа изображения.
+func keepJPEGSegment(marker byte, payload []byte) bool {
+	switch {
+	case marker == jpegAPP0:
+		return bytes.HasPrefix(payload, jfifHeader)
+	case marker == jpegAPP2:
+		return bytes.HasPrefix(payload, iccHeader)
+	case marker == jpegAPP14:
+		return bytes.HasPrefix(payload, adobeHeader)
+	case marker >= jpegAPP0 && marker <= jpegAPP15, marker == jpegCOM:
+		return false
+	}
+	return true
+}
+
+// readJPEGMarker читает маркер сегмента, пропуская байты-заполнители 0xFF.
+func readJPEGMarker(r *bufio.Reader) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != 0xFF {
+		return 0, fmt.Errorf("%w: ожидался маркер JPEG", ErrMalformed)
+	}
+	for b == 0xFF {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+	if b == 0 {
+		return 0, fmt.Errorf("%w: ожидался маркер JPEG", ErrMalformed)
+	}
+	return b, nil
+}
+
+// copyJPEGScan копирует сжатые данные скана до маркера следующего сегмента, не считывая его.
+// В сжатых данных байт 0xFF встречается только перед 0x00 (экранирование) или маркером RSTn.
+func copyJPEGScan(w *bufio.Writer, r *bufio.Reader) error {
+	for {
+		data, err := r.ReadSlice(0xFF)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			w.Write(data)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		w.Write(data[:len(data)-1])
+		r.UnreadByte()
+
+		next, err := r.Peek(2)
+		if err != nil {
+			return err
+		}
+		switch {
+		case next[1] == 0x00 || next[1] >= jpegRST0 && next[1] <= jpegRST7:
+			w.Write(next)
+			r.Discard(2)
+		case next[1] == 0xFF:
+			// Байт-заполнитель перед маркером.
+			r.Discard(1)
+		default:
+			// Маркер следующего сегмента разбирает stripJPEG.
+			return nil
+		}
+	}
+}
+
+// writeJPEGSegment записывает сегмент JPEG с маркером и длиной.
+func writeJPEGSegment(w *bufio.Writer, marker byte, payload []byte) {
+	w.Write([]byte{0xFF, marker})
+	w.Write(binary.BigEndian.AppendUint16(nil, uint16(len(payload)+2)))
+	w.Write(payload)
+}
+
+// stripPNG копирует чанки PNG, пропуская eXIf и текстовые чанки tEXt, zTXt, iTXt
+// (в них хранятся XMP и комментарии) и время изменения tIME. Данные после IEND отбрасываются.
+func stripPNG(w *bufio.Writer, r *bufio.Reader) (Orientation, error) {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, signature); err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(signature, pngSignature) {
+		return 0, fmt.Errorf("%w: нет сигнатуры PNG", ErrMalformed)
+	}
+	w.Write(signature)
+
+	orientation := Orientation(1)
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return 0, err
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		chunkType := string(header[4:])
+		if length > maxPNGChunkSize {
+			return 0, fmt.Errorf("%w: некорректная длина чанка PNG", ErrMalformed)
+		}
+
+		switch chunkType {
+		case "eXIf":
+			if length > maxEXIFSize {
+				return 0, fmt.Errorf("%w: слишком большой чанк eXIf", ErrMalformed)
+			}
+			data := make([]byte, length+4)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return 0, err
+			}
+			orientation = exifOrientation(data[:length])
+			if orientation > 1 {
+				writePNGChunk(w, "eXIf", orientationEXIF(orientation))
+			}
+		case "tEXt", "zTXt", "iTXt", "tIME":
+			if _, err := r.Discard(int(length + 4)); err != nil {
+				return 0, err
+			}
+		default:
+			w.Write(header[:])
+			if _, err := io.CopyN(w, r, length+4); err != nil {
+				return 0, err
+			}
+			if chunkType == "IEND" {
+				return orientation, nil
+			}
+		}
+	}
+}
+
+// writePNGChunk записывает чанк PNG с длиной и контрольной суммой.
+func writePNGChunk(w *bufio.Writer, chunkType string, data []byte) {
+	w.Write(binary.BigEndian.AppendUint32(nil, uint32(len(data))))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(data)
+	w.WriteString(chunkType)
+	w.Write(data)
+	w.Write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+}
+
+// stripGIF копирует блоки GIF, пропуская комментарии и расширения приложений,
+// кроме параметров повтора анимации (NETSCAPE2.0, ANIMEXTS1.0). В расширениях
+// приложений хранится, например, XMP. Данные после завершающего блока отбрасываются.
+func stripGIF(w *bufio.Writer, r *bufio.Reader) error {
+	// Заголовок и логический дескриптор экрана.
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(header, []byte("GIF87a")) && !bytes.HasPrefix(header, []byte("GIF89a")) {
+		return fmt.Errorf("%w: нет сигнатуры GIF", ErrMalformed)
+	}
+	w.Write(header)
+	if err := copyGIFColorTable(w, r, header[10]); err != nil {
+		return err
+	}
+
+	for {
+		introducer, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch introducer {
+		case 0x3B: // завершающий блок
+			return w.WriteByte(introducer)
+		case 0x2C: // изображение
+			descriptor := make([]byte, 9)
+			if _, err := io.ReadFull(r, descriptor); err != nil {
+				return err
+			}
+			w.WriteByte(introducer)
+			w.Write(descriptor)
+			if err := copyGIFColorTable(w, r, descriptor[8]); err != nil {
+				return err
+			}
+			// Минимальный размер кода LZW и сжатые данные.
+			codeSize, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			w.WriteByte(codeSize)
+			if err := copyGIFSubBlocks(w, r); err != nil {
+				return err
+			}
+		case 0x21: // расширение
+			label, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			keep := label != 0xFE
+			if label == 0xFF {
+				identifier, err := r.Peek(12)
+				if err != nil {
+					return err
+				}
+				application := string(identifier[1:12])
+				keep = identifier[0] == 11 && (application == "NETSCAPE2.0" || application == "ANIMEXTS1.0")
+			}
+			if !keep {
+				if err := copyGIFSubBlocks(io.Discard, r); err != nil {
+					return err
+				}
+				continue
+			}
+			w.WriteByte(introducer)
+			w.WriteByte(label)
+			if err := copyGIFSubBlocks(w, r); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: неизвестный блок GIF 0x%02x", ErrMalformed, introducer)
+		}
+	}
+}
+
+// copyGIFColorTable копирует таблицу цветов, если она есть по флагам flags.
+func copyGIFColorTable(w io.Writer, r io.Reader, flags byte) error {
+	if flags&0x80 == 0 {
+		return nil
+	}
+	_, err := io.CopyN(w, r, 3<<((flags&0x07)+1))
+	return err
+}
+
+// copyGIFSubBlocks копирует последовательность подблоков вместе с завершающим нулевым блоком.
+func copyGIFSubBlocks(w io.Writer, r *bufio.Reader) error {
+	for {
+		size, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte{size}); err != nil {
+			return err
+		}
+		if size == 0 {
+			return nil
+		}
+		if _, err := io.CopyN(w, r, int64(size)); err != nil {
+			return err
+		}
+	}
+}
+
+// exifOrientation возвращает значение тега Orientation (0x0112) из первого каталога TIFF
+// в блоке EXIF или 1, если тега нет или блок поврежден.
+func exifOrientation(tiff []byte) Orientation {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:4]) != 42 {
+		return 1
+	}
+
+	offset := int64(order.Uint32(tiff[4:8]))
+	if offset+2 > int64(len(tiff)) {
+		return 1
+	}
+	count := int64(order.Uint16(tiff[offset:]))
+	for i := range count {
+		entry := offset + 2 + i*12
+		if entry+12 > int64(len(tiff)) {
+			return 1
+		}
+		// Тег Orientation типа SHORT с одним значением.
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			value := Orientation(order.Uint16(tiff[entry+8:]))
+			if value < 1 || value > 8 {
+				return 1
+			}
+			return value
+		}
+	}
+	return 1
+}
+
+// orientationEXIF возвращает блок TIFF с единственным тегом Orientation.
+func orientationEXIF(orientation Orientation) []byte {
+	tiff := []byte("MM\x00\x2a")
+	tiff = binary.BigEndian.AppendUint32(tiff, 8) // смещение первого каталога
+	tiff = binary.BigEndian.AppendUint16(tiff, 1) // число записей
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = binary.BigEndian.AppendUint16(tiff, 0)
+	return binary.BigEndian.AppendUint32(tiff, 0) // следующего каталога нет
+}
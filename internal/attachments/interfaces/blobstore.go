@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"io"
+)
+
+// BlobStore хранит содержимое вложений. Блоб заполняется последовательно
+// частями загрузки и читается после ее завершения.
+type BlobStore interface {
+	// Create создает пустой блоб с идентификатором id.
+	Create(id string) error
+	// Append дописывает данные из r в блоб, текущий размер которого должен быть равен offset,
+	// и возвращает число записанных байт. Данные, записанные до ошибки чтения r, сохраняются.
+	Append(id string, offset int64, r io.Reader) (int64, error)
+	// Replace атомарно заменяет содержимое блоба данными из r, создавая блоб, если его нет,
+	// и возвращает число записанных байт. При ошибке прежнее содержимое сохраняется.
+	Replace(id string, r io.Reader) (int64, error)
+	// Open открывает блоб для чтения.
+	Open(id string) (io.ReadSeekCloser, error)
+	// Delete удаляет блоб. Удаление несуществующего блоба не является ошибкой.
+	Delete(id string) error
+}
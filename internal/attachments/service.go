@@ -0,0 +1,651 @@
+// Package attachments реализует вложения сообщений: возобновляемую загрузку файлов
+// частями, определение типа содержимого, квоты пользователей и доступ к скачиванию
+// для участников бесед, в которые вложение отправлено. Из изображений удаляются
+// метаданные, для них создаются миниатюры и заглушка BlurHash.
+//
+// Описания вложений хранятся в памяти узла, а содержимое — в BlobStore.
+package attachments
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/attachments/images"
+	"messenger/internal/attachments/interfaces"
+	"messenger/internal/logging"
+	msginterfaces "messenger/internal/messaging/interfaces"
+)
+
+const (
+	// maxNameLength — максимальная длина имени файла вложения в символах.
+	maxNameLength = 255
+	// sniffLength — число первых байт файла, по которым определяется тип содержимого.
+	sniffLength = 512
+	// minCleanupInterval — минимальный интервал удаления незавершенных загрузок.
+	minCleanupInterval = time.Second
+)
+
+var (
+	// ErrNotFound — вложение не существует или недоступно пользователю.
+	ErrNotFound = errors.New("вложение не найдено")
+	// ErrInvalidName — имя файла пустое, слишком длинное или содержит управляющие символы.
+	ErrInvalidName = errors.New("некорректное имя файла")
+	// ErrInvalidSize — размер файла не положительный.
+	ErrInvalidSize = errors.New("размер файла должен быть положительным")
+	// ErrTooLarge — размер файла больше допустимого.
+	ErrTooLarge = errors.New("файл больше допустимого размера")
+	// ErrQuotaExceeded — вложения пользователя превысят его квоту.
+	ErrQuotaExceeded = errors.New("превышена квота вложений")
+	// ErrOffsetMismatch — смещение части не совпадает с числом загруженных байт.
+	ErrOffsetMismatch = errors.New("смещение не совпадает с загруженным размером")
+	// ErrBusy — часть этой загрузки уже записывается.
+	ErrBusy = errors.New("загрузка уже выполняется")
+	// ErrTooMuchData — часть выходит за объявленный размер файла; загрузка удаляется.
+	ErrTooMuchData = errors.New("данные длиннее объявленного размера файла")
+	// ErrTypeNotAllowed — тип содержимого файла не разрешен; загрузка удаляется.
+	ErrTypeNotAllowed = errors.New("тип файла не разрешен")
+	// ErrIncomplete — загрузка вложения не завершена.
+	ErrIncomplete = errors.New("загрузка вложения не завершена")
+	// ErrInvalidImage — файл с типом изображения поврежден; загрузка удаляется.
+	ErrInvalidImage = errors.New("файл изображения поврежден")
+)
+
+// Info — описание вложения и состояние его загрузки.
+type Info struct {
+	ID    string
+	Name  string
+	Owner string
+	// Size — размер файла в байтах: объявленный при создании загрузки, а после
+	// удаления метаданных из изображения — фактический.
+	Size int64
+	// Offset — число загруженных байт; следующая часть загружается с этого смещения.
+	Offset int64
+	// ContentType — тип содержимого, определенный по данным после завершения загрузки.
+	ContentType string
+	Complete    bool
+	CreatedAt   time.Time
+	CompletedAt time.Time
+
+	// Width и Height — размеры изображения с учетом ориентации, Placeholder — его BlurHash.
+	// Заполняются после завершения загрузки изображения.
+	Width       int
+	Height      int
+	Placeholder string
+	// Thumbnails — миниатюры изображения от большей к меньшей.
+	Thumbnails []Thumbnail
+}
+
+// Thumbnail — миниатюра изображения.
+type Thumbnail struct {
+	// Size — ограничение большей стороны из настроек, по которому миниатюра запрашивается.
+	Size        int
+	Width       int
+	Height      int
+	ContentType string
+}
+
+// attachment — вложение и его состояние. Поля Info и conversations защищены мьютексом
+// Service, а write сериализует запись частей.
+type attachment struct {
+	Info
+	conversations []string
+	updatedAt     time.Time
+	write         sync.Mutex
+}
+
+// Service управляет вложениями: создает загрузки, принимает их части, проверяет квоты
+// и тип содержимого, прикрепляет вложения к сообщениям и открывает их для скачивания.
+// Реализует interfaces.AttachmentResolver.
+type Service struct {
+	blobs         interfaces.BlobStore
+	store         msginterfaces.ConversationStore
+	urlPrefix     string
+	maxSize       int64
+	userQuota     int64
+	uploadTimeout time.Duration
+	allowedTypes  []string
+	logger        *slog.Logger
+
+	thumbnailSizes []int
+	maxPixels      int64
+	// imageSlots ограничивает число одновременно обрабатываемых изображений.
+	imageSlots chan struct{}
+
+	mu          sync.Mutex
+	attachments map[string]*attachment
+	usage       map[string]int64
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type Options struct {
+	// Blobs хранит содержимое вложений.
+	Blobs interfaces.BlobStore
+	// Store — хранилище бесед; по нему проверяется, участвует ли пользователь в беседе
+	// с вложением.
+	Store msginterfaces.ConversationStore
+	// URLPrefix — префикс путей HTTP-обработчиков вложений; из него строится URL скачивания.
+	URLPrefix string
+	// MaxSize — максимальный размер вложения в байтах.
+	MaxSize int64
+	// UserQuota — квота пользователя на суммарный размер вложений в байтах,
+	// включая незавершенные загрузки. 0 — без ограничения.
+	UserQuota int64
+	// UploadTimeout — время неактивности, после которого незавершенная загрузка удаляется.
+	UploadTimeout time.Duration
+	// AllowedTypes — допустимые типы содержимого ("image/png") или их префиксы ("image/").
+	// Пустой — любые.
+	AllowedTypes []string
+	// ThumbnailSizes — ограничения большей стороны миниатюр изображений в пикселях.
+	// Пустой — миниатюры и заглушки не создаются.
+	ThumbnailSizes []int
+	// MaxImagePixels — максимальное число пикселей изображения, для которого создаются
+	// миниатюры. 0 — без ограничения.
+	MaxImagePixels int64
+	// Logger — логгер службы. Если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+// New создает службу вложений и запускает удаление незавершенных загрузок,
+// неактивных дольше UploadTimeout. Остановить его можно через Close.
+func New(options Options) *Service {
+	s := &Service{
+		blobs:         options.Blobs,
+		store:         options.Store,
+		urlPrefix:     options.URLPrefix,
+		maxSize:       options.MaxSize,
+		userQuota:     options.UserQuota,
+		uploadTimeout: options.UploadTimeout,
+		allowedTypes:  options.AllowedTypes,
+		maxPixels:     options.MaxImagePixels,
+		imageSlots:    make(chan struct{}, runtime.GOMAXPROCS(0)),
+		attachments:   make(map[string]*attachment),
+		usage:         make(map[string]int64),
+		done:          make(chan struct{}),
+	}
+	s.logger = logging.Component(options.Logger, s.Tag())
+
+	s.thumbnailSizes = slices.Clone(options.ThumbnailSizes)
+	slices.Sort(s.thumbnailSizes)
+	slices.Reverse(s.thumbnailSizes)
+	s.thumbnailSizes = slices.Compact(s.thumbnailSizes)
+
+	if s.uploadTimeout > 0 {
+		go s.cleanupLoop(max(s.uploadTimeout/4, minCleanupInterval))
+	}
+	return s
+}
+
+// Tag возвращает строковый идентификатор для Service.
+func (*Service) Tag() string {
+	return "ATTACHMENTS"
+}
+
+// Close останавливает удаление незавершенных загрузок. Повторные вызовы безопасны.
+func (s *Service) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// Create начинает загрузку файла name размером size байт от имени пользователя owner.
+// Объявленный размер сразу учитывается в квоте пользователя.
+func (s *Service) Create(owner, name string, size int64) (Info, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return Info{}, err
+	}
+	if size <= 0 {
+		return Info{}, ErrInvalidSize
+	}
+	if s.maxSize > 0 && size > s.maxSize {
+		return Info{}, ErrTooLarge
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Info{}, err
+	}
+
+	s.mu.Lock()
+	if s.userQuota > 0 && s.usage[owner]+size > s.userQuota {
+		s.mu.Unlock()
+		return Info{}, ErrQuotaExceeded
+	}
+	// Квота резервируется до создания блоба, чтобы параллельные загрузки не превысили ее.
+	s.usage[owner] += size
+	s.mu.Unlock()
+
+	if err := s.blobs.Create(id); err != nil {
+		s.release(owner, size)
+		return Info{}, fmt.Errorf("ошибка создания файла вложения: %w", err)
+	}
+
+	now := time.Now()
+	a := &attachment{
+		Info: Info{
+			ID:        id,
+			Name:      name,
+			Owner:     owner,
+			Size:      size,
+			CreatedAt: now,
+		},
+		updatedAt: now,
+	}
+
+	s.mu.Lock()
+	s.attachments[id] = a
+	s.mu.Unlock()
+
+	s.logger.Debug("Загрузка вложения начата",
+		slog.String("attachment", id), slog.String(logging.KeyUserID, owner), slog.Int64("size", size))
+	return a.Info, nil
+}
+
+// Info возвращает описание вложения. Незавершенная загрузка доступна только ее владельцу,
+// завершенная — также участникам бесед, в которые вложение отправлено.
+func (s *Service) Info(userID, id string) (Info, error) {
+	s.mu.Lock()
+	a, ok := s.attachments[id]
+	if !ok {
+		s.mu.Unlock()
+		return Info{}, ErrNotFound
+	}
+	info := a.Info
+	conversations := slices.Clone(a.conversations)
+	s.mu.Unlock()
+
+	if !s.canRead(userID, info, conversations) {
+		return Info{}, ErrNotFound
+	}
+	return info, nil
+}
+
+// Write записывает часть загрузки владельца owner, начинающуюся со смещения offset.
+// Смещение должно совпадать с числом уже загруженных байт, поэтому после обрыва
+// загрузку можно продолжить с Info.Offset. Если часть завершает файл, по его первым байтам
+// определяется тип содержимого; файл недопустимого типа удаляется с ошибкой ErrTypeNotAllowed.
+// Из изображения удаляются метаданные, после чего размер файла может уменьшиться,
+// и создаются миниатюры.
+//
+// Возвращает состояние загрузки после записи, в том числе вместе с ошибкой записи,
+// чтобы клиент мог продолжить загрузку с сохраненного смещения.
+func (s *Service) Write(owner, id string, offset int64, r io.Reader) (Info, error) {
+	s.mu.Lock()
+	a, ok := s.attachments[id]
+	if !ok || a.Owner != owner {
+		s.mu.Unlock()
+		return Info{}, ErrNotFound
+	}
+	if !a.write.TryLock() {
+		info := a.Info
+		s.mu.Unlock()
+		return info, ErrBusy
+	}
+	defer a.write.Unlock()
+	if a.Complete || a.Offset != offset {
+		info := a.Info
+		s.mu.Unlock()
+		return info, ErrOffsetMismatch
+	}
+	remaining := a.Size - a.Offset
+	s.mu.Unlock()
+
+	written, writeErr := s.blobs.Append(id, offset, io.LimitReader(r, remaining))
+
+	s.mu.Lock()
+	a.Offset += written
+	a.updatedAt = time.Now()
+	info := a.Info
+	s.mu.Unlock()
+
+	if writeErr != nil {
+		return info, writeErr
+	}
+	if written == remaining {
+		if n, _ := r.Read(make([]byte, 1)); n > 0 {
+			s.remove(a)
+			return Info{}, ErrTooMuchData
+		}
+	}
+	if info.Offset < info.Size {
+		return info, nil
+	}
+	return s.complete(a)
+}
+
+// complete завершает загрузку: определяет тип содержимого по первым байтам файла,
+// проверяет, что он разрешен, и обрабатывает изображения. Вызывается с захваченной
+// записью вложения.
+func (s *Service) complete(a *attachment) (Info, error) {
+	contentType, err := s.sniff(a.ID)
+	if err != nil {
+		s.remove(a)
+		return Info{}, fmt.Errorf("ошибка определения типа файла: %w", err)
+	}
+	if !s.allowed(contentType) {
+		s.remove(a)
+		s.logger.Info("Вложение отклонено: тип файла не разрешен",
+			slog.String("attachment", a.ID), slog.String("content_type", contentType))
+		return Info{}, ErrTypeNotAllowed
+	}
+
+	var processed processedImage
+	if images.Supported(contentType) {
+		processed, err = s.processImage(a.ID, contentType)
+		if errors.Is(err, images.ErrMalformed) {
+			s.remove(a)
+			s.logger.Info("Вложение отклонено: изображение повреждено",
+				slog.String("attachment", a.ID), slog.Any("error", err))
+			return Info{}, ErrInvalidImage
+		}
+		if err != nil {
+			s.remove(a)
+			return Info{}, fmt.Errorf("ошибка обработки изображения: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	if processed.size > 0 && processed.size != a.Size {
+		// Без метаданных файл меньше: квота пользователя уменьшается на разницу.
+		s.usage[a.Owner] += processed.size - a.Size
+		a.Size, a.Offset = processed.size, processed.size
+	}
+	a.Width, a.Height = processed.width, processed.height
+	a.Placeholder = processed.placeholder
+	a.Thumbnails = processed.thumbnails
+	a.ContentType = contentType
+	a.Complete = true
+	a.CompletedAt = time.Now()
+	info := a.Info
+	s.mu.Unlock()
+
+	s.logger.Info("Вложение загружено",
+		slog.String("attachment", a.ID), slog.String(logging.KeyUserID, a.Owner),
+		slog.Int64("size", info.Size), slog.String("content_type", contentType))
+	return info, nil
+}
+
+// Resolve проверяет, что вложение id полностью загружено пользователем userID,
+// и возвращает описание вложения для сообщения.
+func (s *Service) Resolve(userID, id string) (msg.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.attachments[id]
+	if !ok || a.Owner != userID {
+		return msg.Attachment{}, ErrNotFound
+	}
+	if !a.Complete {
+		return msg.Attachment{}, ErrIncomplete
+	}
+	return s.Reference(a.Info), nil
+}
+
+// Attach открывает доступ к вложению id пользователя userID участникам беседы
+// conversation, в которую сохранено сообщение с этим вложением.
+func (s *Service) Attach(userID, conversation, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.attachments[id]
+	if !ok || a.Owner != userID {
+		return ErrNotFound
+	}
+	if !slices.Contains(a.conversations, conversation) {
+		a.conversations = append(a.conversations, conversation)
+	}
+	return nil
+}
+
+// Open открывает содержимое завершенного вложения для скачивания пользователем userID.
+// Скачать вложение может его владелец и участники бесед, в которые оно отправлено.
+func (s *Service) Open(userID, id string) (io.ReadSeekCloser, Info, error) {
+	info, err := s.Info(userID, id)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	if !info.Complete {
+		return nil, Info{}, ErrIncomplete
+	}
+
+	content, err := s.blobs.Open(id)
+	if err != nil {
+		return nil, Info{}, fmt.Errorf("ошибка открытия файла вложения: %w", err)
+	}
+	return content, info, nil
+}
+
+// OpenThumbnail открывает миниатюру size завершенного вложения для скачивания пользователем
+// userID. Доступ проверяется так же, как в Open.
+func (s *Service) OpenThumbnail(userID, id string, size int) (io.ReadSeekCloser, Info, Thumbnail, error) {
+	info, err := s.Info(userID, id)
+	if err != nil {
+		return nil, Info{}, Thumbnail{}, err
+	}
+	if !info.Complete {
+		return nil, Info{}, Thumbnail{}, ErrIncomplete
+	}
+	index := slices.IndexFunc(info.Thumbnails, func(thumbnail Thumbnail) bool { return thumbnail.Size == size })
+	if index < 0 {
+		return nil, Info{}, Thumbnail{}, ErrNotFound
+	}
+
+	content, err := s.blobs.Open(thumbnailBlobID(id, size))
+	if err != nil {
+		return nil, Info{}, Thumbnail{}, fmt.Errorf("ошибка открытия миниатюры: %w", err)
+	}
+	return content, info, info.Thumbnails[index], nil
+}
+
+// Delete удаляет вложение владельца owner и освобождает его квоту. Сообщения
+// с этим вложением сохраняются, но скачать его больше нельзя.
+func (s *Service) Delete(owner, id string) error {
+	s.mu.Lock()
+	a, ok := s.attachments[id]
+	if !ok || a.Owner != owner {
+		s.mu.Unlock()
+		return ErrNotFound
+	}
+	if !a.write.TryLock() {
+		s.mu.Unlock()
+		return ErrBusy
+	}
+	s.mu.Unlock()
+	defer a.write.Unlock()
+
+	s.remove(a)
+	return nil
+}
+
+// URL возвращает путь для скачивания вложения id.
+func (s *Service) URL(id string) string {
+	return s.urlPrefix + "/" + id + "/content"
+}
+
+// ThumbnailURL возвращает путь для скачивания миниатюры size вложения id.
+func (s *Service) ThumbnailURL(id string, size int) string {
+	return s.urlPrefix + "/" + id + "/thumbnails/" + strconv.Itoa(size)
+}
+
+// Reference возвращает описание вложения для сообщения.
+func (s *Service) Reference(info Info) msg.Attachment {
+	reference := msg.Attachment{
+		ID:          info.ID,
+		Name:        info.Name,
+		ContentType: info.ContentType,
+		Size:        info.Size,
+		URL:         s.URL(info.ID),
+		Width:       info.Width,
+		Height:      info.Height,
+		Placeholder: info.Placeholder,
+	}
+	for _, thumbnail := range info.Thumbnails {
+		reference.Thumbnails = append(reference.Thumbnails, msg.Thumbnail{
+			Width:       thumbnail.Width,
+			Height:      thumbnail.Height,
+			ContentType: thumbnail.ContentType,
+			URL:         s.ThumbnailURL(info.ID, thumbnail.Size),
+		})
+	}
+	return reference
+}
+
+// canRead сообщает, может ли пользователь читать вложение: владелец — всегда,
+// участник беседы с вложением — после завершения загрузки.
+func (s *Service) canRead(userID string, info Info, conversations []string) bool {
+	if info.Owner == userID {
+		return true
+	}
+	if !info.Complete || s.store == nil {
+		return false
+	}
+	for _, conversation := range conversations {
+		members, err := s.store.Members(conversation)
+		if err != nil {
+			s.logger.Warn("Ошибка получения участников беседы",
+				slog.String("conversation", conversation), slog.Any("error", err))
+			continue
+		}
+		if slices.Contains(members, userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniff определяет тип содержимого файла по его первым байтам.
+func (s *Service) sniff(id string) (string, error) {
+	content, err := s.blobs.Open(id)
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// allowed сообщает, разрешен ли тип содержимого настройками службы.
+func (s *Service) allowed(contentType string) bool {
+	if len(s.allowedTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range s.allowedTypes {
+		if strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed) || mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// remove удаляет вложение, его файл и миниатюры и освобождает квоту владельца.
+func (s *Service) remove(a *attachment) {
+	s.mu.Lock()
+	delete(s.attachments, a.ID)
+	size, thumbnails := a.Size, a.Thumbnails
+	s.mu.Unlock()
+	s.release(a.Owner, size)
+
+	if err := s.blobs.Delete(a.ID); err != nil {
+		s.logger.Warn("Ошибка удаления файла вложения", slog.String("attachment", a.ID), slog.Any("error", err))
+	}
+	s.deleteThumbnails(a.ID, thumbnails)
+}
+
+// release возвращает size байт в квоту пользователя owner.
+func (s *Service) release(owner string, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usage[owner] -= size
+	if s.usage[owner] <= 0 {
+		delete(s.usage, owner)
+	}
+}
+
+// cleanupLoop удаляет незавершенные загрузки, неактивные дольше uploadTimeout,
+// пока служба не закрыта.
+func (s *Service) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.cleanup(time.Now().Add(-s.uploadTimeout))
+		}
+	}
+}
+
+// cleanup удаляет незавершенные загрузки, не обновлявшиеся с момента deadline.
+// Загрузки, в которые сейчас записывается часть, пропускаются.
+func (s *Service) cleanup(deadline time.Time) {
+	var expired []*attachment
+	s.mu.Lock()
+	for _, a := range s.attachments {
+		if !a.Complete && a.updatedAt.Before(deadline) && a.write.TryLock() {
+			expired = append(expired, a)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, a := range expired {
+		s.remove(a)
+		a.write.Unlock()
+		s.logger.Info("Незавершенная загрузка удалена",
+			slog.String("attachment", a.ID), slog.String(logging.KeyUserID, a.Owner),
+			slog.Int64("offset", a.Offset), slog.Int64("size", a.Size))
+	}
+}
+
+// cleanName оставляет от имени файла только базовое имя и проверяет его.
+func cleanName(name string) (string, error) {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "" || name == "." || name == ".." || name == "/" || !utf8.ValidString(name) ||
+		utf8.RuneCountInString(name) > maxNameLength {
+		return "", ErrInvalidName
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return "", ErrInvalidName
+		}
+	}
+	return name, nil
+}
+
+// newID возвращает случайный идентификатор вложения.
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,161 @@
+package attachments
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"messenger/internal/attachments/images"
+)
+
+const (
+	// placeholderSize — большая сторона изображения, по которому вычисляется BlurHash.
+	placeholderSize = 32
+	// placeholderComponents — число гармоник BlurHash по длинной стороне изображения;
+	// по короткой — на одну меньше.
+	placeholderComponents = 4
+)
+
+// processedImage — результат обработки изображения.
+type processedImage struct {
+	// size — размер файла после удаления метаданных.
+	size          int64
+	width, height int
+	placeholder   string
+	thumbnails    []Thumbnail
+}
+
+// processImage удаляет метаданные из изображения id, создает его миниатюры и вычисляет
+// заглушку BlurHash. Для изображений больше maxPixels пикселей миниатюры не создаются.
+// Повреждение файла возвращается как images.ErrMalformed.
+func (s *Service) processImage(id, contentType string) (processedImage, error) {
+	s.imageSlots <- struct{}{}
+	defer func() { <-s.imageSlots }()
+
+	orientation, size, err := s.stripMetadata(id, contentType)
+	if err != nil {
+		return processedImage{}, err
+	}
+	result := processedImage{size: size}
+	if len(s.thumbnailSizes) == 0 {
+		return result, nil
+	}
+
+	content, err := s.blobs.Open(id)
+	if err != nil {
+		return processedImage{}, err
+	}
+	defer content.Close()
+
+	img, err := images.Decode(content, s.maxPixels)
+	if errors.Is(err, images.ErrTooManyPixels) {
+		s.logger.Info("Миниатюры не созданы: изображение слишком большое", slog.String("attachment", id))
+		return result, nil
+	}
+	if err != nil {
+		return processedImage{}, err
+	}
+
+	bounds := img.Bounds()
+	result.width, result.height = bounds.Dx(), bounds.Dy()
+	if orientation >= 5 {
+		result.width, result.height = result.height, result.width
+	}
+
+	// Каждая следующая миниатюра уменьшается из предыдущей, а не из исходного изображения.
+	current := img
+	for _, thumbnailSize := range s.thumbnailSizes {
+		if max(bounds.Dx(), bounds.Dy()) <= thumbnailSize {
+			continue
+		}
+		width, height := images.Fit(bounds.Dx(), bounds.Dy(), thumbnailSize)
+		resized := images.Resize(current, width, height)
+		current = resized
+
+		thumbnail, err := s.writeThumbnail(id, thumbnailSize, images.Orient(resized, orientation))
+		if err != nil {
+			s.deleteThumbnails(id, result.thumbnails)
+			return processedImage{}, err
+		}
+		result.thumbnails = append(result.thumbnails, thumbnail)
+	}
+
+	width, height := images.Fit(bounds.Dx(), bounds.Dy(), placeholderSize)
+	tiny := images.Orient(images.Resize(current, width, height), orientation)
+	xComponents, yComponents := placeholderComponents, placeholderComponents-1
+	if tiny.Rect.Dy() > tiny.Rect.Dx() {
+		xComponents, yComponents = yComponents, xComponents
+	}
+	result.placeholder = images.Placeholder(tiny, xComponents, yComponents)
+
+	s.logger.Debug("Изображение обработано",
+		slog.String("attachment", id), slog.Int("width", result.width), slog.Int("height", result.height),
+		slog.Int("thumbnails", len(result.thumbnails)))
+	return result, nil
+}
+
+// stripMetadata заменяет содержимое изображения id копией без метаданных и возвращает
+// ориентацию из EXIF и новый размер файла.
+func (s *Service) stripMetadata(id, contentType string) (images.Orientation, int64, error) {
+	content, err := s.blobs.Open(id)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer content.Close()
+
+	reader, writer := io.Pipe()
+	var orientation images.Orientation
+	var stripErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		orientation, stripErr = images.StripMetadata(writer, content, contentType)
+		writer.CloseWithError(stripErr)
+	}()
+
+	size, err := s.blobs.Replace(id, reader)
+	reader.Close()
+	<-done
+
+	if stripErr != nil {
+		return 0, 0, stripErr
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return orientation, size, nil
+}
+
+// writeThumbnail кодирует и сохраняет миниатюру size изображения id.
+func (s *Service) writeThumbnail(id string, size int, img *image.RGBA) (Thumbnail, error) {
+	data, contentType, err := images.Encode(img)
+	if err != nil {
+		return Thumbnail{}, err
+	}
+	if _, err := s.blobs.Replace(thumbnailBlobID(id, size), bytes.NewReader(data)); err != nil {
+		return Thumbnail{}, err
+	}
+	return Thumbnail{
+		Size:        size,
+		Width:       img.Rect.Dx(),
+		Height:      img.Rect.Dy(),
+		ContentType: contentType,
+	}, nil
+}
+
+// deleteThumbnails удаляет файлы миниатюр вложения id.
+func (s *Service) deleteThumbnails(id string, thumbnails []Thumbnail) {
+	for _, thumbnail := range thumbnails {
+		if err := s.blobs.Delete(thumbnailBlobID(id, thumbnail.Size)); err != nil {
+			s.logger.Warn("Ошибка удаления миниатюры", slog.String("attachment", id), slog.Any("error", err))
+		}
+	}
+}
+
+// thumbnailBlobID возвращает идентификатор блоба миниатюры size вложения id.
+func thumbnailBlobID(id string, size int) string {
+	return id + "-" + strconv.Itoa(size)
+}
@@ -0,0 +1,26 @@
+package authenticators
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"messenger/internal/auth/models"
+	"net/http"
+)
+
+// AnonymousAuthenticator используется при выключенной аутентификации:
+// каждому запросу присваивается случайный анонимный идентификатор пользователя.
+type AnonymousAuthenticator struct{}
+
+// NewAnonymous создает AnonymousAuthenticator.
+func NewAnonymous() *AnonymousAuthenticator {
+	return &AnonymousAuthenticator{}
+}
+
+// Authenticate возвращает личность со случайным идентификатором вида "anonymous-<hex>".
+func (*AnonymousAuthenticator) Authenticate(r *http.Request) (models.Identity, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return models.Identity{}, err
+	}
+	return models.Identity{UserID: "anonymous-" + hex.EncodeToString(buf), Anonymous: true}, nil
+}
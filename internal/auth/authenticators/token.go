@@ -0,0 +1,63 @@
+package authenticators
+
+import (
+	"crypto/subtle"
+	"errors"
+	"messenger/internal/auth/models"
+	"net/http"
+	"strings"
+)
+
+// ErrUnauthorized возвращается, если запрос не содержит действительного токена.
+var ErrUnauthorized = errors.New("требуется аутентификация")
+
+// accessTokenParam — имя параметра запроса с токеном. Браузерный WebSocket API
+// не позволяет задать заголовок Authorization, поэтому токен можно передать в URL.
+const accessTokenParam = "access_token"
+
+// TokenAuthenticator аутентифицирует клиентов по статическим токенам доступа из конфигурации.
+// Одинаково используется для WebSocket-соединений, резервных транспортов и REST API.
+type TokenAuthenticator struct {
+	users map[string]string
+}
+
+// NewToken создает TokenAuthenticator.
+//
+// Параметры:
+//   - users: Соответствие токена доступа идентификатору пользователя.
+func NewToken(users map[string]string) *TokenAuthenticator {
+	return &TokenAuthenticator{
+		users: users,
+	}
+}
+
+// Authenticate извлекает токен из заголовка "Authorization: Bearer <токен>" или из параметра
+// запроса access_token и возвращает соответствующую ему личность.
+// Токены сравниваются за постоянное время. Возвращает ErrUnauthorized, если токен
+// отсутствует или неизвестен.
+func (ta *TokenAuthenticator) Authenticate(r *http.Request) (models.Identity, error) {
+	token := extractToken(r)
+	if token == "" {
+		return models.Identity{}, ErrUnauthorized
+	}
+
+	for knownToken, userID := range ta.users {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(knownToken)) == 1 {
+			return models.Identity{UserID: userID}, nil
+		}
+	}
+
+	return models.Identity{}, ErrUnauthorized
+}
+
+// extractToken возвращает токен доступа из заголовка Authorization или параметра запроса.
+func extractToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return r.URL.Query().Get(accessTokenParam)
+}
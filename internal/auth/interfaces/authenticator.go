@@ -0,0 +1,10 @@
+package interfaces
+
+import (
+	"messenger/internal/auth/models"
+	"net/http"
+)
+
+type Authenticator interface {
+	Authenticate(r *http.Request) (models.Identity, error)
+}
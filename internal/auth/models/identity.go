@@ -0,0 +1,9 @@
+package models
+
+// Identity описывает аутентифицированного клиента.
+type Identity struct {
+	UserID string
+	// Anonymous равен true, если аутентификация выключена и идентификатор
+	// пользователя сгенерирован случайно для отдельного запроса.
+	Anonymous bool
+}
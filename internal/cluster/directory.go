@@ -0,0 +1,390 @@
+// Package cluster содержит каталог подключений кластера: какие пользователи подключены
+// к каким узлам. Узлы обмениваются состоянием через шину сообщений.
+package cluster
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"log/slog"
+	"messenger/internal/logging"
+	"messenger/internal/messaging/bus"
+	"messenger/internal/messaging/interfaces"
+	"messenger/internal/metrics"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultHeartbeatInterval — период отправки пульса узла, если не задан.
+	defaultHeartbeatInterval = 5 * time.Second
+	// leaseIntervals — срок аренды по умолчанию в периодах пульса: узел исключается
+	// из каталога, если пропустил столько пульсов подряд.
+	leaseIntervals = 3
+	// publishTimeout — таймаут публикации событий каталога в шину.
+	publishTimeout = 5 * time.Second
+)
+
+// Типы сообщений каталога. Передаются только между узлами через шину; From — идентификатор узла.
+var (
+	// heartbeatType — пульс узла; Text — JSON-массив подключенных к узлу пользователей.
+	// Продлевает аренду узла и заменяет его записи в каталоге.
+	heartbeatType = msg.MustRegister("cluster.heartbeat")
+	// userOnlineType и userOfflineType — подключение первого и отключение последнего
+	// соединения пользователя на узле; Text — идентификатор пользователя.
+	userOnlineType  = msg.MustRegister("cluster.user_online")
+	userOfflineType = msg.MustRegister("cluster.user_offline")
+	// nodeLeftType — штатная остановка узла: его записи удаляются сразу, не дожидаясь
+	// истечения аренды.
+	nodeLeftType = msg.MustRegister("cluster.node_left")
+	// syncType — запрос нового узла: получившие его узлы сразу отправляют пульс.
+	syncType = msg.MustRegister("cluster.sync")
+)
+
+// Directory — каталог подключений кластера. Каждый узел хранит копию каталога:
+// своих пользователей он знает сам, а о пользователях других узлов узнает из событий
+// подключения и отключения и из периодических пульсов с полным списком пользователей.
+// Пульс продлевает аренду узла; если узел перестал присылать пульс (например, упал),
+// после истечения аренды его записи удаляются из каталога.
+//
+// Каталог согласован в конечном счете: в течение первого периода пульса после запуска
+// узел еще не знает всех пользователей кластера, и Lookup сообщает об этом.
+type Directory struct {
+	nodeID            string
+	bus               interfaces.Bus
+	heartbeatInterval time.Duration
+	leaseTTL          time.Duration
+	logger            *slog.Logger
+
+	// publishMu упорядочивает публикацию событий узла, чтобы пульс со списком
+	// пользователей не обогнал в шине более раннее событие подключения или отключения.
+	publishMu sync.Mutex
+
+	mu      sync.RWMutex
+	local   map[string]struct{}
+	nodes   map[string]*node
+	readyAt time.Time
+
+	unsubscribe func()
+	done        chan struct{}
+	stopped     chan struct{}
+	closeOnce   sync.Once
+}
+
+type node struct {
+	users   map[string]struct{}
+	expires time.Time
+}
+
+// NodeInfo — состояние узла в каталоге.
+type NodeInfo struct {
+	ID    string `json:"id"`
+	Users int    `json:"users"`
+	// Local — узел, которому принадлежит каталог.
+	Local bool `json:"local"`
+	// LeaseExpires — время истечения аренды узла. Для локального узла не задается.
+	LeaseExpires time.Time `json:"lease_expires,omitempty"`
+}
+
+type Options struct {
+	// NodeID — идентификатор узла, уникальный в кластере. По умолчанию — имя хоста
+	// со случайным суффиксом.
+	NodeID string
+	// Bus — шина сообщений между узлами.
+	Bus interfaces.Bus
+	// HeartbeatInterval — период отправки пульса. По умолчанию 5s.
+	HeartbeatInterval time.Duration
+	// LeaseTTL — срок аренды узла после последнего события от него. По умолчанию три периода пульса.
+	LeaseTTL time.Duration
+	// Logger — логгер каталога. Если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+// New создает каталог подключений узла. Обмен состоянием с другими узлами начинается после Start.
+func New(options Options) *Directory {
+	nodeID := options.NodeID
+	if nodeID == "" {
+		nodeID = defaultNodeID()
+	}
+	heartbeatInterval := options.HeartbeatInterval
+	if heartbeatInterval == 0 {
+		heartbeatInterval = defaultHeartbeatInterval
+	}
+	leaseTTL := options.LeaseTTL
+	if leaseTTL == 0 {
+		leaseTTL = leaseIntervals * heartbeatInterval
+	}
+
+	return &Directory{
+		nodeID:            nodeID,
+		bus:               options.Bus,
+		heartbeatInterval: heartbeatInterval,
+		leaseTTL:          leaseTTL,
+		logger:            logging.Component(options.Logger, "CLUSTER_DIRECTORY").With(slog.String("node_id", nodeID)),
+		local:             make(map[string]struct{}),
+		nodes:             make(map[string]*node),
+		done:              make(chan struct{}),
+		stopped:           make(chan struct{}),
+	}
+}
+
+// Start подписывает каталог на события других узлов, запрашивает их состояние
+// и запускает отправку пульса и удаление узлов с истекшей арендой.
+func (d *Directory) Start() error {
+	unsubscribe, err := d.bus.Subscribe(bus.DirectoryTopic, d.handle)
+	if err != nil {
+		return err
+	}
+	d.unsubscribe = unsubscribe
+
+	d.mu.Lock()
+	d.readyAt = time.Now().Add(d.heartbeatInterval)
+	d.mu.Unlock()
+	metrics.ClusterNodes.Set(1)
+
+	d.publish(msg.Message{Type: syncType})
+	d.heartbeat()
+
+	go d.run()
+
+	d.logger.Info("Каталог подключений кластера запущен",
+		slog.Duration("heartbeat_interval", d.heartbeatInterval), slog.Duration("lease_ttl", d.leaseTTL))
+	return nil
+}
+
+// Close сообщает другим узлам об остановке узла и прекращает обмен состоянием.
+// Повторные вызовы безопасны.
+func (d *Directory) Close() error {
+	d.closeOnce.Do(func() {
+		close(d.done)
+		if d.unsubscribe == nil {
+			return
+		}
+		<-d.stopped
+		d.unsubscribe()
+		d.publish(msg.Message{Type: nodeLeftType})
+	})
+	return nil
+}
+
+// NodeID возвращает идентификатор узла.
+func (d *Directory) NodeID() string {
+	return d.nodeID
+}
+
+// Connected отмечает, что к узлу подключилось первое соединение пользователя userID,
+// и сообщает об этом другим узлам.
+func (d *Directory) Connected(userID string) {
+	d.publishMu.Lock()
+	defer d.publishMu.Unlock()
+
+	d.mu.Lock()
+	d.local[userID] = struct{}{}
+	d.mu.Unlock()
+
+	d.publishLocked(msg.Message{Type: userOnlineType, Text: userID})
+}
+
+// Disconnected отмечает, что закрылось последнее соединение пользователя userID
+// на узле, и сообщает об этом другим узлам.
+func (d *Directory) Disconnected(userID string) {
+	d.publishMu.Lock()
+	defer d.publishMu.Unlock()
+
+	d.mu.Lock()
+	delete(d.local, userID)
+	d.mu.Unlock()
+
+	d.publishLocked(msg.Message{Type: userOfflineType, Text: userID})
+}
+
+// Lookup возвращает идентификаторы узлов, к которым подключен пользователь userID.
+// Возвращает false, если каталог еще не получил состояние всех узлов после запуска
+// и пустой список не означает, что пользователь не подключен.
+func (d *Directory) Lookup(userID string) ([]string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var nodeIDs []string
+	if _, ok := d.local[userID]; ok {
+		nodeIDs = append(nodeIDs, d.nodeID)
+	}
+	for nodeID, n := range d.nodes {
+		if _, ok := n.users[userID]; ok {
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+	}
+	return nodeIDs, !d.readyAt.IsZero() && !time.Now().Before(d.readyAt)
+}
+
+// Nodes возвращает состояние узлов кластера, известных каталогу, включая локальный.
+func (d *Directory) Nodes() []NodeInfo {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	nodes := make([]NodeInfo, 0, len(d.nodes)+1)
+	nodes = append(nodes, NodeInfo{ID: d.nodeID, Users: len(d.local), Local: true})
+	for nodeID, n := range d.nodes {
+		nodes = append(nodes, NodeInfo{ID: nodeID, Users: len(n.users), LeaseExpires: n.expires})
+	}
+	slices.SortFunc(nodes[1:], func(a, b NodeInfo) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return nodes
+}
+
+// run периодически отправляет пульс узла и удаляет узлы с истекшей арендой.
+func (d *Directory) run() {
+	defer close(d.stopped)
+
+	ticker := time.NewTicker(d.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			d.heartbeat()
+			d.expire(time.Now())
+		}
+	}
+}
+
+// heartbeat публикует пульс со списком подключенных к узлу пользователей.
+func (d *Directory) heartbeat() {
+	select {
+	case <-d.done:
+		return
+	default:
+	}
+
+	d.publishMu.Lock()
+	defer d.publishMu.Unlock()
+
+	d.mu.RLock()
+	users := make([]string, 0, len(d.local))
+	for userID := range d.local {
+		users = append(users, userID)
+	}
+	d.mu.RUnlock()
+
+	payload, err := json.Marshal(users)
+	if err != nil {
+		d.logger.Error("Ошибка кодирования пульса узла", slog.Any("error", err))
+		return
+	}
+	d.publishLocked(msg.Message{Type: heartbeatType, Text: string(payload)})
+}
+
+// expire удаляет из каталога узлы, аренда которых истекла к моменту now.
+func (d *Directory) expire(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for nodeID, n := range d.nodes {
+		if now.Before(n.expires) {
+			continue
+		}
+		delete(d.nodes, nodeID)
+		metrics.ClusterLeasesExpired.Inc()
+		d.logger.Warn("Аренда узла истекла, его подключения удалены из каталога",
+			slog.String("peer_node_id", nodeID), slog.Int("users", len(n.users)))
+	}
+	metrics.ClusterNodes.Set(float64(len(d.nodes) + 1))
+}
+
+// handle применяет к каталогу событие другого узла.
+func (d *Directory) handle(message msg.Message) {
+	nodeID := message.From
+	if nodeID == "" || nodeID == d.nodeID {
+		return
+	}
+
+	switch message.Type {
+	case syncType:
+		// Пульс отправляется в отдельной горутине: обработчик может вызываться
+		// синхронно внутри публикации другого узла.
+		go d.heartbeat()
+		d.renew(nodeID, nil)
+	case heartbeatType:
+		var users []string
+		if err := json.Unmarshal([]byte(message.Text), &users); err != nil {
+			d.logger.Warn("Некорректный пульс узла", slog.String("peer_node_id", nodeID), slog.Any("error", err))
+			return
+		}
+		d.renew(nodeID, func(n *node) {
+			n.users = make(map[string]struct{}, len(users))
+			for _, userID := range users {
+				n.users[userID] = struct{}{}
+			}
+		})
+	case userOnlineType:
+		d.renew(nodeID, func(n *node) { n.users[message.Text] = struct{}{} })
+	case userOfflineType:
+		d.renew(nodeID, func(n *node) { delete(n.users, message.Text) })
+	case nodeLeftType:
+		d.mu.Lock()
+		delete(d.nodes, nodeID)
+		metrics.ClusterNodes.Set(float64(len(d.nodes) + 1))
+		d.mu.Unlock()
+		d.logger.Info("Узел покинул кластер", slog.String("peer_node_id", nodeID))
+	}
+}
+
+// renew продлевает аренду узла nodeID, добавляя его в каталог при первом событии,
+// и применяет к его записям update.
+func (d *Directory) renew(nodeID string, update func(n *node)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n, ok := d.nodes[nodeID]
+	if !ok {
+		n = &node{users: make(map[string]struct{})}
+		d.nodes[nodeID] = n
+		metrics.ClusterNodes.Set(float64(len(d.nodes) + 1))
+		d.logger.Info("Узел присоединился к кластеру", slog.String("peer_node_id", nodeID))
+	}
+	n.expires = time.Now().Add(d.leaseTTL)
+	if update != nil {
+		update(n)
+	}
+}
+
+// publish публикует событие узла в шину.
+func (d *Directory) publish(message msg.Message) {
+	d.publishMu.Lock()
+	defer d.publishMu.Unlock()
+
+	d.publishLocked(message)
+}
+
+// publishLocked публикует событие узла в шину. Вызывается под publishMu.
+// Ошибка публикации логируется: состояние узла восстановится следующим пульсом.
+func (d *Directory) publishLocked(message msg.Message) {
+	message.From = d.nodeID
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	if err := d.bus.Publish(ctx, bus.DirectoryTopic, message); err != nil {
+		d.logger.Warn("Ошибка публикации события каталога", logging.MessageType(message.Type), slog.Any("error", err))
+	}
+}
+
+// defaultNodeID возвращает имя хоста со случайным суффиксом, чтобы перезапущенный
+// узел не унаследовал записи своего предыдущего экземпляра.
+func defaultNodeID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "node"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return hostname + "-" + hex.EncodeToString(suffix)
+}
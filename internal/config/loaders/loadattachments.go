@@ -0,0 +1,90 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+	"time"
+)
+
+const (
+	// defaultAttachmentMaxSize — максимальный размер вложения в байтах,
+	// если attachments.max_size не задан.
+	defaultAttachmentMaxSize = 25 << 20
+	// defaultAttachmentMaxChunkSize — максимальный размер части загрузки в байтах,
+	// если attachments.max_chunk_size не задан.
+	defaultAttachmentMaxChunkSize = 1 << 20
+	// defaultAttachmentUploadTimeout — время неактивности, после которого незавершенная
+	// загрузка удаляется, если attachments.upload_timeout не задан.
+	defaultAttachmentUploadTimeout = time.Hour
+	// defaultAttachmentMaxPixels — максимальное число пикселей изображения, для которого
+	// создаются миниатюры, если attachments.images.max_pixels не задан.
+	defaultAttachmentMaxPixels = 50_000_000
+)
+
+// defaultThumbnailSizes — размеры большей стороны миниатюр в пикселях,
+// если attachments.images.thumbnail_sizes не задан.
+var defaultThumbnailSizes = []int{1280, 320}
+
+// LoadAttachments загружает настройки вложений из предоставленного объекта attachmentsConfig.
+//
+// Параметры:
+//   - attachmentsConfig: Объект conf.Attachments, содержащий настройки вложений.
+//
+// Возвращает:
+//   - bool: Флаг включения вложений.
+//   - string: Префикс путей HTTP-обработчиков загрузки и скачивания.
+//   - string: Каталог хранения файлов вложений.
+//   - int64: Максимальный размер вложения в байтах (по умолчанию 25 MiB).
+//   - int64: Максимальный размер одной части загрузки в байтах (по умолчанию 1 MiB).
+//   - int64: Квота пользователя на суммарный размер вложений в байтах (0 — без ограничения).
+//   - time.Duration: Время неактивности, после которого незавершенная загрузка удаляется (по умолчанию 1h).
+//   - []string: Допустимые типы содержимого или их префиксы (пустой — любые).
+func LoadAttachments(attachmentsConfig conf.Attachments) (bool, string, string, int64, int64, int64, time.Duration, []string) {
+	enabled := attachmentsConfig.Enabled
+	prefix := attachmentsConfig.Prefix
+	dir := attachmentsConfig.Dir
+
+	maxSize := attachmentsConfig.MaxSize
+	if maxSize == 0 {
+		maxSize = defaultAttachmentMaxSize
+	}
+
+	maxChunkSize := attachmentsConfig.MaxChunkSize
+	if maxChunkSize == 0 {
+		maxChunkSize = defaultAttachmentMaxChunkSize
+	}
+
+	userQuota := attachmentsConfig.UserQuota
+
+	uploadTimeout := attachmentsConfig.UploadTimeout
+	if uploadTimeout == 0 {
+		uploadTimeout = defaultAttachmentUploadTimeout
+	}
+
+	allowedTypes := attachmentsConfig.AllowedTypes
+
+	return enabled, prefix, dir, maxSize, maxChunkSize, userQuota, uploadTimeout, allowedTypes
+}
+
+// LoadAttachmentImages загружает настройки обработки изображений во вложениях
+// из предоставленного объекта imagesConfig.
+//
+// Параметры:
+//   - imagesConfig: Объект conf.AttachmentImages, содержащий настройки обработки изображений.
+//
+// Возвращает:
+//   - []int: Размеры большей стороны миниатюр в пикселях (по умолчанию 1280 и 320).
+//   - int64: Максимальное число пикселей изображения, для которого создаются миниатюры
+//     (по умолчанию 50 миллионов).
+func LoadAttachmentImages(imagesConfig conf.AttachmentImages) ([]int, int64) {
+	thumbnailSizes := imagesConfig.ThumbnailSizes
+	if len(thumbnailSizes) == 0 {
+		thumbnailSizes = defaultThumbnailSizes
+	}
+
+	maxPixels := imagesConfig.MaxPixels
+	if maxPixels == 0 {
+		maxPixels = defaultAttachmentMaxPixels
+	}
+
+	return thumbnailSizes, maxPixels
+}
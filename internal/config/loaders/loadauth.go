@@ -0,0 +1,45 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+)
+
+// LoadAuth загружает настройки аутентификации из предоставленного объекта authConfig.
+//
+// Параметры:
+//   - authConfig: Объект conf.Auth, содержащий настройки аутентификации.
+//
+// Возвращает:
+//   - bool: Флаг включения аутентификации.
+//   - map[string]string: Соответствие токена доступа идентификатору пользователя.
+func LoadAuth(authConfig conf.Auth) (bool, map[string]string) {
+	enabled := authConfig.Enabled
+	users := make(map[string]string, len(authConfig.Users))
+	for _, user := range authConfig.Users {
+		users[user.Token] = user.ID
+	}
+
+	return enabled, users
+}
+
+// LoadServiceUsers возвращает идентификаторы учетных записей сервисов из authConfig.
+// При выключенной аутентификации сервисов нет: анонимные клиенты не могут ими представиться.
+//
+// Параметры:
+//   - authConfig: Объект conf.Auth, содержащий настройки аутентификации.
+//
+// Возвращает:
+//   - []string: Идентификаторы пользователей с флагом service.
+func LoadServiceUsers(authConfig conf.Auth) []string {
+	if !authConfig.Enabled {
+		return nil
+	}
+	var services []string
+	for _, user := range authConfig.Users {
+		if user.Service {
+			services = append(services, user.ID)
+		}
+	}
+
+	return services
+}
@@ -0,0 +1,32 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+	"time"
+)
+
+// defaultBusType — тип шины сообщений, если bus.type не задан.
+const defaultBusType = "memory"
+
+// LoadBus загружает настройки шины сообщений между узлами из предоставленного объекта busConfig.
+//
+// Параметры:
+//   - busConfig: Объект conf.Bus, содержащий настройки шины сообщений.
+//
+// Возвращает:
+//   - string: Тип шины: memory или redis (по умолчанию memory).
+//   - string: Адрес сервера Redis.
+//   - string: Пароль Redis (пустая строка — без аутентификации).
+//   - time.Duration: Таймаут подключения и команд Redis (0 — значение по умолчанию шины).
+func LoadBus(busConfig conf.Bus) (string, string, string, time.Duration) {
+	busType := busConfig.Type
+	if busType == "" {
+		busType = defaultBusType
+	}
+
+	address := busConfig.Redis.Address
+	password := busConfig.Redis.Password
+	timeout := busConfig.Redis.Timeout
+
+	return busType, address, password, timeout
+}
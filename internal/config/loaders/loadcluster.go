@@ -0,0 +1,38 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+	"time"
+)
+
+const (
+	// defaultHeartbeatInterval — период пульса узла, если cluster.heartbeat_interval не задан.
+	defaultHeartbeatInterval = 5 * time.Second
+	// defaultLeaseIntervals — срок аренды узла в периодах пульса, если cluster.lease_ttl не задан.
+	defaultLeaseIntervals = 3
+)
+
+// LoadCluster загружает настройки каталога подключений кластера из предоставленного объекта clusterConfig.
+//
+// Параметры:
+//   - clusterConfig: Объект conf.Cluster, содержащий настройки каталога подключений.
+//
+// Возвращает:
+//   - string: Идентификатор узла (пустая строка — имя хоста со случайным суффиксом).
+//   - time.Duration: Период отправки пульса узла (по умолчанию 5s).
+//   - time.Duration: Срок аренды узла (по умолчанию три периода пульса).
+func LoadCluster(clusterConfig conf.Cluster) (string, time.Duration, time.Duration) {
+	nodeID := clusterConfig.NodeID
+
+	heartbeatInterval := clusterConfig.HeartbeatInterval
+	if heartbeatInterval == 0 {
+		heartbeatInterval = defaultHeartbeatInterval
+	}
+
+	leaseTTL := clusterConfig.LeaseTTL
+	if leaseTTL == 0 {
+		leaseTTL = defaultLeaseIntervals * heartbeatInterval
+	}
+
+	return nodeID, heartbeatInterval, leaseTTL
+}
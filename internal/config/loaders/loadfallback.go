@@ -0,0 +1,30 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+	"time"
+)
+
+// LoadFallback загружает настройки резервных транспортов (SSE и long-polling)
+// из предоставленного объекта fallbackConfig.
+//
+// Параметры:
+//   - fallbackConfig: Объект conf.Fallback, содержащий настройки резервных транспортов.
+//
+// Возвращает:
+//   - bool: Флаг включения резервных транспортов.
+//   - string: Префикс путей HTTP-обработчиков.
+//   - time.Duration: Время неактивности, после которого сессия закрывается.
+//   - time.Duration: Максимальное время ожидания одного long-polling запроса.
+//   - time.Duration: Интервал пульсов SSE-потока.
+//   - int: Размер буферов сообщений сессии.
+func LoadFallback(fallbackConfig conf.Fallback) (bool, string, time.Duration, time.Duration, time.Duration, int) {
+	enabled := fallbackConfig.Enabled
+	prefix := fallbackConfig.Prefix
+	sessionTimeout := fallbackConfig.SessionTimeout
+	pollTimeout := fallbackConfig.PollTimeout
+	heartbeatInterval := fallbackConfig.HeartbeatInterval
+	bufferSize := fallbackConfig.BufferSize
+
+	return enabled, prefix, sessionTimeout, pollTimeout, heartbeatInterval, bufferSize
+}
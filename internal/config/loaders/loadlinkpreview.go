@@ -0,0 +1,76 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+	"time"
+)
+
+const (
+	// defaultLinkPreviewTimeout — максимальное время загрузки страницы,
+	// если link_preview.timeout не задан.
+	defaultLinkPreviewTimeout = 5 * time.Second
+	// defaultLinkPreviewMaxBodySize — максимальное число читаемых байт страницы,
+	// если link_preview.max_body_size не задан.
+	defaultLinkPreviewMaxBodySize = 512 << 10
+	// defaultLinkPreviewMaxURLs — максимальное число ссылок сообщения с превью,
+	// если link_preview.max_urls не задан.
+	defaultLinkPreviewMaxURLs = 3
+	// defaultLinkPreviewCacheTTL — время хранения превью в кеше, если link_preview.cache_ttl не задан.
+	defaultLinkPreviewCacheTTL = time.Hour
+	// defaultLinkPreviewCacheSize — максимальное число превью в кеше, если link_preview.cache_size не задан.
+	defaultLinkPreviewCacheSize = 1000
+	// defaultLinkPreviewWorkers — число обработчиков очереди, если link_preview.workers не задан.
+	defaultLinkPreviewWorkers = 4
+)
+
+// LoadLinkPreview загружает настройки превью ссылок из предоставленного объекта linkPreviewConfig.
+//
+// Параметры:
+//   - linkPreviewConfig: Объект conf.LinkPreview, содержащий настройки превью ссылок.
+//
+// Возвращает:
+//   - bool: Флаг включения превью ссылок.
+//   - time.Duration: Максимальное время загрузки одной страницы (по умолчанию 5s).
+//   - int64: Максимальное число читаемых байт страницы (по умолчанию 512 KiB).
+//   - int: Максимальное число ссылок одного сообщения, для которых загружаются превью (по умолчанию 3).
+//   - time.Duration: Время хранения превью в кеше (по умолчанию 1h).
+//   - int: Максимальное число превью в кеше (по умолчанию 1000).
+//   - int: Число одновременно обрабатываемых сообщений (по умолчанию 4).
+//   - bool: Флаг разрешения загрузки страниц из внутренних сетей.
+func LoadLinkPreview(linkPreviewConfig conf.LinkPreview) (bool, time.Duration, int64, int, time.Duration, int, int, bool) {
+	enabled := linkPreviewConfig.Enabled
+
+	timeout := linkPreviewConfig.Timeout
+	if timeout == 0 {
+		timeout = defaultLinkPreviewTimeout
+	}
+
+	maxBodySize := linkPreviewConfig.MaxBodySize
+	if maxBodySize == 0 {
+		maxBodySize = defaultLinkPreviewMaxBodySize
+	}
+
+	maxURLs := linkPreviewConfig.MaxURLs
+	if maxURLs == 0 {
+		maxURLs = defaultLinkPreviewMaxURLs
+	}
+
+	cacheTTL := linkPreviewConfig.CacheTTL
+	if cacheTTL == 0 {
+		cacheTTL = defaultLinkPreviewCacheTTL
+	}
+
+	cacheSize := linkPreviewConfig.CacheSize
+	if cacheSize == 0 {
+		cacheSize = defaultLinkPreviewCacheSize
+	}
+
+	workers := linkPreviewConfig.Workers
+	if workers == 0 {
+		workers = defaultLinkPreviewWorkers
+	}
+
+	allowPrivateNetworks := linkPreviewConfig.AllowPrivateNetworks
+
+	return enabled, timeout, maxBodySize, maxURLs, cacheTTL, cacheSize, workers, allowPrivateNetworks
+}
@@ -0,0 +1,26 @@
+package loaders
+
+import (
+	"log/slog"
+
+	conf "messenger/internal/config/models"
+)
+
+// LoadLog загружает настройки журнала из предоставленного объекта logConfig.
+// Значения проверяются при загрузке конфигурации, поэтому ошибка разбора уровня здесь не ожидается.
+//
+// Параметры:
+//   - logConfig: Объект conf.Log, содержащий настройки журнала.
+//
+// Возвращает:
+//   - slog.Level: Минимальный уровень записей журнала (по умолчанию info).
+//   - bool: Флаг вывода записей в формате JSON.
+func LoadLog(logConfig conf.Log) (slog.Level, bool) {
+	level := slog.LevelInfo
+	if logConfig.Level != "" {
+		_ = level.UnmarshalText([]byte(logConfig.Level))
+	}
+	json := logConfig.Format == "json"
+
+	return level, json
+}
@@ -0,0 +1,36 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+)
+
+const (
+	// defaultMaxFrameSize — максимальный размер сообщения клиента в байтах,
+	// если messages.max_frame_size не задан.
+	defaultMaxFrameSize = 64 << 10
+	// defaultMaxTextLength — максимальная длина текста сообщения в символах,
+	// если messages.max_text_length не задан.
+	defaultMaxTextLength = 4096
+)
+
+// LoadMessages загружает ограничения входящих сообщений из предоставленного объекта messagesConfig.
+//
+// Параметры:
+//   - messagesConfig: Объект conf.Messages, содержащий ограничения входящих сообщений.
+//
+// Возвращает:
+//   - int64: Максимальный размер сообщения клиента в байтах (по умолчанию 64 KiB).
+//   - int: Максимальная длина текста сообщения в символах (по умолчанию 4096).
+func LoadMessages(messagesConfig conf.Messages) (int64, int) {
+	maxFrameSize := messagesConfig.MaxFrameSize
+	if maxFrameSize == 0 {
+		maxFrameSize = defaultMaxFrameSize
+	}
+
+	maxTextLength := messagesConfig.MaxTextLength
+	if maxTextLength == 0 {
+		maxTextLength = defaultMaxTextLength
+	}
+
+	return maxFrameSize, maxTextLength
+}
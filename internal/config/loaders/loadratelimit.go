@@ -0,0 +1,66 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+	"messenger/internal/ratelimit"
+	"time"
+)
+
+const (
+	// defaultConnectionRate и defaultConnectionBurst — лимит соединения для всех типов
+	// сообщений, если в rate_limit не задано ни одного правила.
+	defaultConnectionRate  = 10
+	defaultConnectionBurst = 20
+	// defaultMaxViolations — число отклоненных сообщений за окно, после которого
+	// соединение закрывается, если rate_limit.max_violations не задан.
+	defaultMaxViolations = 20
+	// defaultViolationWindow — окно подсчета отклоненных сообщений,
+	// если rate_limit.violation_window не задан.
+	defaultViolationWindow = 10 * time.Second
+)
+
+// LoadRateLimit загружает настройки ограничения частоты сообщений из предоставленного объекта rateLimitConfig.
+//
+// Параметры:
+//   - rateLimitConfig: Объект conf.RateLimit, содержащий настройки ограничения частоты сообщений.
+//
+// Возвращает:
+//   - bool: Включено ли ограничение частоты сообщений.
+//   - ratelimit.Rules: Лимиты одного соединения по типам сообщений. Если не задано
+//     ни одного правила, для всех типов действует лимит 10 сообщений в секунду с запасом 20.
+//   - ratelimit.Rules: Лимиты пользователя по типам сообщений, общие для его соединений.
+//   - ratelimit.Rules: Лимиты IP-адреса по типам сообщений, общие для соединений с него.
+//   - int: Число отклоненных сообщений за окно, после которого соединение закрывается (по умолчанию 20).
+//   - time.Duration: Окно подсчета отклоненных сообщений (по умолчанию 10s).
+func LoadRateLimit(rateLimitConfig conf.RateLimit) (bool, ratelimit.Rules, ratelimit.Rules, ratelimit.Rules, int, time.Duration) {
+	enabled := rateLimitConfig.Enabled
+
+	connectionRules := toRules(rateLimitConfig.Connection)
+	userRules := toRules(rateLimitConfig.User)
+	ipRules := toRules(rateLimitConfig.IP)
+	if len(connectionRules) == 0 && len(userRules) == 0 && len(ipRules) == 0 {
+		connectionRules = ratelimit.Rules{
+			ratelimit.DefaultKey: {Rate: defaultConnectionRate, Burst: defaultConnectionBurst},
+		}
+	}
+
+	maxViolations := rateLimitConfig.MaxViolations
+	if maxViolations == 0 {
+		maxViolations = defaultMaxViolations
+	}
+
+	violationWindow := rateLimitConfig.ViolationWindow
+	if violationWindow == 0 {
+		violationWindow = defaultViolationWindow
+	}
+
+	return enabled, connectionRules, userRules, ipRules, maxViolations, violationWindow
+}
+
+func toRules(rules map[string]conf.RateRule) ratelimit.Rules {
+	result := make(ratelimit.Rules, len(rules))
+	for typeName, rule := range rules {
+		result[typeName] = ratelimit.Rule{Rate: rule.Rate, Burst: rule.Burst}
+	}
+	return result
+}
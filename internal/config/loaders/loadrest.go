@@ -0,0 +1,20 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+)
+
+// LoadREST загружает настройки REST API из предоставленного объекта restConfig.
+//
+// Параметры:
+//   - restConfig: Объект conf.REST, содержащий настройки REST API.
+//
+// Возвращает:
+//   - bool: Флаг включения REST API.
+//   - string: Префикс путей REST API.
+func LoadREST(restConfig conf.REST) (bool, string) {
+	enabled := restConfig.Enabled
+	prefix := restConfig.Prefix
+
+	return enabled, prefix
+}
@@ -0,0 +1,28 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+)
+
+// defaultWebSocketPath — путь WebSocket-эндпоинта, если он не задан в конфигурации.
+const defaultWebSocketPath = "/ws"
+
+// LoadRoutes загружает настройки HTTP-маршрутов из предоставленного объекта routesConfig.
+//
+// Параметры:
+//   - routesConfig: Объект conf.Routes, содержащий настройки маршрутов.
+//
+// Возвращает:
+//   - string: Путь WebSocket-эндпоинта (по умолчанию "/ws").
+//   - string: Префикс служебных эндпоинтов администратора (пустой — выключены).
+//   - []string: Идентификаторы пользователей с доступом к служебным эндпоинтам.
+func LoadRoutes(routesConfig conf.Routes) (string, string, []string) {
+	webSocketPath := routesConfig.WebSocketPath
+	if webSocketPath == "" {
+		webSocketPath = defaultWebSocketPath
+	}
+	adminPrefix := routesConfig.AdminPrefix
+	adminUsers := routesConfig.AdminUsers
+
+	return webSocketPath, adminPrefix, adminUsers
+}
@@ -0,0 +1,34 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+	"time"
+)
+
+// defaultGracePeriod — время на закрытие соединений при остановке сервера,
+// если shutdown.grace_period не задан.
+const defaultGracePeriod = 5 * time.Second
+
+// LoadShutdown загружает настройки остановки сервера из предоставленного объекта shutdownConfig.
+//
+// Параметры:
+//   - shutdownConfig: Объект conf.Shutdown, содержащий настройки остановки сервера.
+//
+// Возвращает:
+//   - time.Duration: Время между снятием готовности и остановкой HTTP-сервера,
+//     за которое балансировщик выводит сервер из ротации.
+//   - time.Duration: Время, которое клиентам дается на закрытие соединений после
+//     close-фрейма, прежде чем оставшиеся соединения будут закрыты принудительно (по умолчанию 5s).
+//   - time.Duration: Верхняя граница задержки переподключения, подсказываемой клиентам.
+func LoadShutdown(shutdownConfig conf.Shutdown) (time.Duration, time.Duration, time.Duration) {
+	drainDelay := shutdownConfig.DrainDelay
+
+	gracePeriod := shutdownConfig.GracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = defaultGracePeriod
+	}
+
+	reconnectDelay := shutdownConfig.ReconnectDelay
+
+	return drainDelay, gracePeriod, reconnectDelay
+}
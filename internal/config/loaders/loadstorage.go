@@ -0,0 +1,18 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+)
+
+// LoadStorage загружает настройки хранилища бесед из предоставленного объекта storageConfig.
+//
+// Параметры:
+//   - storageConfig: Объект conf.Storage, содержащий настройки хранилища.
+//
+// Возвращает:
+//   - int: Максимальное число хранимых сообщений одной беседы (0 — без ограничения).
+func LoadStorage(storageConfig conf.Storage) int {
+	historyLimit := storageConfig.HistoryLimit
+
+	return historyLimit
+}
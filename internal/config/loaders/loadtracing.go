@@ -0,0 +1,37 @@
+package loaders
+
+import (
+	conf "messenger/internal/config/models"
+)
+
+// defaultServiceName — имя сервиса в трассах, если tracing.service_name не задан.
+const defaultServiceName = "messenger"
+
+// LoadTracing загружает настройки трассировки из предоставленного объекта tracingConfig.
+//
+// Параметры:
+//   - tracingConfig: Объект conf.Tracing, содержащий настройки трассировки.
+//
+// Возвращает:
+//   - string: Способ экспорта спанов (пустая строка — трассировка выключена).
+//   - string: Путь к файлу для экспорта file.
+//   - string: URL коллектора OTLP/HTTP.
+//   - string: Имя сервиса в трассах (по умолчанию messenger).
+//   - float64: Доля записываемых трасс (по умолчанию 1 — все трассы).
+func LoadTracing(tracingConfig conf.Tracing) (string, string, string, string, float64) {
+	exporter := tracingConfig.Exporter
+	file := tracingConfig.File
+	endpoint := tracingConfig.Endpoint
+
+	serviceName := tracingConfig.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	sampleRatio := 1.0
+	if tracingConfig.SampleRatio != nil {
+		sampleRatio = *tracingConfig.SampleRatio
+	}
+
+	return exporter, file, endpoint, serviceName, sampleRatio
+}
@@ -2,10 +2,23 @@ package loaders
 
 import (
 	conf "messenger/internal/config/models"
+	"net/netip"
+	"time"
 )
 
+// defaultMissingOrigin — политика для запросов без Origin, если ws.missing_origin не задан.
+const defaultMissingOrigin = "reject"
+
+// defaultWriteTimeout — максимальное время записи сообщения в соединение, если
+// ws.write_timeout не задан.
+const defaultWriteTimeout = 10 * time.Second
+
+// defaultAdmissionRetryAfter — время, через которое отклоненному клиенту предлагается
+// повторить подключение, если admission.retry_after не задан.
+const defaultAdmissionRetryAfter = 5 * time.Second
+
 // LoadWebsocketConfig загружает конфигурацию WebSocket из предоставленного объекта webSocketConfig.
-// Она извлекает хост, порт, флаг debug и список недопустимых источников для WebSocket.
+// Она извлекает хост, порт, флаг debug и правила проверки Origin для WebSocket.
 //
 // Параметры:
 //   - webSocketConfig: Указатель на объект conf.WebSocket, содержащий конфигурацию WebSocket.
@@ -14,12 +27,78 @@ import (
 //   - string: Хост WebSocket.
 //   - string: Порт WebSocket.
 //   - bool: Флаг debug WebSocket.
-//   - []string: Список недопустимых источников WebSocket.
-func LoadWebsocket(webSocketConfig conf.WebSocket) (string, string, bool, []string) {
+//   - []string: Разрешающие правила Origin (пустой список — запрещен любой Origin, "*" — разрешен любой).
+//   - []string: Запрещающие правила Origin.
+//   - string: Политика для запросов без Origin (по умолчанию reject).
+//   - time.Duration: Максимальное время записи сообщения в соединение (по умолчанию 10s).
+func LoadWebsocket(webSocketConfig conf.WebSocket) (string, string, bool, []string, []string, string, time.Duration) {
 	wsHost := webSocketConfig.Host
 	wsPort := webSocketConfig.Port
 	wsDebug := webSocketConfig.Debug
+	allowedOrigins := webSocketConfig.AllowedOrigins
 	invalidOrigins := webSocketConfig.InvalidOrigins
 
-	return wsHost, wsPort, wsDebug, invalidOrigins
+	missingOrigin := webSocketConfig.MissingOrigin
+	if missingOrigin == "" {
+		missingOrigin = defaultMissingOrigin
+	}
+
+	writeTimeout := webSocketConfig.WriteTimeout
+	if writeTimeout == 0 {
+		writeTimeout = defaultWriteTimeout
+	}
+
+	return wsHost, wsPort, wsDebug, allowedOrigins, invalidOrigins, missingOrigin, writeTimeout
+}
+
+// LoadCompression загружает настройки сжатия permessage-deflate из предоставленного
+// объекта compressionConfig.
+//
+// Параметры:
+//   - compressionConfig: Объект conf.Compression, содержащий настройки сжатия.
+//
+// Возвращает:
+//   - bool: Флаг согласования permessage-deflate с клиентами.
+//   - int: Уровень сжатия (0 — уровень по умолчанию).
+//   - int: Минимальный размер сообщения в байтах, начиная с которого оно сжимается.
+func LoadCompression(compressionConfig conf.Compression) (bool, int, int) {
+	enabled := compressionConfig.Enabled
+	level := compressionConfig.Level
+	threshold := compressionConfig.Threshold
+
+	return enabled, level, threshold
+}
+
+// LoadAdmission загружает настройки допуска соединений из предоставленного объекта admissionConfig.
+//
+// Параметры:
+//   - admissionConfig: Объект conf.Admission, содержащий настройки допуска соединений.
+//
+// Возвращает:
+//   - int: Максимальное число соединений сервера (0 — без ограничения).
+//   - int: Максимальное число соединений с одного IP-адреса (0 — без ограничения).
+//   - int: Максимальное число соединений одного пользователя (0 — без ограничения).
+//   - []netip.Prefix: Доверенные внутренние сети, на которые не действуют ограничения по IP-адресу и пользователю.
+//   - time.Duration: Время, через которое отклоненному клиенту предлагается повторить попытку (по умолчанию 5s).
+func LoadAdmission(admissionConfig conf.Admission) (int, int, int, []netip.Prefix, time.Duration) {
+	maxConnections := admissionConfig.MaxConnections
+	maxPerIP := admissionConfig.MaxConnectionsPerIP
+	maxPerUser := admissionConfig.MaxConnectionsPerUser
+
+	// Сети проверены в conf.Admission.Validate.
+	trusted := make([]netip.Prefix, 0, len(admissionConfig.TrustedCIDRs))
+	for _, cidr := range admissionConfig.TrustedCIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		trusted = append(trusted, prefix.Masked())
+	}
+
+	retryAfter := admissionConfig.RetryAfter
+	if retryAfter == 0 {
+		retryAfter = defaultAdmissionRetryAfter
+	}
+
+	return maxConnections, maxPerIP, maxPerUser, trusted, retryAfter
 }
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+type Admission struct {
+	MaxConnections        int           `mapstructure:"max_connections"`
+	MaxConnectionsPerIP   int           `mapstructure:"max_connections_per_ip"`
+	MaxConnectionsPerUser int           `mapstructure:"max_connections_per_user"`
+	TrustedCIDRs          []string      `mapstructure:"trusted_cidrs"`
+	RetryAfter            time.Duration `mapstructure:"retry_after"`
+}
+
+// Validate проверяет настройки допуска соединений.
+// Что:
+// - Поля MaxConnections, MaxConnectionsPerIP и MaxConnectionsPerUser не отрицательные (0 — без ограничения).
+// - Каждый элемент TrustedCIDRs — сеть в нотации CIDR, например 10.0.0.0/8.
+// - Поле RetryAfter не отрицательное.
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (a *Admission) Validate() error {
+	if a.MaxConnections < 0 {
+		return errors.New("admission.max_connections не может быть отрицательным")
+	}
+	if a.MaxConnectionsPerIP < 0 {
+		return errors.New("admission.max_connections_per_ip не может быть отрицательным")
+	}
+	if a.MaxConnectionsPerUser < 0 {
+		return errors.New("admission.max_connections_per_user не может быть отрицательным")
+	}
+	for _, cidr := range a.TrustedCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("admission.trusted_cidrs: некорректная сеть %q", cidr)
+		}
+	}
+	if a.RetryAfter < 0 {
+		return errors.New("admission.retry_after не может быть отрицательным")
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Attachments struct {
+	Enabled       bool             `mapstructure:"enabled"`
+	Prefix        string           `mapstructure:"prefix"`
+	Dir           string           `mapstructure:"dir"`
+	MaxSize       int64            `mapstructure:"max_size"`
+	MaxChunkSize  int64            `mapstructure:"max_chunk_size"`
+	UserQuota     int64            `mapstructure:"user_quota"`
+	UploadTimeout time.Duration    `mapstructure:"upload_timeout"`
+	AllowedTypes  []string         `mapstructure:"allowed_types"`
+	Images        AttachmentImages `mapstructure:"images"`
+}
+
+type AttachmentImages struct {
+	ThumbnailSizes []int `mapstructure:"thumbnail_sizes"`
+	MaxPixels      int64 `mapstructure:"max_pixels"`
+}
+
+// Допустимый размер большей стороны миниатюры в пикселях.
+const (
+	minThumbnailSize = 16
+	maxThumbnailSize = 4096
+)
+
+// Validate проверяет настройки вложений.
+// Если вложения выключены, проверка не выполняется. Иначе:
+// - Поле Prefix начинается с "/" и не заканчивается на "/".
+// - Поле Dir задано.
+// - Поля MaxSize, MaxChunkSize, UserQuota и UploadTimeout не отрицательные (0 — значение по умолчанию,
+// для UserQuota — без ограничения).
+// - Поле UserQuota, если задано, не меньше MaxSize.
+// - Каждый элемент AllowedTypes — тип содержимого ("image/png") или его префикс ("image/").
+// - Каждый элемент Images.ThumbnailSizes находится в диапазоне от 16 до 4096 пикселей.
+// - Поле Images.MaxPixels не отрицательное (0 — значение по умолчанию).
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (a *Attachments) Validate() error {
+	if !a.Enabled {
+		return nil
+	}
+	if !strings.HasPrefix(a.Prefix, "/") || strings.HasSuffix(a.Prefix, "/") {
+		return errors.New("attachments.prefix должен начинаться с / и не заканчиваться на /")
+	}
+	if a.Dir == "" {
+		return errors.New("attachments.dir не задан")
+	}
+	if a.MaxSize < 0 {
+		return errors.New("attachments.max_size не может быть отрицательным")
+	}
+	if a.MaxChunkSize < 0 {
+		return errors.New("attachments.max_chunk_size не может быть отрицательным")
+	}
+	if a.UserQuota < 0 {
+		return errors.New("attachments.user_quota не может быть отрицательным")
+	}
+	if a.UserQuota > 0 && a.UserQuota < a.MaxSize {
+		return errors.New("attachments.user_quota не может быть меньше attachments.max_size")
+	}
+	if a.UploadTimeout < 0 {
+		return errors.New("attachments.upload_timeout не может быть отрицательным")
+	}
+	for _, contentType := range a.AllowedTypes {
+		major, _, found := strings.Cut(contentType, "/")
+		if !found || major == "" || strings.ContainsAny(contentType, " ;") {
+			return fmt.Errorf("attachments.allowed_types: некорректный тип содержимого %q", contentType)
+		}
+	}
+	for _, size := range a.Images.ThumbnailSizes {
+		if size < minThumbnailSize || size > maxThumbnailSize {
+			return fmt.Errorf("attachments.images.thumbnail_sizes: размер %d вне диапазона от %d до %d",
+				size, minThumbnailSize, maxThumbnailSize)
+		}
+	}
+	if a.Images.MaxPixels < 0 {
+		return errors.New("attachments.images.max_pixels не может быть отрицательным")
+	}
+	return nil
+}
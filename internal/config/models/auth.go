@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Auth struct {
+	Enabled bool       `mapstructure:"enabled"`
+	Users   []AuthUser `mapstructure:"users"`
+}
+
+type AuthUser struct {
+	ID    string `mapstructure:"id"`
+	Token string `mapstructure:"token"`
+	// Service отмечает учетную запись внутреннего сервиса, например отправителя
+	// системных уведомлений: сервис может писать в любую беседу, не будучи ее участником.
+	Service bool `mapstructure:"service"`
+}
+
+// Validate проверяет настройки аутентификации.
+// Если аутентификация выключена, проверка не выполняется. Иначе:
+// - Список Users не пустой.
+// - У каждого пользователя заданы ID и Token.
+// - Токены пользователей не повторяются.
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (a *Auth) Validate() error {
+	if !a.Enabled {
+		return nil
+	}
+	if len(a.Users) == 0 {
+		return errors.New("auth.users не может быть пустым при включенной аутентификации")
+	}
+
+	tokens := make(map[string]struct{}, len(a.Users))
+	for i, user := range a.Users {
+		if user.ID == "" {
+			return fmt.Errorf("auth.users[%d]: id обязателен", i)
+		}
+		if user.Token == "" {
+			return fmt.Errorf("auth.users[%d]: token обязателен", i)
+		}
+		if _, ok := tokens[user.Token]; ok {
+			return fmt.Errorf("auth.users[%d]: token повторяется", i)
+		}
+		tokens[user.Token] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+type Bus struct {
+	Type  string   `mapstructure:"type"`
+	Redis RedisBus `mapstructure:"redis"`
+}
+
+type RedisBus struct {
+	Address  string        `mapstructure:"address"`
+	Password string        `mapstructure:"password"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// Validate проверяет настройки шины сообщений между узлами:
+// - Поле Type пустое (шина в памяти процесса) или одно из memory, redis.
+// - Для шины redis поле Redis.Address задано в виде host:port.
+// - Поле Redis.Timeout не отрицательное.
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (b *Bus) Validate() error {
+	switch b.Type {
+	case "", "memory":
+	case "redis":
+		if _, _, err := net.SplitHostPort(b.Redis.Address); err != nil {
+			return errors.New("bus.redis.address должен быть задан в виде host:port")
+		}
+	default:
+		return errors.New("bus.type должен быть одним из: memory, redis")
+	}
+	if b.Redis.Timeout < 0 {
+		return errors.New("bus.redis.timeout не может быть отрицательным")
+	}
+	return nil
+}
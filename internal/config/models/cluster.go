@@ -0,0 +1,30 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+type Cluster struct {
+	NodeID            string        `mapstructure:"node_id"`
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
+	LeaseTTL          time.Duration `mapstructure:"lease_ttl"`
+}
+
+// Validate проверяет настройки каталога подключений кластера:
+// - Поля HeartbeatInterval и LeaseTTL не отрицательные (0 — значение по умолчанию).
+// - Если заданы оба поля, LeaseTTL больше HeartbeatInterval: иначе аренда живого узла
+// будет истекать между пульсами.
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (c *Cluster) Validate() error {
+	if c.HeartbeatInterval < 0 {
+		return errors.New("cluster.heartbeat_interval не может быть отрицательным")
+	}
+	if c.LeaseTTL < 0 {
+		return errors.New("cluster.lease_ttl не может быть отрицательным")
+	}
+	if c.HeartbeatInterval > 0 && c.LeaseTTL > 0 && c.LeaseTTL <= c.HeartbeatInterval {
+		return errors.New("cluster.lease_ttl должен быть больше cluster.heartbeat_interval")
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"compress/flate"
+	"errors"
+)
+
+type Compression struct {
+	Enabled   bool `mapstructure:"enabled"`
+	Level     int  `mapstructure:"level"`
+	Threshold int  `mapstructure:"threshold"`
+}
+
+// Validate проверяет настройки сжатия permessage-deflate.
+// Что:
+// - Поле Level равно 0 (уровень по умолчанию) или находится в диапазоне от -2 до 9.
+// - Поле Threshold не отрицательное.
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (c *Compression) Validate() error {
+	if c.Level < flate.HuffmanOnly || c.Level > flate.BestCompression {
+		return errors.New("compression.level должен быть в диапазоне от -2 до 9")
+	}
+	if c.Threshold < 0 {
+		return errors.New("compression.threshold не может быть отрицательным")
+	}
+	return nil
+}
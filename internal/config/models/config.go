@@ -3,12 +3,27 @@ package models
 type Config struct {
 	WebSocket   WebSocket   `mapstructure:"ws"`
 	Certificate Certificate `mapstructure:"certificate"`
+	Fallback    Fallback    `mapstructure:"fallback"`
+	Auth        Auth        `mapstructure:"auth"`
+	REST        REST        `mapstructure:"rest"`
+	Storage     Storage     `mapstructure:"storage"`
+	Routes      Routes      `mapstructure:"routes"`
+	Log         Log         `mapstructure:"log"`
+	Tracing     Tracing     `mapstructure:"tracing"`
+	Shutdown    Shutdown    `mapstructure:"shutdown"`
+	RateLimit   RateLimit   `mapstructure:"rate_limit"`
+	Messages    Messages    `mapstructure:"messages"`
+	Bus         Bus         `mapstructure:"bus"`
+	Cluster     Cluster     `mapstructure:"cluster"`
+	Attachments Attachments `mapstructure:"attachments"`
+	LinkPreview LinkPreview `mapstructure:"link_preview"`
 }
 
 // Validate проверяет поля конфигурации структуры Config на корректность.
-// Она проверяет конфигурации WebSocket и Certificate, вызывая их
-// соответствующие методы Validate. Если какая-либо проверка не проходит,
-// возвращается ошибка; в противном случае возвращается nil.
+// Она проверяет конфигурации WebSocket, Certificate, Fallback, Auth, REST, Storage,
+// Routes, Log, Tracing, Shutdown, RateLimit, Messages, Bus, Cluster, Attachments
+// и LinkPreview, вызывая их соответствующие методы Validate. Если какая-либо проверка
+// не проходит, возвращается ошибка; в противном случае возвращается nil.
 func (c *Config) Validate() error {
 	if err := c.WebSocket.Validate(); err != nil {
 		return err
@@ -16,5 +31,47 @@ func (c *Config) Validate() error {
 	if err := c.Certificate.Validate(); err != nil {
 		return err
 	}
+	if err := c.Fallback.Validate(); err != nil {
+		return err
+	}
+	if err := c.Auth.Validate(); err != nil {
+		return err
+	}
+	if err := c.REST.Validate(); err != nil {
+		return err
+	}
+	if err := c.Storage.Validate(); err != nil {
+		return err
+	}
+	if err := c.Routes.Validate(); err != nil {
+		return err
+	}
+	if err := c.Log.Validate(); err != nil {
+		return err
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		return err
+	}
+	if err := c.Shutdown.Validate(); err != nil {
+		return err
+	}
+	if err := c.RateLimit.Validate(); err != nil {
+		return err
+	}
+	if err := c.Messages.Validate(); err != nil {
+		return err
+	}
+	if err := c.Bus.Validate(); err != nil {
+		return err
+	}
+	if err := c.Cluster.Validate(); err != nil {
+		return err
+	}
+	if err := c.Attachments.Validate(); err != nil {
+		return err
+	}
+	if err := c.LinkPreview.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+type Fallback struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	Prefix            string        `mapstructure:"prefix"`
+	SessionTimeout    time.Duration `mapstructure:"session_timeout"`
+	PollTimeout       time.Duration `mapstructure:"poll_timeout"`
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
+	BufferSize        int           `mapstructure:"buffer_size"`
+}
+
+// Validate проверяет настройки резервных транспортов (SSE и long-polling).
+// Если транспорты выключены, проверка не выполняется. Иначе:
+// - Поле Prefix начинается с "/" и не заканчивается на "/".
+// - Поля SessionTimeout, PollTimeout и HeartbeatInterval положительные.
+// - Поле PollTimeout меньше SessionTimeout, иначе сессия истечет во время ожидания.
+// - Поле BufferSize положительное.
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (f *Fallback) Validate() error {
+	if !f.Enabled {
+		return nil
+	}
+	if !strings.HasPrefix(f.Prefix, "/") || strings.HasSuffix(f.Prefix, "/") {
+		return errors.New("fallback.prefix должен начинаться с / и не заканчиваться на /")
+	}
+	if f.SessionTimeout <= 0 {
+		return errors.New("fallback.session_timeout должен быть положительным")
+	}
+	if f.PollTimeout <= 0 {
+		return errors.New("fallback.poll_timeout должен быть положительным")
+	}
+	if f.PollTimeout >= f.SessionTimeout {
+		return errors.New("fallback.poll_timeout должен быть меньше fallback.session_timeout")
+	}
+	if f.HeartbeatInterval <= 0 {
+		return errors.New("fallback.heartbeat_interval должен быть положительным")
+	}
+	if f.BufferSize <= 0 {
+		return errors.New("fallback.buffer_size должен быть положительным")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+type LinkPreview struct {
+	Enabled              bool          `mapstructure:"enabled"`
+	Timeout              time.Duration `mapstructure:"timeout"`
+	MaxBodySize          int64         `mapstructure:"max_body_size"`
+	MaxURLs              int           `mapstructure:"max_urls"`
+	CacheTTL             time.Duration `mapstructure:"cache_ttl"`
+	CacheSize            int           `mapstructure:"cache_size"`
+	Workers              int           `mapstructure:"workers"`
+	AllowPrivateNetworks bool          `mapstructure:"allow_private_networks"`
+}
+
+// Validate проверяет настройки превью ссылок.
+// Если превью выключены, проверка не выполняется. Иначе поля Timeout, MaxBodySize, MaxURLs,
+// CacheTTL, CacheSize и Workers не отрицательные (0 — значение по умолчанию).
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (l *LinkPreview) Validate() error {
+	if !l.Enabled {
+		return nil
+	}
+	if l.Timeout < 0 {
+		return errors.New("link_preview.timeout не может быть отрицательным")
+	}
+	if l.MaxBodySize < 0 {
+		return errors.New("link_preview.max_body_size не может быть отрицательным")
+	}
+	if l.MaxURLs < 0 {
+		return errors.New("link_preview.max_urls не может быть отрицательным")
+	}
+	if l.CacheTTL < 0 {
+		return errors.New("link_preview.cache_ttl не может быть отрицательным")
+	}
+	if l.CacheSize < 0 {
+		return errors.New("link_preview.cache_size не может быть отрицательным")
+	}
+	if l.Workers < 0 {
+		return errors.New("link_preview.workers не может быть отрицательным")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"errors"
+	"log/slog"
+)
+
+type Log struct {
+	Level  string `mapstructure:"level"`
+	Format string `mapstructure:"format"`
+}
+
+// Validate проверяет настройки журнала: поле Level может быть пустым (info)
+// или одним из debug, info, warn, error, а поле Format — пустым (text), text или json.
+func (l *Log) Validate() error {
+	if l.Level != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+			return errors.New("log.level должен быть одним из: debug, info, warn, error")
+		}
+	}
+	switch l.Format {
+	case "", "text", "json":
+	default:
+		return errors.New("log.format должен быть text или json")
+	}
+	return nil
+}
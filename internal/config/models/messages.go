@@ -0,0 +1,24 @@
+package models
+
+import (
+	"errors"
+)
+
+type Messages struct {
+	MaxFrameSize  int64 `mapstructure:"max_frame_size"`
+	MaxTextLength int   `mapstructure:"max_text_length"`
+}
+
+// Validate проверяет ограничения входящих сообщений клиентов:
+// - Поле MaxFrameSize не может быть отрицательным (0 — значение по умолчанию).
+// - Поле MaxTextLength не может быть отрицательным (0 — значение по умолчанию).
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (m *Messages) Validate() error {
+	if m.MaxFrameSize < 0 {
+		return errors.New("messages.max_frame_size не может быть отрицательным")
+	}
+	if m.MaxTextLength < 0 {
+		return errors.New("messages.max_text_length не может быть отрицательным")
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"slices"
+	"strings"
+	"time"
+)
+
+type RateLimit struct {
+	Enabled         bool                `mapstructure:"enabled"`
+	Connection      map[string]RateRule `mapstructure:"connection"`
+	User            map[string]RateRule `mapstructure:"user"`
+	IP              map[string]RateRule `mapstructure:"ip"`
+	MaxViolations   int                 `mapstructure:"max_violations"`
+	ViolationWindow time.Duration       `mapstructure:"violation_window"`
+}
+
+// RateRule — лимит сообщений одного типа: Rate сообщений в секунду с запасом Burst.
+type RateRule struct {
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// rateLimitKeys — ключи правил помимо имен типов сообщений клиента: default
+// для типов без собственного правила и invalid для некорректных сообщений.
+var rateLimitKeys = []string{"default", "invalid"}
+
+// validRateLimitKey сообщает, допустим ли ключ правила: имя типа сообщения,
+// который могут отправлять клиенты, по реестру типов или один из rateLimitKeys.
+func validRateLimitKey(key string) bool {
+	if slices.Contains(rateLimitKeys, key) {
+		return true
+	}
+	messageType, ok := msg.Lookup(key)
+	return ok && messageType.IsClient()
+}
+
+// Validate проверяет настройки ограничения частоты сообщений:
+// - Ключи правил connection, user и ip — имена типов сообщений клиента из реестра типов, default или invalid.
+// - Поле Rate каждого правила положительное, поле Burst не меньше 1.
+// - Поля MaxViolations и ViolationWindow не могут быть отрицательными.
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (rl *RateLimit) Validate() error {
+	scopes := []struct {
+		name  string
+		rules map[string]RateRule
+	}{
+		{"connection", rl.Connection},
+		{"user", rl.User},
+		{"ip", rl.IP},
+	}
+	for _, scope := range scopes {
+		for typeName, rule := range scope.rules {
+			if !validRateLimitKey(typeName) {
+				return fmt.Errorf("rate_limit.%s: неизвестный тип сообщения %q, допустимые: %s",
+					scope.name, typeName, strings.Join(append(msg.ClientTypeNames(), rateLimitKeys...), ", "))
+			}
+			if rule.Rate <= 0 {
+				return fmt.Errorf("rate_limit.%s.%s.rate должен быть положительным", scope.name, typeName)
+			}
+			if rule.Burst < 1 {
+				return fmt.Errorf("rate_limit.%s.%s.burst должен быть не меньше 1", scope.name, typeName)
+			}
+		}
+	}
+	if rl.MaxViolations < 0 {
+		return errors.New("rate_limit.max_violations не может быть отрицательным")
+	}
+	if rl.ViolationWindow < 0 {
+		return errors.New("rate_limit.violation_window не может быть отрицательным")
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+type REST struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Prefix  string `mapstructure:"prefix"`
+}
+
+// Validate проверяет настройки REST API.
+// Если REST API выключен, проверка не выполняется. Иначе поле Prefix
+// должно начинаться с "/" и не заканчиваться на "/".
+func (r *REST) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if !strings.HasPrefix(r.Prefix, "/") || strings.HasSuffix(r.Prefix, "/") {
+		return errors.New("rest.prefix должен начинаться с / и не заканчиваться на /")
+	}
+	return nil
+}
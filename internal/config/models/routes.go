@@ -0,0 +1,35 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+type Routes struct {
+	WebSocketPath string   `mapstructure:"websocket_path"`
+	AdminPrefix   string   `mapstructure:"admin_prefix"`
+	AdminUsers    []string `mapstructure:"admin_users"`
+}
+
+// Validate проверяет настройки HTTP-маршрутов.
+// Что:
+// - Поле WebSocketPath пустое (используется путь по умолчанию) или начинается с "/".
+// - Поле AdminPrefix пустое (служебные эндпоинты выключены) или начинается с "/"
+// и не заканчивается на "/".
+// - Если задан AdminPrefix, список AdminUsers не пустой.
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (r *Routes) Validate() error {
+	if r.WebSocketPath != "" && !strings.HasPrefix(r.WebSocketPath, "/") {
+		return errors.New("routes.websocket_path должен начинаться с /")
+	}
+	if r.AdminPrefix == "" {
+		return nil
+	}
+	if !strings.HasPrefix(r.AdminPrefix, "/") || strings.HasSuffix(r.AdminPrefix, "/") {
+		return errors.New("routes.admin_prefix должен начинаться с / и не заканчиваться на /")
+	}
+	if len(r.AdminUsers) == 0 {
+		return errors.New("routes.admin_users не может быть пустым при заданном admin_prefix")
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+type Shutdown struct {
+	DrainDelay     time.Duration `mapstructure:"drain_delay"`
+	GracePeriod    time.Duration `mapstructure:"grace_period"`
+	ReconnectDelay time.Duration `mapstructure:"reconnect_delay"`
+}
+
+// Validate проверяет настройки остановки сервера:
+// - Поле DrainDelay не может быть отрицательным (0 — остановка без ожидания).
+// - Поле GracePeriod не может быть отрицательным (0 — значение по умолчанию).
+// - Поле ReconnectDelay не может быть отрицательным (0 — переподключаться сразу).
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (s *Shutdown) Validate() error {
+	if s.DrainDelay < 0 {
+		return errors.New("shutdown.drain_delay не может быть отрицательным")
+	}
+	if s.GracePeriod < 0 {
+		return errors.New("shutdown.grace_period не может быть отрицательным")
+	}
+	if s.ReconnectDelay < 0 {
+		return errors.New("shutdown.reconnect_delay не может быть отрицательным")
+	}
+	return nil
+}
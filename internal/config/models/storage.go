@@ -0,0 +1,18 @@
+package models
+
+import (
+	"errors"
+)
+
+type Storage struct {
+	HistoryLimit int `mapstructure:"history_limit"`
+}
+
+// Validate проверяет настройки хранилища бесед: поле HistoryLimit
+// не может быть отрицательным (0 — без ограничения).
+func (s *Storage) Validate() error {
+	if s.HistoryLimit < 0 {
+		return errors.New("storage.history_limit не может быть отрицательным")
+	}
+	return nil
+}
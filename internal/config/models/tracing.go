@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+)
+
+type Tracing struct {
+	Exporter    string   `mapstructure:"exporter"`
+	File        string   `mapstructure:"file"`
+	Endpoint    string   `mapstructure:"endpoint"`
+	ServiceName string   `mapstructure:"service_name"`
+	SampleRatio *float64 `mapstructure:"sample_ratio"`
+}
+
+// Validate проверяет настройки трассировки:
+// - Поле Exporter пустое (трассировка выключена) или одно из none, stdout, file, otlp.
+// - Для экспорта file задано поле File.
+// - Для экспорта otlp поле Endpoint — URL коллектора со схемой http или https.
+// - Поле SampleRatio, если задано, находится в диапазоне от 0 до 1.
+// Если какое-либо из этих условий не выполнено, возвращается ошибка.
+func (t *Tracing) Validate() error {
+	switch t.Exporter {
+	case "", "none", "stdout":
+	case "file":
+		if t.File == "" {
+			return errors.New("tracing.file обязателен для экспорта file")
+		}
+	case "otlp":
+		endpoint, err := url.Parse(t.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return errors.New("tracing.endpoint должен быть URL коллектора OTLP/HTTP, например http://localhost:4318")
+		}
+	default:
+		return errors.New("tracing.exporter должен быть одним из: none, stdout, file, otlp")
+	}
+	if t.SampleRatio != nil && (*t.SampleRatio < 0 || *t.SampleRatio > 1) {
+		return errors.New("tracing.sample_ratio должен быть в диапазоне от 0 до 1")
+	}
+	return nil
+}
@@ -2,22 +2,38 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	wsupgr "messenger/internal/ws/upgraders"
 	"net"
 	"strconv"
+	"time"
 )
 
 type WebSocket struct {
-	Host           string   `mapstructure:"host"`
-	Port           string   `mapstructure:"port"`
-	Debug          bool     `mapstructure:"debug"`
-	InvalidOrigins []string `mapstructure:"invalid_origins"`
+	Host           string      `mapstructure:"host"`
+	Port           string      `mapstructure:"port"`
+	Debug          bool        `mapstructure:"debug"`
+	AllowedOrigins []string    `mapstructure:"allowed_origins"`
+	InvalidOrigins []string    `mapstructure:"invalid_origins"`
+	MissingOrigin  string      `mapstructure:"missing_origin"`
+	Compression    Compression `mapstructure:"compression"`
+	Admission      Admission   `mapstructure:"admission"`
+	// WriteTimeout — максимальное время записи сообщения в соединение. Клиент, который
+	// не читает сообщения дольше, отключается.
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 }
 
 // Validate проверяет конфигурацию WebSocket на корректность.
 // Что:
 // - Поле Host не пустое и содержит валидный IP-адрес.
 // - Поле Port не пустое, является числом и находится в диапазоне от 1 до 65535.
-// - Поле InvalidOrigins не пустое.
+// - Поле AllowedOrigins не пустое: без разрешающих правил отклоняется любой Origin,
+// а разрешить любой Origin можно только явным правилом "*".
+// - Правила AllowedOrigins и InvalidOrigins корректны: "https://app.example.com",
+// "app.example.com", "*.example.com", "*" или регулярное выражение с префиксом "re:".
+// - Поле MissingOrigin пустое или одно из reject, allow.
+// - Поле WriteTimeout неотрицательное.
+// - Настройки Compression и Admission корректны.
 // Если какое-либо из этих условий не выполнено, возвращается ошибка.
 func (ws *WebSocket) Validate() error {
 	if ws.Host == "" {
@@ -33,8 +49,37 @@ func (ws *WebSocket) Validate() error {
 	if err != nil || port <= 0 || port > 65535 {
 		return errors.New("port должен быть числом в диапазоне от 1 до 65535")
 	}
-	if len(ws.InvalidOrigins) == 0 {
-		return errors.New("invalid_origins не может быть пустым")
+	if len(ws.AllowedOrigins) == 0 {
+		return errors.New(`allowed_origins обязателен; чтобы разрешить любой Origin, укажите "*"`)
+	}
+	if err := validateOriginRules("allowed_origins", ws.AllowedOrigins); err != nil {
+		return err
+	}
+	if err := validateOriginRules("invalid_origins", ws.InvalidOrigins); err != nil {
+		return err
+	}
+	switch ws.MissingOrigin {
+	case "", "reject", "allow":
+	default:
+		return errors.New("missing_origin должен быть одним из: reject, allow")
+	}
+	if ws.WriteTimeout < 0 {
+		return errors.New("write_timeout не может быть отрицательным")
+	}
+	if err := ws.Compression.Validate(); err != nil {
+		return err
+	}
+	if err := ws.Admission.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateOriginRules проверяет правила сравнения Origin тем же разбором,
+// который применяет политика проверки Origin при запуске сервера.
+func validateOriginRules(name string, rules []string) error {
+	if _, err := wsupgr.NewOriginPolicy(wsupgr.OriginOptions{Allowed: rules}); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
 	}
 	return nil
 }
@@ -1,11 +1,18 @@
 package websocket
 
 import (
+	"log/slog"
+	"messenger/internal/admission"
+	authinterfaces "messenger/internal/auth/interfaces"
+	msginterfaces "messenger/internal/messaging/interfaces"
 	"messenger/internal/messaging/processor"
 	"messenger/internal/messaging/receiver"
 	"messenger/internal/messaging/sender"
+	"messenger/internal/ratelimit"
 
+	"messenger/internal/ws/connections"
 	"messenger/internal/ws/handlers"
+	wsinterfaces "messenger/internal/ws/interfaces"
 
 	"github.com/gorilla/websocket"
 )
@@ -41,16 +48,59 @@ type Options struct {
 	SenderOptions    sender.Options
 	ReceiverOptions  receiver.Options
 	ProcessorOptions processor.Options
+	// NewSender, NewReceiver и NewProcessor создают компоненты каждого соединения вместо
+	// sender.New, receiver.New и processor.NewMessageProcessor. Получают опции фабрики;
+	// nil — компонент по умолчанию.
+	NewSender     func(options sender.Options) wsinterfaces.WebSocketSender
+	NewReceiver   func(options receiver.Options) wsinterfaces.WebSocketReceiver
+	NewProcessor  func(options processor.Options) msginterfaces.MessageProcessor
+	Authenticator authinterfaces.Authenticator
+	Router        msginterfaces.MessageRouter
+	// Connections — реестр открытых соединений, закрываемых при остановке сервера.
+	Connections *connections.Registry
+	// Admission ограничивает число одновременных соединений сервера, IP-адреса и пользователя.
+	Admission *admission.Controller
+	// Limiter ограничивает частоту сообщений клиентов. nil — ограничение выключено.
+	Limiter *ratelimit.Limiter
+	// Logger — базовый логгер; обработчик дополняет его полями каждого соединения.
+	Logger *slog.Logger
 }
 
 // NewHandler создает и возвращает новый экземпляр handlers.WebSocketHandler,
-// инициализируя его настроенным upgrader, sender, receiver и processor.
-// Зависимости создаются с использованием опций фабрики.
+// инициализируя его настроенным upgrader, sender, receiver, processor, authenticator,
+// router, реестром открытых соединений, ограничителями числа соединений и частоты
+// сообщений и logger. Зависимости создаются с использованием опций фабрики и, если
+// заданы, конструкторов компонентов.
 func (f *WebSocketHandlerFactory) NewHandler() *handlers.WebSocketHandler {
+	var messageSender wsinterfaces.WebSocketSender
+	if f.options.NewSender != nil {
+		messageSender = f.options.NewSender(f.options.SenderOptions)
+	} else {
+		messageSender = sender.New(f.options.SenderOptions)
+	}
+	var messageReceiver wsinterfaces.WebSocketReceiver
+	if f.options.NewReceiver != nil {
+		messageReceiver = f.options.NewReceiver(f.options.ReceiverOptions)
+	} else {
+		messageReceiver = receiver.New(f.options.ReceiverOptions)
+	}
+	var messageProcessor wsinterfaces.WebSocketProcessor
+	if f.options.NewProcessor != nil {
+		messageProcessor = processor.Bind(f.options.NewProcessor(f.options.ProcessorOptions))
+	} else {
+		messageProcessor = processor.New(f.options.ProcessorOptions)
+	}
+
 	return handlers.New(
 		f.options.Upgrader,
-		sender.New(f.options.SenderOptions),
-		receiver.New(f.options.ReceiverOptions),
-		processor.New(f.options.ProcessorOptions),
+		messageSender,
+		messageReceiver,
+		messageProcessor,
+		f.options.Authenticator,
+		f.options.Router,
+		f.options.Connections,
+		f.options.Admission,
+		f.options.Limiter,
+		f.options.Logger,
 	)
 }
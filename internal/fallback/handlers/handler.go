@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"io"
+	"log/slog"
+	authinterfaces "messenger/internal/auth/interfaces"
+	"messenger/internal/fallback/sessions"
+	"messenger/internal/logging"
+	"messenger/internal/messaging/validation"
+	"messenger/internal/metrics"
+	"messenger/internal/server/interfaces"
+	"messenger/internal/tracing"
+	"net/http"
+	"time"
+)
+
+// defaultMaxMessageSize — максимальный размер тела запроса с сообщением клиента,
+// если Options.MaxMessageSize не задан.
+const defaultMaxMessageSize = 64 << 10
+
+// FallbackHandler обслуживает резервные транспорты для клиентов, у которых
+// WebSocket недоступен (например, за прокси, разрывающими Upgrade-соединения):
+//   - POST   {prefix}/sessions                — создание сессии;
+//   - DELETE {prefix}/sessions/{id}           — закрытие сессии;
+//   - POST   {prefix}/sessions/{id}/messages  — отправка сообщения клиентом;
+//   - GET    {prefix}/sessions/{id}/events    — поток ответов сервера (Server-Sent Events);
+//   - GET    {prefix}/sessions/{id}/poll      — получение ответов сервера через long-polling.
+//
+// Клиенты аутентифицируются так же, как WebSocket-клиенты; к сессии может обращаться
+// только пользователь, который ее создал.
+type FallbackHandler struct {
+	store             *sessions.Store
+	authenticator     authinterfaces.Authenticator
+	pollTimeout       time.Duration
+	heartbeatInterval time.Duration
+	maxMessageSize    int64
+	validator         *validation.Validator
+	logger            *slog.Logger
+}
+
+type Options struct {
+	Store         *sessions.Store
+	Authenticator authinterfaces.Authenticator
+	// PollTimeout — максимальное время ожидания сообщений одним long-polling запросом.
+	PollTimeout time.Duration
+	// HeartbeatInterval — интервал отправки комментариев-пульсов в SSE-поток,
+	// не дающих прокси закрыть простаивающее соединение.
+	HeartbeatInterval time.Duration
+	// MaxMessageSize — максимальный размер тела запроса с сообщением клиента в байтах.
+	// Если не задан, используется 64 KiB.
+	MaxMessageSize int64
+	// Validator проверяет разобранные сообщения клиента. Если не задан,
+	// проверяются правила без ограничения длины текста.
+	Validator *validation.Validator
+	// Logger — логгер обработчика. Если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+func New(options Options) *FallbackHandler {
+	validator := options.Validator
+	if validator == nil {
+		validator = validation.New(validation.Options{})
+	}
+	maxMessageSize := options.MaxMessageSize
+	if maxMessageSize == 0 {
+		maxMessageSize = defaultMaxMessageSize
+	}
+	fh := &FallbackHandler{
+		store:             options.Store,
+		authenticator:     options.Authenticator,
+		pollTimeout:       options.PollTimeout,
+		heartbeatInterval: options.HeartbeatInterval,
+		maxMessageSize:    maxMessageSize,
+		validator:         validator,
+	}
+	fh.logger = logging.Component(options.Logger, fh.Tag())
+	return fh
+}
+
+// Tag возвращает строковый идентификатор для FallbackHandler.
+func (*FallbackHandler) Tag() string {
+	return "FALLBACK_HANDLER"
+}
+
+// Register регистрирует обработчики резервных транспортов в группе маршрутов.
+func (fh *FallbackHandler) Register(routes interfaces.Routes) {
+	routes.HandleFunc("POST /sessions", fh.handleCreateSession)
+	routes.HandleFunc("DELETE /sessions/{id}", fh.handleCloseSession)
+	routes.HandleFunc("POST /sessions/{id}/messages", fh.handleMessage)
+	routes.HandleFunc("GET /sessions/{id}/events", fh.handleEvents)
+	routes.HandleFunc("GET /sessions/{id}/poll", fh.handlePoll)
+}
+
+func (fh *FallbackHandler) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	identity, err := fh.authenticator.Authenticate(r)
+	if err != nil {
+		http.Error(w, "Требуется аутентификация", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := fh.store.Create(identity, r.RemoteAddr)
+	if err != nil {
+		fh.logger.Error("Ошибка создания сессии",
+			slog.String(logging.KeyRemoteAddr, r.RemoteAddr),
+			slog.String(logging.KeyUserID, identity.UserID),
+			slog.Any("error", err),
+		)
+		http.Error(w, "Не удалось создать сессию", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"session_id": session.ID()})
+}
+
+func (fh *FallbackHandler) handleCloseSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := fh.session(w, r)
+	if !ok {
+		return
+	}
+	fh.store.Remove(session.ID())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMessage принимает сообщение клиента в формате JSON, проверяет его так же, как
+// сообщения WebSocket, и передает в сессию. На некорректное сообщение отвечает 400
+// со списком неверных полей.
+// Ответ на сообщение доставляется асинхронно через SSE-поток или long-polling.
+// Если в сообщении нет контекста трассировки, используется заголовок traceparent запроса.
+func (fh *FallbackHandler) handleMessage(w http.ResponseWriter, r *http.Request) {
+	session, ok := fh.session(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, fh.maxMessageSize))
+	if err != nil {
+		http.Error(w, "Не удалось прочитать сообщение", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	message, err := codecs.JSONCodec{}.DecodeStrict(body)
+	if err == nil {
+		err = fh.validator.Validate(message)
+	}
+	var validationErr *msg.ValidationError
+	if errors.As(err, &validationErr) {
+		metrics.MessagesInvalid.WithLabelValues(metrics.TransportFallback, validationErr.Fields[0].Code).Inc()
+		session.Logger().Info("Получено некорректное сообщение", slog.Any("error", err))
+		writeValidationError(w, validationErr)
+		return
+	}
+	if message.TraceParent == "" {
+		tracing.Inject(tracing.ExtractHTTP(r), &message)
+	}
+
+	if err := session.Push(r.Context(), message); err != nil {
+		http.Error(w, "Сессия закрыта", http.StatusGone)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleEvents передает ответы сервера клиенту в формате Server-Sent Events.
+// Каждое сообщение отправляется событием "message" с JSON в поле data.
+// Поток завершается при отключении клиента или закрытии сессии.
+func (fh *FallbackHandler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	session, ok := fh.session(w, r)
+	if !ok {
+		return
+	}
+
+	controller := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		session.Logger().Warn("SSE не поддерживается соединением", slog.Any("error", err))
+		return
+	}
+
+	heartbeat := time.NewTicker(fh.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-session.Done():
+			return
+		case <-heartbeat.C:
+			session.Touch()
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case message := <-session.Outbox():
+			session.Touch()
+			if err := writeEvent(w, message); err != nil {
+				session.Logger().Warn("Ошибка отправки SSE-события", logging.MessageType(message.Type), slog.Any("error", err))
+				return
+			}
+		}
+
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// handlePoll ожидает ответы сервера не дольше pollTimeout и возвращает их JSON-массивом.
+// Если за это время сообщений не появилось, возвращается 204 No Content,
+// и клиент должен повторить запрос. Для закрытой сессии возвращается 410 Gone.
+func (fh *FallbackHandler) handlePoll(w http.ResponseWriter, r *http.Request) {
+	session, ok := fh.session(w, r)
+	if !ok {
+		return
+	}
+	session.Touch()
+
+	// Закрытая сессия может сохранить недоставленные сообщения в буфере: проверка
+	// до ожидания не дает отдать их клиенту после закрытия.
+	select {
+	case <-session.Done():
+		http.Error(w, "Сессия закрыта", http.StatusGone)
+		return
+	default:
+	}
+
+	timer := time.NewTimer(fh.pollTimeout)
+	defer timer.Stop()
+
+	var messages []msg.Message
+	select {
+	case <-r.Context().Done():
+		return
+	case <-session.Done():
+		http.Error(w, "Сессия закрыта", http.StatusGone)
+		return
+	case <-timer.C:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case message := <-session.Outbox():
+		messages = append(messages, message)
+	}
+
+	messages = drain(session, messages)
+	session.Touch()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(messages); err != nil {
+		session.Logger().Warn("Ошибка отправки ответа long-polling", slog.Any("error", err))
+	}
+}
+
+// session аутентифицирует клиента и находит его сессию по идентификатору из пути запроса.
+// Если клиент не аутентифицирован, отвечает 401; если сессия не найдена или принадлежит
+// другому пользователю, отвечает 404 и возвращает false. При выключенной аутентификации
+// владелец не проверяется: анонимный идентификатор генерируется заново для каждого запроса,
+// и доступ к сессии определяется знанием ее случайного идентификатора.
+func (fh *FallbackHandler) session(w http.ResponseWriter, r *http.Request) (*sessions.Session, bool) {
+	identity, err := fh.authenticator.Authenticate(r)
+	if err != nil {
+		http.Error(w, "Требуется аутентификация", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	session, ok := fh.store.Get(r.PathValue("id"))
+	if !ok || (!identity.Anonymous && session.Identity().UserID != identity.UserID) {
+		http.Error(w, "Сессия не найдена", http.StatusNotFound)
+		return nil, false
+	}
+	return session, true
+}
+
+// drain забирает из сессии все уже готовые сообщения, не ожидая новых.
+func drain(session *sessions.Session, messages []msg.Message) []msg.Message {
+	for {
+		select {
+		case message := <-session.Outbox():
+			messages = append(messages, message)
+		default:
+			return messages
+		}
+	}
+}
+
+// validationErrorResponse — тело ответа на некорректное сообщение клиента.
+type validationErrorResponse struct {
+	Error  string           `json:"error"`
+	Errors []msg.FieldError `json:"errors"`
+}
+
+// writeValidationError отвечает 400 со списком неверных полей сообщения.
+func writeValidationError(w http.ResponseWriter, validationErr *msg.ValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(validationErrorResponse{
+		Error:  "Некорректное сообщение",
+		Errors: validationErr.Fields,
+	})
+}
+
+// writeEvent записывает сообщение в SSE-поток событием "message".
+func writeEvent(w io.Writer, message msg.Message) error {
+	data, err := codecs.JSONCodec{}.Encode(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+	return err
+}
@@ -0,0 +1,201 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"log/slog"
+	authmodels "messenger/internal/auth/models"
+	"messenger/internal/logging"
+	"messenger/internal/messaging/interfaces"
+	"messenger/internal/messaging/receiver"
+	"messenger/internal/messaging/sender"
+	"messenger/internal/metrics"
+	"messenger/internal/ratelimit"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed возвращается при обращении к закрытой сессии.
+var ErrClosed = errors.New("сессия закрыта")
+
+// Session представляет логическое соединение клиента резервного транспорта.
+// Сообщения клиента поступают в inbox через HTTP POST, ответы сервера попадают
+// в outbox и забираются SSE-потоком или long-polling запросами.
+// Обработка выполняется теми же MessageReceiver, MessageProcessor и MessageSender,
+// что и для WebSocket, поэтому бизнес-логика не зависит от транспорта.
+type Session struct {
+	id        string
+	identity  authmodels.Identity
+	inbox     chan msg.Message
+	outbox    chan msg.Message
+	done      chan struct{}
+	closeOnce sync.Once
+	lastSeen  atomic.Int64
+	overflow  atomic.Bool
+	logger    *slog.Logger
+	limits    *ratelimit.Connection
+
+	messageReceiver  interfaces.MessageReceiver
+	messageSender    interfaces.MessageSender
+	messageProcessor interfaces.MessageProcessor
+	unregister       func()
+}
+
+// New создает сессию с буферами входящих и исходящих сообщений размером bufferSize,
+// регистрирует ее в Router для доставки сообщений других пользователей
+// и запускает цикл обработки сообщений.
+//
+// Параметры:
+//   - id: Идентификатор сессии.
+//   - identity: Аутентифицированный владелец сессии.
+//   - bufferSize: Размер буферов входящих и исходящих сообщений.
+//   - messageProcessor: Обработчик сообщений сессии.
+//   - router: Маршрутизатор сообщений между пользователями.
+//   - limits: Ограничитель частоты сообщений сессии; nil — без ограничения.
+//   - logger: Логгер с полями соединения; записи сессии дополняются полем component.
+func New(
+	id string,
+	identity authmodels.Identity,
+	bufferSize int,
+	messageProcessor interfaces.MessageProcessor,
+	router interfaces.MessageRouter,
+	limits *ratelimit.Connection,
+	logger *slog.Logger,
+) *Session {
+	session := &Session{
+		id:               id,
+		identity:         identity,
+		inbox:            make(chan msg.Message, bufferSize),
+		outbox:           make(chan msg.Message, bufferSize),
+		done:             make(chan struct{}),
+		logger:           logging.Component(logger, "FALLBACK_SESSION"),
+		limits:           limits,
+		messageProcessor: messageProcessor,
+	}
+	session.messageReceiver = receiver.NewChannel(session.inbox, session.done)
+	session.messageSender = sender.NewChannel(session.outbox, session.done, session.closeOverflowed)
+	session.unregister = router.Register(identity.UserID, session.messageSender)
+	session.Touch()
+	metrics.ActiveConnections.WithLabelValues(metrics.TransportFallback).Inc()
+
+	go session.handleMessageLoop()
+
+	return session
+}
+
+// ID возвращает идентификатор сессии.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Identity возвращает владельца сессии.
+func (s *Session) Identity() authmodels.Identity {
+	return s.identity
+}
+
+// Outbox возвращает канал исходящих сообщений, которые нужно доставить клиенту.
+func (s *Session) Outbox() <-chan msg.Message {
+	return s.outbox
+}
+
+// Done возвращает канал, закрываемый при завершении сессии.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Touch отмечает активность клиента, продлевая жизнь сессии.
+func (s *Session) Touch() {
+	s.lastSeen.Store(time.Now().UnixNano())
+}
+
+// IdleSince возвращает время последней активности клиента.
+func (s *Session) IdleSince() time.Time {
+	return time.Unix(0, s.lastSeen.Load())
+}
+
+// Push помещает сообщение клиента во входящий буфер сессии.
+// Если буфер заполнен, ждет освобождения места до отмены ctx или закрытия сессии.
+func (s *Session) Push(ctx context.Context, message msg.Message) error {
+	s.Touch()
+	select {
+	case s.inbox <- message:
+		return nil
+	case <-s.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Logger возвращает логгер сессии с идентификатором сессии, адресом клиента и идентификатором пользователя.
+func (s *Session) Logger() *slog.Logger {
+	return s.logger
+}
+
+// Close завершает сессию. Повторные вызовы безопасны.
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.unregister()
+		metrics.ActiveConnections.WithLabelValues(metrics.TransportFallback).Dec()
+	})
+}
+
+// closeOverflowed закрывает сессию, клиент которой перестал забирать сообщения.
+// Сессия закрывается в отдельной горутине, чтобы не задерживать отправителя:
+// при закрытии сессия снимается с регистрации в Router и отписывается от топиков шины.
+func (s *Session) closeOverflowed() {
+	if s.overflow.Swap(true) {
+		return
+	}
+	s.logger.Warn("Сессия закрыта: клиент не забирает сообщения")
+	go s.Close()
+}
+
+// handleMessageLoop получает сообщения клиента, помечает их владельцем сессии,
+// обрабатывает и помещает ответы в исходящий буфер до закрытия сессии. Ошибка обработки
+// сообщения отправляется клиенту как сообщение об ошибке и не завершает сессию. Сообщения
+// сверх лимита частоты не обрабатываются, а клиенту отправляется сообщение об ошибке;
+// при постоянном превышении лимитов сессия закрывается.
+func (s *Session) handleMessageLoop() {
+	for {
+		message, err := s.messageReceiver.ReceiveMessage()
+		if err != nil {
+			return
+		}
+		message.From = s.identity.UserID
+
+		decision, scope := s.limits.Allow(message.Type.String())
+		if decision != ratelimit.Allowed {
+			metrics.RateLimited.WithLabelValues(metrics.TransportFallback, scope).Inc()
+		}
+		if decision == ratelimit.Abusive {
+			metrics.RateLimitCloses.WithLabelValues(metrics.TransportFallback).Inc()
+			s.logger.Warn("Сессия закрыта: постоянное превышение лимита частоты сообщений",
+				logging.MessageType(message.Type), slog.String("scope", scope))
+			s.Close()
+			return
+		}
+
+		if decision == ratelimit.Throttled {
+			s.logger.Debug("Сообщение отклонено ограничением частоты",
+				logging.MessageType(message.Type), slog.String("scope", scope))
+			if err := s.messageSender.SendMessage(msg.NewErrorResponse("Превышен лимит частоты сообщений")); err != nil {
+				return
+			}
+			continue
+		}
+
+		responseMessage, err := s.messageProcessor.ProcessMessage(message)
+		if err != nil {
+			s.logger.Error("Ошибка при обработке сообщения", logging.MessageType(message.Type), slog.Any("error", err))
+			responseMessage = msg.NewErrorResponse("Ошибка при обработке сообщения")
+		}
+
+		if err := s.messageSender.SendMessage(responseMessage); err != nil {
+			return
+		}
+	}
+}
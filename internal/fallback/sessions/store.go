@@ -0,0 +1,166 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	authmodels "messenger/internal/auth/models"
+	"messenger/internal/logging"
+	"messenger/internal/messaging/interfaces"
+	"messenger/internal/ratelimit"
+	"sync"
+	"time"
+)
+
+// Store хранит сессии резервных транспортов и закрывает сессии,
+// клиенты которых не проявляли активность дольше idleTimeout.
+type Store struct {
+	mu           sync.Mutex
+	sessions     map[string]*Session
+	newProcessor func(logger *slog.Logger) interfaces.MessageProcessor
+	router       interfaces.MessageRouter
+	limiter      *ratelimit.Limiter
+	logger       *slog.Logger
+	bufferSize   int
+	idleTimeout  time.Duration
+	stop         chan struct{}
+	stopOnce     sync.Once
+}
+
+type Options struct {
+	// NewProcessor создает обработчик сообщений для новой сессии, пишущий в журнал через logger сессии.
+	NewProcessor func(logger *slog.Logger) interfaces.MessageProcessor
+	// Router доставляет сессиям сообщения других пользователей.
+	Router interfaces.MessageRouter
+	// Limiter ограничивает частоту сообщений сессий. nil — ограничение выключено.
+	Limiter *ratelimit.Limiter
+	// BufferSize — размер буферов входящих и исходящих сообщений сессии.
+	BufferSize int
+	// IdleTimeout — время неактивности клиента, после которого сессия закрывается.
+	IdleTimeout time.Duration
+	// Logger — базовый логгер; сессии дополняют его своими полями.
+	// Если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+// NewStore создает хранилище сессий и запускает фоновое удаление неактивных сессий.
+func NewStore(options Options) *Store {
+	store := &Store{
+		sessions:     make(map[string]*Session),
+		newProcessor: options.NewProcessor,
+		router:       options.Router,
+		limiter:      options.Limiter,
+		bufferSize:   options.BufferSize,
+		idleTimeout:  options.IdleTimeout,
+		logger:       logging.OrDefault(options.Logger),
+		stop:         make(chan struct{}),
+	}
+
+	go store.expireLoop()
+
+	return store
+}
+
+// Create создает новую сессию пользователя identity со случайным идентификатором.
+// Идентификатор сессии служит идентификатором соединения в записях журнала,
+// remoteAddr — адрес клиента, создавшего сессию.
+func (st *Store) Create(identity authmodels.Identity, remoteAddr string) (*Session, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	logger := st.logger.With(
+		slog.String(logging.KeyConnectionID, id),
+		slog.String(logging.KeyRemoteAddr, remoteAddr),
+		slog.String(logging.KeyUserID, identity.UserID),
+	)
+	limits := st.limiter.NewConnection(identity.UserID, ratelimit.ClientIP(remoteAddr))
+	session := New(id, identity, st.bufferSize, st.newProcessor(logger), st.router, limits, logger)
+
+	st.mu.Lock()
+	st.sessions[id] = session
+	st.mu.Unlock()
+
+	return session, nil
+}
+
+// Get возвращает сессию по идентификатору.
+func (st *Store) Get(id string) (*Session, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	session, ok := st.sessions[id]
+	return session, ok
+}
+
+// Remove закрывает сессию и удаляет ее из хранилища.
+func (st *Store) Remove(id string) {
+	st.mu.Lock()
+	session, ok := st.sessions[id]
+	delete(st.sessions, id)
+	st.mu.Unlock()
+
+	if ok {
+		session.Close()
+	}
+}
+
+// Close останавливает фоновое удаление сессий и закрывает все открытые сессии.
+func (st *Store) Close() {
+	st.stopOnce.Do(func() {
+		close(st.stop)
+	})
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for id, session := range st.sessions {
+		session.Close()
+		delete(st.sessions, id)
+	}
+}
+
+// expireLoop периодически удаляет сессии, неактивные дольше idleTimeout, и сессии,
+// закрытые сервером (например, за превышение лимита частоты сообщений).
+func (st *Store) expireLoop() {
+	ticker := time.NewTicker(st.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-st.stop:
+			return
+		case now := <-ticker.C:
+			st.expire(now)
+		}
+	}
+}
+
+func (st *Store) expire(now time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for id, session := range st.sessions {
+		select {
+		case <-session.Done():
+			delete(st.sessions, id)
+			continue
+		default:
+		}
+		if now.Sub(session.IdleSince()) > st.idleTimeout {
+			session.Logger().Info("Сессия закрыта по неактивности")
+			session.Close()
+			delete(st.sessions, id)
+		}
+	}
+}
+
+// newSessionID генерирует случайный идентификатор сессии.
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
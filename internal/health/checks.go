@@ -0,0 +1,33 @@
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CertificateCheck возвращает проверку того, что в tlsConfig загружен сертификат
+// и срок его действия не истек.
+func CertificateCheck(tlsConfig *tls.Config) Check {
+	return func(context.Context) error {
+		if tlsConfig == nil || len(tlsConfig.Certificates) == 0 {
+			return errors.New("сертификат не загружен")
+		}
+
+		leaf := tlsConfig.Certificates[0].Leaf
+		if leaf == nil {
+			parsed, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+			if err != nil {
+				return fmt.Errorf("некорректный сертификат: %w", err)
+			}
+			leaf = parsed
+		}
+		if time.Now().After(leaf.NotAfter) {
+			return fmt.Errorf("срок действия сертификата истек %s", leaf.NotAfter.Format(time.RFC3339))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout — максимальное время выполнения одной проверки готовности.
+const checkTimeout = 2 * time.Second
+
+// drainingCheckName — имя проверки, не проходящей во время остановки сервера.
+const drainingCheckName = "draining"
+
+// ErrDraining возвращается проверкой draining, когда сервер останавливается
+// и балансировщик должен перестать направлять на него новые запросы.
+var ErrDraining = errors.New("сервер останавливается")
+
+// Check проверяет доступность зависимости подсистемы. Возвращает nil, если
+// зависимость доступна. Проверка должна завершаться при отмене ctx.
+type Check func(ctx context.Context) error
+
+// Result — результат одной проверки готовности.
+type Result struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Report — результат всех проверок готовности. Сервер готов, если пройдены все проверки.
+type Report struct {
+	Ready  bool     `json:"ready"`
+	Checks []Result `json:"checks"`
+}
+
+// Registry хранит проверки готовности, которые регистрируют подсистемы
+// (сертификат, хранилище бесед и т.д.), и признак остановки сервера.
+// После вызова StartDraining сервер считается неготовым независимо от проверок,
+// чтобы балансировщик успел вывести его из ротации до закрытия соединений.
+type Registry struct {
+	mu       sync.RWMutex
+	names    []string
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+// NewRegistry создает пустой реестр проверок готовности.
+func NewRegistry() *Registry {
+	return &Registry{
+		checks: make(map[string]Check),
+	}
+}
+
+// Register добавляет проверку готовности под именем name. Повторная регистрация
+// с тем же именем заменяет проверку.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.checks[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.checks[name] = check
+}
+
+// StartDraining помечает сервер как останавливающийся. Повторные вызовы безопасны.
+func (r *Registry) StartDraining() {
+	r.draining.Store(true)
+}
+
+// Draining сообщает, останавливается ли сервер.
+func (r *Registry) Draining() bool {
+	return r.draining.Load()
+}
+
+// Check выполняет все проверки в порядке регистрации и возвращает отчет.
+// Каждая проверка ограничена по времени checkTimeout.
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	names := append([]string(nil), r.names...)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = r.checks[name]
+	}
+	r.mu.RUnlock()
+
+	report := Report{Ready: true}
+	report.add(drainingCheckName, r.checkDraining())
+	for i, name := range names {
+		report.add(name, runCheck(ctx, checks[i]))
+	}
+	return report
+}
+
+func (r *Registry) checkDraining() error {
+	if r.Draining() {
+		return ErrDraining
+	}
+	return nil
+}
+
+func (rp *Report) add(name string, err error) {
+	result := Result{Name: name, OK: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+		rp.Ready = false
+	}
+	rp.Checks = append(rp.Checks, result)
+}
+
+func runCheck(ctx context.Context, check Check) error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	return check(ctx)
+}
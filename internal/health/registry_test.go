@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistryCheck(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("certificate", func(context.Context) error { return nil })
+	registry.Register("store", func(context.Context) error { return errors.New("хранилище недоступно") })
+
+	want := Report{Checks: []Result{
+		{Name: drainingCheckName, OK: true},
+		{Name: "certificate", OK: true},
+		{Name: "store", Error: "хранилище недоступно"},
+	}}
+	if report := registry.Check(context.Background()); !reflect.DeepEqual(report, want) {
+		t.Fatalf("Получен отчет %+v, ожидался %+v", report, want)
+	}
+
+	// Повторная регистрация заменяет проверку и сохраняет ее место в отчете.
+	registry.Register("store", func(context.Context) error { return nil })
+	want = Report{Ready: true, Checks: []Result{
+		{Name: drainingCheckName, OK: true},
+		{Name: "certificate", OK: true},
+		{Name: "store", OK: true},
+	}}
+	if report := registry.Check(context.Background()); !reflect.DeepEqual(report, want) {
+		t.Fatalf("Получен отчет %+v, ожидался %+v", report, want)
+	}
+}
+
+func TestRegistryDraining(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("store", func(context.Context) error { return nil })
+
+	registry.StartDraining()
+	registry.StartDraining()
+
+	report := registry.Check(context.Background())
+	if report.Ready || !registry.Draining() {
+		t.Fatalf("Останавливающийся сервер готов: %+v", report)
+	}
+	if got := report.Checks[0]; got.Name != drainingCheckName || got.OK || got.Error != ErrDraining.Error() {
+		t.Fatalf("Получен результат %+v, ожидалась непройденная проверка draining", got)
+	}
+}
+
+func TestRegistryCheckTimeout(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("bus", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := registry.Check(context.Background())
+	elapsed := time.Since(start)
+
+	if report.Ready || report.Checks[1].Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("Получен отчет %+v, ожидалась непройденная по тайм-ауту проверка bus", report)
+	}
+	if elapsed < checkTimeout || elapsed > checkTimeout+time.Second {
+		t.Fatalf("Проверка прервана через %s, ожидалось %s", elapsed, checkTimeout)
+	}
+}
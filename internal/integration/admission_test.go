@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"messenger/internal/apptest"
+	"messenger/internal/config/models"
+)
+
+// admissionConfig возвращает тестовую конфигурацию с ограничениями числа соединений.
+func admissionConfig(admission models.Admission) *models.Config {
+	config := apptest.Config()
+	admission.RetryAfter = 1500 * time.Millisecond
+	config.WebSocket.Admission = admission
+	return config
+}
+
+// expectRejected проверяет, что подключение пользователя с токеном token отклонено
+// со статусом status и заголовком Retry-After, округленным до целых секунд вверх.
+func expectRejected(t *testing.T, server *apptest.Server, token string, status int) {
+	t.Helper()
+
+	_, response, err := server.TryDial(t, apptest.DialOptions{Token: token})
+	if response == nil {
+		t.Fatalf("Нет ответа на рукопожатие: %v", err)
+	}
+	if response.StatusCode != status {
+		t.Fatalf("Статус рукопожатия %d, ожидался %d", response.StatusCode, status)
+	}
+	if retryAfter := response.Header.Get("Retry-After"); retryAfter != "2" {
+		t.Fatalf("Retry-After %q, ожидалось 2", retryAfter)
+	}
+}
+
+func TestAdmissionLimits(t *testing.T) {
+	t.Run("сервер", func(t *testing.T) {
+		server := apptest.Start(t, admissionConfig(models.Admission{MaxConnections: 2}))
+
+		server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+		server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+		expectRejected(t, server, apptest.AdminToken, http.StatusServiceUnavailable)
+	})
+
+	t.Run("IP-адрес", func(t *testing.T) {
+		server := apptest.Start(t, admissionConfig(models.Admission{MaxConnectionsPerIP: 2}))
+
+		server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+		server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+		expectRejected(t, server, apptest.AdminToken, http.StatusTooManyRequests)
+	})
+
+	t.Run("пользователь", func(t *testing.T) {
+		server := apptest.Start(t, admissionConfig(models.Admission{MaxConnectionsPerUser: 1}))
+
+		server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+		expectRejected(t, server, apptest.AliceToken, http.StatusTooManyRequests)
+		server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	})
+
+	t.Run("доверенная сеть", func(t *testing.T) {
+		server := apptest.Start(t, admissionConfig(models.Admission{
+			MaxConnections:        3,
+			MaxConnectionsPerIP:   1,
+			MaxConnectionsPerUser: 1,
+			TrustedCIDRs:          []string{"127.0.0.0/8", "::1/128"},
+		}))
+
+		// Ограничения IP-адреса и пользователя на доверенную сеть не действуют,
+		// общее ограничение сервера — действует.
+		for i := 0; i < 3; i++ {
+			server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+		}
+		expectRejected(t, server, apptest.AliceToken, http.StatusServiceUnavailable)
+	})
+}
+
+func TestAdmissionReleasedOnDisconnect(t *testing.T) {
+	server := apptest.Start(t, admissionConfig(models.Admission{MaxConnectionsPerUser: 1}))
+
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	expectRejected(t, server, apptest.AliceToken, http.StatusTooManyRequests)
+
+	// Место освобождается, когда сервер завершает обработку закрытого соединения.
+	alice.Close()
+	deadline := time.Now().Add(apptest.DefaultTimeout)
+	for {
+		client, response, err := server.TryDial(t, apptest.DialOptions{Token: apptest.AliceToken})
+		if err == nil {
+			client.Close()
+			return
+		}
+		if response == nil || response.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("Неожиданный ответ на рукопожатие: %v", err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Место соединения не освобождено после отключения клиента")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
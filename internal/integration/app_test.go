@@ -0,0 +1,162 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/app"
+	"messenger/internal/apptest"
+	"messenger/internal/config/models"
+	"messenger/internal/messaging/interfaces"
+	"messenger/internal/messaging/processor"
+	serverinterfaces "messenger/internal/server/interfaces"
+)
+
+// echoProcessor отвечает на любое сообщение информационным ответом с его текстом.
+type echoProcessor struct{}
+
+func (echoProcessor) ProcessMessage(message msg.Message) (msg.Message, error) {
+	return msg.Message{Type: msg.InfoResponse, Text: "эхо: " + message.Text}, nil
+}
+
+func TestAppCustomProcessor(t *testing.T) {
+	server := apptest.StartApp(t, app.Options{
+		NewProcessor: func(processor.Options) interfaces.MessageProcessor {
+			return echoProcessor{}
+		},
+	})
+	client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+
+	response := client.Request(msg.NewDataMessage("привет"))
+	if response.Type != msg.InfoResponse || response.Text != "эхо: привет" {
+		t.Fatalf("Получен ответ %+v, ожидался ответ подставленного обработчика", response)
+	}
+}
+
+// pingType — тип сообщения клиента, зарегистрированный приложением.
+var pingType = msg.MustRegisterClient("test.ping")
+
+func TestAppClientMessageType(t *testing.T) {
+	config := apptest.Config()
+	config.RateLimit = models.RateLimit{
+		Enabled:         true,
+		Connection:      map[string]models.RateRule{"test.ping": {Rate: 0.1, Burst: 1}},
+		MaxViolations:   10,
+		ViolationWindow: time.Minute,
+	}
+	server := apptest.StartApp(t, app.Options{
+		Config: config,
+		NewProcessor: func(processor.Options) interfaces.MessageProcessor {
+			return echoProcessor{}
+		},
+	})
+	client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+
+	// Зарегистрированный тип клиента проходит проверку, а его имя — ключ правила лимита.
+	ping := msg.Message{Type: pingType, Text: "пинг"}
+	if response := client.Request(ping); response.Type != msg.InfoResponse || response.Text != "эхо: пинг" {
+		t.Fatalf("Получен ответ %+v, ожидался ответ обработчика", response)
+	}
+	if response := client.Request(ping); response.Type != msg.ErrorResponse || len(response.Errors) != 0 {
+		t.Fatalf("Получен ответ %+v, ожидался отказ по лимиту", response)
+	}
+	if response := client.Request(msg.NewInfoMessage("привет")); response.Type != msg.InfoResponse {
+		t.Fatalf("Получен ответ типа %s, ожидался %s", response.Type, msg.InfoResponse)
+	}
+}
+
+func TestAppResponseTexts(t *testing.T) {
+	server := apptest.StartApp(t, app.Options{
+		ProcessorOptions: processor.Options{InfoResponseText: "принято"},
+	})
+	client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+
+	if response := client.Request(msg.NewInfoMessage("привет")); response.Text != "принято" {
+		t.Fatalf("Получен ответ %q, ожидался заданный текст", response.Text)
+	}
+	if response := client.Request(msg.NewErrorMessage("сбой")); response.Text == "" {
+		t.Fatal("Текст ответа по умолчанию не задан")
+	}
+}
+
+func TestAppExtraRoutes(t *testing.T) {
+	server := apptest.StartApp(t, app.Options{
+		Routes: func(routes serverinterfaces.Routes) {
+			routes.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "1.2.3")
+			})
+		},
+	})
+
+	response, err := server.HTTPClient().Get(server.URL + "/version")
+	if err != nil {
+		t.Fatalf("Ошибка запроса: %v", err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK || string(body) != "1.2.3" {
+		t.Fatalf("Получен ответ %d %q, ожидался 200 \"1.2.3\"", response.StatusCode, body)
+	}
+	server.WaitForLog(t, "/version", "status=200")
+}
+
+func TestAppLifecycleHooks(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(event string) app.Hook {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+			return nil
+		}
+	}
+
+	server := apptest.StartApp(t, app.Options{
+		OnStart: []app.Hook{record("start-1"), record("start-2")},
+		OnStop:  []app.Hook{record("stop-1"), record("stop-2")},
+	})
+	if err := server.App.Start(context.Background()); err == nil {
+		t.Fatal("Повторный запуск приложения не вернул ошибку")
+	}
+	server.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"start-1", "start-2", "stop-2", "stop-1"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("Хуки вызваны в порядке %v, ожидался %v", events, want)
+	}
+}
+
+func TestAppStartHookErrorStopsApp(t *testing.T) {
+	hookErr := errors.New("миграция не выполнена")
+	stopped := false
+
+	server := apptest.New(t, app.Options{
+		OnStart: []app.Hook{func(context.Context) error { return hookErr }},
+		OnStop: []app.Hook{func(context.Context) error {
+			stopped = true
+			return nil
+		}},
+	})
+
+	if err := server.App.Start(context.Background()); !errors.Is(err, hookErr) {
+		t.Fatalf("Start вернул %v, ожидалась ошибка хука", err)
+	}
+	if !stopped {
+		t.Fatal("Приложение не остановлено после ошибки хука")
+	}
+	if _, _, err := server.TryDial(t, apptest.DialOptions{Token: apptest.AliceToken}); err == nil {
+		t.Fatal("Остановленное приложение принимает соединения")
+	}
+}
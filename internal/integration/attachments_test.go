@@ -0,0 +1,327 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/apptest"
+)
+
+// noisePNG возвращает PNG со случайными пикселями, который почти не сжимается.
+func noisePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	random := rand.New(rand.NewPCG(1, 2))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(random.Uint32())
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Ошибка кодирования PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+type uploadState struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Size        int64           `json:"size"`
+	Offset      int64           `json:"offset"`
+	Complete    bool            `json:"complete"`
+	ContentType string          `json:"content_type"`
+	URL         string          `json:"url"`
+	Width       int             `json:"width"`
+	Height      int             `json:"height"`
+	Placeholder string          `json:"placeholder"`
+	Thumbnails  []msg.Thumbnail `json:"thumbnails"`
+}
+
+// attachmentRequest выполняет запрос к эндпоинтам вложений от имени пользователя с токеном token.
+func attachmentRequest(t *testing.T, server *apptest.Server, token, method, path string, header http.Header, body []byte) *http.Response {
+	t.Helper()
+
+	request, err := http.NewRequest(method, server.URL+server.Config.Attachments.Prefix+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Ошибка создания запроса: %v", err)
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	response, err := server.HTTPClient().Do(request)
+	if err != nil {
+		t.Fatalf("Ошибка запроса %s %s: %v", method, path, err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+func createUpload(t *testing.T, server *apptest.Server, token, name string, size int) *http.Response {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]any{"name": name, "size": size})
+	return attachmentRequest(t, server, token, http.MethodPost, "/uploads", nil, body)
+}
+
+func writeChunk(t *testing.T, server *apptest.Server, token, id string, offset int, chunk []byte) *http.Response {
+	t.Helper()
+
+	header := http.Header{"Upload-Offset": {strconv.Itoa(offset)}}
+	return attachmentRequest(t, server, token, http.MethodPatch, "/"+id, header, chunk)
+}
+
+func decodeUpload(t *testing.T, response *http.Response, status int) uploadState {
+	t.Helper()
+
+	if response.StatusCode != status {
+		body, _ := io.ReadAll(response.Body)
+		t.Fatalf("Получен ответ %d %s, ожидался %d", response.StatusCode, body, status)
+	}
+	var state uploadState
+	if err := json.NewDecoder(response.Body).Decode(&state); err != nil {
+		t.Fatalf("Ошибка разбора ответа: %v", err)
+	}
+	return state
+}
+
+// upload загружает файл частями по chunkSize байт и возвращает описание завершенного вложения.
+func upload(t *testing.T, server *apptest.Server, token, name string, data []byte, chunkSize int) uploadState {
+	t.Helper()
+
+	state := decodeUpload(t, createUpload(t, server, token, name, len(data)), http.StatusCreated)
+	for offset := 0; offset < len(data); offset += chunkSize {
+		end := min(offset+chunkSize, len(data))
+		state = decodeUpload(t, writeChunk(t, server, token, state.ID, offset, data[offset:end]), http.StatusOK)
+	}
+	if !state.Complete {
+		t.Fatalf("Загрузка не завершена: %+v", state)
+	}
+	return state
+}
+
+func expectStatus(t *testing.T, response *http.Response, status int) {
+	t.Helper()
+
+	if response.StatusCode != status {
+		body, _ := io.ReadAll(response.Body)
+		t.Fatalf("Получен ответ %d %s, ожидался %d", response.StatusCode, body, status)
+	}
+}
+
+func TestAttachmentResumableUploadAndDownload(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	data := noisePNG(t, 200, 200)
+	const chunkSize = 40 << 10
+
+	created := decodeUpload(t, createUpload(t, server, apptest.AliceToken, `C:\фото\кот.png`, len(data)), http.StatusCreated)
+	if created.Name != "кот.png" || created.Offset != 0 || created.Complete {
+		t.Fatalf("Неожиданное описание загрузки: %+v", created)
+	}
+
+	// Первая часть загружается, вторая обрывается на середине: сервер сохраняет полученное.
+	decodeUpload(t, writeChunk(t, server, apptest.AliceToken, created.ID, 0, data[:chunkSize]), http.StatusOK)
+
+	// Часть с неверным смещением отклоняется, а в ответе передается смещение для продолжения.
+	response := writeChunk(t, server, apptest.AliceToken, created.ID, 2*chunkSize, data[2*chunkSize:3*chunkSize])
+	expectStatus(t, response, http.StatusConflict)
+	if got := response.Header.Get("Upload-Offset"); got != strconv.Itoa(chunkSize) {
+		t.Fatalf("Upload-Offset = %q, ожидалось %d", got, chunkSize)
+	}
+
+	// После обрыва клиент узнает смещение и продолжает загрузку с него.
+	state := decodeUpload(t, attachmentRequest(t, server, apptest.AliceToken, http.MethodGet, "/"+created.ID, nil, nil), http.StatusOK)
+	for offset := int(state.Offset); offset < len(data); offset += chunkSize {
+		end := min(offset+chunkSize, len(data))
+		state = decodeUpload(t, writeChunk(t, server, apptest.AliceToken, created.ID, offset, data[offset:end]), http.StatusOK)
+	}
+	if !state.Complete || state.ContentType != "image/png" || state.URL == "" {
+		t.Fatalf("Неожиданное описание завершенного вложения: %+v", state)
+	}
+
+	// Bob создает беседу и приглашает Alice, Alice отправляет в нее сообщение с вложением.
+	bob := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	join := msg.NewDataMessage("я здесь")
+	join.Conversation = "room-1"
+	bob.Request(join)
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	message := msg.Message{Type: msg.DataMessage, Conversation: "room-1", Attachments: []msg.Attachment{{ID: state.ID}}}
+	response2 := alice.Request(message)
+	if response2.Type != msg.DataResponse || len(response2.Attachments) != 1 || response2.Attachments[0].URL != state.URL {
+		t.Fatalf("Неожиданный ответ на сообщение с вложением: %+v", response2)
+	}
+
+	delivered := bob.Expect(msg.DataMessage)
+	if len(delivered.Attachments) != 1 {
+		t.Fatalf("Доставлено сообщение без вложения: %+v", delivered)
+	}
+	attachment := delivered.Attachments[0]
+	if attachment.ID != state.ID || attachment.Name != "кот.png" || attachment.ContentType != "image/png" ||
+		attachment.Size != int64(len(data)) {
+		t.Fatalf("Неожиданное описание вложения: %+v", attachment)
+	}
+
+	// Bob скачивает вложение по URL из сообщения с токеном в параметре запроса.
+	download, err := server.HTTPClient().Get(server.URL + attachment.URL + "?access_token=" + apptest.BobToken)
+	if err != nil {
+		t.Fatalf("Ошибка скачивания: %v", err)
+	}
+	defer download.Body.Close()
+	expectStatus(t, download, http.StatusOK)
+	content, _ := io.ReadAll(download.Body)
+	if !bytes.Equal(content, data) {
+		t.Fatalf("Скачано %d байт, не совпадающих с загруженными %d", len(content), len(data))
+	}
+	if got := download.Header.Get("Content-Type"); got != "image/png" {
+		t.Fatalf("Content-Type = %q, ожидался image/png", got)
+	}
+	if got := download.Header.Get("Content-Disposition"); !strings.HasPrefix(got, "attachment;") {
+		t.Fatalf("Content-Disposition = %q, ожидалось attachment", got)
+	}
+
+	// Пользователь не из беседы не может ни скачать вложение, ни узнать о нем.
+	outsider := attachmentRequest(t, server, apptest.AdminToken, http.MethodGet, "/"+state.ID+"/content", nil, nil)
+	expectStatus(t, outsider, http.StatusNotFound)
+}
+
+func TestAttachmentLimits(t *testing.T) {
+	server := apptest.Start(t, nil)
+	maxSize := int(server.Config.Attachments.MaxSize)
+
+	expectStatus(t, createUpload(t, server, apptest.AliceToken, "big.bin", maxSize+1), http.StatusRequestEntityTooLarge)
+	expectStatus(t, createUpload(t, server, apptest.AliceToken, "", 10), http.StatusBadRequest)
+	expectStatus(t, createUpload(t, server, apptest.AliceToken, "empty.txt", 0), http.StatusBadRequest)
+
+	// Квота 2 MiB: две незавершенные загрузки по 1 MiB занимают ее целиком.
+	first := decodeUpload(t, createUpload(t, server, apptest.AliceToken, "a.bin", maxSize), http.StatusCreated)
+	decodeUpload(t, createUpload(t, server, apptest.AliceToken, "b.bin", maxSize), http.StatusCreated)
+	expectStatus(t, createUpload(t, server, apptest.AliceToken, "c.bin", 1), http.StatusInsufficientStorage)
+	// Квота у каждого пользователя своя.
+	decodeUpload(t, createUpload(t, server, apptest.BobToken, "c.bin", 1), http.StatusCreated)
+
+	// Часть больше max_chunk_size отклоняется.
+	chunk := make([]byte, server.Config.Attachments.MaxChunkSize+1)
+	expectStatus(t, writeChunk(t, server, apptest.AliceToken, first.ID, 0, chunk), http.StatusRequestEntityTooLarge)
+
+	// Чужую загрузку нельзя ни продолжить, ни удалить.
+	expectStatus(t, writeChunk(t, server, apptest.BobToken, first.ID, 0, []byte("x")), http.StatusNotFound)
+	expectStatus(t, attachmentRequest(t, server, apptest.BobToken, http.MethodDelete, "/"+first.ID, nil, nil), http.StatusNotFound)
+
+	// Удаление освобождает квоту.
+	expectStatus(t, attachmentRequest(t, server, apptest.AliceToken, http.MethodDelete, "/"+first.ID, nil, nil), http.StatusNoContent)
+	decodeUpload(t, createUpload(t, server, apptest.AliceToken, "c.bin", 1), http.StatusCreated)
+}
+
+func TestAttachmentDataBeyondDeclaredSize(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	state := decodeUpload(t, createUpload(t, server, apptest.AliceToken, "note.txt", 5), http.StatusCreated)
+	expectStatus(t, writeChunk(t, server, apptest.AliceToken, state.ID, 0, []byte("привет")), http.StatusRequestEntityTooLarge)
+	expectStatus(t, attachmentRequest(t, server, apptest.AliceToken, http.MethodGet, "/"+state.ID, nil, nil), http.StatusNotFound)
+}
+
+func TestAttachmentContentTypeIsSniffed(t *testing.T) {
+	config := apptest.Config()
+	config.Attachments.AllowedTypes = []string{"image/", "text/plain"}
+	server := apptest.Start(t, config)
+
+	// Тип определяется по содержимому, а не по имени файла.
+	state := upload(t, server, apptest.AliceToken, "document.pdf", []byte("просто текст"), 1024)
+	if state.ContentType != "text/plain; charset=utf-8" {
+		t.Fatalf("ContentType = %q, ожидался text/plain", state.ContentType)
+	}
+
+	// Файл неразрешенного типа удаляется после загрузки последней части.
+	html := []byte("<!DOCTYPE html><script>alert(1)</script>")
+	rejected := decodeUpload(t, createUpload(t, server, apptest.AliceToken, "image.png", len(html)), http.StatusCreated)
+	expectStatus(t, writeChunk(t, server, apptest.AliceToken, rejected.ID, 0, html), http.StatusUnsupportedMediaType)
+	expectStatus(t, attachmentRequest(t, server, apptest.AliceToken, http.MethodGet, "/"+rejected.ID, nil, nil), http.StatusNotFound)
+}
+
+func TestMessageAttachmentErrors(t *testing.T) {
+	server := apptest.Start(t, nil)
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+
+	incomplete := decodeUpload(t, createUpload(t, server, apptest.AliceToken, "a.txt", 10), http.StatusCreated)
+	bobs := upload(t, server, apptest.BobToken, "b.txt", []byte("от Bob"), 1024)
+
+	tests := []struct {
+		name    string
+		message msg.Message
+		field   string
+		code    string
+	}{
+		{
+			name:    "загрузка не завершена",
+			message: msg.Message{Type: msg.DataMessage, Conversation: "room-1", Attachments: []msg.Attachment{{ID: incomplete.ID}}},
+			field:   "attachments[0].id",
+			code:    msg.CodeInvalidValue,
+		},
+		{
+			name:    "чужое вложение",
+			message: msg.Message{Type: msg.DataMessage, Conversation: "room-1", Attachments: []msg.Attachment{{ID: bobs.ID}}},
+			field:   "attachments[0].id",
+			code:    msg.CodeInvalidValue,
+		},
+		{
+			name:    "без беседы",
+			message: msg.Message{Type: msg.DataMessage, Attachments: []msg.Attachment{{ID: bobs.ID}}},
+			field:   "attachments",
+			code:    msg.CodeInvalidValue,
+		},
+		{
+			name: "описание от клиента",
+			message: msg.Message{Type: msg.DataMessage, Conversation: "room-1",
+				Attachments: []msg.Attachment{{ID: bobs.ID, URL: "https://evil.example.com"}}},
+			field: "attachments[0]",
+			code:  msg.CodeForbidden,
+		},
+	}
+	for _, tt := range tests {
+		response := alice.Request(tt.message)
+		if response.Type != msg.ErrorResponse {
+			t.Fatalf("%s: получен ответ типа %s, ожидался %s", tt.name, response.Type, msg.ErrorResponse)
+		}
+		found := false
+		for _, fieldError := range response.Errors {
+			found = found || fieldError.Field == tt.field && fieldError.Code == tt.code
+		}
+		if !found {
+			t.Fatalf("%s: нет ошибки %s/%s в %s", tt.name, tt.field, tt.code, fmt.Sprint(response.Errors))
+		}
+	}
+
+	// Сообщения с отклоненными вложениями не сохраняются.
+	history := attachmentHistory(t, server, apptest.AliceToken, "room-1")
+	if history != http.StatusForbidden {
+		t.Fatalf("История беседы без сообщений вернула %d, ожидался 403", history)
+	}
+}
+
+// attachmentHistory запрашивает историю беседы через REST API и возвращает код ответа.
+func attachmentHistory(t *testing.T, server *apptest.Server, token, conversation string) int {
+	t.Helper()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+server.Config.REST.Prefix+"/conversations/"+conversation+"/messages", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := server.HTTPClient().Do(request)
+	if err != nil {
+		t.Fatalf("Ошибка запроса истории: %v", err)
+	}
+	response.Body.Close()
+	return response.StatusCode
+}
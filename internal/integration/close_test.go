@@ -0,0 +1,152 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1ight181/messenger/pkg/protocol"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/apptest"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClientCloseIsHandled(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+	}{
+		{name: "нормальное закрытие", code: websocket.CloseNormalClosure},
+		{name: "клиент уходит", code: websocket.CloseGoingAway},
+		{name: "перезапуск сервиса", code: websocket.CloseServiceRestart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apptest.Start(t, nil)
+			client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+
+			client.SendClose(tt.code, "до свидания")
+			closeErr := client.ExpectClose()
+			if closeErr.Code != tt.code {
+				t.Fatalf("Сервер ответил close-фреймом с кодом %d, ожидался %d", closeErr.Code, tt.code)
+			}
+			server.WaitForLog(t, "Соединение закрыто", "code="+strconv.Itoa(tt.code), "до свидания")
+		})
+	}
+}
+
+func TestClientCloseWithApplicationCode(t *testing.T) {
+	server := apptest.Start(t, nil)
+	client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+
+	client.SendClose(4000, "код приложения")
+	if closeErr := client.ExpectClose(); closeErr.Code != 4000 {
+		t.Fatalf("Сервер ответил close-фреймом с кодом %d, ожидался 4000", closeErr.Code)
+	}
+	// Коды приложения не считаются штатным закрытием и обрабатываются как ошибка чтения.
+	server.WaitForLog(t, "level=ERROR", "Ошибка чтения сообщения")
+}
+
+func TestAbruptDisconnectUnregistersUser(t *testing.T) {
+	server := apptest.Start(t, nil)
+	client := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+
+	waitPresence(t, server, apptest.Bob, true)
+
+	client.Close()
+	server.WaitForLog(t, "Соединение закрыто", "user_id="+apptest.Bob)
+	waitPresence(t, server, apptest.Bob, false)
+}
+
+func TestShutdownClosesConnectionsWithReconnectHint(t *testing.T) {
+	config := apptest.Config()
+	config.Shutdown.ReconnectDelay = 500 * time.Millisecond
+	server := apptest.Start(t, config)
+	client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+
+	go server.Close()
+
+	closeErr := client.ExpectClose()
+	if closeErr.Code != websocket.CloseServiceRestart {
+		t.Fatalf("При остановке получен close-фрейм с кодом %d, ожидался %d", closeErr.Code, websocket.CloseServiceRestart)
+	}
+	delay, ok := protocol.ParseReconnectHint(closeErr.Text)
+	if !ok || delay > config.Shutdown.ReconnectDelay {
+		t.Fatalf("Некорректная подсказка о переподключении %q", closeErr.Text)
+	}
+	client.SendClose(websocket.CloseNormalClosure, "")
+}
+
+// waitPresence ждет, пока REST API не сообщит ожидаемое присутствие пользователя.
+func waitPresence(t *testing.T, server *apptest.Server, userID string, online bool) {
+	t.Helper()
+
+	httpClient := server.HTTPClient()
+	deadline := time.Now().Add(apptest.DefaultTimeout)
+	for {
+		request, err := http.NewRequest(http.MethodGet, server.URL+"/api/presence?user="+userID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Authorization", "Bearer "+apptest.AliceToken)
+		response, err := httpClient.Do(request)
+		if err != nil {
+			t.Fatalf("Ошибка запроса присутствия: %v", err)
+		}
+		var presence []struct {
+			User   string `json:"user"`
+			Online bool   `json:"online"`
+		}
+		err = json.NewDecoder(response.Body).Decode(&presence)
+		response.Body.Close()
+		if err != nil || len(presence) != 1 {
+			t.Fatalf("Некорректный ответ присутствия (HTTP %d): %v", response.StatusCode, err)
+		}
+		if presence[0].Online == online {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Присутствие %s не стало %v", userID, online)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestSlowReaderIsDisconnected(t *testing.T) {
+	config := apptest.Config()
+	config.WebSocket.WriteTimeout = 200 * time.Millisecond
+	config.Messages.MaxTextLength = 60000
+	server := apptest.Start(t, config)
+
+	// Bob создает беседу, приглашает Alice и перестает читать сообщения.
+	bob := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	join := msg.NewDataMessage("я здесь")
+	join.Conversation = "room-1"
+	bob.Request(join)
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+
+	// Alice пишет в беседу, пока буферы соединения Bob не заполнятся и запись не превысит
+	// таймаут. Доставка Bob не задерживает ответы Alice дольше таймаута записи.
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	message := msg.NewDataMessage(strings.Repeat("ю", 25000))
+	message.Conversation = "room-1"
+	deadline := time.Now().Add(apptest.DefaultTimeout)
+	for !strings.Contains(server.Logs(), "Ошибка отправки сообщения") {
+		if time.Now().After(deadline) {
+			t.Fatal("Запись в соединение, которое не читает сообщения, не прервана")
+		}
+		started := time.Now()
+		alice.Request(message)
+		if elapsed := time.Since(started); elapsed > time.Second {
+			t.Fatalf("Ответ Alice задержан на %s", elapsed)
+		}
+	}
+
+	// Соединение Bob закрыто сервером, и Bob отключен.
+	server.WaitForLog(t, "Ошибка чтения сообщения", "user_id="+apptest.Bob, "use of closed network connection")
+	waitPresence(t, server, apptest.Bob, false)
+}
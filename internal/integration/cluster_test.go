@@ -0,0 +1,344 @@
+package integration
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/app"
+	"messenger/internal/apptest"
+	"messenger/internal/cluster"
+	"messenger/internal/config/models"
+	"messenger/internal/messaging/bus"
+	"messenger/internal/messaging/bus/redislocal"
+
+	"github.com/gorilla/websocket"
+)
+
+// startRedis запускает сервер Redis Pub/Sub в памяти процесса на адресе address
+// ("127.0.0.1:0" — эфемерный порт). Сервер останавливается по завершении теста.
+func startRedis(t *testing.T, address string) *redislocal.Server {
+	t.Helper()
+
+	server, err := redislocal.NewServer(address)
+	if err != nil {
+		t.Fatalf("Ошибка запуска Redis: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+// clusterConfig возвращает тестовую конфигурацию узла кластера с шиной Redis по адресу address.
+func clusterConfig(address, nodeID string) *models.Config {
+	config := apptest.Config()
+	config.Bus = models.Bus{
+		Type:  "redis",
+		Redis: models.RedisBus{Address: address, Timeout: time.Second},
+	}
+	config.Cluster.NodeID = nodeID
+	return config
+}
+
+// startNode запускает сторонний узел кластера без приложения: только шину Redis и каталог
+// подключений. Им тесты моделируют узлы, которые падают, не сообщив об остановке.
+func startNode(t *testing.T, address, nodeID string) (*bus.RedisBus, *cluster.Directory) {
+	t.Helper()
+
+	redisBus, err := bus.NewRedis(bus.RedisOptions{Address: address, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Ошибка подключения к Redis: %v", err)
+	}
+	directory := cluster.New(cluster.Options{
+		NodeID:            nodeID,
+		Bus:               redisBus,
+		HeartbeatInterval: 50 * time.Millisecond,
+	})
+	if err := directory.Start(); err != nil {
+		t.Fatalf("Ошибка запуска каталога: %v", err)
+	}
+	t.Cleanup(func() {
+		directory.Close()
+		redisBus.Close()
+	})
+	return redisBus, directory
+}
+
+func TestClusterDirectMessageAcrossNodes(t *testing.T) {
+	redis := startRedis(t, "127.0.0.1:0")
+	node1 := apptest.Start(t, clusterConfig(redis.Addr(), "node-1"))
+	node2 := apptest.Start(t, clusterConfig(redis.Addr(), "node-2"))
+
+	bob := node2.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	waitPresence(t, node1, apptest.Bob, true)
+
+	if status := restRequest(t, node1, apptest.AliceToken, http.MethodPost, "/users/"+apptest.Bob+"/messages",
+		map[string]string{"text": "привет с другого узла"}); status != http.StatusAccepted {
+		t.Fatalf("Отправка личного сообщения вернула %d, ожидался 202", status)
+	}
+	delivered := bob.Expect(msg.DataMessage)
+	if delivered.From != apptest.Alice || delivered.Text != "привет с другого узла" {
+		t.Fatalf("Доставлено сообщение %+v", delivered)
+	}
+
+	// После отключения Bob другие узлы узнают об этом из события каталога.
+	bob.Close()
+	waitPresence(t, node1, apptest.Bob, false)
+}
+
+func TestClusterNodeLeaseExpires(t *testing.T) {
+	redis := startRedis(t, "127.0.0.1:0")
+	node := apptest.Start(t, clusterConfig(redis.Addr(), "node-1"))
+
+	// Штатно остановленный узел сразу исключается из каталога.
+	_, stopped := startNode(t, redis.Addr(), "node-stopped")
+	stopped.Connected("carol")
+	waitPresence(t, node, "carol", true)
+	stopped.Close()
+	waitPresence(t, node, "carol", false)
+
+	// Упавший узел перестает присылать пульс и исключается после истечения аренды.
+	crashedBus, crashed := startNode(t, redis.Addr(), "node-crashed")
+	crashed.Connected("dave")
+	waitPresence(t, node, "dave", true)
+	crashedBus.Close()
+	waitPresence(t, node, "dave", false)
+	node.WaitForLog(t, "Аренда узла истекла", "peer_node_id=node-crashed")
+}
+
+func TestClusterBusReconnects(t *testing.T) {
+	redis := startRedis(t, "127.0.0.1:0")
+	address := redis.Addr()
+	node1 := apptest.Start(t, clusterConfig(address, "node-1"))
+	node2 := apptest.Start(t, clusterConfig(address, "node-2"))
+
+	// Redis перезапускается: узлы переподключаются и восстанавливают подписки.
+	redis.Close()
+	node1.WaitForLog(t, "Соединение подписок Redis разорвано")
+	node2.WaitForLog(t, "Соединение подписок Redis разорвано")
+	startRedis(t, address)
+	node1.WaitForLog(t, "Соединение подписок Redis восстановлено")
+	node2.WaitForLog(t, "Соединение подписок Redis восстановлено")
+
+	bob := node2.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	waitPresence(t, node1, apptest.Bob, true)
+	if status := restRequest(t, node1, apptest.AliceToken, http.MethodPost, "/users/"+apptest.Bob+"/messages",
+		map[string]string{"text": "после перезапуска"}); status != http.StatusAccepted {
+		t.Fatalf("Отправка личного сообщения вернула %d, ожидался 202", status)
+	}
+	if delivered := bob.Expect(msg.DataMessage); delivered.Text != "после перезапуска" {
+		t.Fatalf("Доставлено сообщение %+v", delivered)
+	}
+}
+
+func TestClusterRoomMembershipAcrossNodes(t *testing.T) {
+	redis := startRedis(t, "127.0.0.1:0")
+	node1 := apptest.Start(t, clusterConfig(redis.Addr(), "node-1"))
+	node2 := apptest.Start(t, clusterConfig(redis.Addr(), "node-2"))
+
+	send := func(conversation, text string) {
+		t.Helper()
+		if status := restRequest(t, node1, apptest.AliceToken, http.MethodPost, "/conversations/"+conversation+"/messages",
+			map[string]string{"text": text}); status != http.StatusCreated {
+			t.Fatalf("Отправка сообщения в беседу %s вернула %d, ожидался 201", conversation, status)
+		}
+	}
+
+	// Bob подключен к node-2, а приглашен в беседу через node-1: node-2 узнает об участии
+	// из события в шине.
+	bob := node2.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	send("room-1", "создание беседы")
+	node1.Invite(t, apptest.AliceToken, "room-1", apptest.Bob)
+	node2.WaitForLog(t, "Узел подписан на топик беседы", "conversation=room-1")
+	send("room-1", "для участника на другом узле")
+	if delivered := bob.Expect(msg.DataMessage); delivered.Text != "для участника на другом узле" {
+		t.Fatalf("Доставлено сообщение %+v", delivered)
+	}
+
+	// Admin приглашен, пока не подключен ни к одному узлу: при подключении к node-2
+	// его беседы сообщает node-1, в хранилище которого они есть.
+	send("room-2", "создание беседы")
+	node1.Invite(t, apptest.AliceToken, "room-2", apptest.Admin)
+	admin := node2.Dial(t, apptest.DialOptions{Token: apptest.AdminToken})
+	node2.WaitForLog(t, "Узел подписан на топик беседы", "conversation=room-2")
+	send("room-2", "после подключения")
+	if delivered := admin.Expect(msg.DataMessage); delivered.Text != "после подключения" {
+		t.Fatalf("Доставлено сообщение %+v", delivered)
+	}
+}
+
+func TestClusterNodeShutdownClosesBusAfterConnections(t *testing.T) {
+	redis := startRedis(t, "127.0.0.1:0")
+	node1 := apptest.Start(t, clusterConfig(redis.Addr(), "node-1"))
+	node2 := apptest.Start(t, clusterConfig(redis.Addr(), "node-2"))
+
+	bob := node2.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	waitPresence(t, node1, apptest.Bob, true)
+
+	// Отключения пользователей при остановке узла публикуются в шину до ее закрытия.
+	stopped := make(chan struct{})
+	go func() {
+		node2.Close()
+		close(stopped)
+	}()
+	if closeErr := bob.ExpectClose(); closeErr.Code != websocket.CloseServiceRestart {
+		t.Fatalf("При остановке получен close-фрейм с кодом %d, ожидался %d", closeErr.Code, websocket.CloseServiceRestart)
+	}
+	bob.SendClose(websocket.CloseNormalClosure, "")
+	<-stopped
+	waitPresence(t, node1, apptest.Bob, false)
+	if logs := node2.Logs(); strings.Contains(logs, "Ошибка публикации") {
+		t.Fatalf("При остановке узла шина закрыта до закрытия соединений:\n%s", logs)
+	}
+}
+
+func TestClusterNodeFailedToBuildLeavesCluster(t *testing.T) {
+	redis := startRedis(t, "127.0.0.1:0")
+	_, observer := startNode(t, redis.Addr(), "observer")
+
+	// Каталог вложений нельзя создать на месте файла: сборка узла завершается ошибкой
+	// уже после подключения к Redis и запуска каталога подключений.
+	file := filepath.Join(t.TempDir(), "attachments")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := clusterConfig(redis.Addr(), "node-failed")
+	config.Attachments.Dir = file
+	if _, err := app.New(app.Options{
+		Config:    config,
+		TLSConfig: &tls.Config{},
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}); err == nil {
+		t.Fatal("Сборка приложения с некорректным каталогом вложений завершилась без ошибки")
+	}
+
+	// Узел сообщает другим узлам об остановке и больше не присылает пульс.
+	listed := func() bool {
+		return slices.ContainsFunc(observer.Nodes(), func(node cluster.NodeInfo) bool { return node.ID == "node-failed" })
+	}
+	deadline := time.Now().Add(apptest.DefaultTimeout)
+	for listed() {
+		if time.Now().After(deadline) {
+			t.Fatal("Узел, сборка которого завершилась ошибкой, не исключен из каталога")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(5 * config.Cluster.HeartbeatInterval)
+	if listed() {
+		t.Fatal("Узел, сборка которого завершилась ошибкой, продолжает присылать пульс")
+	}
+}
+
+func TestClusterSlowSubscriberDoesNotBlockBus(t *testing.T) {
+	redis := startRedis(t, "127.0.0.1:0")
+	logs := &strings.Builder{}
+	var logsMu sync.Mutex
+	redisBus, err := bus.NewRedis(bus.RedisOptions{
+		Address:   redis.Addr(),
+		Timeout:   time.Second,
+		QueueSize: 1,
+		Logger:    slog.New(slog.NewTextHandler(lockedWriter{&logsMu, logs}, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Ошибка подключения к Redis: %v", err)
+	}
+	t.Cleanup(func() { redisBus.Close() })
+
+	// Обработчик топика slow не возвращается, пока тест его не отпустит.
+	release := make(chan struct{})
+	slowReceived := make(chan string, 8)
+	if _, err := redisBus.Subscribe("slow", func(message msg.Message) {
+		slowReceived <- message.Text
+		<-release
+	}); err != nil {
+		t.Fatal(err)
+	}
+	fastReceived := make(chan string, 8)
+	if _, err := redisBus.Subscribe("fast", func(message msg.Message) {
+		fastReceived <- message.Text
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	publish := func(topic, text string) {
+		t.Helper()
+		if err := redisBus.Publish(context.Background(), topic, msg.NewInfoMessage(text)); err != nil {
+			t.Fatalf("Ошибка публикации: %v", err)
+		}
+	}
+	// receive ждет сообщение expected, пропуская запоздавшие сообщения прогрева.
+	receive := func(received chan string, expected string) {
+		t.Helper()
+		for {
+			select {
+			case text := <-received:
+				if text == "прогрев" {
+					continue
+				}
+				if text != expected {
+					t.Fatalf("Получено сообщение %q, ожидалось %q", text, expected)
+				}
+				return
+			case <-time.After(apptest.DefaultTimeout):
+				t.Fatalf("Сообщение %q не получено", expected)
+			}
+		}
+	}
+
+	// Подписки оформляются асинхронно и по порядку: когда до fast доходят сообщения,
+	// slow тоже подписан.
+	deadline := time.Now().Add(apptest.DefaultTimeout)
+	for subscribed := false; !subscribed; {
+		publish("fast", "прогрев")
+		select {
+		case <-fastReceived:
+			subscribed = true
+		case <-time.After(50 * time.Millisecond):
+			if time.Now().After(deadline) {
+				t.Fatal("Подписка на топик не оформлена")
+			}
+		}
+	}
+	// Пока обработчик slow занят первым сообщением, второе ждет в очереди, третье
+	// отбрасывается, а сообщения других подписчиков доставляются без задержки.
+	publish("slow", "1")
+	receive(slowReceived, "1")
+	publish("slow", "2")
+	publish("slow", "3")
+	publish("fast", "быстрое")
+	receive(fastReceived, "быстрое")
+
+	close(release)
+	receive(slowReceived, "2")
+	select {
+	case text := <-slowReceived:
+		t.Fatalf("Получено сообщение %q сверх очереди подписчика", text)
+	case <-time.After(100 * time.Millisecond):
+	}
+	logsMu.Lock()
+	defer logsMu.Unlock()
+	if !strings.Contains(logs.String(), "Сообщение шины отброшено") {
+		t.Fatalf("Отброшенное сообщение не записано в журнал:\n%s", logs)
+	}
+}
+
+// lockedWriter — io.Writer, запись в который синхронизирована мьютексом.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (lw lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
@@ -0,0 +1,106 @@
+package integration
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/apptest"
+	"messenger/internal/ws/traffic"
+)
+
+// trafficDelta возвращает прирост общих счетчиков трафика с момента снимка before.
+func trafficDelta(before traffic.Snapshot) traffic.Snapshot {
+	after := traffic.Total.Snapshot()
+	return traffic.Snapshot{
+		PayloadRead:    after.PayloadRead - before.PayloadRead,
+		PayloadWritten: after.PayloadWritten - before.PayloadWritten,
+		WireRead:       after.WireRead - before.WireRead,
+		WireWritten:    after.WireWritten - before.WireWritten,
+	}
+}
+
+// exchangeLargeMessage подключает Alice и Bob с предложением сжатия compression, пересылает
+// большое хорошо сжимаемое сообщение от Bob к Alice через беседу и закрывает соединения.
+// Возвращает прирост общих счетчиков трафика за время жизни соединений.
+func exchangeLargeMessage(t *testing.T, server *apptest.Server, compression bool) traffic.Snapshot {
+	t.Helper()
+
+	before := traffic.Total.Snapshot()
+
+	bob, response, err := server.TryDial(t, apptest.DialOptions{Token: apptest.BobToken, Compression: compression})
+	if err != nil {
+		t.Fatalf("Ошибка подключения: %v", err)
+	}
+	negotiated := strings.Contains(response.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+	if negotiated != compression {
+		t.Fatalf("Сжатие согласовано: %t, ожидалось %t", negotiated, compression)
+	}
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken, Compression: compression})
+
+	create := msg.NewDataMessage("привет")
+	create.Conversation = "room-1"
+	bob.Request(create)
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+
+	large := msg.NewDataMessage(strings.Repeat("сжимаемый текст ", 200))
+	large.Conversation = "room-1"
+	if response := bob.Request(large); response.Type != msg.DataResponse {
+		t.Fatalf("Неожиданный ответ на большое сообщение: %+v", response)
+	}
+	if delivered := alice.Expect(msg.DataMessage); delivered.Text != large.Text {
+		t.Fatalf("Доставлен текст длиной %d, ожидалось %d", len(delivered.Text), len(large.Text))
+	}
+
+	// Счетчики соединения переносятся в общие при его завершении.
+	bob.Close()
+	alice.Close()
+	server.WaitForLog(t, "Трафик соединения", "user_id="+apptest.Bob)
+	server.WaitForLog(t, "Трафик соединения", "user_id="+apptest.Alice)
+	return trafficDelta(before)
+}
+
+func TestCompression(t *testing.T) {
+	config := apptest.Config()
+	config.WebSocket.Compression.Enabled = true
+	config.WebSocket.Compression.Threshold = 256
+	server := apptest.Start(t, config)
+
+	delta := exchangeLargeMessage(t, server, true)
+	if delta.PayloadRead == 0 || delta.PayloadWritten == 0 {
+		t.Fatalf("Счетчики полезной нагрузки не изменились: %+v", delta)
+	}
+	// Сжатые фреймы в обе стороны занимают в сети меньше полезной нагрузки.
+	if ratio := delta.WriteRatio(); ratio >= 0.5 {
+		t.Errorf("Отношение отправленных байт к полезной нагрузке %.2f, ожидалось сжатие: %+v", ratio, delta)
+	}
+	if ratio := delta.ReadRatio(); ratio >= 0.5 {
+		t.Errorf("Отношение полученных байт к полезной нагрузке %.2f, ожидалось сжатие: %+v", ratio, delta)
+	}
+}
+
+func TestCompressionNotOffered(t *testing.T) {
+	config := apptest.Config()
+	config.WebSocket.Compression.Enabled = true
+	server := apptest.Start(t, config)
+
+	// Клиент без сжатия получает несжатые фреймы: в сети не меньше полезной нагрузки.
+	delta := exchangeLargeMessage(t, server, false)
+	if delta.WriteRatio() < 1 || delta.ReadRatio() < 1 {
+		t.Fatalf("Трафик без сжатия меньше полезной нагрузки: %+v", delta)
+	}
+}
+
+func TestCompressionDisabled(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	_, response, err := server.TryDial(t, apptest.DialOptions{Token: apptest.AliceToken, Compression: true})
+	if err != nil {
+		t.Fatalf("Ошибка подключения: %v", err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols || response.Header.Get("Sec-WebSocket-Extensions") != "" {
+		t.Fatalf("Сервер с выключенным сжатием согласовал расширения %q",
+			response.Header.Get("Sec-WebSocket-Extensions"))
+	}
+}
@@ -0,0 +1,4 @@
+// Package integration содержит интеграционные тесты сервера мессенджера: каждый тест
+// запускает полную сборку приложения через харнесс apptest и проверяет поведение
+// по сети, как его видит клиент.
+package integration
@@ -0,0 +1,404 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/apptest"
+	"messenger/internal/config/models"
+)
+
+// fallbackConfig возвращает тестовую конфигурацию с включенными резервными транспортами
+// (/fallback) и буфером исходящих сообщений сессии размером bufferSize.
+func fallbackConfig(bufferSize int) *models.Config {
+	config := apptest.Config()
+	config.Fallback = models.Fallback{
+		Enabled:           true,
+		Prefix:            "/fallback",
+		SessionTimeout:    2 * time.Second,
+		PollTimeout:       200 * time.Millisecond,
+		HeartbeatInterval: 50 * time.Millisecond,
+		BufferSize:        bufferSize,
+	}
+	return config
+}
+
+// fallbackRequest выполняет запрос к резервным транспортам от имени владельца token
+// и возвращает статус и тело ответа.
+func fallbackRequest(t *testing.T, server *apptest.Server, token, method, path string, body any) (int, []byte) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	request, _ := http.NewRequest(method, server.URL+server.Config.Fallback.Prefix+path, reader)
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := server.HTTPClient().Do(request)
+	if err != nil {
+		t.Fatalf("Ошибка запроса %s %s: %v", method, path, err)
+	}
+	defer response.Body.Close()
+	data, _ := io.ReadAll(response.Body)
+	return response.StatusCode, data
+}
+
+// createSession создает сессию резервного транспорта и возвращает ее идентификатор.
+func createSession(t *testing.T, server *apptest.Server, token string) string {
+	t.Helper()
+
+	status, data := fallbackRequest(t, server, token, http.MethodPost, "/sessions", nil)
+	var created struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := json.Unmarshal(data, &created); status != http.StatusCreated || err != nil {
+		t.Fatalf("Создание сессии вернуло %d %s", status, data)
+	}
+	return created.SessionID
+}
+
+// poll забирает сообщения сессии одним long-polling запросом.
+func poll(t *testing.T, server *apptest.Server, token, sessionID string) (int, []msg.Message) {
+	t.Helper()
+
+	status, data := fallbackRequest(t, server, token, http.MethodGet, "/sessions/"+sessionID+"/poll", nil)
+	var messages []msg.Message
+	if status == http.StatusOK {
+		if err := json.Unmarshal(data, &messages); err != nil {
+			t.Fatalf("Некорректный ответ long-polling %s: %v", data, err)
+		}
+	}
+	return status, messages
+}
+
+func TestFallbackSessionOverflowDoesNotBlockDelivery(t *testing.T) {
+	server := apptest.Start(t, fallbackConfig(2))
+
+	// Bob создает сессию и перестает забирать сообщения.
+	sessionID := createSession(t, server, apptest.BobToken)
+
+	// Доставка Bob не задерживает отправителя: переполненная сессия закрывается,
+	// а лишние сообщения отбрасываются.
+	for i := 0; i < 5; i++ {
+		started := time.Now()
+		if status := restRequest(t, server, apptest.AliceToken, http.MethodPost, "/users/"+apptest.Bob+"/messages",
+			map[string]string{"text": "привет"}); status != http.StatusAccepted {
+			t.Fatalf("Отправка личного сообщения вернула %d, ожидался 202", status)
+		}
+		if elapsed := time.Since(started); elapsed > time.Second {
+			t.Fatalf("Отправка задержана переполненной сессией на %s", elapsed)
+		}
+	}
+	server.WaitForLog(t, "Сессия закрыта: клиент не забирает сообщения", "user_id="+apptest.Bob)
+	waitPresence(t, server, apptest.Bob, false)
+	if status, _ := poll(t, server, apptest.BobToken, sessionID); status != http.StatusGone && status != http.StatusNotFound {
+		t.Fatalf("Запрос к закрытой сессии вернул %d, ожидался 410 или 404", status)
+	}
+}
+
+// pollUntil повторяет long-polling запросы, пока сервер не вернет сообщения,
+// и возвращает их. Пустые ответы (204) повторяются до истечения DefaultTimeout.
+func pollUntil(t *testing.T, server *apptest.Server, token, sessionID string) []msg.Message {
+	t.Helper()
+
+	deadline := time.Now().Add(apptest.DefaultTimeout)
+	for time.Now().Before(deadline) {
+		status, messages := poll(t, server, token, sessionID)
+		switch status {
+		case http.StatusOK:
+			return messages
+		case http.StatusNoContent:
+		default:
+			t.Fatalf("Long-polling запрос вернул %d", status)
+		}
+	}
+	t.Fatal("Сообщения не получены через long-polling")
+	return nil
+}
+
+// postMessage отправляет сообщение в сессию и возвращает статус и тело ответа.
+func postMessage(t *testing.T, server *apptest.Server, token, sessionID string, body any) (int, []byte) {
+	t.Helper()
+
+	return fallbackRequest(t, server, token, http.MethodPost, "/sessions/"+sessionID+"/messages", body)
+}
+
+// sseStream — открытый SSE-поток сессии.
+type sseStream struct {
+	events     chan sseEvent
+	heartbeats chan struct{}
+}
+
+// sseEvent — событие SSE-потока.
+type sseEvent struct {
+	name string
+	data string
+}
+
+// openEvents открывает SSE-поток сессии и разбирает его в фоне. Поток закрывается
+// по завершении теста.
+func openEvents(t *testing.T, server *apptest.Server, token, sessionID string) *sseStream {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet,
+		server.URL+server.Config.Fallback.Prefix+"/sessions/"+sessionID+"/events", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := server.HTTPClient().Do(request)
+	if err != nil {
+		t.Fatalf("Ошибка открытия SSE-потока: %v", err)
+	}
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		response.Body.Close()
+		t.Fatalf("SSE-поток открыт со статусом %d и типом %q", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+
+	stream := &sseStream{events: make(chan sseEvent, 16), heartbeats: make(chan struct{}, 1)}
+	go func() {
+		defer response.Body.Close()
+		defer close(stream.events)
+
+		var event sseEvent
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if event.name != "" {
+					stream.events <- event
+				}
+				event = sseEvent{}
+			case strings.HasPrefix(line, ":"):
+				select {
+				case stream.heartbeats <- struct{}{}:
+				default:
+				}
+			case strings.HasPrefix(line, "event: "):
+				event.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return stream
+}
+
+// Expect ожидает следующее событие потока и возвращает сообщение из него.
+func (s *sseStream) Expect(t *testing.T) msg.Message {
+	t.Helper()
+
+	select {
+	case event, ok := <-s.events:
+		if !ok {
+			t.Fatal("SSE-поток закрыт сервером")
+		}
+		if event.name != "message" {
+			t.Fatalf("Получено событие %q, ожидалось message", event.name)
+		}
+		var message msg.Message
+		if err := json.Unmarshal([]byte(event.data), &message); err != nil {
+			t.Fatalf("Некорректные данные события %q: %v", event.data, err)
+		}
+		return message
+	case <-time.After(apptest.DefaultTimeout):
+		t.Fatal("Событие SSE не получено")
+		return msg.Message{}
+	}
+}
+
+func TestFallbackLongPolling(t *testing.T) {
+	server := apptest.Start(t, fallbackConfig(16))
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	sessionID := createSession(t, server, apptest.BobToken)
+
+	// Без сообщений запрос ждет PollTimeout и возвращает 204.
+	if status, _ := poll(t, server, apptest.BobToken, sessionID); status != http.StatusNoContent {
+		t.Fatalf("Пустой long-polling запрос вернул %d, ожидался 204", status)
+	}
+
+	// Ответы на сообщения клиента приходят через long-polling.
+	join := map[string]string{"type": "data", "text": "я здесь", "conversation": "room-1"}
+	if status, data := postMessage(t, server, apptest.BobToken, sessionID, join); status != http.StatusAccepted {
+		t.Fatalf("Отправка сообщения вернула %d %s", status, data)
+	}
+	messages := pollUntil(t, server, apptest.BobToken, sessionID)
+	if len(messages) != 1 || messages[0].Type != msg.DataResponse || messages[0].ID == "" {
+		t.Fatalf("Получены сообщения %+v, ожидался ответ на сохраненное сообщение", messages)
+	}
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+
+	// Сообщения других пользователей доставляются в сессию так же, как в WebSocket.
+	message := msg.NewDataMessage("привет, Bob")
+	message.Conversation = "room-1"
+	response := alice.Request(message)
+	messages = pollUntil(t, server, apptest.BobToken, sessionID)
+	if len(messages) != 1 || messages[0].ID != response.ID || messages[0].From != apptest.Alice ||
+		messages[0].Text != message.Text {
+		t.Fatalf("Получены сообщения %+v, ожидалось сообщение %s от %s", messages, response.ID, apptest.Alice)
+	}
+
+	// Сессия доступна только создавшему ее пользователю.
+	if status, _ := poll(t, server, apptest.AliceToken, sessionID); status != http.StatusNotFound {
+		t.Fatalf("Запрос к чужой сессии вернул %d, ожидался 404", status)
+	}
+}
+
+func TestFallbackServerSentEvents(t *testing.T) {
+	server := apptest.Start(t, fallbackConfig(16))
+	sessionID := createSession(t, server, apptest.BobToken)
+	events := openEvents(t, server, apptest.BobToken, sessionID)
+
+	select {
+	case <-events.heartbeats:
+	case <-time.After(apptest.DefaultTimeout):
+		t.Fatal("Пульс SSE-потока не получен")
+	}
+
+	if status, data := postMessage(t, server, apptest.BobToken, sessionID, msg.NewInfoMessage("привет")); status != http.StatusAccepted {
+		t.Fatalf("Отправка сообщения вернула %d %s", status, data)
+	}
+	if response := events.Expect(t); response.Type != msg.InfoResponse {
+		t.Fatalf("Получено сообщение %+v, ожидался ответ %s", response, msg.InfoResponse)
+	}
+
+	for i := 0; i < 3; i++ {
+		if status := restRequest(t, server, apptest.AliceToken, http.MethodPost, "/users/"+apptest.Bob+"/messages",
+			map[string]string{"text": "привет"}); status != http.StatusAccepted {
+			t.Fatalf("Отправка личного сообщения вернула %d, ожидался 202", status)
+		}
+		if delivered := events.Expect(t); delivered.Type != msg.DataMessage || delivered.From != apptest.Alice {
+			t.Fatalf("Получено сообщение %+v, ожидалось сообщение от %s", delivered, apptest.Alice)
+		}
+	}
+
+	// Закрытие сессии завершает поток.
+	if status, _ := fallbackRequest(t, server, apptest.BobToken, http.MethodDelete, "/sessions/"+sessionID, nil); status != http.StatusNoContent {
+		t.Fatalf("Закрытие сессии вернуло %d, ожидался 204", status)
+	}
+	select {
+	case _, ok := <-events.events:
+		if ok {
+			t.Fatal("После закрытия сессии получено событие")
+		}
+	case <-time.After(apptest.DefaultTimeout):
+		t.Fatal("SSE-поток не завершен после закрытия сессии")
+	}
+}
+
+func TestFallbackSessionTimeout(t *testing.T) {
+	config := fallbackConfig(16)
+	config.Fallback.SessionTimeout = 300 * time.Millisecond
+	config.Fallback.PollTimeout = 100 * time.Millisecond
+	server := apptest.Start(t, config)
+
+	// Открытый SSE-поток с пульсами продлевает сессию дольше SessionTimeout.
+	active := createSession(t, server, apptest.AliceToken)
+	openEvents(t, server, apptest.AliceToken, active)
+
+	// Сессия без запросов клиента удаляется по неактивности.
+	idle := createSession(t, server, apptest.BobToken)
+	waitPresence(t, server, apptest.Bob, true)
+	server.WaitForLog(t, "Сессия закрыта по неактивности", "user_id="+apptest.Bob)
+	waitPresence(t, server, apptest.Bob, false)
+	if status, _ := poll(t, server, apptest.BobToken, idle); status != http.StatusNotFound {
+		t.Fatalf("Запрос к удаленной сессии вернул %d, ожидался 404", status)
+	}
+
+	if status, _ := poll(t, server, apptest.AliceToken, active); status != http.StatusNoContent {
+		t.Fatalf("Запрос к активной сессии вернул %d, ожидался 204", status)
+	}
+}
+
+func TestFallbackMessageValidation(t *testing.T) {
+	server := apptest.Start(t, fallbackConfig(16))
+	sessionID := createSession(t, server, apptest.AliceToken)
+
+	tests := []struct {
+		name  string
+		body  any
+		field string
+		code  string
+	}{
+		{name: "неизвестное поле", body: map[string]string{"type": "info", "text": "привет", "color": "red"},
+			field: "color", code: msg.CodeUnknownField},
+		{name: "пустой текст", body: map[string]string{"type": "data", "text": ""},
+			field: "text", code: msg.CodeRequired},
+		{name: "идентификатор клиента", body: map[string]string{"type": "data", "text": "привет", "id": "client-id"},
+			field: "id", code: msg.CodeForbidden},
+		{name: "не объект", body: "привет", code: msg.CodeInvalidType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, data := postMessage(t, server, apptest.AliceToken, sessionID, tt.body)
+			var response struct {
+				Errors []msg.FieldError `json:"errors"`
+			}
+			if err := json.Unmarshal(data, &response); status != http.StatusBadRequest || err != nil {
+				t.Fatalf("Некорректное сообщение получило ответ %d %s, ожидался 400", status, data)
+			}
+			for _, fieldErr := range response.Errors {
+				if fieldErr.Field == tt.field && fieldErr.Code == tt.code {
+					return
+				}
+			}
+			t.Fatalf("Получены ошибки %+v, ожидалась ошибка %s поля %q", response.Errors, tt.code, tt.field)
+		})
+	}
+
+	// Некорректные сообщения не попадают в сессию.
+	if status, _ := poll(t, server, apptest.AliceToken, sessionID); status != http.StatusNoContent {
+		t.Fatalf("Long-polling после некорректных сообщений вернул %d, ожидался 204", status)
+	}
+}
+
+func TestFallbackMessagesAreRateLimited(t *testing.T) {
+	config := fallbackConfig(16)
+	config.RateLimit = models.RateLimit{
+		Enabled:         true,
+		Connection:      map[string]models.RateRule{"default": {Rate: 0.1, Burst: 1}},
+		MaxViolations:   3,
+		ViolationWindow: time.Minute,
+	}
+	server := apptest.Start(t, config)
+	sessionID := createSession(t, server, apptest.AliceToken)
+
+	// Первое сообщение укладывается в лимит, следующие получают ошибку лимита,
+	// а постоянное превышение закрывает сессию.
+	send := func() msg.Message {
+		t.Helper()
+		if status, data := postMessage(t, server, apptest.AliceToken, sessionID, msg.NewInfoMessage("привет")); status != http.StatusAccepted {
+			t.Fatalf("Отправка сообщения вернула %d %s", status, data)
+		}
+		messages := pollUntil(t, server, apptest.AliceToken, sessionID)
+		if len(messages) != 1 {
+			t.Fatalf("Получены сообщения %+v, ожидался один ответ", messages)
+		}
+		return messages[0]
+	}
+	if response := send(); response.Type != msg.InfoResponse {
+		t.Fatalf("Получен ответ %+v, ожидался %s", response, msg.InfoResponse)
+	}
+	for i := 0; i < 2; i++ {
+		if response := send(); response.Type != msg.ErrorResponse {
+			t.Fatalf("Получен ответ %+v, ожидался отказ по лимиту", response)
+		}
+	}
+
+	if status, data := postMessage(t, server, apptest.AliceToken, sessionID, msg.NewInfoMessage("привет")); status != http.StatusAccepted {
+		t.Fatalf("Отправка сообщения вернула %d %s", status, data)
+	}
+	server.WaitForLog(t, "Сессия закрыта: постоянное превышение лимита частоты сообщений", "user_id="+apptest.Alice)
+	if status, _ := poll(t, server, apptest.AliceToken, sessionID); status != http.StatusGone && status != http.StatusNotFound {
+		t.Fatalf("Запрос к закрытой сессии вернул %d, ожидался 410 или 404", status)
+	}
+}
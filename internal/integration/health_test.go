@@ -0,0 +1,103 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"messenger/internal/apptest"
+	"messenger/internal/health"
+)
+
+// readiness запрашивает /readyz и возвращает код ответа и отчет о готовности.
+func readiness(t *testing.T, server *apptest.Server) (int, health.Report) {
+	t.Helper()
+
+	status, body := get(t, server, "/readyz")
+	var report health.Report
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatalf("Некорректный отчет о готовности %q: %v", body, err)
+	}
+	return status, report
+}
+
+// failedCheck возвращает результат первой непройденной проверки отчета.
+func failedCheck(report health.Report) health.Result {
+	for _, result := range report.Checks {
+		if !result.OK {
+			return result
+		}
+	}
+	return health.Result{}
+}
+
+func TestHealthz(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	if status, body := get(t, server, "/healthz"); status != http.StatusOK || string(body) != "ok\n" {
+		t.Fatalf("Получен ответ %d %q, ожидался 200 ok", status, body)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	status, report := readiness(t, server)
+	if status != http.StatusOK || !report.Ready {
+		t.Fatalf("Получен ответ %d %+v, ожидался готовый сервер", status, report)
+	}
+	names := make([]string, 0, len(report.Checks))
+	for _, result := range report.Checks {
+		names = append(names, result.Name)
+	}
+	if want := []string{"draining", "certificate", "store", "bus"}; !slices.Equal(names, want) {
+		t.Fatalf("Выполнены проверки %v, ожидались %v", names, want)
+	}
+}
+
+func TestReadyzFailingCheck(t *testing.T) {
+	redis := startRedis(t, "127.0.0.1:0")
+	server := apptest.Start(t, clusterConfig(redis.Addr(), "node-1"))
+
+	if status, _ := readiness(t, server); status != http.StatusOK {
+		t.Fatalf("Готовность с доступной шиной вернула %d, ожидался 200", status)
+	}
+
+	// Недоступная шина снимает готовность узла.
+	redis.Close()
+	status, report := readiness(t, server)
+	if status != http.StatusServiceUnavailable || report.Ready {
+		t.Fatalf("Получен ответ %d %+v, ожидался неготовый сервер", status, report)
+	}
+	if failed := failedCheck(report); failed.Name != "bus" || failed.Error == "" {
+		t.Fatalf("Не пройдена проверка %+v, ожидалась bus", failed)
+	}
+}
+
+func TestReadyzDraining(t *testing.T) {
+	config := apptest.Config()
+	config.Shutdown.DrainDelay = time.Second
+	server := apptest.Start(t, config)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		server.Close()
+	}()
+	server.WaitForLog(t, "Сервер снимает готовность")
+
+	// Во время снятия готовности сервер еще обслуживает запросы, но /readyz отвечает 503.
+	status, report := readiness(t, server)
+	if status != http.StatusServiceUnavailable || report.Ready {
+		t.Fatalf("Получен ответ %d %+v, ожидался неготовый сервер", status, report)
+	}
+	if failed := failedCheck(report); failed.Name != "draining" {
+		t.Fatalf("Не пройдена проверка %+v, ожидалась draining", failed)
+	}
+	if status, _ := get(t, server, "/healthz"); status != http.StatusOK {
+		t.Fatalf("Проверка жизни во время остановки вернула %d, ожидался 200", status)
+	}
+	<-stopped
+}
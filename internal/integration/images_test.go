@@ -0,0 +1,223 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"testing"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/apptest"
+)
+
+// secret — строка, которая есть только в метаданных тестовых изображений.
+const secret = "SECRET-GPS-59.9386,30.3141"
+
+// gradient возвращает изображение width×height с плавным переходом цветов.
+func gradient(width, height int, alpha uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 255 / width), G: uint8(y * 255 / height), B: 128, A: alpha})
+		}
+	}
+	return img
+}
+
+// exifTIFF возвращает блок EXIF с ориентацией orientation и тегом Make, содержащим secret.
+func exifTIFF(orientation uint16) []byte {
+	order := binary.LittleEndian
+	tiff := []byte("II\x2a\x00")
+	tiff = order.AppendUint32(tiff, 8)
+	tiff = order.AppendUint16(tiff, 2)
+	// Make (ASCII), значение после каталога.
+	tiff = order.AppendUint16(tiff, 0x010F)
+	tiff = order.AppendUint16(tiff, 2)
+	tiff = order.AppendUint32(tiff, uint32(len(secret)+1))
+	tiff = order.AppendUint32(tiff, 8+2+2*12+4)
+	// Orientation (SHORT).
+	tiff = order.AppendUint16(tiff, 0x0112)
+	tiff = order.AppendUint16(tiff, 3)
+	tiff = order.AppendUint32(tiff, 1)
+	tiff = order.AppendUint16(tiff, orientation)
+	tiff = order.AppendUint16(tiff, 0)
+	tiff = order.AppendUint32(tiff, 0)
+	return append(append(tiff, secret...), 0)
+}
+
+// photoJPEG возвращает JPEG, как его сохраняет камера телефона: с EXIF (ориентация
+// и сведения о камере), XMP, комментарием и данными после конца изображения.
+func photoJPEG(t *testing.T, width, height int, orientation uint16) []byte {
+	t.Helper()
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, gradient(width, height, 255), nil); err != nil {
+		t.Fatalf("Ошибка кодирования JPEG: %v", err)
+	}
+	segment := func(marker byte, payload []byte) []byte {
+		return append(binary.BigEndian.AppendUint16([]byte{0xFF, marker}, uint16(len(payload)+2)), payload...)
+	}
+
+	data := []byte{0xFF, 0xD8}
+	data = append(data, segment(0xE1, append([]byte("Exif\x00\x00"), exifTIFF(orientation)...))...)
+	data = append(data, segment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta>"+secret+"</x:xmpmeta>"))...)
+	data = append(data, segment(0xFE, []byte(secret))...)
+	data = append(data, encoded.Bytes()[2:]...)
+	return append(data, secret...)
+}
+
+// textPNG возвращает полупрозрачный PNG с текстовым чанком, содержащим secret.
+func textPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, gradient(width, height, 128)); err != nil {
+		t.Fatalf("Ошибка кодирования PNG: %v", err)
+	}
+	data := encoded.Bytes()
+
+	// Чанк tEXt вставляется сразу после IHDR (сигнатура 8 байт + IHDR 25 байт).
+	text := []byte("Comment\x00" + secret)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)))
+	chunk = append(chunk, "tEXt"...)
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	return append(append(append([]byte{}, data[:33]...), chunk...), data[33:]...)
+}
+
+func download(t *testing.T, server *apptest.Server, token, url string) (*http.Response, []byte) {
+	t.Helper()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+url, nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := server.HTTPClient().Do(request)
+	if err != nil {
+		t.Fatalf("Ошибка скачивания %s: %v", url, err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	return response, body
+}
+
+func TestImageAttachmentMetadataAndThumbnails(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	// Камера сохранила кадр 800×400, который нужно повернуть на 90° по часовой стрелке.
+	photo := photoJPEG(t, 800, 400, 6)
+	state := upload(t, server, apptest.AliceToken, "IMG_0001.jpg", photo, 64<<10)
+	if state.ContentType != "image/jpeg" {
+		t.Fatalf("ContentType = %q, ожидался image/jpeg", state.ContentType)
+	}
+	if state.Size >= int64(len(photo)) || state.Offset != state.Size {
+		t.Fatalf("Размер после удаления метаданных %d (offset %d), исходный %d", state.Size, state.Offset, len(photo))
+	}
+
+	bob := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	join := msg.NewDataMessage("я здесь")
+	join.Conversation = "room-1"
+	bob.Request(join)
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	alice.Request(msg.Message{Type: msg.DataMessage, Conversation: "room-1", Attachments: []msg.Attachment{{ID: state.ID}}})
+	delivered := bob.Expect(msg.DataMessage)
+	attachment := delivered.Attachments[0]
+
+	// Размеры — с учетом ориентации: изображение показывается вертикальным.
+	if attachment.Width != 400 || attachment.Height != 800 || attachment.Size != state.Size {
+		t.Fatalf("Неожиданное описание изображения: %+v", attachment)
+	}
+	// BlurHash 3×4 для вертикального изображения: 1 + 1 + 4 + 2·11 символов.
+	if len(attachment.Placeholder) != 28 || attachment.Placeholder[0] != 'T' {
+		t.Fatalf("Неожиданная заглушка %q", attachment.Placeholder)
+	}
+	if len(attachment.Thumbnails) != 2 {
+		t.Fatalf("Получено %d миниатюр, ожидалось 2: %+v", len(attachment.Thumbnails), attachment.Thumbnails)
+	}
+
+	// Оригинал без метаданных, но с ориентацией, и без данных после конца изображения.
+	response, original := download(t, server, apptest.BobToken, attachment.URL)
+	if response.StatusCode != http.StatusOK || int64(len(original)) != state.Size {
+		t.Fatalf("Скачивание оригинала: %d, %d байт", response.StatusCode, len(original))
+	}
+	if bytes.Contains(original, []byte(secret)) {
+		t.Fatal("Метаданные не удалены из оригинала")
+	}
+	if !bytes.HasSuffix(original, []byte{0xFF, 0xD9}) {
+		t.Fatal("Данные после конца изображения не удалены")
+	}
+	if !bytes.Contains(original, []byte("Exif\x00\x00")) {
+		t.Fatal("Ориентация не сохранена в оригинале")
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(original))
+	if err != nil || decoded.Bounds().Dx() != 800 || decoded.Bounds().Dy() != 400 {
+		t.Fatalf("Оригинал поврежден: %v", err)
+	}
+
+	// Миниатюры уже повернуты и не содержат метаданных.
+	for i, expected := range []image.Point{{160, 320}, {32, 64}} {
+		thumbnail := attachment.Thumbnails[i]
+		if thumbnail.Width != expected.X || thumbnail.Height != expected.Y || thumbnail.ContentType != "image/jpeg" {
+			t.Fatalf("Неожиданная миниатюра %d: %+v", i, thumbnail)
+		}
+		response, data := download(t, server, apptest.BobToken, thumbnail.URL)
+		if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "image/jpeg" {
+			t.Fatalf("Скачивание миниатюры %d: %d %s", i, response.StatusCode, response.Header.Get("Content-Type"))
+		}
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil || img.Bounds().Dx() != expected.X || img.Bounds().Dy() != expected.Y {
+			t.Fatalf("Миниатюра %d повреждена или неверного размера: %v", i, err)
+		}
+		// Кадр повернут по часовой стрелке: левый нижний угол исходного кадра (зеленый)
+		// становится левым верхним.
+		if r, g, _, _ := img.At(2, 2).RGBA(); g>>8 < 200 || r>>8 > 60 {
+			t.Fatalf("Миниатюра %d не повернута: цвет левого верхнего угла %v", i, img.At(2, 2))
+		}
+		if bytes.Contains(data, []byte("Exif")) {
+			t.Fatalf("Миниатюра %d содержит EXIF", i)
+		}
+	}
+
+	// Миниатюры доступны тем же пользователям, что и оригинал.
+	if response, _ := download(t, server, apptest.AdminToken, attachment.Thumbnails[0].URL); response.StatusCode != http.StatusNotFound {
+		t.Fatalf("Миниатюра доступна пользователю не из беседы: %d", response.StatusCode)
+	}
+}
+
+func TestImageAttachmentTransparentPNG(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	state := upload(t, server, apptest.AliceToken, "logo.png", textPNG(t, 100, 50), 64<<10)
+	if state.ContentType != "image/png" || state.Width != 100 || state.Height != 50 || state.Placeholder == "" {
+		t.Fatalf("Неожиданное описание изображения: %+v", state)
+	}
+	// Изображение меньше миниатюры 320 пикселей: создается только миниатюра 64.
+	if len(state.Thumbnails) != 1 || state.Thumbnails[0].Width != 64 || state.Thumbnails[0].Height != 32 ||
+		state.Thumbnails[0].ContentType != "image/png" {
+		t.Fatalf("Неожиданные миниатюры: %+v", state.Thumbnails)
+	}
+
+	_, original := download(t, server, apptest.AliceToken, state.URL)
+	if bytes.Contains(original, []byte(secret)) {
+		t.Fatal("Текстовые метаданные не удалены из PNG")
+	}
+	if _, err := png.Decode(bytes.NewReader(original)); err != nil {
+		t.Fatalf("PNG поврежден: %v", err)
+	}
+}
+
+func TestImageAttachmentCorrupted(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	// Заголовок JPEG без данных изображения.
+	data := photoJPEG(t, 64, 64, 1)[:200]
+	created := decodeUpload(t, createUpload(t, server, apptest.AliceToken, "broken.jpg", len(data)), http.StatusCreated)
+	expectStatus(t, writeChunk(t, server, apptest.AliceToken, created.ID, 0, data), http.StatusUnprocessableEntity)
+	expectStatus(t, attachmentRequest(t, server, apptest.AliceToken, http.MethodGet, "/"+created.ID, nil, nil), http.StatusNotFound)
+}
@@ -0,0 +1,240 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/apptest"
+	"messenger/internal/config/models"
+)
+
+// articlePage — страница с метаданными OpenGraph, пробелами и сущностями HTML в значениях.
+const articlePage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Заголовок из title</title>
+  <meta property="og:title" content="Статья &amp; новости">
+  <meta property="og:description" content="  Описание
+     статьи  ">
+  <meta property="og:image" content="/images/cover.png">
+  <meta property="og:site_name" content="Пример">
+</head>
+<body><meta property="og:title" content="Не из head"></body>
+</html>`
+
+// pageServer — сайт со страницами для превью; считает запросы каждой страницы.
+type pageServer struct {
+	*httptest.Server
+	hits map[string]*atomic.Int32
+}
+
+func startPageServer(t *testing.T, pages map[string]http.HandlerFunc) *pageServer {
+	t.Helper()
+
+	site := &pageServer{hits: make(map[string]*atomic.Int32)}
+	mux := http.NewServeMux()
+	for path, handler := range pages {
+		counter := &atomic.Int32{}
+		site.hits[path] = counter
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			counter.Add(1)
+			handler(w, r)
+		})
+	}
+	site.Server = httptest.NewServer(mux)
+	t.Cleanup(site.Close)
+	return site
+}
+
+func htmlPage(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, body)
+	}
+}
+
+// linkPreviewConfig возвращает тестовую конфигурацию с превью ссылок. Тестовый сайт
+// слушает loopback, поэтому загрузка из внутренних сетей разрешена.
+func linkPreviewConfig() *models.Config {
+	config := apptest.Config()
+	config.LinkPreview = models.LinkPreview{
+		Enabled:              true,
+		Timeout:              time.Second,
+		AllowPrivateNetworks: true,
+	}
+	return config
+}
+
+func TestLinkPreviewUnfurling(t *testing.T) {
+	// Страница отвечает только после того, как отправитель получил ответ на сообщение:
+	// превью загружается асинхронно и не задерживает ответ.
+	release := make(chan struct{})
+	site := startPageServer(t, map[string]http.HandlerFunc{
+		"/article": func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-time.After(apptest.DefaultTimeout):
+			}
+			htmlPage(articlePage)(w, r)
+		},
+	})
+	server := apptest.Start(t, linkPreviewConfig())
+
+	bob := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	join := msg.NewDataMessage("я здесь")
+	join.Conversation = "room-1"
+	bob.Request(join)
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	message := msg.NewDataMessage("Смотри (" + site.URL + "/article#comments).")
+	message.Conversation = "room-1"
+	response := alice.Request(message)
+	if response.Type != msg.DataResponse || len(response.Previews) != 0 {
+		t.Fatalf("Неожиданный ответ: %+v", response)
+	}
+	close(release)
+
+	delivered := bob.Expect(msg.DataMessage)
+	if len(delivered.Previews) != 0 {
+		t.Fatalf("Сообщение доставлено с превью до их загрузки: %+v", delivered.Previews)
+	}
+
+	expected := msg.LinkPreview{
+		URL:         site.URL + "/article",
+		Title:       "Статья & новости",
+		Description: "Описание статьи",
+		Image:       site.URL + "/images/cover.png",
+		SiteName:    "Пример",
+	}
+	// Событие получают и участники беседы, и отправитель.
+	for name, client := range map[string]*apptest.Client{"bob": bob, "alice": alice} {
+		updated := client.Expect(msg.MessageUpdated)
+		if updated.ID != response.ID || updated.SentAt != response.SentAt || updated.From != apptest.Alice ||
+			updated.Text != message.Text || updated.Conversation != "room-1" {
+			t.Fatalf("%s: событие не соответствует сообщению: %+v", name, updated)
+		}
+		if len(updated.Previews) != 1 || updated.Previews[0] != expected {
+			t.Fatalf("%s: неожиданные превью %+v", name, updated.Previews)
+		}
+	}
+
+	// Превью сохранены в истории беседы.
+	request, _ := http.NewRequest(http.MethodGet, server.URL+server.Config.REST.Prefix+"/conversations/room-1/messages", nil)
+	request.Header.Set("Authorization", "Bearer "+apptest.BobToken)
+	historyResponse, err := server.HTTPClient().Do(request)
+	if err != nil {
+		t.Fatalf("Ошибка запроса истории: %v", err)
+	}
+	defer historyResponse.Body.Close()
+	var history []struct {
+		ID       string            `json:"id"`
+		Previews []msg.LinkPreview `json:"previews"`
+	}
+	if err := json.NewDecoder(historyResponse.Body).Decode(&history); err != nil {
+		t.Fatalf("Ошибка разбора истории: %v", err)
+	}
+	if len(history) != 2 || history[1].ID != response.ID || len(history[1].Previews) != 1 || history[1].Previews[0] != expected {
+		t.Fatalf("Превью не сохранены в истории: %+v", history)
+	}
+
+	// Повторная ссылка берется из кеша, страница не загружается снова.
+	again := msg.NewDataMessage("еще раз " + site.URL + "/article")
+	again.Conversation = "room-1"
+	alice.Request(again)
+	bob.Expect(msg.DataMessage)
+	if updated := bob.Expect(msg.MessageUpdated); len(updated.Previews) != 1 || updated.Previews[0] != expected {
+		t.Fatalf("Неожиданные превью из кеша: %+v", updated.Previews)
+	}
+	if hits := site.hits["/article"].Load(); hits != 1 {
+		t.Fatalf("Страница загружена %d раз, ожидалась одна загрузка", hits)
+	}
+}
+
+func TestLinkPreviewLimits(t *testing.T) {
+	site := startPageServer(t, map[string]http.HandlerFunc{
+		"/ok": htmlPage(`<html><head><title>Доступная страница</title></head></html>`),
+		// Метаданные дальше допустимого размера страницы.
+		"/large": htmlPage(`<html><head><!--` + strings.Repeat("x", 4096) + `--><title>Большая</title></head></html>`),
+		// Страница отвечает дольше допустимого времени.
+		"/slow": func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(apptest.DefaultTimeout):
+			}
+		},
+		"/image": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(noisePNG(t, 8, 8))
+		},
+		"/missing": http.NotFound,
+	})
+	config := linkPreviewConfig()
+	config.LinkPreview.Timeout = 200 * time.Millisecond
+	config.LinkPreview.MaxBodySize = 1024
+	config.LinkPreview.MaxURLs = 5
+	server := apptest.Start(t, config)
+
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	var links []string
+	for _, path := range []string{"/large", "/slow", "/image", "/missing", "/ok", "/ok?extra"} {
+		links = append(links, site.URL+path)
+	}
+	message := msg.NewDataMessage(strings.Join(links, " "))
+	message.Conversation = "room-1"
+	alice.Request(message)
+
+	// Превью получила только доступная страница; шестая ссылка — сверх MaxURLs.
+	updated := alice.Expect(msg.MessageUpdated)
+	if len(updated.Previews) != 1 || updated.Previews[0].URL != site.URL+"/ok" ||
+		updated.Previews[0].Title != "Доступная страница" {
+		t.Fatalf("Неожиданные превью: %+v", updated.Previews)
+	}
+	for _, path := range []string{"/large", "/slow", "/image", "/missing", "/ok"} {
+		if hits := site.hits[path].Load(); hits != 1 {
+			t.Fatalf("Страница %s загружена %d раз", path, hits)
+		}
+	}
+}
+
+func TestLinkPreviewPrivateNetworkBlocked(t *testing.T) {
+	site := startPageServer(t, map[string]http.HandlerFunc{
+		"/internal": htmlPage(`<html><head><title>Внутренний сервис</title></head></html>`),
+	})
+	config := linkPreviewConfig()
+	config.LinkPreview.AllowPrivateNetworks = false
+	server := apptest.Start(t, config)
+
+	bob := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	join := msg.NewDataMessage("я здесь")
+	join.Conversation = "room-1"
+	bob.Request(join)
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	for _, link := range []string{site.URL + "/internal", strings.Replace(site.URL, "127.0.0.1", "localhost", 1) + "/internal"} {
+		message := msg.NewDataMessage(link)
+		message.Conversation = "room-1"
+		alice.Request(message)
+		server.WaitForLog(t, "Превью ссылки не загружено", "адрес во внутренней сети", link)
+		bob.Expect(msg.DataMessage)
+	}
+
+	// Событие с превью не рассылается: следующее сообщение Боба — снова сообщение с данными.
+	next := msg.NewDataMessage("без ссылок")
+	next.Conversation = "room-1"
+	alice.Request(next)
+	if delivered := bob.Expect(msg.DataMessage); delivered.Text != next.Text {
+		t.Fatalf("Получено сообщение %q, ожидалось %q", delivered.Text, next.Text)
+	}
+	if hits := site.hits["/internal"].Load(); hits != 0 {
+		t.Fatalf("Запрос во внутреннюю сеть выполнен %d раз", hits)
+	}
+}
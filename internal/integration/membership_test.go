@@ -0,0 +1,151 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/apptest"
+	"messenger/internal/config/models"
+)
+
+// restRequest выполняет запрос к REST API от имени владельца токена token и возвращает код ответа.
+func restRequest(t *testing.T, server *apptest.Server, token, method, path string, body any) int {
+	t.Helper()
+
+	data, _ := json.Marshal(body)
+	request, _ := http.NewRequest(method, server.URL+server.Config.REST.Prefix+path, bytes.NewReader(data))
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/json")
+	response, err := server.HTTPClient().Do(request)
+	if err != nil {
+		t.Fatalf("Ошибка запроса %s %s: %v", method, path, err)
+	}
+	response.Body.Close()
+	return response.StatusCode
+}
+
+func TestConversationMembershipIsExplicit(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	// Bob создает беседу сообщением с вложением.
+	state := upload(t, server, apptest.BobToken, "photo.png", noisePNG(t, 16, 16), 1024)
+	bob := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	create := msg.Message{Type: msg.DataMessage, Conversation: "room-1", Text: "секрет",
+		Attachments: []msg.Attachment{{ID: state.ID}}}
+	if response := bob.Request(create); response.Type != msg.DataResponse {
+		t.Fatalf("Неожиданный ответ создателю беседы: %+v", response)
+	}
+
+	// Сообщение не участника не сохраняется и не дает доступа к истории и вложениям.
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	intrusion := msg.NewDataMessage("можно войти?")
+	intrusion.Conversation = "room-1"
+	response := alice.Request(intrusion)
+	if response.Type != msg.ErrorResponse || len(response.Errors) != 1 ||
+		response.Errors[0].Field != "conversation" || response.Errors[0].Code != msg.CodeForbidden {
+		t.Fatalf("Неожиданный ответ не участнику: %+v", response)
+	}
+	if status := restRequest(t, server, apptest.AliceToken, http.MethodPost, "/conversations/room-1/messages",
+		map[string]string{"text": "можно войти?"}); status != http.StatusForbidden {
+		t.Fatalf("Отправка не участником через REST вернула %d, ожидался 403", status)
+	}
+	if status := attachmentHistory(t, server, apptest.AliceToken, "room-1"); status != http.StatusForbidden {
+		t.Fatalf("История для не участника вернула %d, ожидался 403", status)
+	}
+	if response, _ := download(t, server, apptest.AliceToken, state.URL); response.StatusCode != http.StatusNotFound {
+		t.Fatalf("Скачивание вложения не участником вернуло %d, ожидался 404", response.StatusCode)
+	}
+
+	// Отклоненное сообщение с вложением не открывает участникам беседы доступ к нему.
+	alices := upload(t, server, apptest.AliceToken, "alice.png", noisePNG(t, 16, 16), 1024)
+	intrusion = msg.Message{Type: msg.DataMessage, Conversation: "room-1", Text: "с вложением",
+		Attachments: []msg.Attachment{{ID: alices.ID}}}
+	if response := alice.Request(intrusion); response.Type != msg.ErrorResponse {
+		t.Fatalf("Неожиданный ответ не участнику на сообщение с вложением: %+v", response)
+	}
+	if response, _ := download(t, server, apptest.BobToken, alices.URL); response.StatusCode != http.StatusNotFound {
+		t.Fatalf("Скачивание вложения из отклоненного сообщения вернуло %d, ожидался 404", response.StatusCode)
+	}
+
+	// Не участник не может пригласить ни себя, ни других, в том числе в несуществующую беседу.
+	for _, path := range []string{"/conversations/room-1/members", "/conversations/room-2/members"} {
+		if status := restRequest(t, server, apptest.AliceToken, http.MethodPost, path,
+			map[string][]string{"users": {apptest.Alice}}); status != http.StatusForbidden {
+			t.Fatalf("Приглашение не участником в %s вернуло %d, ожидался 403", path, status)
+		}
+	}
+	if status := restRequest(t, server, apptest.BobToken, http.MethodPost, "/conversations/room-1/members",
+		map[string][]string{"users": {}}); status != http.StatusBadRequest {
+		t.Fatalf("Приглашение без пользователей вернуло %d, ожидался 400", status)
+	}
+
+	// Приглашенная участником Alice сразу получает сообщения беседы, историю и вложения.
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+	next := msg.NewDataMessage("добро пожаловать")
+	next.Conversation = "room-1"
+	bob.Request(next)
+	if delivered := alice.Expect(msg.DataMessage); delivered.Text != next.Text {
+		t.Fatalf("Получено сообщение %q, ожидалось %q", delivered.Text, next.Text)
+	}
+	if status := attachmentHistory(t, server, apptest.AliceToken, "room-1"); status != http.StatusOK {
+		t.Fatalf("История для участника вернула %d, ожидался 200", status)
+	}
+	if response, _ := download(t, server, apptest.AliceToken, state.URL); response.StatusCode != http.StatusOK {
+		t.Fatalf("Скачивание вложения участником вернуло %d, ожидался 200", response.StatusCode)
+	}
+	if response := alice.Request(intrusion); response.Type != msg.DataResponse {
+		t.Fatalf("Неожиданный ответ участнику: %+v", response)
+	}
+
+	var me struct {
+		User string `json:"user"`
+	}
+	request, _ := http.NewRequest(http.MethodGet, server.URL+server.Config.REST.Prefix+"/me", nil)
+	request.Header.Set("Authorization", "Bearer "+apptest.AliceToken)
+	meResponse, err := server.HTTPClient().Do(request)
+	if err != nil {
+		t.Fatalf("Ошибка запроса личности: %v", err)
+	}
+	defer meResponse.Body.Close()
+	if err := json.NewDecoder(meResponse.Body).Decode(&me); err != nil || me.User != apptest.Alice {
+		t.Fatalf("Неожиданная личность %+v: %v", me, err)
+	}
+}
+
+func TestServiceUserPostsWithoutMembership(t *testing.T) {
+	config := apptest.Config()
+	config.Auth.Users = append(config.Auth.Users,
+		models.AuthUser{ID: "notifier", Token: "notifier-token", Service: true})
+	server := apptest.Start(t, config)
+
+	bob := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	create := msg.NewDataMessage("привет")
+	create.Conversation = "room-1"
+	if response := bob.Request(create); response.Type != msg.DataResponse {
+		t.Fatalf("Неожиданный ответ создателю беседы: %+v", response)
+	}
+
+	// Сервис пишет в беседу без приглашения, и участники получают его сообщение.
+	if status := restRequest(t, server, "notifier-token", http.MethodPost, "/conversations/room-1/messages",
+		map[string]string{"text": "плановые работы в 03:00"}); status != http.StatusCreated {
+		t.Fatalf("Отправка сервисом вернула %d, ожидался 201", status)
+	}
+	if delivered := bob.Expect(msg.DataMessage); delivered.From != "notifier" || delivered.Text != "плановые работы в 03:00" {
+		t.Fatalf("Неожиданное сообщение сервиса: %+v", delivered)
+	}
+
+	// Сервис не становится участником: история беседы ему недоступна.
+	if status := restRequest(t, server, "notifier-token", http.MethodGet, "/conversations/room-1/messages",
+		nil); status != http.StatusForbidden {
+		t.Fatalf("История для сервиса вернула %d, ожидался 403", status)
+	}
+
+	// Для обычных пользователей проверка участия сохраняется.
+	if status := restRequest(t, server, apptest.AliceToken, http.MethodPost, "/conversations/room-1/messages",
+		map[string]string{"text": "можно войти?"}); status != http.StatusForbidden {
+		t.Fatalf("Отправка не участником вернула %d, ожидался 403", status)
+	}
+}
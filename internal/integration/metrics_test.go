@@ -0,0 +1,44 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/apptest"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	server := apptest.Start(t, nil)
+	client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	client.Request(msg.NewInfoMessage("для метрик"))
+	client.Close()
+	waitPresence(t, server, apptest.Alice, false)
+
+	response, err := server.HTTPClient().Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Ошибка запроса метрик: %v", err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Запрос метрик вернул %d, ожидался 200", response.StatusCode)
+	}
+
+	// Метрики мессенджера, счетчики трафика закрытых соединений и стандартные метрики Go
+	// отдаются в формате экспозиции Prometheus.
+	for _, expected := range []string{
+		`messenger_messages_received_total{transport="websocket",type="info"}`,
+		`messenger_message_processing_seconds_bucket{type="info",le="0.0001"}`,
+		`messenger_ws_upgrades_accepted_total`,
+		`# TYPE messenger_ws_payload_bytes_total counter`,
+		`messenger_ws_wire_bytes_total{direction="read"}`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("В ответе /metrics нет %s", expected)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package integration
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"messenger/internal/apptest"
+)
+
+func TestOriginCheck(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	tests := []struct {
+		name   string
+		origin string
+		status int
+	}{
+		{name: "разрешенный Origin", origin: apptest.Origin, status: http.StatusSwitchingProtocols},
+		{name: "другой Origin", origin: "https://evil.example.com", status: http.StatusForbidden},
+		{name: "другая схема", origin: "http://app.example.com", status: http.StatusForbidden},
+		{name: "поддомен", origin: "https://sub.app.example.com", status: http.StatusForbidden},
+		{name: "некорректный Origin", origin: "not a url", status: http.StatusForbidden},
+		{name: "без Origin", origin: apptest.NoOrigin, status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, response, err := server.TryDial(t, apptest.DialOptions{Token: apptest.AliceToken, Origin: tt.origin})
+			if response == nil {
+				t.Fatalf("Нет ответа на рукопожатие: %v", err)
+			}
+			if response.StatusCode != tt.status {
+				t.Fatalf("Статус рукопожатия %d, ожидался %d (ошибка: %v)", response.StatusCode, tt.status, err)
+			}
+		})
+	}
+}
+
+func TestOriginRules(t *testing.T) {
+	config := apptest.Config()
+	config.WebSocket.AllowedOrigins = []string{"*.example.com"}
+	config.WebSocket.InvalidOrigins = []string{"https://blocked.example.com"}
+	config.WebSocket.MissingOrigin = "allow"
+	server := apptest.Start(t, config)
+
+	tests := []struct {
+		name   string
+		origin string
+		status int
+	}{
+		{name: "поддомен по маске", origin: "https://chat.example.com", status: http.StatusSwitchingProtocols},
+		{name: "запрещающее правило важнее разрешающего", origin: "https://blocked.example.com", status: http.StatusForbidden},
+		{name: "домен вне маски", origin: "https://example.org", status: http.StatusForbidden},
+		{name: "без Origin при missing_origin=allow", origin: apptest.NoOrigin, status: http.StatusSwitchingProtocols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, response, err := server.TryDial(t, apptest.DialOptions{Token: apptest.AliceToken, Origin: tt.origin})
+			if response == nil {
+				t.Fatalf("Нет ответа на рукопожатие: %v", err)
+			}
+			if response.StatusCode != tt.status {
+				t.Fatalf("Статус рукопожатия %d, ожидался %d (ошибка: %v)", response.StatusCode, tt.status, err)
+			}
+		})
+	}
+}
+
+func TestOriginCheckedBeforeAuthentication(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	_, response, _ := server.TryDial(t, apptest.DialOptions{Origin: "https://evil.example.com"})
+	if response == nil || response.StatusCode != http.StatusForbidden {
+		t.Fatalf("Запрос с чужим Origin без токена должен получить 403, получен %v", response)
+	}
+
+	_, response, _ = server.TryDial(t, apptest.DialOptions{})
+	if response == nil || response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Запрос с разрешенным Origin без токена должен получить 401, получен %v", response)
+	}
+}
+
+func TestAnyOriginMustBeExplicit(t *testing.T) {
+	config := apptest.Config()
+	config.WebSocket.AllowedOrigins = nil
+	if err := config.Validate(); err == nil {
+		t.Fatal("Конфигурация без разрешающих правил Origin прошла проверку")
+	}
+
+	config.WebSocket.AllowedOrigins = []string{"*"}
+	config.WebSocket.InvalidOrigins = []string{"https://blocked.example.com"}
+	server := apptest.Start(t, config)
+
+	tests := []struct {
+		name   string
+		origin string
+		status int
+	}{
+		{name: "любой Origin", origin: "https://example.org", status: http.StatusSwitchingProtocols},
+		{name: "запрещающее правило важнее *", origin: "https://blocked.example.com", status: http.StatusForbidden},
+		{name: "некорректный Origin", origin: "null", status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, response, err := server.TryDial(t, apptest.DialOptions{Token: apptest.AliceToken, Origin: tt.origin})
+			if response == nil {
+				t.Fatalf("Нет ответа на рукопожатие: %v", err)
+			}
+			if response.StatusCode != tt.status {
+				t.Fatalf("Статус рукопожатия %d, ожидался %d (ошибка: %v)", response.StatusCode, tt.status, err)
+			}
+		})
+	}
+}
+
+func TestInvalidOriginRulesRejectedByConfig(t *testing.T) {
+	rules := []string{
+		"ftp://app.example.com",
+		"https://app.example.com/chat",
+		"*.*.example.com",
+		"app.*.com",
+		"re:(",
+	}
+	for _, rule := range rules {
+		config := apptest.Config()
+		config.WebSocket.AllowedOrigins = []string{rule}
+		if err := config.Validate(); err == nil {
+			t.Errorf("Правило allowed_origins %q прошло проверку", rule)
+		}
+
+		config = apptest.Config()
+		config.WebSocket.InvalidOrigins = []string{rule}
+		if err := config.Validate(); err == nil {
+			t.Errorf("Правило invalid_origins %q прошло проверку", rule)
+		}
+	}
+}
+
+func TestOriginCheckedOnce(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	_, response, _ := server.TryDial(t, apptest.DialOptions{Token: apptest.AliceToken, Origin: "https://evil.example.com"})
+	if response == nil || response.StatusCode != http.StatusForbidden {
+		t.Fatalf("Запрос с чужим Origin должен получить 403, получен %v", response)
+	}
+	server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	server.WaitForLog(t, "Соединение установлено")
+
+	// Каждый запрос проверяется один раз: отказ записан в журнал одной записью,
+	// а разрешенный Origin не проверяется повторно при апгрейде.
+	logs := server.Logs()
+	if count := strings.Count(logs, "Origin отклонен"); count != 1 {
+		t.Fatalf("Отказ по Origin записан в журнал %d раз, ожидалась одна запись", count)
+	}
+	if count := strings.Count(logs, "Origin разрешен"); count != 1 {
+		t.Fatalf("Разрешенный Origin проверен %d раз, ожидалась одна проверка", count)
+	}
+}
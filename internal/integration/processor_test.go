@@ -0,0 +1,176 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/apptest"
+	"messenger/internal/config/models"
+
+	"github.com/gorilla/websocket"
+)
+
+var subprotocols = []string{codecs.JSONSubprotocol, codecs.MsgpackSubprotocol, codecs.ProtoSubprotocol}
+
+func TestProcessorResponses(t *testing.T) {
+	for _, subprotocol := range subprotocols {
+		t.Run(subprotocol, func(t *testing.T) {
+			server := apptest.Start(t, nil)
+			client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken, Subprotocol: subprotocol})
+			if got := client.Conn().Subprotocol(); got != subprotocol {
+				t.Fatalf("Согласован подпротокол %q, ожидался %q", got, subprotocol)
+			}
+
+			tests := []struct {
+				name     string
+				message  msg.Message
+				response msg.MessageType
+			}{
+				{name: "ошибка", message: msg.NewErrorMessage("что-то пошло не так"), response: msg.ErrorResponse},
+				{name: "информация", message: msg.NewInfoMessage("привет"), response: msg.InfoResponse},
+				{name: "данные без беседы", message: msg.NewDataMessage("данные"), response: msg.DataResponse},
+			}
+			for _, tt := range tests {
+				response := client.Request(tt.message)
+				if response.Type != tt.response {
+					t.Fatalf("%s: получен ответ типа %s, ожидался %s", tt.name, response.Type, tt.response)
+				}
+				if response.Text == "" {
+					t.Fatalf("%s: ответ без текста", tt.name)
+				}
+				if response.ID != "" || len(response.Errors) > 0 {
+					t.Fatalf("%s: неожиданный ответ %+v", tt.name, response)
+				}
+			}
+		})
+	}
+}
+
+func TestDataMessageIsStoredAndDelivered(t *testing.T) {
+	server := apptest.Start(t, nil)
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	bob := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken, Subprotocol: codecs.MsgpackSubprotocol})
+
+	// Bob создает беседу своим первым сообщением и приглашает в нее Alice.
+	join := msg.NewDataMessage("я здесь")
+	join.Conversation = "room-1"
+	bob.Request(join)
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+
+	message := msg.NewDataMessage("привет, Bob")
+	message.Conversation = "room-1"
+	response := alice.Request(message)
+	if response.Type != msg.DataResponse || response.ID == "" || response.SentAt == 0 || response.Conversation != "room-1" {
+		t.Fatalf("Некорректный ответ на сохраненное сообщение: %+v", response)
+	}
+
+	delivered := bob.Expect(msg.DataMessage)
+	if delivered.ID != response.ID || delivered.From != apptest.Alice || delivered.Text != message.Text ||
+		delivered.Conversation != "room-1" || delivered.SentAt != response.SentAt {
+		t.Fatalf("Доставлено сообщение %+v, ожидалось сообщение %s от %s", delivered, response.ID, apptest.Alice)
+	}
+
+	// Отправитель не получает собственное сообщение: следующим приходит ответ на новый запрос.
+	if response := alice.Request(msg.NewInfoMessage("дальше")); response.Type != msg.InfoResponse {
+		t.Fatalf("Получено сообщение типа %s, ожидался ответ %s", response.Type, msg.InfoResponse)
+	}
+}
+
+func TestInvalidMessageGetsValidationErrors(t *testing.T) {
+	server := apptest.Start(t, nil)
+	client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+
+	message := msg.NewDataMessage("")
+	message.ID = "client-id"
+	response := client.Request(message)
+	if response.Type != msg.ErrorResponse {
+		t.Fatalf("Получен ответ типа %s, ожидался %s", response.Type, msg.ErrorResponse)
+	}
+	codes := make(map[string]string)
+	for _, fieldErr := range response.Errors {
+		codes[fieldErr.Field] = fieldErr.Code
+	}
+	if codes["text"] != msg.CodeRequired || codes["id"] != msg.CodeForbidden {
+		t.Fatalf("Некорректные ошибки проверки: %+v", response.Errors)
+	}
+
+	// После некорректного сообщения соединение продолжает работать.
+	if response := client.Request(msg.NewInfoMessage("привет")); response.Type != msg.InfoResponse {
+		t.Fatalf("Получен ответ типа %s, ожидался %s", response.Type, msg.InfoResponse)
+	}
+}
+
+func TestMalformedFrameGetsValidationError(t *testing.T) {
+	server := apptest.Start(t, nil)
+	client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken, Subprotocol: codecs.JSONSubprotocol})
+
+	client.SendRaw(websocket.TextMessage, []byte(`{"type":"info","text":`))
+	response := client.Expect(msg.ErrorResponse)
+	if len(response.Errors) == 0 || response.Errors[0].Code != msg.CodeMalformed {
+		t.Fatalf("Некорректные ошибки проверки: %+v", response.Errors)
+	}
+}
+
+func TestMalformedFramesAreRateLimited(t *testing.T) {
+	config := apptest.Config()
+	config.RateLimit = models.RateLimit{
+		Enabled:         true,
+		Connection:      map[string]models.RateRule{"invalid": {Rate: 0.1, Burst: 1}},
+		MaxViolations:   3,
+		ViolationWindow: time.Minute,
+	}
+	server := apptest.Start(t, config)
+	client := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken, Subprotocol: codecs.JSONSubprotocol})
+
+	// Первый некорректный фрейм укладывается в лимит и получает ошибки проверки,
+	// следующие отклоняются лимитом, а постоянное превышение закрывает соединение.
+	malformed := []byte(`{"type":"info","text":`)
+	client.SendRaw(websocket.TextMessage, malformed)
+	if response := client.Expect(msg.ErrorResponse); len(response.Errors) == 0 {
+		t.Fatalf("Не получены ошибки проверки: %+v", response)
+	}
+	for i := 0; i < 2; i++ {
+		client.SendRaw(websocket.TextMessage, malformed)
+		if response := client.Expect(msg.ErrorResponse); len(response.Errors) != 0 {
+			t.Fatalf("Получены ошибки проверки вместо отказа по лимиту: %+v", response)
+		}
+	}
+	client.SendRaw(websocket.TextMessage, malformed)
+	if closeErr := client.ExpectClose(); closeErr.Code != websocket.ClosePolicyViolation {
+		t.Fatalf("Получен close-фрейм с кодом %d, ожидался %d", closeErr.Code, websocket.ClosePolicyViolation)
+	}
+}
+
+func TestRESTMessagesAreValidated(t *testing.T) {
+	config := apptest.Config()
+	config.Messages.MaxTextLength = 5
+	server := apptest.Start(t, config)
+
+	attachments := make([]string, 11)
+	for i := range attachments {
+		attachments[i] = "attachment"
+	}
+	requests := []struct {
+		name string
+		path string
+		body any
+	}{
+		{"текст длиннее лимита", "/conversations/room-1/messages", map[string]string{"text": "слишком длинный"}},
+		{"без текста", "/conversations/room-1/messages", map[string]string{}},
+		{"слишком много вложений", "/conversations/room-1/messages", map[string]any{"attachments": attachments}},
+		{"личное сообщение длиннее лимита", "/users/" + apptest.Bob + "/messages", map[string]string{"text": "слишком длинный"}},
+	}
+	for _, request := range requests {
+		if status := restRequest(t, server, apptest.AliceToken, http.MethodPost, request.path, request.body); status != http.StatusBadRequest {
+			t.Errorf("%s: получен код %d, ожидался 400", request.name, status)
+		}
+	}
+
+	if status := restRequest(t, server, apptest.AliceToken, http.MethodPost, "/conversations/room-1/messages",
+		map[string]string{"text": "да"}); status != http.StatusCreated {
+		t.Fatalf("Отправка корректного сообщения вернула %d, ожидался 201", status)
+	}
+}
@@ -0,0 +1,35 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"messenger/internal/apptest"
+)
+
+// get выполняет GET-запрос к пути path сервера и возвращает код и тело ответа.
+func get(t *testing.T, server *apptest.Server, path string) (int, []byte) {
+	t.Helper()
+
+	response, err := server.HTTPClient().Get(server.URL + path)
+	if err != nil {
+		t.Fatalf("Ошибка запроса %s: %v", path, err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("Ошибка чтения ответа %s: %v", path, err)
+	}
+	return response.StatusCode, body
+}
+
+func TestUnknownPathNotFound(t *testing.T) {
+	server := apptest.Start(t, nil)
+
+	for _, path := range []string{"/", "/anything", "/ws/extra", "/healthz/extra"} {
+		if status, _ := get(t, server, path); status != http.StatusNotFound {
+			t.Errorf("Запрос %s вернул %d, ожидался 404", path, status)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/apptest"
+	"messenger/internal/config/models"
+
+	"go.opentelemetry.io/otel"
+)
+
+// exportedSpan — спан из файла экспорта трассировки; поля, которые проверяют тесты.
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		SpanID string
+	}
+}
+
+// tracingConfig включает экспорт спанов в файл с долей записываемых трасс ratio
+// и возвращает путь к файлу. TracerProvider приложения глобальный, поэтому прежний
+// восстанавливается по завершении теста.
+func tracingConfig(t *testing.T, config *models.Config, ratio float64) string {
+	t.Helper()
+
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	path := filepath.Join(t.TempDir(), "spans.json")
+	config.Tracing = models.Tracing{Exporter: "file", File: path, ServiceName: "messenger", SampleRatio: &ratio}
+	return path
+}
+
+// readSpans останавливает сервер, чтобы выгрузить накопленные спаны, и возвращает
+// спаны трассы traceID из файла экспорта path.
+func readSpans(t *testing.T, server *apptest.Server, path, traceID string) []exportedSpan {
+	t.Helper()
+
+	server.Close()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Ошибка чтения файла трассировки: %v", err)
+	}
+	var spans []exportedSpan
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var span exportedSpan
+		if err := decoder.Decode(&span); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("Некорректный файл трассировки: %v", err)
+		}
+		if span.SpanContext.TraceID == traceID {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// expectSpans проверяет, что в spans есть спаны с именами names.
+func expectSpans(t *testing.T, spans []exportedSpan, names ...string) {
+	t.Helper()
+
+	recorded := make(map[string]bool, len(spans))
+	for _, span := range spans {
+		recorded[span.Name] = true
+	}
+	for _, name := range names {
+		if !recorded[name] {
+			t.Errorf("В трассе нет спана %s, записаны %v", name, recorded)
+		}
+	}
+}
+
+// traceID возвращает идентификатор трассы из значения traceparent.
+func traceID(t *testing.T, traceParent string) string {
+	t.Helper()
+
+	parts := strings.Split(traceParent, "-")
+	if len(parts) != 4 {
+		t.Fatalf("Некорректный traceparent %q", traceParent)
+	}
+	return parts[1]
+}
+
+// clientTraceParent — трасса, начатая клиентом, с флагом sampled.
+const clientTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTracingWebSocket(t *testing.T) {
+	config := apptest.Config()
+	path := tracingConfig(t, config, 1)
+	server := apptest.Start(t, config)
+
+	bob := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	create := msg.NewDataMessage("привет")
+	create.Conversation = "room-1"
+	bob.Request(create)
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+
+	upgradeTraceParent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	alice := server.Dial(t, apptest.DialOptions{
+		Token:  apptest.AliceToken,
+		Header: http.Header{"Traceparent": {upgradeTraceParent}},
+	})
+	message := msg.NewDataMessage("с трассой")
+	message.Conversation = "room-1"
+	message.TraceParent = clientTraceParent
+	alice.Request(message)
+
+	// Доставленное сообщение продолжает трассу клиента.
+	delivered := bob.Expect(msg.DataMessage)
+	if got := traceID(t, delivered.TraceParent); got != traceID(t, clientTraceParent) {
+		t.Fatalf("Сообщение доставлено в трассе %s, ожидалась трасса клиента", got)
+	}
+
+	spans := readSpans(t, server, path, traceID(t, clientTraceParent))
+	expectSpans(t, spans, "message.receive", "message.process", "message.persist", "message.fanout", "message.send")
+	for _, span := range spans {
+		if span.Name == "message.receive" && span.Parent.SpanID != "00f067aa0ba902b7" {
+			t.Fatalf("Родитель спана message.receive %s, ожидался спан клиента", span.Parent.SpanID)
+		}
+	}
+
+	// Апгрейд продолжает трассу из заголовка traceparent запроса.
+	upgrade := readSpans(t, server, path, traceID(t, upgradeTraceParent))
+	if len(upgrade) != 1 || upgrade[0].Name != "websocket.upgrade" || upgrade[0].Parent.SpanID != "b7ad6b7169203331" {
+		t.Fatalf("Спаны трассы апгрейда %+v, ожидался websocket.upgrade с родителем из заголовка", upgrade)
+	}
+}
+
+func TestTracingREST(t *testing.T) {
+	config := apptest.Config()
+	path := tracingConfig(t, config, 1)
+	server := apptest.Start(t, config)
+
+	bob := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	create := msg.NewDataMessage("привет")
+	create.Conversation = "room-1"
+	bob.Request(create)
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+
+	request, _ := http.NewRequest(http.MethodPost, server.URL+server.Config.REST.Prefix+"/conversations/room-1/messages",
+		strings.NewReader(`{"text":"через REST"}`))
+	request.Header.Set("Authorization", "Bearer "+apptest.AliceToken)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Traceparent", clientTraceParent)
+	response, err := server.HTTPClient().Do(request)
+	if err != nil {
+		t.Fatalf("Ошибка запроса: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("Отправка через REST вернула %d, ожидался 201", response.StatusCode)
+	}
+
+	delivered := bob.Expect(msg.DataMessage)
+	if got := traceID(t, delivered.TraceParent); got != traceID(t, clientTraceParent) {
+		t.Fatalf("Сообщение доставлено в трассе %s, ожидалась трасса клиента", got)
+	}
+	expectSpans(t, readSpans(t, server, path, traceID(t, clientTraceParent)),
+		"message.process", "message.persist", "message.fanout", "message.send")
+}
+
+func TestTracingAcrossNodes(t *testing.T) {
+	redis := startRedis(t, "127.0.0.1:0")
+	node1 := apptest.Start(t, clusterConfig(redis.Addr(), "node-1"))
+	// Узлы работают в одном процессе и делят глобальный TracerProvider, поэтому экспорт
+	// включен только на втором узле: в его файл попадают спаны обоих узлов.
+	config := clusterConfig(redis.Addr(), "node-2")
+	path := tracingConfig(t, config, 1)
+	node2 := apptest.Start(t, config)
+
+	bob := node2.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	waitPresence(t, node1, apptest.Bob, true)
+
+	request, _ := http.NewRequest(http.MethodPost, node1.URL+node1.Config.REST.Prefix+"/users/"+apptest.Bob+"/messages",
+		strings.NewReader(`{"text":"с другого узла"}`))
+	request.Header.Set("Authorization", "Bearer "+apptest.AliceToken)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Traceparent", clientTraceParent)
+	response, err := node1.HTTPClient().Do(request)
+	if err != nil {
+		t.Fatalf("Ошибка запроса: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusAccepted {
+		t.Fatalf("Отправка личного сообщения вернула %d, ожидался 202", response.StatusCode)
+	}
+
+	// Контекст трассировки передается через шину вместе с сообщением.
+	delivered := bob.Expect(msg.DataMessage)
+	if got := traceID(t, delivered.TraceParent); got != traceID(t, clientTraceParent) {
+		t.Fatalf("Сообщение доставлено в трассе %s, ожидалась трасса клиента", got)
+	}
+	expectSpans(t, readSpans(t, node2, path, traceID(t, clientTraceParent)), "message.send")
+}
+
+func TestTracingClientCannotForceSampling(t *testing.T) {
+	config := apptest.Config()
+	path := tracingConfig(t, config, 0)
+	server := apptest.Start(t, config)
+
+	bob := server.Dial(t, apptest.DialOptions{Token: apptest.BobToken})
+	create := msg.NewDataMessage("привет")
+	create.Conversation = "room-1"
+	bob.Request(create)
+	server.Invite(t, apptest.BobToken, "room-1", apptest.Alice)
+
+	alice := server.Dial(t, apptest.DialOptions{Token: apptest.AliceToken})
+	message := msg.NewDataMessage("запиши меня")
+	message.Conversation = "room-1"
+	message.TraceParent = clientTraceParent
+	alice.Request(message)
+
+	// Трасса клиента продолжается, но флаг sampled снят решением сервера.
+	delivered := bob.Expect(msg.DataMessage)
+	if traceID(t, delivered.TraceParent) != traceID(t, clientTraceParent) || !strings.HasSuffix(delivered.TraceParent, "-00") {
+		t.Fatalf("Доставлено сообщение с traceparent %q, ожидалась трасса клиента без записи", delivered.TraceParent)
+	}
+	if spans := readSpans(t, server, path, traceID(t, clientTraceParent)); len(spans) != 0 {
+		t.Fatalf("Записаны спаны трассы клиента %+v при sample_ratio 0", spans)
+	}
+}
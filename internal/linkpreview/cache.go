@@ -0,0 +1,109 @@
+package linkpreview
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+)
+
+// failureTTL — максимальное время хранения неудачной загрузки в кеше: страница могла
+// быть временно недоступна.
+const failureTTL = time.Minute
+
+// cacheEntry — результат загрузки превью ссылки. Пока загрузка идет, ready не закрыт,
+// и остальные запросы той же ссылки ждут ее, а не загружают страницу повторно.
+type cacheEntry struct {
+	url     string
+	preview msg.LinkPreview
+	err     error
+	expires time.Time
+	ready   chan struct{}
+}
+
+// cache хранит превью ссылок, в том числе неудачные загрузки, не дольше ttl и вытесняет
+// давно не запрошенные превью при превышении maxEntries.
+type cache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order — записи от недавно запрошенных к давно не запрошенным.
+	order *list.List
+}
+
+func newCache(ttl time.Duration, maxEntries int) *cache {
+	return &cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// get возвращает превью ссылки url из кеша или загружает его через load. Одновременные
+// запросы одной ссылки выполняют одну загрузку. Загрузки, прерванные отменой ctx,
+// не кешируются.
+func (c *cache) get(
+	ctx context.Context,
+	url string,
+	load func(ctx context.Context, url string) (msg.LinkPreview, error),
+) (msg.LinkPreview, error) {
+	c.mu.Lock()
+	if element, ok := c.entries[url]; ok {
+		entry := element.Value.(*cacheEntry)
+		select {
+		case <-entry.ready:
+			if time.Now().Before(entry.expires) {
+				c.order.MoveToFront(element)
+				c.mu.Unlock()
+				return entry.preview, entry.err
+			}
+			c.remove(element)
+		default:
+			c.mu.Unlock()
+			select {
+			case <-entry.ready:
+				return entry.preview, entry.err
+			case <-ctx.Done():
+				return msg.LinkPreview{}, ctx.Err()
+			}
+		}
+	}
+
+	entry := &cacheEntry{url: url, ready: make(chan struct{})}
+	element := c.order.PushFront(entry)
+	c.entries[url] = element
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	c.mu.Unlock()
+
+	preview, err := load(ctx, url)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.preview, entry.err = preview, err
+	ttl := c.ttl
+	if err != nil {
+		ttl = min(ttl, failureTTL)
+	}
+	entry.expires = time.Now().Add(ttl)
+	close(entry.ready)
+	if err != nil && (errors.Is(err, context.Canceled) || ctx.Err() != nil) {
+		if current, ok := c.entries[url]; ok && current == element {
+			c.remove(element)
+		}
+	}
+	return preview, err
+}
+
+// remove удаляет запись из кеша. Вызывается под mu.
+func (c *cache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).url)
+}
@@ -0,0 +1,127 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/metrics"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// userAgent — заголовок User-Agent запросов страниц; по нему сайты отличают загрузку
+	// превью от браузера.
+	userAgent = "MessengerLinkPreview/1.0"
+	// maxRedirects — максимальное число перенаправлений при загрузке страницы.
+	maxRedirects = 5
+	// dialTimeout — максимальное время установки TCP-соединения.
+	dialTimeout = 3 * time.Second
+)
+
+var (
+	// errNotHTML — страница не является HTML-документом.
+	errNotHTML = errors.New("содержимое не является HTML")
+	// errNoMetadata — на странице нет ни заголовка, ни описания, ни изображения.
+	errNoMetadata = errors.New("на странице нет метаданных")
+)
+
+// newClient создает HTTP-клиент для загрузки страниц. Клиент не использует прокси
+// из окружения и, если allowPrivate не задан, не подключается к адресам внутренних сетей.
+// Перенаправления разрешены только на http и https и не больше maxRedirects.
+func newClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if !allowPrivate {
+		dialer.Control = guardControl
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			TLSHandshakeTimeout:   dialTimeout,
+			ResponseHeaderTimeout: 2 * dialTimeout,
+			MaxIdleConns:          16,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("больше %d перенаправлений", maxRedirects)
+			}
+			if r.URL.Scheme != "http" && r.URL.Scheme != "https" {
+				return fmt.Errorf("перенаправление на неподдерживаемую схему %q", r.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// fetch загружает страницу rawURL и разбирает ее метаданные. Загрузка ограничена Timeout,
+// а из тела ответа читаются не больше MaxBodySize байт.
+func (u *Unfurler) fetch(ctx context.Context, rawURL string) (msg.LinkPreview, error) {
+	preview, err := u.load(ctx, rawURL)
+	switch {
+	case err == nil:
+		metrics.LinkPreviewFetches.WithLabelValues(metrics.LinkPreviewOK).Inc()
+	case errors.Is(err, ErrForbiddenAddress):
+		metrics.LinkPreviewFetches.WithLabelValues(metrics.LinkPreviewBlocked).Inc()
+	default:
+		metrics.LinkPreviewFetches.WithLabelValues(metrics.LinkPreviewFailed).Inc()
+	}
+	return preview, err
+}
+
+func (u *Unfurler) load(ctx context.Context, rawURL string) (msg.LinkPreview, error) {
+	if u.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, u.timeout)
+		defer cancel()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return msg.LinkPreview{}, err
+	}
+	request.Header.Set("User-Agent", userAgent)
+	request.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9")
+
+	response, err := u.client.Do(request)
+	if err != nil {
+		return msg.LinkPreview{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return msg.LinkPreview{}, fmt.Errorf("страница вернула статус %d", response.StatusCode)
+	}
+	contentType := response.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return msg.LinkPreview{}, fmt.Errorf("%w: %q", errNotHTML, contentType)
+	}
+
+	var body io.Reader = response.Body
+	if u.maxBodySize > 0 {
+		body = io.LimitReader(body, u.maxBodySize)
+	}
+	body, err = charset.NewReader(body, contentType)
+	if err != nil {
+		return msg.LinkPreview{}, fmt.Errorf("ошибка определения кодировки страницы: %w", err)
+	}
+
+	// Изображение и относительные ссылки разрешаются от адреса после перенаправлений.
+	preview := parsePage(body, response.Request.URL)
+	if preview.Title == "" && preview.Description == "" && preview.Image == "" {
+		return msg.LinkPreview{}, errNoMetadata
+	}
+	preview.URL = rawURL
+	return preview, nil
+}
@@ -0,0 +1,63 @@
+package linkpreview
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrForbiddenAddress — страница находится во внутренней сети, загрузка запрещена.
+var ErrForbiddenAddress = errors.New("адрес во внутренней сети")
+
+// blockedPrefixes — специальные диапазоны адресов, не покрытые проверками netip.Addr:
+// текущая сеть, CGNAT, служебные и тестовые сети, резерв IPv4, NAT64 и устаревшие
+// site-local адреса IPv6.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("fec0::/10"),
+}
+
+// publicAddress сообщает, является ли адрес публичным адресом интернета. Адреса IPv4,
+// отображенные в IPv6 (::ffff:10.0.0.1), проверяются как IPv4.
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// guardControl проверяет адрес перед установкой соединения. Проверяется адрес, к которому
+// действительно подключается клиент после разрешения имени, поэтому запрет нельзя обойти
+// ни именем, указывающим во внутреннюю сеть, ни его повторным разрешением в другой адрес.
+func guardControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package linkpreview
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Максимальная длина полей превью в символах; более длинные значения обрезаются.
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+	maxSiteNameLength    = 100
+	maxImageURLLength    = 2048
+)
+
+// pageMeta — метаданные страницы из <head>.
+type pageMeta struct {
+	title      string
+	properties map[string]string
+}
+
+// parsePage разбирает метаданные HTML-страницы и возвращает превью без URL.
+// Заголовок и описание берутся из OpenGraph, затем из Twitter Cards, затем из <title>
+// и <meta name="description">. Изображение разрешается относительно base; ссылки
+// на изображения не по http и https пропускаются. Разбор заканчивается на <body>,
+// поэтому тело страницы не читается.
+func parsePage(r io.Reader, base *url.URL) msg.LinkPreview {
+	meta := readHead(r)
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := meta.properties[key]; value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	preview := msg.LinkPreview{
+		Title:       clean(first("og:title", "twitter:title"), maxTitleLength),
+		Description: clean(first("og:description", "twitter:description", "description"), maxDescriptionLength),
+		SiteName:    clean(first("og:site_name"), maxSiteNameLength),
+	}
+	if preview.Title == "" {
+		preview.Title = clean(meta.title, maxTitleLength)
+	}
+
+	image := first("og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src")
+	if reference, err := url.Parse(strings.TrimSpace(image)); image != "" && err == nil {
+		resolved := base.ResolveReference(reference)
+		if (resolved.Scheme == "http" || resolved.Scheme == "https") && resolved.Host != "" &&
+			len(resolved.String()) <= maxImageURLLength {
+			preview.Image = resolved.String()
+		}
+	}
+	return preview
+}
+
+// readHead читает заголовок страницы и значения тегов <meta> до <body>. Из повторяющихся
+// тегов берется первый. Ключи — значения атрибутов property или name в нижнем регистре.
+func readHead(r io.Reader) pageMeta {
+	meta := pageMeta{properties: make(map[string]string)}
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return meta
+		case html.TextToken:
+			if inTitle && meta.title == "" {
+				meta.title = string(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				return meta
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch atom.Lookup(name) {
+			case atom.Body:
+				return meta
+			case atom.Title:
+				inTitle = true
+			case atom.Meta:
+				var key, content string
+				for more := hasAttr; more; {
+					var attrName, value []byte
+					attrName, value, more = tokenizer.TagAttr()
+					switch string(attrName) {
+					case "property", "name":
+						if key == "" {
+							key = strings.ToLower(strings.TrimSpace(string(value)))
+						}
+					case "content":
+						content = string(value)
+					}
+				}
+				if _, seen := meta.properties[key]; key != "" && !seen {
+					meta.properties[key] = content
+				}
+			}
+		}
+	}
+}
+
+// clean заменяет последовательности пробельных символов одним пробелом и обрезает
+// строку до limit символов, добавляя многоточие.
+func clean(value string, limit int) string {
+	value = strings.Join(strings.Fields(strings.ToValidUTF8(value, "\uFFFD")), " ")
+	if utf8.RuneCountInString(value) <= limit {
+		return value
+	}
+	runes := []rune(value)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
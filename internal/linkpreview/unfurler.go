@@ -0,0 +1,210 @@
+// Package linkpreview загружает превью ссылок из сообщений с данными: заголовок,
+// описание и изображение страницы из тегов <title>, <meta name="description">,
+// OpenGraph и Twitter Cards.
+//
+// Страницы загружаются в фоне после доставки сообщения, поэтому превью не задерживают
+// ответ отправителю. Загруженные превью сохраняются в сообщение и рассылаются участникам
+// беседы событием message_updated. Загрузка ограничена по времени и размеру ответа,
+// а адреса внутренних сетей запрещены, чтобы через превью нельзя было обратиться
+// к внутренним сервисам. Результаты, в том числе неудачные, кешируются.
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/logging"
+	"messenger/internal/messaging/interfaces"
+	"messenger/internal/messaging/store"
+	"messenger/internal/metrics"
+	"messenger/internal/tracing"
+)
+
+// queuePerWorker — число сообщений в очереди на одного обработчика.
+const queuePerWorker = 64
+
+// job — сообщение в очереди на загрузку превью и ссылки из его текста.
+type job struct {
+	message msg.Message
+	urls    []string
+}
+
+// Unfurler загружает превью ссылок из сохраненных сообщений пулом обработчиков.
+// Реализует interfaces.LinkUnfurler.
+type Unfurler struct {
+	store       interfaces.ConversationStore
+	router      interfaces.MessageRouter
+	client      *http.Client
+	timeout     time.Duration
+	maxBodySize int64
+	maxURLs     int
+	cache       *cache
+	logger      *slog.Logger
+
+	queue chan job
+	// ctx отменяется при закрытии и прерывает загрузку страниц.
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+type Options struct {
+	// Store — хранилище бесед, в котором сообщения дополняются превью.
+	Store interfaces.ConversationStore
+	// Router рассылает участникам беседы событие message_updated.
+	Router interfaces.MessageRouter
+	// Timeout — максимальное время загрузки одной страницы, включая перенаправления.
+	Timeout time.Duration
+	// MaxBodySize — максимальное число байт страницы, которое читается в поисках метаданных.
+	MaxBodySize int64
+	// MaxURLs — максимальное число ссылок одного сообщения, для которых загружаются превью.
+	MaxURLs int
+	// CacheTTL — время хранения загруженного превью в кеше.
+	CacheTTL time.Duration
+	// CacheSize — максимальное число превью в кеше.
+	CacheSize int
+	// Workers — число одновременно обрабатываемых сообщений.
+	Workers int
+	// AllowPrivateNetworks разрешает загрузку страниц из внутренних сетей: loopback,
+	// частных и link-local адресов. Только для тестов и закрытых внутренних установок.
+	AllowPrivateNetworks bool
+	// Logger — логгер службы. Если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+// New создает службу превью ссылок и запускает Workers обработчиков очереди.
+// Остановить их можно через Close.
+func New(options Options) *Unfurler {
+	workers := max(options.Workers, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	u := &Unfurler{
+		store:       options.Store,
+		router:      options.Router,
+		client:      newClient(options.AllowPrivateNetworks),
+		timeout:     options.Timeout,
+		maxBodySize: options.MaxBodySize,
+		maxURLs:     options.MaxURLs,
+		cache:       newCache(options.CacheTTL, options.CacheSize),
+		queue:       make(chan job, workers*queuePerWorker),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	u.logger = logging.Component(options.Logger, u.Tag())
+
+	u.wg.Add(workers)
+	for range workers {
+		go u.worker()
+	}
+	return u
+}
+
+// Tag возвращает строковый идентификатор для Unfurler.
+func (*Unfurler) Tag() string {
+	return "LINK_PREVIEW"
+}
+
+// Unfurl ставит сохраненное сообщение в очередь на загрузку превью ссылок из его текста.
+// Сообщения без ссылок пропускаются. Если очередь заполнена, сообщение остается без превью.
+func (u *Unfurler) Unfurl(message msg.Message) {
+	urls := extractURLs(message.Text, u.maxURLs)
+	if len(urls) == 0 {
+		return
+	}
+
+	select {
+	case <-u.done:
+		return
+	default:
+	}
+
+	select {
+	case u.queue <- job{message: message, urls: urls}:
+	default:
+		metrics.LinkPreviewsDropped.Inc()
+		u.logger.Warn("Очередь загрузки превью заполнена, сообщение пропущено",
+			slog.String("message_id", message.ID), slog.String("conversation", message.Conversation))
+	}
+}
+
+// Close прерывает загрузку страниц и останавливает обработчиков. Сообщения, оставшиеся
+// в очереди, остаются без превью.
+func (u *Unfurler) Close() {
+	u.closeOnce.Do(func() {
+		close(u.done)
+		u.cancel()
+		u.wg.Wait()
+		u.client.CloseIdleConnections()
+	})
+}
+
+func (u *Unfurler) worker() {
+	defer u.wg.Done()
+	for {
+		select {
+		case <-u.done:
+			return
+		case next := <-u.queue:
+			u.unfurl(next)
+		}
+	}
+}
+
+// unfurl загружает превью ссылок сообщения, сохраняет их в сообщение и рассылает
+// событие message_updated участникам беседы и всем соединениям отправителя.
+// Загрузка записывается в спан message.unfurl, продолжающий трассу сообщения.
+func (u *Unfurler) unfurl(next job) {
+	message := next.message
+	_, span := tracing.StartMessageSpan(&message, "message.unfurl")
+	defer span.End()
+
+	previews := make([]msg.LinkPreview, len(next.urls))
+	var wg sync.WaitGroup
+	for i, url := range next.urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			preview, err := u.cache.get(u.ctx, url, u.fetch)
+			if err != nil {
+				u.logger.Debug("Превью ссылки не загружено", slog.String("url", url), slog.Any("error", err))
+				return
+			}
+			previews[i] = preview
+		}()
+	}
+	wg.Wait()
+
+	var loaded []msg.LinkPreview
+	for _, preview := range previews {
+		if preview.URL != "" {
+			loaded = append(loaded, preview)
+		}
+	}
+	if len(loaded) == 0 || u.ctx.Err() != nil {
+		return
+	}
+	message.Previews = loaded
+
+	updated, err := u.store.Update(message)
+	if errors.Is(err, store.ErrMessageNotFound) {
+		u.logger.Debug("Сообщение удалено из истории до загрузки превью", slog.String("message_id", message.ID))
+		return
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		u.logger.Error("Ошибка сохранения превью ссылок", slog.String("message_id", message.ID), slog.Any("error", err))
+		return
+	}
+
+	event := updated
+	event.Type = msg.MessageUpdated
+	u.router.DeliverRoom(event)
+	u.router.Deliver(event, []string{event.From})
+}
@@ -0,0 +1,64 @@
+package linkpreview
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxURLLength — максимальная длина ссылки, для которой загружается превью.
+const maxURLLength = 2048
+
+// urlPattern находит в тексте ссылки http и https.
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'` + "`" + `]+`)
+
+// extractURLs возвращает до limit различных ссылок http и https из текста в порядке
+// появления. Знаки препинания в конце ссылки и закрывающие скобки без пары считаются
+// частью текста, а не ссылки. Ссылки с учетными данными (user:password@host) пропускаются.
+func extractURLs(text string, limit int) []string {
+	if limit <= 0 {
+		return nil
+	}
+
+	var urls []string
+	for _, candidate := range urlPattern.FindAllString(text, -1) {
+		candidate = trimURL(candidate)
+		if len(candidate) > maxURLLength {
+			continue
+		}
+		parsed, err := url.Parse(candidate)
+		if err != nil || parsed.Host == "" || parsed.User != nil {
+			continue
+		}
+		parsed.Fragment = ""
+		normalized := parsed.String()
+		if !slices.Contains(urls, normalized) {
+			urls = append(urls, normalized)
+		}
+		if len(urls) == limit {
+			break
+		}
+	}
+	return urls
+}
+
+// trimURL отрезает от ссылки знаки препинания в конце предложения и закрывающие
+// скобки и кавычки, для которых нет открывающих внутри ссылки.
+func trimURL(candidate string) string {
+	for candidate != "" {
+		last := candidate[len(candidate)-1]
+		switch {
+		case strings.IndexByte(".,:;!?", last) >= 0:
+		case last == ')' && strings.Count(candidate, "(") < strings.Count(candidate, ")"):
+		case last == ']' && strings.Count(candidate, "[") < strings.Count(candidate, "]"):
+		case strings.HasSuffix(candidate, "»"):
+			candidate = strings.TrimSuffix(candidate, "»")
+			continue
+		default:
+			return candidate
+		}
+		candidate = candidate[:len(candidate)-1]
+	}
+	return candidate
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+)
+
+// Имена полей записей журнала. Поля соединения добавляются к логгеру соединения
+// один раз, поэтому попадают во все записи обработчика, отправителя, получателя
+// и обработчика сообщений этого соединения.
+const (
+	// KeyComponent — компонент, создавший запись (значение Tag() компонента).
+	KeyComponent = "component"
+	// KeyConnectionID — идентификатор соединения или сессии резервного транспорта.
+	KeyConnectionID = "conn_id"
+	// KeyRemoteAddr — адрес клиента.
+	KeyRemoteAddr = "remote_addr"
+	// KeyUserID — идентификатор аутентифицированного пользователя.
+	KeyUserID = "user_id"
+	// KeyMessageType — тип обрабатываемого сообщения.
+	KeyMessageType = "message_type"
+)
+
+type Options struct {
+	// Level — минимальный уровень записей, попадающих в журнал.
+	Level slog.Level
+	// JSON включает вывод записей в формате JSON вместо текстового формата key=value.
+	JSON bool
+	// Output — поток вывода журнала. Если не задан, используется os.Stderr.
+	Output io.Writer
+}
+
+// New создает логгер с указанными уровнем и форматом вывода.
+func New(options Options) *slog.Logger {
+	output := options.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: options.Level}
+	if options.JSON {
+		return slog.New(slog.NewJSONHandler(output, handlerOptions))
+	}
+	return slog.New(slog.NewTextHandler(output, handlerOptions))
+}
+
+// OrDefault возвращает logger или, если он не задан, логгер по умолчанию slog.Default().
+// Используется конструкторами компонентов, которым логгер передается через Options.
+func OrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// Component возвращает логгер компонента с полем component.
+func Component(logger *slog.Logger, tag string) *slog.Logger {
+	return OrDefault(logger).With(KeyComponent, tag)
+}
+
+// MessageType возвращает поле записи с типом сообщения.
+func MessageType(messageType msg.MessageType) slog.Attr {
+	return slog.String(KeyMessageType, messageType.String())
+}
+
+// NewConnectionID генерирует случайный идентификатор соединения для журнала.
+func NewConnectionID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,86 @@
+package bus
+
+import (
+	"context"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"sync"
+)
+
+// MemoryBus — шина сообщений в пределах одного процесса. Используется по умолчанию,
+// когда сервер работает в одном экземпляре. Обработчики вызываются синхронно
+// в горутине, опубликовавшей сообщение.
+type MemoryBus struct {
+	mu       sync.RWMutex
+	handlers map[string]map[*subscription]struct{}
+}
+
+type subscription struct {
+	handler func(msg.Message)
+}
+
+// NewMemory создает пустую шину в памяти процесса.
+func NewMemory() *MemoryBus {
+	return &MemoryBus{
+		handlers: make(map[string]map[*subscription]struct{}),
+	}
+}
+
+// Publish вызывает обработчики всех подписчиков топика.
+func (mb *MemoryBus) Publish(ctx context.Context, topic string, message msg.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, handler := range mb.lookup(topic) {
+		handler(message)
+	}
+	return nil
+}
+
+// Subscribe подписывает handler на сообщения топика.
+func (mb *MemoryBus) Subscribe(topic string, handler func(msg.Message)) (func(), error) {
+	entry := &subscription{handler: handler}
+
+	mb.mu.Lock()
+	if mb.handlers[topic] == nil {
+		mb.handlers[topic] = make(map[*subscription]struct{})
+	}
+	mb.handlers[topic][entry] = struct{}{}
+	mb.mu.Unlock()
+
+	return func() {
+		mb.mu.Lock()
+		defer mb.mu.Unlock()
+
+		delete(mb.handlers[topic], entry)
+		if len(mb.handlers[topic]) == 0 {
+			delete(mb.handlers, topic)
+		}
+	}, nil
+}
+
+// Ping проверяет доступность шины. Шина в памяти доступна всегда, пока не отменен ctx.
+func (mb *MemoryBus) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Close отписывает всех подписчиков.
+func (mb *MemoryBus) Close() error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	mb.handlers = make(map[string]map[*subscription]struct{})
+	return nil
+}
+
+// lookup собирает обработчики топика под блокировкой чтения, чтобы сами обработчики
+// вызывались без удержания блокировки и могли подписываться и отписываться.
+func (mb *MemoryBus) lookup(topic string) []func(msg.Message) {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	handlers := make([]func(msg.Message), 0, len(mb.handlers[topic]))
+	for entry := range mb.handlers[topic] {
+		handlers = append(handlers, entry.handler)
+	}
+	return handlers
+}
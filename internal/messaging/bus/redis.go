@@ -0,0 +1,413 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"log/slog"
+	"messenger/internal/logging"
+	"messenger/internal/metrics"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRedisTimeout — таймаут подключения и команд Redis, если RedisOptions.Timeout не задан.
+	defaultRedisTimeout = 5 * time.Second
+	// minReconnectDelay и maxReconnectDelay — границы экспоненциальной задержки
+	// переподключения соединения подписки.
+	minReconnectDelay = 100 * time.Millisecond
+	maxReconnectDelay = 5 * time.Second
+	// defaultQueueSize — размер очереди сообщений подписчика, если RedisOptions.QueueSize не задан.
+	defaultQueueSize = 1024
+)
+
+// ErrClosed возвращается при обращении к закрытой шине.
+var ErrClosed = errors.New("шина сообщений закрыта")
+
+// RedisBus — шина сообщений между узлами кластера поверх Redis Pub/Sub.
+// Сообщения передаются в JSON. Шина держит два соединения: для публикации и для
+// подписок; при разрыве соединение подписок восстанавливается автоматически, и все
+// топики переподписываются. Сообщения, опубликованные во время разрыва, теряются,
+// как и в самом Redis Pub/Sub.
+//
+// У каждого подписчика своя очередь и горутина, вызывающая обработчик: медленный
+// обработчик (например, доставка клиенту, который не читает сообщения) не задерживает
+// чтение соединения подписок и других подписчиков. Если очередь подписчика заполнена,
+// сообщение отбрасывается и учитывается в метрике BusMessagesDropped.
+type RedisBus struct {
+	address   string
+	password  string
+	timeout   time.Duration
+	queueSize int
+	logger    *slog.Logger
+
+	pubMu sync.Mutex
+	pub   *respConn
+
+	// subMu упорядочивает изменения набора топиков и команды SUBSCRIBE/UNSUBSCRIBE.
+	subMu sync.Mutex
+	sub   *respConn
+
+	mu       sync.RWMutex
+	handlers map[string]map[*redisSubscription]struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type RedisOptions struct {
+	// Address — адрес сервера Redis в виде host:port.
+	Address string
+	// Password — пароль Redis. Пустая строка — без аутентификации.
+	Password string
+	// Timeout — таймаут подключения и выполнения команд. По умолчанию 5s.
+	Timeout time.Duration
+	// QueueSize — размер очереди сообщений каждого подписчика. По умолчанию 1024.
+	QueueSize int
+	// Logger — логгер шины. Если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+// NewRedis подключается к серверу Redis и запускает чтение сообщений подписок.
+func NewRedis(options RedisOptions) (*RedisBus, error) {
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = defaultRedisTimeout
+	}
+	queueSize := options.QueueSize
+	if queueSize == 0 {
+		queueSize = defaultQueueSize
+	}
+	rb := &RedisBus{
+		address:   options.Address,
+		password:  options.Password,
+		timeout:   timeout,
+		queueSize: queueSize,
+		logger:    logging.Component(options.Logger, "REDIS_BUS"),
+		handlers:  make(map[string]map[*redisSubscription]struct{}),
+		done:      make(chan struct{}),
+	}
+
+	sub, err := rb.dial(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	rb.sub = sub
+
+	go rb.readLoop(sub)
+
+	return rb, nil
+}
+
+// Publish публикует сообщение в канал Redis topic.
+func (rb *RedisBus) Publish(ctx context.Context, topic string, message msg.Message) error {
+	payload, err := codecs.JSONCodec{}.Encode(message)
+	if err != nil {
+		return err
+	}
+	_, err = rb.command(ctx, "PUBLISH", topic, string(payload))
+	return err
+}
+
+// Subscribe подписывает handler на сообщения канала Redis topic. Обработчик вызывается
+// в отдельной горутине подписчика. Если соединение подписок сейчас разорвано, подписка
+// будет оформлена после переподключения.
+func (rb *RedisBus) Subscribe(topic string, handler func(msg.Message)) (func(), error) {
+	rb.subMu.Lock()
+	defer rb.subMu.Unlock()
+
+	select {
+	case <-rb.done:
+		return nil, ErrClosed
+	default:
+	}
+
+	entry := newRedisSubscription(handler, rb.queueSize)
+
+	rb.mu.Lock()
+	first := rb.handlers[topic] == nil
+	if first {
+		rb.handlers[topic] = make(map[*redisSubscription]struct{})
+	}
+	rb.handlers[topic][entry] = struct{}{}
+	rb.mu.Unlock()
+
+	if first {
+		rb.sendSubscription("SUBSCRIBE", topic)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { rb.unsubscribe(topic, entry) })
+	}, nil
+}
+
+func (rb *RedisBus) unsubscribe(topic string, entry *redisSubscription) {
+	entry.stop()
+
+	rb.subMu.Lock()
+	defer rb.subMu.Unlock()
+
+	rb.mu.Lock()
+	delete(rb.handlers[topic], entry)
+	last := rb.handlers[topic] != nil && len(rb.handlers[topic]) == 0
+	if last {
+		delete(rb.handlers, topic)
+	}
+	rb.mu.Unlock()
+
+	if last {
+		rb.sendSubscription("UNSUBSCRIBE", topic)
+	}
+}
+
+// sendSubscription отправляет команду подписки. Вызывается под subMu. Ошибка записи
+// только логируется: цикл чтения обнаружит разрыв и переподпишет все топики.
+func (rb *RedisBus) sendSubscription(command, topic string) {
+	select {
+	case <-rb.done:
+		return
+	default:
+	}
+
+	rb.sub.conn.SetWriteDeadline(time.Now().Add(rb.timeout))
+	if err := rb.sub.writeCommand(command, topic); err != nil {
+		rb.logger.Warn("Ошибка отправки команды подписки", slog.String("command", command),
+			slog.String("topic", topic), slog.Any("error", err))
+	}
+}
+
+// Ping проверяет доступность сервера Redis.
+func (rb *RedisBus) Ping(ctx context.Context) error {
+	_, err := rb.command(ctx, "PING")
+	return err
+}
+
+// Close закрывает соединения с Redis и останавливает горутины подписчиков.
+// Повторные вызовы безопасны.
+func (rb *RedisBus) Close() error {
+	rb.closeOnce.Do(func() {
+		close(rb.done)
+
+		rb.mu.RLock()
+		for _, entries := range rb.handlers {
+			for entry := range entries {
+				entry.stop()
+			}
+		}
+		rb.mu.RUnlock()
+
+		rb.subMu.Lock()
+		rb.sub.Close()
+		rb.subMu.Unlock()
+
+		rb.pubMu.Lock()
+		if rb.pub != nil {
+			rb.pub.Close()
+			rb.pub = nil
+		}
+		rb.pubMu.Unlock()
+	})
+	return nil
+}
+
+// command выполняет команду в соединении публикации. Разорванное соединение
+// восстанавливается, и команда повторяется один раз.
+func (rb *RedisBus) command(ctx context.Context, args ...string) (any, error) {
+	rb.pubMu.Lock()
+	defer rb.pubMu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		select {
+		case <-rb.done:
+			return nil, ErrClosed
+		default:
+		}
+
+		if rb.pub == nil {
+			if rb.pub, err = rb.dial(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		deadline := time.Now().Add(rb.timeout)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		rb.pub.conn.SetDeadline(deadline)
+
+		var reply any
+		reply, err = rb.pub.do(args...)
+		var replyErr respError
+		if err == nil || errors.As(err, &replyErr) {
+			return reply, err
+		}
+		rb.pub.Close()
+		rb.pub = nil
+	}
+	return nil, err
+}
+
+func (rb *RedisBus) dial(ctx context.Context) (*respConn, error) {
+	return dialRESP(ctx, rb.address, rb.password, rb.timeout)
+}
+
+// readLoop читает сообщения соединения подписок и передает их обработчикам топиков.
+// При разрыве соединения переподключается и восстанавливает подписки.
+func (rb *RedisBus) readLoop(sub *respConn) {
+	for {
+		reply, err := sub.readReply()
+		if err != nil {
+			select {
+			case <-rb.done:
+				return
+			default:
+			}
+			rb.logger.Warn("Соединение подписок Redis разорвано", slog.Any("error", err))
+			if sub = rb.reconnect(); sub == nil {
+				return
+			}
+			continue
+		}
+		rb.dispatch(reply)
+	}
+}
+
+// dispatch ставит сообщение из ответа вида ["message", канал, данные] в очереди
+// подписчиков топика, не дожидаясь обработчиков. Если очередь подписчика заполнена,
+// сообщение для него отбрасывается. Подтверждения подписок и отписок пропускаются.
+func (rb *RedisBus) dispatch(reply any) {
+	items, ok := reply.([]any)
+	if !ok || len(items) != 3 {
+		return
+	}
+	kind, _ := items[0].([]byte)
+	topic, _ := items[1].([]byte)
+	payload, _ := items[2].([]byte)
+	if string(kind) != "message" {
+		return
+	}
+
+	message, err := codecs.JSONCodec{}.Decode(payload)
+	if err != nil {
+		rb.logger.Warn("Некорректное сообщение в шине", slog.String("topic", string(topic)), slog.Any("error", err))
+		return
+	}
+
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+
+	for entry := range rb.handlers[string(topic)] {
+		if !entry.enqueue(message) {
+			metrics.BusMessagesDropped.Inc()
+			rb.logger.Warn("Сообщение шины отброшено: очередь подписчика заполнена",
+				slog.String("topic", string(topic)), slog.String("message_id", message.ID), logging.MessageType(message.Type))
+		}
+	}
+}
+
+// reconnect восстанавливает соединение подписок с экспоненциальной задержкой и
+// переподписывает все топики. Возвращает nil, если шина закрыта.
+func (rb *RedisBus) reconnect() *respConn {
+	delay := minReconnectDelay
+	for {
+		select {
+		case <-rb.done:
+			return nil
+		case <-time.After(delay):
+		}
+
+		sub, err := rb.resubscribe()
+		if err == nil {
+			rb.logger.Info("Соединение подписок Redis восстановлено")
+			return sub
+		}
+		rb.logger.Warn("Ошибка переподключения к Redis", slog.Any("error", err))
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// resubscribe подключается заново и подписывается на все топики, у которых есть обработчики.
+func (rb *RedisBus) resubscribe() (*respConn, error) {
+	rb.subMu.Lock()
+	defer rb.subMu.Unlock()
+
+	select {
+	case <-rb.done:
+		return nil, ErrClosed
+	default:
+	}
+
+	sub, err := rb.dial(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	rb.mu.RLock()
+	topics := make([]string, 0, len(rb.handlers))
+	for topic := range rb.handlers {
+		topics = append(topics, topic)
+	}
+	rb.mu.RUnlock()
+
+	if len(topics) > 0 {
+		sub.conn.SetWriteDeadline(time.Now().Add(rb.timeout))
+		if err := sub.writeCommand(append([]string{"SUBSCRIBE"}, topics...)...); err != nil {
+			sub.Close()
+			return nil, err
+		}
+	}
+
+	rb.sub.Close()
+	rb.sub = sub
+	return sub, nil
+}
+
+// redisSubscription — подписчик топика с очередью сообщений ограниченного размера
+// и горутиной, передающей сообщения обработчику по порядку.
+type redisSubscription struct {
+	handler  func(msg.Message)
+	queue    chan msg.Message
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newRedisSubscription(handler func(msg.Message), queueSize int) *redisSubscription {
+	entry := &redisSubscription{
+		handler: handler,
+		queue:   make(chan msg.Message, queueSize),
+		done:    make(chan struct{}),
+	}
+	go entry.run()
+	return entry
+}
+
+// enqueue ставит сообщение в очередь подписчика. Возвращает false, если очередь заполнена.
+func (rs *redisSubscription) enqueue(message msg.Message) bool {
+	select {
+	case rs.queue <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop останавливает горутину подписчика; сообщения, оставшиеся в очереди, отбрасываются.
+// Не ждет завершения обработчика, поэтому отписаться можно и из самого обработчика.
+func (rs *redisSubscription) stop() {
+	rs.stopOnce.Do(func() { close(rs.done) })
+}
+
+func (rs *redisSubscription) run() {
+	for {
+		select {
+		case <-rs.done:
+			return
+		case message := <-rs.queue:
+			rs.handler(message)
+		}
+	}
+}
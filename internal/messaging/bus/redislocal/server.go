@@ -0,0 +1,310 @@
+// Package redislocal содержит минимальный сервер, совместимый с Redis Pub/Sub по протоколу
+// RESP2. Используется для локального запуска нескольких узлов и проверки bus.RedisBus
+// без внешнего Redis. Поддерживаются команды PING, AUTH, SELECT, SUBSCRIBE, UNSUBSCRIBE,
+// PUBLISH и QUIT; данные не сохраняются.
+package redislocal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxBulkSize — максимальный размер аргумента команды в байтах.
+const maxBulkSize = 16 << 20
+
+// Server — сервер Redis Pub/Sub в памяти процесса.
+type Server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	channels map[string]map[*client]struct{}
+	clients  map[*client]struct{}
+	closed   bool
+
+	wg sync.WaitGroup
+}
+
+type client struct {
+	conn net.Conn
+
+	// writeMu упорядочивает запись ответов клиенту и сообщений из PUBLISH других клиентов.
+	writeMu sync.Mutex
+	writer  *bufio.Writer
+
+	// channels — каналы, на которые подписан клиент. Доступ под Server.mu.
+	channels map[string]struct{}
+}
+
+// NewServer запускает сервер на адресе address (например, "127.0.0.1:0").
+func NewServer(address string) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: listener,
+		channels: make(map[string]map[*client]struct{}),
+		clients:  make(map[*client]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// Addr возвращает адрес, на котором сервер принимает соединения.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close останавливает сервер и закрывает все клиентские соединения.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &client{
+			conn:     conn,
+			writer:   bufio.NewWriter(conn),
+			channels: make(map[string]struct{}),
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.clients[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(c)
+	}
+}
+
+// handle читает и выполняет команды клиента до закрытия соединения.
+func (s *Server) handle(c *client) {
+	defer s.wg.Done()
+	defer s.disconnect(c)
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if !s.execute(c, args) {
+			return
+		}
+	}
+}
+
+// execute выполняет команду. Возвращает false, если соединение нужно закрыть.
+func (s *Server) execute(c *client, args []string) bool {
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		if len(args) > 1 {
+			return c.reply(bulk(args[1]))
+		}
+		return c.reply("+PONG\r\n")
+	case "AUTH", "SELECT":
+		return c.reply("+OK\r\n")
+	case "QUIT":
+		c.reply("+OK\r\n")
+		return false
+	case "SUBSCRIBE":
+		if len(args) < 2 {
+			return c.reply(wrongArgs(args[0]))
+		}
+		for _, channel := range args[1:] {
+			count := s.subscribe(c, channel)
+			if !c.reply(array(bulk("subscribe"), bulk(channel), integer(count))) {
+				return false
+			}
+		}
+		return true
+	case "UNSUBSCRIBE":
+		channels := args[1:]
+		if len(channels) == 0 {
+			channels = s.subscriptions(c)
+		}
+		for _, channel := range channels {
+			count := s.unsubscribe(c, channel)
+			if !c.reply(array(bulk("unsubscribe"), bulk(channel), integer(count))) {
+				return false
+			}
+		}
+		return true
+	case "PUBLISH":
+		if len(args) != 3 {
+			return c.reply(wrongArgs(args[0]))
+		}
+		return c.reply(integer(s.publish(args[1], args[2])))
+	default:
+		return c.reply(fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0]))
+	}
+}
+
+func (s *Server) subscribe(c *client, channel string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.channels[channel] == nil {
+		s.channels[channel] = make(map[*client]struct{})
+	}
+	s.channels[channel][c] = struct{}{}
+	c.channels[channel] = struct{}{}
+	return len(c.channels)
+}
+
+func (s *Server) unsubscribe(c *client, channel string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.channels[channel], c)
+	if len(s.channels[channel]) == 0 {
+		delete(s.channels, channel)
+	}
+	delete(c.channels, channel)
+	return len(c.channels)
+}
+
+func (s *Server) subscriptions(c *client) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channels := make([]string, 0, len(c.channels))
+	for channel := range c.channels {
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// publish рассылает сообщение подписчикам канала и возвращает их число.
+func (s *Server) publish(channel, payload string) int {
+	s.mu.Lock()
+	subscribers := make([]*client, 0, len(s.channels[channel]))
+	for c := range s.channels[channel] {
+		subscribers = append(subscribers, c)
+	}
+	s.mu.Unlock()
+
+	message := array(bulk("message"), bulk(channel), bulk(payload))
+	for _, c := range subscribers {
+		c.reply(message)
+	}
+	return len(subscribers)
+}
+
+func (s *Server) disconnect(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for channel := range c.channels {
+		delete(s.channels[channel], c)
+		if len(s.channels[channel]) == 0 {
+			delete(s.channels, channel)
+		}
+	}
+	delete(s.clients, c)
+	c.conn.Close()
+}
+
+// reply отправляет клиенту закодированный ответ. Возвращает false при ошибке записи.
+func (c *client) reply(data string) bool {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if _, err := c.writer.WriteString(data); err != nil {
+		return false
+	}
+	return c.writer.Flush() == nil
+}
+
+// readCommand читает команду клиента: массив bulk-строк или inline-команду.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count < 0 {
+		return nil, errors.New("некорректная длина массива")
+	}
+	args := make([]string, count)
+	for i := range args {
+		header, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(header, "$") {
+			return nil, errors.New("ожидалась bulk-строка")
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 0 || size > maxBulkSize {
+			return nil, errors.New("некорректная длина строки")
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+func integer(n int) string {
+	return ":" + strconv.Itoa(n) + "\r\n"
+}
+
+func array(items ...string) string {
+	return "*" + strconv.Itoa(len(items)) + "\r\n" + strings.Join(items, "")
+}
+
+func wrongArgs(command string) string {
+	return fmt.Sprintf("-ERR wrong number of arguments for '%s' command\r\n", strings.ToLower(command))
+}
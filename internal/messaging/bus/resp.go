@@ -0,0 +1,142 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// respError — ответ сервера Redis с ошибкой.
+type respError string
+
+func (e respError) Error() string {
+	return "redis: " + string(e)
+}
+
+// respConn — соединение с сервером Redis по протоколу RESP2.
+// Не потокобезопасно: доступ синхронизирует владелец.
+type respConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// dialRESP устанавливает соединение с сервером Redis и, если задан пароль, аутентифицируется.
+func dialRESP(ctx context.Context, address, password string, timeout time.Duration) (*respConn, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	rc := &respConn{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+	}
+
+	if password != "" {
+		conn.SetDeadline(time.Now().Add(timeout))
+		if _, err := rc.do("AUTH", password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ошибка аутентификации в Redis: %w", err)
+		}
+		conn.SetDeadline(time.Time{})
+	}
+	return rc, nil
+}
+
+// do отправляет команду и читает ответ. Ответ-ошибка возвращается как respError.
+func (rc *respConn) do(args ...string) (any, error) {
+	if err := rc.writeCommand(args...); err != nil {
+		return nil, err
+	}
+	reply, err := rc.readReply()
+	if err != nil {
+		return nil, err
+	}
+	if replyErr, ok := reply.(respError); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+// writeCommand отправляет команду массивом bulk-строк.
+func (rc *respConn) writeCommand(args ...string) error {
+	fmt.Fprintf(rc.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(rc.writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return rc.writer.Flush()
+}
+
+// readReply читает один ответ сервера: string для простых строк, respError для ошибок,
+// int64 для целых чисел, []byte (или nil) для bulk-строк и []any для массивов.
+func (rc *respConn) readReply() (any, error) {
+	line, err := rc.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: пустой ответ")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return respError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: некорректная длина строки: %w", err)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rc.reader, buf); err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: некорректная длина массива: %w", err)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = rc.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: неизвестный тип ответа %q", line[0])
+	}
+}
+
+// readLine читает строку ответа без завершающего \r\n.
+func (rc *respConn) readLine() (string, error) {
+	line, err := rc.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", errors.New("redis: некорректное завершение строки")
+	}
+	return line[:len(line)-2], nil
+}
+
+func (rc *respConn) Close() error {
+	return rc.conn.Close()
+}
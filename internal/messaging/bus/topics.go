@@ -0,0 +1,25 @@
+package bus
+
+// Префиксы топиков шины.
+const (
+	userTopicPrefix = "user:"
+	roomTopicPrefix = "room:"
+)
+
+// UserTopic возвращает топик сообщений, адресованных пользователю userID.
+func UserTopic(userID string) string {
+	return userTopicPrefix + userID
+}
+
+// RoomTopic возвращает топик сообщений беседы roomID.
+func RoomTopic(roomID string) string {
+	return roomTopicPrefix + roomID
+}
+
+// DirectoryTopic — топик событий каталога подключений кластера: пульсов узлов,
+// подключений и отключений пользователей.
+const DirectoryTopic = "cluster:directory"
+
+// MembershipTopic — топик событий участия пользователей в беседах: по ним роутеры
+// всех узлов подписываются на топики бесед своих подключенных пользователей.
+const MembershipTopic = "conversation:members"
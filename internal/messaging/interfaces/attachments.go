@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	message "github.com/1ight181/messenger/pkg/protocol/message"
+)
+
+// AttachmentResolver прикрепляет загруженные вложения к сообщениям.
+type AttachmentResolver interface {
+	// Resolve проверяет, что вложение id полностью загружено пользователем userID,
+	// и возвращает описание вложения для сообщения. Доступ к вложению не меняется.
+	Resolve(userID, id string) (message.Attachment, error)
+	// Attach открывает доступ к вложению id пользователя userID участникам беседы
+	// conversation. Вызывается после сохранения сообщения с вложением в беседу.
+	Attach(userID, conversation, id string) error
+}
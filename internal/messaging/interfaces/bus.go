@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+	message "github.com/1ight181/messenger/pkg/protocol/message"
+)
+
+type Bus interface {
+	// Publish публикует сообщение в топик; его получат подписчики топика на всех узлах.
+	Publish(ctx context.Context, topic string, message message.Message) error
+	// Subscribe подписывает handler на сообщения топика. Возвращает функцию отписки.
+	Subscribe(topic string, handler func(message.Message)) (unsubscribe func(), err error)
+	// Ping проверяет доступность шины для проверки готовности сервера.
+	Ping(ctx context.Context) error
+	// Close закрывает шину и отписывает всех подписчиков.
+	Close() error
+}
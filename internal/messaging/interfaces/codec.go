@@ -0,0 +1,9 @@
+package interfaces
+
+import (
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
+)
+
+// Codec — кодек сообщений протокола. Объявлен в публичном пакете кодеков,
+// которым пользуются и клиенты.
+type Codec = codecs.Codec
@@ -0,0 +1,13 @@
+package interfaces
+
+type Directory interface {
+	// NodeID возвращает идентификатор текущего узла кластера.
+	NodeID() string
+	// Connected отмечает подключение первого соединения пользователя к текущему узлу.
+	Connected(userID string)
+	// Disconnected отмечает закрытие последнего соединения пользователя на текущем узле.
+	Disconnected(userID string)
+	// Lookup возвращает узлы, к которым подключен пользователь. false означает, что
+	// каталог еще не знает состояния всего кластера.
+	Lookup(userID string) (nodeIDs []string, ok bool)
+}
@@ -1,7 +1,7 @@
 package interfaces
 
 import (
-	message "messenger/internal/messaging/models/message"
+	message "github.com/1ight181/messenger/pkg/protocol/message"
 )
 
 type MessageReceiver interface {
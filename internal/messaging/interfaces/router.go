@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	message "github.com/1ight181/messenger/pkg/protocol/message"
+)
+
+type MessageRouter interface {
+	Register(userID string, sender MessageSender) (unregister func())
+	Deliver(message message.Message, userIDs []string)
+	Join(userID string, roomID string)
+	DeliverRoom(message message.Message)
+}
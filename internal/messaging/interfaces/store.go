@@ -0,0 +1,23 @@
+package interfaces
+
+import (
+	"context"
+	conversation "github.com/1ight181/messenger/pkg/protocol/conversation"
+	message "github.com/1ight181/messenger/pkg/protocol/message"
+)
+
+type ConversationStore interface {
+	// Append сохраняет сообщение участника беседы; первое сообщение создает беседу,
+	// и отправитель становится ее участником.
+	Append(message message.Message) (message.Message, error)
+	// Invite добавляет пользователей в участники беседы от имени ее участника.
+	Invite(conversationID, inviterID string, userIDs []string) (conversation.Conversation, error)
+	Conversations(userID string) ([]conversation.Conversation, error)
+	History(conversationID string, before string, limit int) ([]message.Message, error)
+	Members(conversationID string) ([]string, error)
+	// Update заменяет сохраненное сообщение с тем же ID в беседе message.Conversation
+	// и возвращает его новую версию.
+	Update(message message.Message) (message.Message, error)
+	// Ping проверяет доступность хранилища для проверки готовности сервера.
+	Ping(ctx context.Context) error
+}
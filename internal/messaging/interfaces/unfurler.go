@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	message "github.com/1ight181/messenger/pkg/protocol/message"
+)
+
+// LinkUnfurler загружает превью ссылок из сохраненных сообщений.
+type LinkUnfurler interface {
+	// Unfurl ставит сохраненное сообщение в очередь на загрузку превью ссылок из его текста
+	// и сразу возвращает управление. Загруженные превью сохраняются в сообщение и рассылаются
+	// участникам беседы событием message_updated.
+	Unfurl(message message.Message)
+}
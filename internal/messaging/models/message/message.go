@@ -1,6 +0,0 @@
-package message
-
-type Message struct {
-	Type MessageType `json:"type"`
-	Text string      `json:"text"`
-}
@@ -0,0 +1,319 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"log/slog"
+	"messenger/internal/logging"
+	"messenger/internal/messaging/interfaces"
+	"messenger/internal/messaging/store"
+	"messenger/internal/metrics"
+	"messenger/internal/tracing"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// processorTag — значение поля component в записях журнала обработчика сообщений.
+const processorTag = "MESSAGE_PROCESSOR"
+
+// MessageProcessor содержит бизнес-логику обработки сообщений и не зависит от транспорта.
+// Используется как WebSocket-обработчиком (через WebSocketMessageProcessor),
+// так и резервными HTTP-транспортами (SSE и long-polling).
+type MessageProcessor struct {
+	errorResponseText   string
+	infoResponseText    string
+	dataResponseText    string
+	unknownResponseText string
+	store               interfaces.ConversationStore
+	router              interfaces.MessageRouter
+	attachments         interfaces.AttachmentResolver
+	unfurler            interfaces.LinkUnfurler
+	logger              *slog.Logger
+}
+
+// NewMessageProcessor создает новый экземпляр MessageProcessor с предоставленными параметрами.
+//
+// Параметры:
+//   - options: Структура Options, содержащая тексты ответов.
+//
+// Возвращает:
+//
+//	Указатель на вновь инициализированный MessageProcessor.
+func NewMessageProcessor(options Options) *MessageProcessor {
+	return &MessageProcessor{
+		errorResponseText:   options.ErrorResponseText,
+		infoResponseText:    options.InfoResponseText,
+		dataResponseText:    options.DataResponseText,
+		unknownResponseText: options.UnknownResponseText,
+		store:               options.Store,
+		router:              options.Router,
+		attachments:         options.Attachments,
+		unfurler:            options.Unfurler,
+		logger:              logging.Component(options.Logger, processorTag),
+	}
+}
+
+// SetLogger устанавливает логгер обработчика. Обработчик соединения передает
+// сюда логгер с полями соединения, чтобы они попадали во все записи обработки;
+// записи дополняются полем component.
+//
+// Параметры:
+//   - logger: Логгер соединения.
+func (mp *MessageProcessor) SetLogger(logger *slog.Logger) {
+	mp.logger = logging.Component(logger, processorTag)
+}
+
+// ProcessMessage обрабатывает входящее сообщение в зависимости от его типа.
+// Обрабатывает различные типы сообщений ("error", "info", "data") с использованием соответствующих
+// методов обработки и возвращает ответное сообщение.
+//
+// Параметры:
+//   - message: Входящее сообщение типа msg.Message для обработки.
+//
+// Возвращает:
+//   - msg.Message: Обработанное ответное сообщение.
+//   - error: Ошибка, если при обработке возникла проблема.
+//
+// Поведение:
+//   - Для известных типов сообщений (ErrorMessage, InfoMessage, DataMessage) обрабатывает сообщение с использованием
+//     соответствующих методов (processError, processInfo, processData).
+//   - Для неизвестных типов сообщений регистрирует проблему и возвращает ответное сообщение
+//     с типом UnknownResponse и описанием ошибки.
+//   - Длительность обработки учитывается в гистограмме metrics.ProcessingDuration.
+//   - Обработка записывается в спан message.process, продолжающий трассу сообщения;
+//     ответное сообщение получает контекст этого спана.
+func (mp *MessageProcessor) ProcessMessage(message msg.Message) (msg.Message, error) {
+	timer := prometheus.NewTimer(metrics.ProcessingDuration.WithLabelValues(message.Type.String()))
+	defer timer.ObserveDuration()
+
+	_, span := tracing.StartMessageSpan(&message, "message.process")
+	defer span.End()
+
+	responseMessage, err := mp.processMessage(message)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return msg.Message{}, err
+	}
+	responseMessage.TraceParent = message.TraceParent
+	return responseMessage, nil
+}
+
+// processMessage выбирает обработку по типу сообщения.
+func (mp *MessageProcessor) processMessage(message msg.Message) (msg.Message, error) {
+	switch message.Type {
+	case msg.ErrorMessage:
+		responseMessage := mp.processError(message, mp.errorResponseText)
+		return responseMessage, nil
+	case msg.InfoMessage:
+		responseMessage := mp.processInfo(message, mp.infoResponseText)
+		return responseMessage, nil
+	case msg.DataMessage:
+		return mp.processData(message, mp.dataResponseText)
+	default:
+		mp.logger.Warn("Получен неизвестный тип сообщения", logging.MessageType(message.Type))
+		responseMessage := msg.Message{
+			Type: msg.UnknownResponse,
+			Text: "Неизвестный тип сообщения",
+		}
+		return responseMessage, nil
+	}
+}
+
+// createResponseMessage создает новое ответное сообщение с указанным типом сообщения и текстом.
+//
+// Параметры:
+//   - messageType: Тип создаваемого сообщения.
+//   - responseText: Текстовое содержимое ответного сообщения.
+//
+// Возвращает:
+//   - Экземпляр msg.Message, содержащий указанный тип и текст.
+func (mp *MessageProcessor) createResponseMessage(
+	messageType msg.MessageType,
+	responseText string,
+) msg.Message {
+	return msg.Message{
+		Type: messageType,
+		Text: responseText,
+	}
+}
+
+// processError обрабатывает сообщение об ошибке, полученное от клиента.
+// Регистрирует сообщение об ошибке и возвращает ответное сообщение, указывающее,
+// что сообщение об ошибке было получено.
+//
+// Параметры:
+//   - errorMessage: Сообщение об ошибке, отправленное клиентом.
+//   - responseText: Предопределенный текст для ответа.
+//
+// Возвращает:
+//   - msg.Message: Ответное сообщение с типом "error_response" и предоставленным текстом ответа.
+func (mp *MessageProcessor) processError(
+	errorMessage msg.Message,
+	responseText string,
+) msg.Message {
+	mp.logger.Info("Клиент отправил сообщение об ошибке",
+		logging.MessageType(errorMessage.Type), slog.String("text", errorMessage.Text))
+	return mp.createResponseMessage(msg.ErrorResponse, responseText)
+}
+
+// processInfo обрабатывает информационное сообщение, полученное от клиента.
+// Регистрирует содержимое сообщения и возвращает ответное сообщение,
+// подтверждающее получение информационного сообщения.
+//
+// Параметры:
+//   - infoMessage: Информационное сообщение, отправленное клиентом.
+//   - responseText: Предопределенный текст для ответа.
+//
+// Возвращает:
+//   - msg.Message: Ответное сообщение, указывающее, что информационное
+//     сообщение было получено.
+func (mp *MessageProcessor) processInfo(
+	infoMessage msg.Message,
+	responseText string,
+) msg.Message {
+	mp.logger.Info("Клиент отправил информационное сообщение",
+		logging.MessageType(infoMessage.Type), slog.String("text", infoMessage.Text))
+	return mp.createResponseMessage(msg.InfoResponse, responseText)
+}
+
+// processData обрабатывает входящее сообщение с данными и генерирует ответное сообщение.
+// Регистрирует текст полученного сообщения и возвращает предопределенный ответ.
+// Если в сообщении указана беседа, сообщение сохраняется в ее историю и доставляется
+// остальным участникам беседы, а ответ дополняется идентификатором и временем сохранения.
+// Писать в беседу могут только ее участники; первое сообщение создает беседу, и отправитель
+// становится ее участником. Сообщение не участника отклоняется ответом с ошибкой проверки
+// поля conversation; сервисы пишут в беседы, не становясь их участниками.
+//
+// Вложения сообщения проверяются до сохранения, и сообщение и ответ получают их описание;
+// если вложение прикрепить нельзя, клиент получает ответ с ошибкой проверки поля, а сообщение
+// не сохраняется. Доступ к вложениям участники беседы получают только после сохранения
+// сообщения, поэтому отклоненное сообщение доступа не открывает. Превью ссылок из текста
+// загружаются после доставки асинхронно и не задерживают ответ.
+//
+// Параметры:
+//   - dataMessage: Входящее сообщение типа msg.Message, содержащее данные.
+//   - responseText: Предопределенный текст для ответа.
+//
+// Возвращает:
+//   - msg.Message: Ответное сообщение с типом "data_response" и предоставленным текстом ответа.
+//   - error: Ошибка, если сообщение не удалось сохранить.
+func (mp *MessageProcessor) processData(
+	dataMessage msg.Message,
+	responseText string,
+) (msg.Message, error) {
+	mp.logger.Debug("Клиент отправил сообщение с данными",
+		logging.MessageType(dataMessage.Type), slog.String("conversation", dataMessage.Conversation))
+	responseMessage := mp.createResponseMessage(msg.DataResponse, responseText)
+
+	if dataMessage.Conversation == "" || mp.store == nil {
+		return responseMessage, nil
+	}
+
+	if len(dataMessage.Attachments) > 0 {
+		attachments, validationErr := mp.resolve(dataMessage)
+		if validationErr != nil {
+			return validationErr.Response(), nil
+		}
+		dataMessage.Attachments = attachments
+	}
+
+	storedMessage, err := mp.persist(dataMessage)
+	if errors.Is(err, store.ErrNotMember) {
+		return msg.NewValidationError("conversation", msg.CodeForbidden, "отправитель не участник беседы").Response(), nil
+	}
+	if err != nil {
+		return msg.Message{}, fmt.Errorf("ошибка сохранения сообщения: %w", err)
+	}
+	storedMessage.TraceParent = dataMessage.TraceParent
+	mp.attach(storedMessage)
+	if mp.router != nil && mp.member(storedMessage.Conversation, storedMessage.From) {
+		mp.router.Join(storedMessage.From, storedMessage.Conversation)
+	}
+	mp.deliver(storedMessage)
+	if mp.unfurler != nil && storedMessage.Text != "" {
+		mp.unfurler.Unfurl(storedMessage)
+	}
+
+	responseMessage.ID = storedMessage.ID
+	responseMessage.Conversation = storedMessage.Conversation
+	responseMessage.SentAt = storedMessage.SentAt
+	responseMessage.Attachments = storedMessage.Attachments
+	return responseMessage, nil
+}
+
+// resolve проверяет вложения сообщения от имени отправителя и возвращает их описания
+// или ошибку проверки со всеми вложениями, которые прикрепить нельзя.
+func (mp *MessageProcessor) resolve(message msg.Message) ([]msg.Attachment, *msg.ValidationError) {
+	if mp.attachments == nil {
+		return nil, msg.NewValidationError("attachments", msg.CodeInvalidValue, "вложения не поддерживаются сервером")
+	}
+
+	attachments := make([]msg.Attachment, 0, len(message.Attachments))
+	var fields []msg.FieldError
+	for i, reference := range message.Attachments {
+		attachment, err := mp.attachments.Resolve(message.From, reference.ID)
+		if err != nil {
+			mp.logger.Debug("Вложение не прикреплено",
+				slog.String("attachment", reference.ID), slog.Any("error", err))
+			fields = append(fields, msg.FieldError{
+				Field:   fmt.Sprintf("attachments[%d].id", i),
+				Code:    msg.CodeInvalidValue,
+				Message: err.Error(),
+			})
+			continue
+		}
+		attachments = append(attachments, attachment)
+	}
+	if len(fields) > 0 {
+		return nil, &msg.ValidationError{Fields: fields}
+	}
+	return attachments, nil
+}
+
+// attach открывает участникам беседы доступ к вложениям сохраненного сообщения.
+// Ошибка логируется: вложение могли удалить после проверки, и тогда скачать его нельзя,
+// как и после удаления вложения из отправленного сообщения.
+func (mp *MessageProcessor) attach(message msg.Message) {
+	for _, attachment := range message.Attachments {
+		if err := mp.attachments.Attach(message.From, message.Conversation, attachment.ID); err != nil {
+			mp.logger.Warn("Не удалось открыть доступ к вложению",
+				slog.String("attachment", attachment.ID), slog.String("conversation", message.Conversation),
+				slog.Any("error", err))
+		}
+	}
+}
+
+// persist сохраняет сообщение в историю беседы в рамках спана message.persist.
+func (mp *MessageProcessor) persist(message msg.Message) (msg.Message, error) {
+	_, span := tracing.StartMessageSpan(&message, "message.persist")
+	defer span.End()
+
+	storedMessage, err := mp.store.Append(message)
+	tracing.RecordError(span, err)
+	return storedMessage, err
+}
+
+// member сообщает, участвует ли пользователь в беседе. Сервисы пишут в беседы,
+// не участвуя в них, и не должны получать их сообщения.
+func (mp *MessageProcessor) member(conversationID, userID string) bool {
+	members, err := mp.store.Members(conversationID)
+	return err == nil && slices.Contains(members, userID)
+}
+
+// deliver доставляет сохраненное сообщение участникам беседы, кроме отправителя, через
+// топик беседы в шине сообщений, поэтому сообщение получают участники, подключенные
+// к любому узлу. Доставка записывается в спан message.fanout; отправка сообщения каждому
+// получателю на этом узле становится дочерним спаном, а на других узлах продолжает
+// трассу через контекст в сообщении, поэтому отправитель и получатели оказываются в одной трассе.
+func (mp *MessageProcessor) deliver(message msg.Message) {
+	if mp.router == nil {
+		return
+	}
+
+	_, span := tracing.StartMessageSpan(&message, "message.fanout")
+	defer span.End()
+
+	mp.router.DeliverRoom(message)
+}
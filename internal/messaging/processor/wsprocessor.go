@@ -2,18 +2,18 @@ package processor
 
 import (
 	"errors"
-	"log"
-	msg "messenger/internal/messaging/models/message"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"log/slog"
+	"messenger/internal/messaging/interfaces"
 
 	"github.com/gorilla/websocket"
 )
 
+// WebSocketMessageProcessor привязывает обработчик сообщений к WebSocket-соединению:
+// сообщения обрабатываются только после установки соединения.
 type WebSocketMessageProcessor struct {
-	connection          *websocket.Conn
-	errorResponseText   string
-	infoResponseText    string
-	dataResponseText    string
-	unknownResponseText string
+	processor  interfaces.MessageProcessor
+	connection *websocket.Conn
 }
 
 type Options struct {
@@ -21,6 +21,16 @@ type Options struct {
 	InfoResponseText    string
 	DataResponseText    string
 	UnknownResponseText string
+	// Store — хранилище бесед. Если не задано, сообщения с данными не сохраняются.
+	Store interfaces.ConversationStore
+	// Router доставляет сообщения участникам беседы. Если не задан, сообщения не доставляются.
+	Router interfaces.MessageRouter
+	// Attachments прикрепляет вложения к сообщениям. Если не задан, сообщения с вложениями отклоняются.
+	Attachments interfaces.AttachmentResolver
+	// Unfurler загружает превью ссылок из сохраненных сообщений. Если не задан, превью не загружаются.
+	Unfurler interfaces.LinkUnfurler
+	// Logger — логгер обработчика. Если не задан, используется slog.Default().
+	Logger *slog.Logger
 }
 
 // New создает новый экземпляр WebSocketMessageProcessor с предоставленными параметрами.
@@ -33,133 +43,54 @@ type Options struct {
 //
 //	Указатель на вновь инициализированный WebSocketMessageProcessor.
 func New(options Options) *WebSocketMessageProcessor {
-	return &WebSocketMessageProcessor{
-		connection:          nil,
-		errorResponseText:   options.ErrorResponseText,
-		infoResponseText:    options.InfoResponseText,
-		dataResponseText:    options.DataResponseText,
-		unknownResponseText: options.UnknownResponseText,
-	}
+	return Bind(NewMessageProcessor(options))
 }
 
-// SetConnection устанавливает WebSocket-соединение для WebSocketMessageProcessor.
+// Bind привязывает к WebSocket-соединению произвольный обработчик сообщений,
+// например обработчик, подставленный приложением вместо MessageProcessor.
 //
 // Параметры:
-//   - conn: Указатель на websocket.Conn, представляющий устанавливаемое WebSocket-соединение.
-func (wsmp *WebSocketMessageProcessor) SetConnection(conn *websocket.Conn) {
-	wsmp.connection = conn
-}
-
-// ProcessMessage обрабатывает входящее сообщение WebSocket в зависимости от его типа.
-// Обрабатывает различные типы сообщений ("error", "info", "data") с использованием соответствующих
-// методов обработки и возвращает ответное сообщение.
-//
-// Параметры:
-//   - message: Входящее сообщение типа msg.Message для обработки.
+//   - messageProcessor: Обработчик сообщений, которому передаются сообщения соединения.
 //
 // Возвращает:
-//   - msg.Message: Обработанное ответное сообщение.
-//   - error: Ошибка, если WebSocket-соединение не установлено или возникла другая проблема.
 //
-// Поведение:
-//   - Если WebSocket-соединение не установлено, возвращает ошибку типа *websocket.CloseError.
-//   - Для известных типов сообщений (ErrorMessage, InfoMessage, DataMessage) обрабатывает сообщение с использованием
-//     соответствующих методов (processError, processInfo, processData).
-//   - Для неизвестных типов сообщений регистрирует проблему и возвращает ответное сообщение
-//     с типом UnknownResponse и описанием ошибки.
-func (wsmp *WebSocketMessageProcessor) ProcessMessage(message msg.Message) (msg.Message, error) {
-	if wsmp.connection != nil {
-		switch message.Type {
-		case msg.ErrorMessage:
-			responseMessage := wsmp.processError(message, wsmp.errorResponseText)
-			return responseMessage, nil
-		case msg.InfoMessage:
-			responseMessage := wsmp.processInfo(message, wsmp.infoResponseText)
-			return responseMessage, nil
-		case msg.DataMessage:
-			responseMessage := wsmp.processData(message, wsmp.dataResponseText)
-			return responseMessage, nil
-		default:
-			log.Printf("Получен неизвестный тип сообщения: %s\n", message)
-			responseMessage := msg.Message{
-				Type: msg.UnknownResponse,
-				Text: "Неизвестный тип сообщения",
-			}
-			return responseMessage, nil
-		}
-	} else {
-		return msg.Message{}, errors.New("соединение не установлено")
+//	Указатель на вновь инициализированный WebSocketMessageProcessor.
+func Bind(messageProcessor interfaces.MessageProcessor) *WebSocketMessageProcessor {
+	return &WebSocketMessageProcessor{
+		processor:  messageProcessor,
+		connection: nil,
 	}
 }
 
-// createResponseMessage создает новое ответное сообщение с указанным типом сообщения и текстом.
+// SetLogger передает логгер соединения обработчику, если он его принимает.
 //
 // Параметры:
-//   - messageType: Тип создаваемого сообщения.
-//   - responseText: Текстовое содержимое ответного сообщения.
-//
-// Возвращает:
-//   - Экземпляр msg.Message, содержащий указанный тип и текст.
-func (wsmp *WebSocketMessageProcessor) createResponseMessage(
-	messageType msg.MessageType,
-	responseText string,
-) msg.Message {
-	return msg.Message{
-		Type: messageType,
-		Text: responseText,
+//   - logger: Логгер соединения.
+func (wsmp *WebSocketMessageProcessor) SetLogger(logger *slog.Logger) {
+	if withLogger, ok := wsmp.processor.(interface{ SetLogger(*slog.Logger) }); ok {
+		withLogger.SetLogger(logger)
 	}
 }
 
-// processError обрабатывает сообщение об ошибке, полученное от клиента.
-// Регистрирует сообщение об ошибке и возвращает ответное сообщение, указывающее,
-// что сообщение об ошибке было получено.
-//
-// Параметры:
-//   - errorMessage: Сообщение об ошибке, отправленное клиентом.
-//   - responseText: Предопределенный текст для ответа.
-//
-// Возвращает:
-//   - msg.Message: Ответное сообщение с типом "error_response" и предоставленным текстом ответа.
-func (wsmp *WebSocketMessageProcessor) processError(
-	errorMessage msg.Message,
-	responseText string,
-) msg.Message {
-	log.Printf("Клиент отправил сообщение об ошибке: %s\n", errorMessage.Text)
-	return wsmp.createResponseMessage(msg.ErrorMessage, responseText)
-}
-
-// processInfo обрабатывает информационное сообщение, полученное от клиента.
-// Регистрирует содержимое сообщения и возвращает ответное сообщение,
-// подтверждающее получение информационного сообщения.
+// SetConnection устанавливает WebSocket-соединение для WebSocketMessageProcessor.
 //
 // Параметры:
-//   - infoMessage: Информационное сообщение, отправленное клиентом.
-//   - responseText: Предопределенный текст для ответа.
-//
-// Возвращает:
-//   - msg.Message: Ответное сообщение, указывающее, что информационное
-//     сообщение было получено.
-func (wsmp *WebSocketMessageProcessor) processInfo(
-	infoMessage msg.Message,
-	responseText string,
-) msg.Message {
-	log.Printf("Клиент отправил информационное сообщение: %s\n", infoMessage.Text)
-	return wsmp.createResponseMessage(msg.InfoResponse, responseText)
+//   - conn: Указатель на websocket.Conn, представляющий устанавливаемое WebSocket-соединение.
+func (wsmp *WebSocketMessageProcessor) SetConnection(conn *websocket.Conn) {
+	wsmp.connection = conn
 }
 
-// processData обрабатывает входящее сообщение с данными и генерирует ответное сообщение.
-// Регистрирует текст полученного сообщения и возвращает предопределенный ответ.
+// ProcessMessage обрабатывает входящее сообщение WebSocket с помощью MessageProcessor.
 //
 // Параметры:
-//   - dataMessage: Входящее сообщение типа msg.Message, содержащее данные.
-//   - responseText: Предопределенный текст для ответа.
+//   - message: Входящее сообщение типа msg.Message для обработки.
 //
 // Возвращает:
-//   - msg.Message: Ответное сообщение с типом "data_response" и предоставленным текстом ответа.
-func (wsmp *WebSocketMessageProcessor) processData(
-	dataMessage msg.Message,
-	responseText string,
-) msg.Message {
-	log.Printf("Клиент отправил сообщение с данными: %s\n", dataMessage.Text)
-	return wsmp.createResponseMessage(msg.DataResponse, responseText)
+//   - msg.Message: Обработанное ответное сообщение.
+//   - error: Ошибка, если WebSocket-соединение не установлено или возникла другая проблема.
+func (wsmp *WebSocketMessageProcessor) ProcessMessage(message msg.Message) (msg.Message, error) {
+	if wsmp.connection == nil {
+		return msg.Message{}, errors.New("соединение не установлено")
+	}
+	return wsmp.processor.ProcessMessage(message)
 }
@@ -0,0 +1,46 @@
+package receiver
+
+import (
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"io"
+	"messenger/internal/metrics"
+	"messenger/internal/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ChannelMessageReceiver получает сообщения из канала входящих сообщений сессии.
+// Канал наполняется HTTP-обработчиком, принимающим сообщения клиента запросами POST.
+type ChannelMessageReceiver struct {
+	inbox <-chan msg.Message
+	done  <-chan struct{}
+}
+
+// NewChannel создает ChannelMessageReceiver, читающий из канала inbox.
+//
+// Параметры:
+//   - inbox: Канал входящих сообщений сессии.
+//   - done: Канал, закрываемый при завершении сессии.
+func NewChannel(inbox <-chan msg.Message, done <-chan struct{}) *ChannelMessageReceiver {
+	return &ChannelMessageReceiver{
+		inbox: inbox,
+		done:  done,
+	}
+}
+
+// ReceiveMessage ожидает следующее сообщение клиента. Возвращает io.EOF,
+// если сессия закрыта. Получение записывается в спан message.receive.
+func (cmr *ChannelMessageReceiver) ReceiveMessage() (msg.Message, error) {
+	select {
+	case message := <-cmr.inbox:
+		metrics.MessagesReceived.WithLabelValues(metrics.TransportFallback, message.Type.String()).Inc()
+		_, span := tracing.StartMessageSpan(&message, "message.receive",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(tracing.AttrTransport.String(metrics.TransportFallback)),
+		)
+		span.End()
+		return message, nil
+	case <-cmr.done:
+		return msg.Message{}, io.EOF
+	}
+}
@@ -1,51 +1,136 @@
 package receiver
 
 import (
-	"log"
-	msg "messenger/internal/messaging/models/message"
+	"errors"
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"log/slog"
+	"messenger/internal/logging"
+	"messenger/internal/messaging/interfaces"
+	"messenger/internal/messaging/validation"
+	"messenger/internal/metrics"
+	"messenger/internal/tracing"
+	"messenger/internal/ws/traffic"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type WebSocketMessageReceiver struct {
-	connection *websocket.Conn
+	connection   *websocket.Conn
+	codec        interfaces.Codec
+	traffic      *traffic.Counters
+	logger       *slog.Logger
+	maxFrameSize int64
+	validator    *validation.Validator
 }
 
 type Options struct {
-	// Здесь можно добавить дополнительные параметры конфигурации
+	// MaxFrameSize — максимальный размер входящего сообщения в байтах. При превышении
+	// соединение закрывается с кодом 1009 (message too big). 0 — без ограничения.
+	MaxFrameSize int64
+	// Validator проверяет разобранные сообщения клиента. Если не задан,
+	// проверяются правила без ограничения длины текста.
+	Validator *validation.Validator
 }
 
 func New(options Options) *WebSocketMessageReceiver {
+	validator := options.Validator
+	if validator == nil {
+		validator = validation.New(validation.Options{})
+	}
 	return &WebSocketMessageReceiver{
-		connection: nil,
-		// option: options.option,
+		connection:   nil,
+		codec:        codecs.Default(),
+		traffic:      &traffic.Counters{},
+		logger:       logging.Component(nil, "WEBSOCKET_RECEIVER"),
+		maxFrameSize: options.MaxFrameSize,
+		validator:    validator,
 	}
 }
 
 // SetConnection устанавливает WebSocket-соединение для WebSocketMessageReceiver.
-// Этот метод присваивает переданный экземпляр websocket.Conn в поле connection получателя.
+// Этот метод присваивает переданный экземпляр websocket.Conn в поле connection получателя
+// и ограничивает размер входящих сообщений соединения значением MaxFrameSize.
 //
 // Параметры:
 //   - conn: Указатель на экземпляр websocket.Conn, представляющий WebSocket-соединение.
 func (wsmr *WebSocketMessageReceiver) SetConnection(conn *websocket.Conn) {
 	wsmr.connection = conn
+	if conn != nil && wsmr.maxFrameSize > 0 {
+		conn.SetReadLimit(wsmr.maxFrameSize)
+	}
+}
+
+// SetCodec устанавливает кодек, согласованный с клиентом при апгрейде соединения.
+// По умолчанию используется JSON-кодек.
+//
+// Параметры:
+//   - codec: Кодек для декодирования входящих фреймов.
+func (wsmr *WebSocketMessageReceiver) SetCodec(codec interfaces.Codec) {
+	wsmr.codec = codec
+}
+
+// SetTraffic устанавливает счетчики трафика соединения, в которых учитывается
+// размер полученных сообщений после распаковки.
+//
+// Параметры:
+//   - counters: Счетчики трафика WebSocket-соединения.
+func (wsmr *WebSocketMessageReceiver) SetTraffic(counters *traffic.Counters) {
+	wsmr.traffic = counters
+}
+
+// SetLogger устанавливает логгер с полями соединения; записи получателя дополняются полем component.
+//
+// Параметры:
+//   - logger: Логгер WebSocket-соединения.
+func (wsmr *WebSocketMessageReceiver) SetLogger(logger *slog.Logger) {
+	wsmr.logger = logging.Component(logger, "WEBSOCKET_RECEIVER")
 }
 
-// ReceiveMessage читает сообщение в формате JSON из WebSocket-соединения
-// и возвращает его как экземпляр msg.Message. Если соединение не установлено,
-// возвращается ошибка websocket.CloseError, указывающая на ненормальное закрытие.
+// ReceiveMessage читает фрейм из WebSocket-соединения, строго декодирует его установленным
+// кодеком, проверяет валидатором и возвращает как экземпляр msg.Message. Если соединение
+// не установлено, возвращается ошибка websocket.CloseError, указывающая на ненормальное закрытие.
 // В случае ошибки при чтении возвращается ошибка вместе с пустым сообщением.
 //
 // Возвращает:
 // - msg.Message: Декодированное сообщение из WebSocket-соединения.
-// - error: Ошибка, если соединение не установлено или если чтение сообщения завершилось неудачей.
+// - error: Ошибка, если соединение не установлено или если чтение сообщения завершилось неудачей;
+// *msg.ValidationError, если сообщение не удалось разобрать или оно не прошло проверку —
+// в этом случае соединение остается пригодным для чтения следующих сообщений.
+//
+// Декодирование сообщения записывается в спан message.receive, который продолжает трассу
+// из поля TraceParent сообщения (или начинает новую) и записывается в это поле.
 func (wsmr *WebSocketMessageReceiver) ReceiveMessage() (msg.Message, error) {
 	if wsmr.connection != nil {
-		var message msg.Message
-		if err := wsmr.connection.ReadJSON(&message); err != nil {
+		_, data, err := wsmr.connection.ReadMessage()
+		if err != nil {
+			return msg.Message{}, err
+		}
+		wsmr.traffic.AddPayloadRead(len(data))
+		receivedAt := time.Now()
+		message, err := wsmr.codec.DecodeStrict(data)
+		if err == nil {
+			err = wsmr.validator.Validate(message)
+		}
+		_, span := tracing.StartMessageSpan(&message, "message.receive",
+			trace.WithTimestamp(receivedAt),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(tracing.AttrTransport.String(metrics.TransportWebSocket)),
+		)
+		defer span.End()
+		if err != nil {
+			tracing.RecordError(span, err)
+			var validationErr *msg.ValidationError
+			if errors.As(err, &validationErr) {
+				metrics.MessagesInvalid.WithLabelValues(metrics.TransportWebSocket, validationErr.Fields[0].Code).Inc()
+				wsmr.logger.Info("Получено некорректное сообщение", slog.Int("size", len(data)), slog.Any("error", err))
+			}
 			return msg.Message{}, err
 		}
-		log.Printf("Получено сообщение: %s\n", message)
+		metrics.MessagesReceived.WithLabelValues(metrics.TransportWebSocket, message.Type.String()).Inc()
+		wsmr.logger.Debug("Получено сообщение", logging.MessageType(message.Type), slog.Int("size", len(data)))
 		return message, nil
 	} else {
 		return msg.Message{}, &websocket.CloseError{Code: websocket.CloseAbnormalClosure, Text: "Connection is not set"}
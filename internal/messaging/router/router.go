@@ -0,0 +1,424 @@
+package router
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"log/slog"
+	"messenger/internal/logging"
+	"messenger/internal/messaging/bus"
+	"messenger/internal/messaging/interfaces"
+	"sync"
+)
+
+// Router доставляет сообщения подключенным пользователям. Каждое соединение
+// пользователя (WebSocket или сессия резервного транспорта) регистрирует свой
+// MessageSender; у одного пользователя может быть несколько соединений.
+//
+// Доставка идет через шину сообщений: Deliver и DeliverRoom публикуют сообщение
+// в топик пользователя или беседы, а роутер каждого узла подписан на топики
+// пользователей, подключенных к этому узлу, и бесед, в которых они участвуют.
+// Поэтому сообщение доходит до получателя, к какому бы узлу он ни был подключен.
+//
+// Если задан каталог подключений кластера, личные сообщения (Deliver) публикуются только
+// для пользователей, подключенных хотя бы к одному узлу, а пользователям, подключенным
+// только к текущему узлу, доставляются напрямую, минуя шину.
+//
+// Участие в беседах роутеры узлов согласуют через топик bus.MembershipTopic: Join
+// публикует событие участия, и его получают роутеры всех узлов, к которым подключен
+// пользователь. При первом подключении пользователя к узлу роутер, кроме своего хранилища,
+// запрашивает беседы пользователя у остальных узлов, и те отвечают событиями участия
+// по своим хранилищам. Поэтому пользователь получает сообщения беседы, к какому бы узлу
+// он ни был подключен и на каком бы узле ни был приглашен в нее.
+type Router struct {
+	// id — идентификатор экземпляра роутера, по которому он отличает свои запросы участия.
+	id        string
+	bus       interfaces.Bus
+	store     interfaces.ConversationStore
+	directory interfaces.Directory
+	logger    *slog.Logger
+
+	// subMu упорядочивает подписки и отписки на шине при подключении и отключении
+	// пользователей, чтобы подписка на топик создавалась и снималась ровно один раз.
+	subMu sync.Mutex
+
+	mu      sync.RWMutex
+	senders map[string]map[*registration]struct{}
+	// users — функции отписки от топиков пользователей, подключенных к узлу.
+	users map[string]func()
+	// rooms — беседы, в которых участвуют подключенные к узлу пользователи.
+	rooms map[string]*room
+	// joined — беседы каждого подключенного к узлу пользователя.
+	joined map[string]map[string]struct{}
+}
+
+type registration struct {
+	sender interfaces.MessageSender
+}
+
+type room struct {
+	members     map[string]struct{}
+	unsubscribe func()
+}
+
+// Типы сообщений участия в беседах. Передаются только между узлами через топик
+// bus.MembershipTopic; From — пользователь.
+var (
+	// memberJoinedType — пользователь участвует в беседе Conversation.
+	memberJoinedType = msg.MustRegister("conversation.member_joined")
+	// memberQueryType — запрос бесед пользователя при его подключении к узлу; Text —
+	// идентификатор запросившего роутера. Остальные роутеры отвечают событиями memberJoinedType.
+	memberQueryType = msg.MustRegister("conversation.member_query")
+)
+
+type Options struct {
+	// Bus — шина сообщений между узлами. Если не задана, используется шина в памяти
+	// процесса, и сообщения доставляются только в пределах одного узла.
+	Bus interfaces.Bus
+	// Store — хранилище бесед, из которого при подключении пользователя загружаются
+	// его беседы и по которому роутер отвечает на запросы бесед от других узлов.
+	// Если не задано, беседы пользователя становятся известны узлу только через Join
+	// и от других узлов.
+	Store interfaces.ConversationStore
+	// Directory — каталог подключений кластера. Если не задан, личные сообщения
+	// публикуются в шину для всех получателей.
+	Directory interfaces.Directory
+	// Logger — логгер роутера. Если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+// New создает пустой Router и подписывает его на события участия в беседах.
+// Ошибка подписки логируется: тогда узел узнает об участии только из своего хранилища и Join.
+func New(options Options) *Router {
+	messageBus := options.Bus
+	if messageBus == nil {
+		messageBus = bus.NewMemory()
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	rt := &Router{
+		id:        hex.EncodeToString(id),
+		bus:       messageBus,
+		store:     options.Store,
+		directory: options.Directory,
+		logger:    logging.Component(options.Logger, "MESSAGE_ROUTER"),
+		senders:   make(map[string]map[*registration]struct{}),
+		users:     make(map[string]func()),
+		rooms:     make(map[string]*room),
+		joined:    make(map[string]map[string]struct{}),
+	}
+	if _, err := messageBus.Subscribe(bus.MembershipTopic, rt.handleMembership); err != nil {
+		rt.logger.Error("Ошибка подписки на топик участия в беседах", slog.Any("error", err))
+	}
+	return rt
+}
+
+// Register регистрирует отправителя соединения пользователя userID.
+// Первое соединение пользователя на узле подписывает узел на топик пользователя
+// и топики его бесед из хранилища узла и запрашивает беседы пользователя у других узлов.
+// Возвращает функцию, снимающую регистрацию при закрытии соединения; после закрытия
+// последнего соединения узел отписывается от этих топиков.
+func (rt *Router) Register(userID string, sender interfaces.MessageSender) func() {
+	entry := &registration{sender: sender}
+
+	rt.subMu.Lock()
+	defer rt.subMu.Unlock()
+
+	rt.mu.Lock()
+	first := rt.senders[userID] == nil
+	if first {
+		rt.senders[userID] = make(map[*registration]struct{})
+	}
+	rt.senders[userID][entry] = struct{}{}
+	rt.mu.Unlock()
+
+	if first {
+		rt.subscribeUser(userID)
+		for _, roomID := range rt.conversations(userID) {
+			rt.join(userID, roomID)
+		}
+		if rt.directory != nil {
+			rt.directory.Connected(userID)
+		}
+		rt.publish(bus.MembershipTopic, msg.Message{Type: memberQueryType, From: userID, Text: rt.id})
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { rt.unregister(userID, entry) })
+	}
+}
+
+func (rt *Router) unregister(userID string, entry *registration) {
+	rt.subMu.Lock()
+	defer rt.subMu.Unlock()
+
+	rt.mu.Lock()
+	delete(rt.senders[userID], entry)
+	last := len(rt.senders[userID]) == 0
+	if last {
+		delete(rt.senders, userID)
+	}
+	rt.mu.Unlock()
+
+	if last {
+		rt.unsubscribeUser(userID)
+		if rt.directory != nil {
+			rt.directory.Disconnected(userID)
+		}
+	}
+}
+
+// Join отмечает, что пользователь userID участвует в беседе roomID. Узлы, к которым
+// подключен пользователь, подписываются на топик беседы, чтобы доставлять ему ее сообщения.
+// Если пользователь уже участвует в беседе на этом узле, событие участия не публикуется:
+// о ней узнал каждый узел, к которому пользователь подключен.
+func (rt *Router) Join(userID string, roomID string) {
+	rt.mu.RLock()
+	_, joined := rt.joined[userID][roomID]
+	rt.mu.RUnlock()
+	if joined {
+		return
+	}
+
+	// Сначала пользователь присоединяется на своем узле, чтобы не зависеть от шины,
+	// затем событие участия получают остальные узлы. Публикация идет без subMu:
+	// шина в памяти вызывает обработчик в том же потоке.
+	rt.joinConnected(userID, roomID)
+	rt.publish(bus.MembershipTopic, msg.Message{Type: memberJoinedType, From: userID, Conversation: roomID})
+}
+
+// joinConnected присоединяет пользователя к беседе, если он подключен к узлу.
+func (rt *Router) joinConnected(userID string, roomID string) {
+	rt.subMu.Lock()
+	defer rt.subMu.Unlock()
+
+	rt.mu.RLock()
+	connected := rt.senders[userID] != nil
+	_, joined := rt.joined[userID][roomID]
+	rt.mu.RUnlock()
+
+	if connected && !joined {
+		rt.join(userID, roomID)
+	}
+}
+
+// handleMembership обрабатывает события участия в беседах из шины. На запрос бесед
+// пользователя другого узла роутер отвечает событиями участия по своему хранилищу.
+func (rt *Router) handleMembership(message msg.Message) {
+	switch message.Type {
+	case memberJoinedType:
+		rt.joinConnected(message.From, message.Conversation)
+	case memberQueryType:
+		if message.Text == rt.id {
+			return
+		}
+		for _, roomID := range rt.conversations(message.From) {
+			rt.publish(bus.MembershipTopic, msg.Message{Type: memberJoinedType, From: message.From, Conversation: roomID})
+		}
+	}
+}
+
+// Deliver публикует сообщение в топики перечисленных пользователей; его получат все
+// соединения этих пользователей на всех узлах. Ошибки публикации логируются.
+// Получатели, которые по каталогу подключений не подключены ни к одному узлу,
+// пропускаются, а подключенным только к текущему узлу сообщение доставляется напрямую.
+func (rt *Router) Deliver(message msg.Message, userIDs []string) {
+	for _, userID := range userIDs {
+		switch rt.route(userID) {
+		case routeLocal:
+			rt.send(message, rt.lookup([]string{userID}, ""))
+		case routeBus:
+			rt.publish(bus.UserTopic(userID), message)
+		default:
+			rt.logger.Debug("Получатель не подключен ни к одному узлу",
+				slog.String("user_id", userID), slog.String("message_id", message.ID))
+		}
+	}
+}
+
+// Способы доставки личного сообщения пользователю.
+const (
+	routeNone = iota
+	routeLocal
+	routeBus
+)
+
+// route выбирает способ доставки личного сообщения пользователю по каталогу подключений.
+func (rt *Router) route(userID string) int {
+	if rt.directory == nil {
+		return routeBus
+	}
+	nodeIDs, ok := rt.directory.Lookup(userID)
+	switch {
+	case !ok:
+		return routeBus
+	case len(nodeIDs) == 0:
+		return routeNone
+	case len(nodeIDs) == 1 && nodeIDs[0] == rt.directory.NodeID():
+		return routeLocal
+	default:
+		return routeBus
+	}
+}
+
+// DeliverRoom публикует сообщение в топик беседы message.Conversation; его получат
+// соединения всех участников беседы, кроме отправителя message.From, на всех узлах.
+func (rt *Router) DeliverRoom(message msg.Message) {
+	rt.publish(bus.RoomTopic(message.Conversation), message)
+}
+
+// Connections возвращает число подключенных пользователей и их соединений.
+func (rt *Router) Connections() (int, int) {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	connections := 0
+	for _, entries := range rt.senders {
+		connections += len(entries)
+	}
+	return len(rt.senders), connections
+}
+
+func (rt *Router) publish(topic string, message msg.Message) {
+	if err := rt.bus.Publish(context.Background(), topic, message); err != nil {
+		rt.logger.Error("Ошибка публикации сообщения в шину", slog.String("topic", topic),
+			slog.String("message_id", message.ID), logging.MessageType(message.Type), slog.Any("error", err))
+	}
+}
+
+// subscribeUser подписывает узел на топик пользователя. Вызывается под subMu.
+func (rt *Router) subscribeUser(userID string) {
+	unsubscribe, err := rt.bus.Subscribe(bus.UserTopic(userID), func(message msg.Message) {
+		rt.send(message, rt.lookup([]string{userID}, ""))
+	})
+	if err != nil {
+		rt.logger.Error("Ошибка подписки на топик пользователя", slog.String("user_id", userID), slog.Any("error", err))
+		return
+	}
+
+	rt.mu.Lock()
+	rt.users[userID] = unsubscribe
+	rt.mu.Unlock()
+}
+
+// unsubscribeUser отписывает узел от топиков пользователя и его бесед, в которых
+// не осталось других подключенных к узлу участников. Вызывается под subMu.
+func (rt *Router) unsubscribeUser(userID string) {
+	var unsubscribes []func()
+
+	rt.mu.Lock()
+	if unsubscribe, ok := rt.users[userID]; ok {
+		unsubscribes = append(unsubscribes, unsubscribe)
+		delete(rt.users, userID)
+	}
+	for roomID := range rt.joined[userID] {
+		r := rt.rooms[roomID]
+		delete(r.members, userID)
+		if len(r.members) == 0 {
+			unsubscribes = append(unsubscribes, r.unsubscribe)
+			delete(rt.rooms, roomID)
+		}
+	}
+	delete(rt.joined, userID)
+	rt.mu.Unlock()
+
+	for _, unsubscribe := range unsubscribes {
+		unsubscribe()
+	}
+}
+
+// join добавляет пользователя в участники беседы на узле и при необходимости
+// подписывает узел на топик беседы. Вызывается под subMu.
+func (rt *Router) join(userID string, roomID string) {
+	rt.mu.RLock()
+	_, subscribed := rt.rooms[roomID]
+	rt.mu.RUnlock()
+
+	if !subscribed {
+		unsubscribe, err := rt.bus.Subscribe(bus.RoomTopic(roomID), func(message msg.Message) {
+			rt.send(message, rt.roomSenders(roomID, message.From))
+		})
+		if err != nil {
+			rt.logger.Error("Ошибка подписки на топик беседы", slog.String("conversation", roomID), slog.Any("error", err))
+			return
+		}
+		rt.logger.Debug("Узел подписан на топик беседы", slog.String("conversation", roomID))
+
+		rt.mu.Lock()
+		rt.rooms[roomID] = &room{members: make(map[string]struct{}), unsubscribe: unsubscribe}
+		rt.mu.Unlock()
+	}
+
+	rt.mu.Lock()
+	rt.rooms[roomID].members[userID] = struct{}{}
+	if rt.joined[userID] == nil {
+		rt.joined[userID] = make(map[string]struct{})
+	}
+	rt.joined[userID][roomID] = struct{}{}
+	rt.mu.Unlock()
+}
+
+// conversations возвращает идентификаторы бесед пользователя из хранилища.
+// Ошибка хранилища логируется: беседы станут известны узлу через Join и от других узлов.
+func (rt *Router) conversations(userID string) []string {
+	if rt.store == nil {
+		return nil
+	}
+
+	conversations, err := rt.store.Conversations(userID)
+	if err != nil {
+		rt.logger.Warn("Ошибка получения бесед пользователя", slog.String("user_id", userID), slog.Any("error", err))
+		return nil
+	}
+
+	roomIDs := make([]string, 0, len(conversations))
+	for _, conversation := range conversations {
+		roomIDs = append(roomIDs, conversation.ID)
+	}
+	return roomIDs
+}
+
+// send отправляет сообщение в соединения. Ошибки доставки в отдельные соединения
+// логируются и не прерывают доставку остальным.
+func (rt *Router) send(message msg.Message, senders []interfaces.MessageSender) {
+	for _, sender := range senders {
+		if err := sender.SendMessage(message); err != nil {
+			rt.logger.Warn("Ошибка доставки сообщения",
+				slog.String("message_id", message.ID), logging.MessageType(message.Type), slog.Any("error", err))
+		}
+	}
+}
+
+// roomSenders собирает отправителей подключенных к узлу участников беседы, кроме exclude.
+func (rt *Router) roomSenders(roomID string, exclude string) []interfaces.MessageSender {
+	var userIDs []string
+	rt.mu.RLock()
+	if r, ok := rt.rooms[roomID]; ok {
+		for userID := range r.members {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	rt.mu.RUnlock()
+
+	return rt.lookup(userIDs, exclude)
+}
+
+// lookup собирает отправителей пользователей, кроме exclude, под блокировкой чтения,
+// чтобы сама отправка выполнялась без удержания блокировки.
+func (rt *Router) lookup(userIDs []string, exclude string) []interfaces.MessageSender {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	var senders []interfaces.MessageSender
+	for _, userID := range userIDs {
+		if userID == exclude {
+			continue
+		}
+		for entry := range rt.senders[userID] {
+			senders = append(senders, entry.sender)
+		}
+	}
+	return senders
+}
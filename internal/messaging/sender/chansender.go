@@ -0,0 +1,78 @@
+package sender
+
+import (
+	"errors"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"messenger/internal/metrics"
+	"messenger/internal/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrSessionClosed возвращается, если сессия, в которую отправляется сообщение, уже закрыта.
+var ErrSessionClosed = errors.New("сессия закрыта")
+
+// ErrOutboxFull возвращается, если клиент не забирает сообщения и канал исходящих
+// сообщений сессии заполнен.
+var ErrOutboxFull = errors.New("буфер исходящих сообщений сессии заполнен")
+
+// ChannelMessageSender отправляет сообщения в канал исходящих сообщений сессии.
+// Используется резервными HTTP-транспортами (SSE и long-polling), которые
+// забирают сообщения из канала и передают их клиенту.
+type ChannelMessageSender struct {
+	outbox   chan<- msg.Message
+	done     <-chan struct{}
+	overflow func()
+}
+
+// NewChannel создает ChannelMessageSender, пишущий в канал outbox.
+//
+// Параметры:
+//   - outbox: Канал исходящих сообщений сессии.
+//   - done: Канал, закрываемый при завершении сессии.
+//   - overflow: Вызывается, если канал исходящих сообщений заполнен; должен закрыть сессию.
+func NewChannel(outbox chan<- msg.Message, done <-chan struct{}, overflow func()) *ChannelMessageSender {
+	return &ChannelMessageSender{
+		outbox:   outbox,
+		done:     done,
+		overflow: overflow,
+	}
+}
+
+// SendMessage помещает сообщение в канал исходящих сообщений, не блокируясь.
+// Маршрутизатор и шина доставляют сообщения синхронно, поэтому клиент, который перестал
+// забирать сообщения, не должен задерживать доставку другим: если канал заполнен,
+// сообщение отбрасывается, вызывается overflow и возвращается ErrOutboxFull — так же,
+// как WebSocketMessageSender отключает клиента, который не читает сообщения.
+// Возвращает ErrSessionClosed, если сессия закрыта. Отправка записывается в спан message.send.
+func (cms *ChannelMessageSender) SendMessage(message msg.Message) error {
+	_, span := tracing.StartMessageSpan(&message, "message.send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(tracing.AttrTransport.String(metrics.TransportFallback)),
+	)
+	defer span.End()
+
+	if err := cms.sendMessage(message); err != nil {
+		tracing.RecordError(span, err)
+		metrics.SendErrors.WithLabelValues(metrics.TransportFallback).Inc()
+		return err
+	}
+	metrics.MessagesSent.WithLabelValues(metrics.TransportFallback, message.Type.String()).Inc()
+	return nil
+}
+
+func (cms *ChannelMessageSender) sendMessage(message msg.Message) error {
+	select {
+	case <-cms.done:
+		return ErrSessionClosed
+	default:
+	}
+
+	select {
+	case cms.outbox <- message:
+		return nil
+	default:
+		cms.overflow()
+		return ErrOutboxFull
+	}
+}
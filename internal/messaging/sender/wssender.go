@@ -2,46 +2,176 @@ package sender
 
 import (
 	"errors"
-	msg "messenger/internal/messaging/models/message"
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"log/slog"
+	"messenger/internal/logging"
+	"messenger/internal/messaging/interfaces"
+	"messenger/internal/metrics"
+	"messenger/internal/tracing"
+	"messenger/internal/ws/traffic"
+	"net"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// WebSocketMessageSender сериализует запись в соединение: помимо цикла обработки
+// сообщений в него пишет Router, доставляющий сообщения других пользователей,
+// а websocket.Conn не допускает конкурентной записи.
+//
+// Router, шина сообщений и цикл обработки вызывают SendMessage синхронно, поэтому каждая
+// запись ограничена WriteTimeout: клиент, который перестал читать сообщения, не блокирует
+// доставку остальным дольше этого времени, а его соединение закрывается.
 type WebSocketMessageSender struct {
-	connection *websocket.Conn
+	mu                   sync.Mutex
+	connection           *websocket.Conn
+	codec                interfaces.Codec
+	traffic              *traffic.Counters
+	compressionLevel     int
+	compressionThreshold int
+	writeTimeout         time.Duration
+	logger               *slog.Logger
 }
 
 type Options struct {
-	// Здесь можно добавить дополнительные параметры конфигурации
+	// CompressionLevel — уровень сжатия permessage-deflate (0 — уровень по умолчанию).
+	CompressionLevel int
+	// CompressionThreshold — минимальный размер закодированного сообщения в байтах,
+	// начиная с которого оно сжимается. Сообщения меньшего размера отправляются без сжатия.
+	CompressionThreshold int
+	// WriteTimeout — максимальное время записи одного сообщения в соединение.
+	// Если 0, используется 10s.
+	WriteTimeout time.Duration
 }
 
+// defaultWriteTimeout — максимальное время записи сообщения, если Options.WriteTimeout не задан.
+const defaultWriteTimeout = 10 * time.Second
+
 // New создает и возвращает новый экземпляр WebSocketMessageSender, используя предоставленные Options.
 // Возвращаемый отправитель инициализируется с указанными параметрами конфигурации.
 func New(options Options) *WebSocketMessageSender {
+	writeTimeout := options.WriteTimeout
+	if writeTimeout == 0 {
+		writeTimeout = defaultWriteTimeout
+	}
 	return &WebSocketMessageSender{
-		connection: nil,
-		// option: options.option,
+		connection:           nil,
+		codec:                codecs.Default(),
+		traffic:              &traffic.Counters{},
+		compressionLevel:     options.CompressionLevel,
+		compressionThreshold: options.CompressionThreshold,
+		writeTimeout:         writeTimeout,
+		logger:               logging.Component(nil, "WEBSOCKET_SENDER"),
 	}
 }
 
 // SetConnection устанавливает WebSocket-соединение для WebSocketMessageSender.
-// Этот метод присваивает предоставленный экземпляр websocket.Conn внутреннему полю соединения отправителя.
+// Этот метод присваивает предоставленный экземпляр websocket.Conn внутреннему полю соединения отправителя
+// и применяет настроенный уровень сжатия. Уровень проверяется при загрузке конфигурации,
+// поэтому ошибка SetCompressionLevel здесь не ожидается.
 //
 // Параметры:
 //   - conn: Указатель на websocket.Conn, представляющий WebSocket-соединение, которое будет использоваться.
 func (wsms *WebSocketMessageSender) SetConnection(conn *websocket.Conn) {
 	wsms.connection = conn
+	if wsms.compressionLevel != 0 {
+		_ = conn.SetCompressionLevel(wsms.compressionLevel)
+	}
+}
+
+// SetTraffic устанавливает счетчики трафика соединения, в которых учитывается
+// размер отправляемых сообщений до сжатия.
+//
+// Параметры:
+//   - counters: Счетчики трафика WebSocket-соединения.
+func (wsms *WebSocketMessageSender) SetTraffic(counters *traffic.Counters) {
+	wsms.traffic = counters
+}
+
+// SetCodec устанавливает кодек, согласованный с клиентом при апгрейде соединения.
+// По умолчанию используется JSON-кодек.
+//
+// Параметры:
+//   - codec: Кодек для кодирования исходящих сообщений.
+func (wsms *WebSocketMessageSender) SetCodec(codec interfaces.Codec) {
+	wsms.codec = codec
+}
+
+// SetLogger устанавливает логгер с полями соединения; записи отправителя дополняются полем component.
+//
+// Параметры:
+//   - logger: Логгер WebSocket-соединения.
+func (wsms *WebSocketMessageSender) SetLogger(logger *slog.Logger) {
+	wsms.logger = logging.Component(logger, "WEBSOCKET_SENDER")
 }
 
 // SendMessage отправляет сообщение через WebSocket-соединение.
-// Принимает msg.Message в качестве входного параметра и записывает его в формате JSON в WebSocket.
+// Принимает msg.Message в качестве входного параметра, кодирует его установленным кодеком
+// и записывает во фрейм того типа, который требует кодек (текстовый для JSON, бинарный для остальных).
+// Сообщения, размер которых меньше порога сжатия, отправляются без сжатия: для коротких
+// сообщений затраты CPU на deflate не окупаются. Если сжатие не согласовано с клиентом,
+// флаг сжатия игнорируется.
 // Возвращает ошибку, если сообщение не может быть отправлено или если возникли проблемы с соединением.
+// Запись ограничена WriteTimeout. После ошибки записи websocket.Conn непригоден для
+// отправки, поэтому соединение закрывается: цикл чтения получает ошибку и отключает клиента.
+// Отправленные сообщения и ошибки отправки учитываются в метриках и журнале.
+// Отправка записывается в спан message.send; клиент получает его контекст в поле TraceParent.
 func (wsms *WebSocketMessageSender) SendMessage(message msg.Message) error {
+	_, span := tracing.StartMessageSpan(&message, "message.send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(tracing.AttrTransport.String(metrics.TransportWebSocket)),
+	)
+	defer span.End()
+
+	if err := wsms.sendMessage(message); err != nil {
+		tracing.RecordError(span, err)
+		metrics.SendErrors.WithLabelValues(metrics.TransportWebSocket).Inc()
+		wsms.logger.Warn("Ошибка отправки сообщения", logging.MessageType(message.Type), slog.Any("error", err))
+		return err
+	}
+	wsms.logger.Debug("Отправлено сообщение", logging.MessageType(message.Type))
+	metrics.MessagesSent.WithLabelValues(metrics.TransportWebSocket, message.Type.String()).Inc()
+	return nil
+}
+
+func (wsms *WebSocketMessageSender) sendMessage(message msg.Message) error {
 	if wsms.connection == nil {
 		return errors.New("соединение не установлено")
 	}
-	return wsms.connection.WriteJSON(message)
+	data, err := wsms.codec.Encode(message)
+	if err != nil {
+		return err
+	}
+
+	wsms.mu.Lock()
+	defer wsms.mu.Unlock()
+
+	wsms.connection.EnableWriteCompression(len(data) >= wsms.compressionThreshold)
+	wsms.traffic.AddPayloadWritten(len(data))
+	wsms.connection.SetWriteDeadline(time.Now().Add(wsms.writeTimeout))
+	if err := wsms.connection.WriteMessage(wsms.codec.FrameType(), data); err != nil {
+		closeTransport(wsms.connection)
+		return err
+	}
+	return nil
+}
+
+// closeTransport закрывает TCP-соединение под WebSocket-соединением, снимая обертки
+// (TLS, учет трафика) через NetConn. tls.Conn.Close сначала отправляет close_notify
+// и ждет записи до 5s, а клиент, который не читает сообщения, ее не примет.
+func closeTransport(conn *websocket.Conn) {
+	transport := conn.NetConn()
+	for {
+		wrapper, ok := transport.(interface{ NetConn() net.Conn })
+		if !ok {
+			break
+		}
+		transport = wrapper.NetConn()
+	}
+	transport.Close()
 }
 
 func (wsms *WebSocketMessageSender) SendCloseMessage(code int, text string, timeout time.Duration) error {
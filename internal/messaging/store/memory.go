@@ -0,0 +1,257 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	conv "github.com/1ight181/messenger/pkg/protocol/conversation"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrMessageNotFound возвращается, если сообщение, указанное курсором пагинации
+// или переданное для изменения, не найдено.
+var ErrMessageNotFound = errors.New("сообщение не найдено")
+
+// ErrNotMember возвращается, если пользователь не участник существующей беседы:
+// писать в беседу и приглашать в нее могут только участники (писать — еще и сервисы).
+var ErrNotMember = errors.New("пользователь не участник беседы")
+
+// MemoryStore хранит беседы и историю сообщений в памяти процесса.
+// Беседу создает первое сообщение в нее, и ее единственным участником становится
+// отправитель. Остальные пользователи становятся участниками, только когда их
+// приглашает участник беседы (Invite); сообщения от остальных отклоняются, кроме
+// сообщений сервисов (Options.ServiceUsers).
+type MemoryStore struct {
+	mu            sync.RWMutex
+	conversations map[string]*conversationRecord
+	historyLimit  int
+	services      map[string]struct{}
+}
+
+type conversationRecord struct {
+	members       map[string]struct{}
+	messages      []msg.Message
+	lastMessageAt int64
+}
+
+type Options struct {
+	// HistoryLimit — максимальное число хранимых сообщений одной беседы.
+	// Более старые сообщения отбрасываются. 0 — без ограничения.
+	HistoryLimit int
+	// ServiceUsers — идентификаторы сервисов, которые пишут в беседы, не будучи их
+	// участниками, например системные уведомления. Сервис не становится участником
+	// существующей беседы и не получает доступа к ее истории.
+	ServiceUsers []string
+}
+
+// NewMemory создает пустое хранилище бесед в памяти.
+func NewMemory(options Options) *MemoryStore {
+	services := make(map[string]struct{}, len(options.ServiceUsers))
+	for _, userID := range options.ServiceUsers {
+		services[userID] = struct{}{}
+	}
+	return &MemoryStore{
+		conversations: make(map[string]*conversationRecord),
+		historyLimit:  options.HistoryLimit,
+		services:      services,
+	}
+}
+
+// Append сохраняет сообщение в историю беседы message.Conversation, присваивая ему
+// идентификатор и время отправки. Если беседы нет, она создается, и отправитель
+// становится ее участником. Сервисы пишут в существующие беседы без участия в них.
+//
+// Возвращает:
+//   - msg.Message: Сохраненное сообщение с заполненными ID и SentAt.
+//   - error: ErrNotMember, если отправитель не участник существующей беседы, ошибка
+//     генерации идентификатора или отсутствие идентификатора беседы или отправителя.
+func (ms *MemoryStore) Append(message msg.Message) (msg.Message, error) {
+	if message.Conversation == "" {
+		return msg.Message{}, errors.New("не указана беседа")
+	}
+	if message.From == "" {
+		return msg.Message{}, errors.New("не указан отправитель")
+	}
+
+	id, err := newMessageID()
+	if err != nil {
+		return msg.Message{}, err
+	}
+	message.ID = id
+	message.SentAt = time.Now().UnixMilli()
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	record, ok := ms.conversations[message.Conversation]
+	if !ok {
+		record = &conversationRecord{members: map[string]struct{}{message.From: {}}}
+		ms.conversations[message.Conversation] = record
+	}
+	_, member := record.members[message.From]
+	_, service := ms.services[message.From]
+	if !member && !service {
+		return msg.Message{}, ErrNotMember
+	}
+
+	record.messages = append(record.messages, message)
+	if ms.historyLimit > 0 && len(record.messages) > ms.historyLimit {
+		record.messages = slices.Clone(record.messages[len(record.messages)-ms.historyLimit:])
+	}
+	record.lastMessageAt = message.SentAt
+
+	return message, nil
+}
+
+// Conversations возвращает беседы, участником которых является пользователь,
+// отсортированные по времени последнего сообщения (сначала новые).
+func (ms *MemoryStore) Conversations(userID string) ([]conv.Conversation, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	conversations := []conv.Conversation{}
+	for id, record := range ms.conversations {
+		if _, ok := record.members[userID]; !ok {
+			continue
+		}
+		conversations = append(conversations, conv.Conversation{
+			ID:            id,
+			Members:       sortedMembers(record.members),
+			LastMessageAt: record.lastMessageAt,
+		})
+	}
+
+	sort.Slice(conversations, func(i, j int) bool {
+		return conversations[i].LastMessageAt > conversations[j].LastMessageAt
+	})
+
+	return conversations, nil
+}
+
+// History возвращает до limit последних сообщений беседы в хронологическом порядке.
+// Если задан before, возвращаются сообщения, отправленные раньше сообщения с этим
+// идентификатором. Для неизвестной беседы возвращается пустой список.
+func (ms *MemoryStore) History(conversationID string, before string, limit int) ([]msg.Message, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	record, ok := ms.conversations[conversationID]
+	if !ok {
+		return []msg.Message{}, nil
+	}
+
+	end := len(record.messages)
+	if before != "" {
+		end = slices.IndexFunc(record.messages, func(m msg.Message) bool {
+			return m.ID == before
+		})
+		if end < 0 {
+			return nil, ErrMessageNotFound
+		}
+	}
+
+	start := 0
+	if limit > 0 && end-limit > start {
+		start = end - limit
+	}
+
+	return slices.Clone(record.messages[start:end]), nil
+}
+
+// Update заменяет сообщение с идентификатором message.ID в истории беседы
+// message.Conversation. Идентификатор, отправитель и время отправки сохраняются
+// прежними, остальные поля берутся из message.
+//
+// Возвращает:
+//   - msg.Message: Новая версия сохраненного сообщения.
+//   - error: ErrMessageNotFound, если сообщения нет в истории, например если оно
+//     уже вытеснено ограничением HistoryLimit.
+func (ms *MemoryStore) Update(message msg.Message) (msg.Message, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	record, ok := ms.conversations[message.Conversation]
+	if !ok {
+		return msg.Message{}, ErrMessageNotFound
+	}
+	index := slices.IndexFunc(record.messages, func(m msg.Message) bool {
+		return m.ID == message.ID
+	})
+	if index < 0 {
+		return msg.Message{}, ErrMessageNotFound
+	}
+
+	stored := record.messages[index]
+	message.From = stored.From
+	message.SentAt = stored.SentAt
+	record.messages[index] = message
+	return message, nil
+}
+
+// Invite добавляет пользователей userIDs в участники беседы conversationID от имени
+// участника inviterID.
+//
+// Возвращает:
+//   - conv.Conversation: Беседа с новым списком участников.
+//   - error: ErrNotMember, если inviterID не участник беседы или беседы нет.
+func (ms *MemoryStore) Invite(conversationID, inviterID string, userIDs []string) (conv.Conversation, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	record, ok := ms.conversations[conversationID]
+	if !ok {
+		return conv.Conversation{}, ErrNotMember
+	}
+	if _, member := record.members[inviterID]; !member {
+		return conv.Conversation{}, ErrNotMember
+	}
+	for _, userID := range userIDs {
+		record.members[userID] = struct{}{}
+	}
+	return conv.Conversation{
+		ID:            conversationID,
+		Members:       sortedMembers(record.members),
+		LastMessageAt: record.lastMessageAt,
+	}, nil
+}
+
+// Members возвращает участников беседы. Для неизвестной беседы возвращается пустой список.
+func (ms *MemoryStore) Members(conversationID string) ([]string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	record, ok := ms.conversations[conversationID]
+	if !ok {
+		return []string{}, nil
+	}
+	return sortedMembers(record.members), nil
+}
+
+// Ping проверяет доступность хранилища. Хранилище в памяти доступно всегда,
+// пока не отменен ctx.
+func (ms *MemoryStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func sortedMembers(members map[string]struct{}) []string {
+	result := make([]string, 0, len(members))
+	for member := range members {
+		result = append(result, member)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// newMessageID генерирует случайный идентификатор сообщения.
+func newMessageID() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
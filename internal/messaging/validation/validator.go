@@ -0,0 +1,133 @@
+package validation
+
+import (
+	"fmt"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxConversationLength — максимальная длина идентификатора беседы в символах.
+const maxConversationLength = 128
+
+// Ограничения вложений сообщения.
+const (
+	// maxAttachments — максимальное число вложений в одном сообщении.
+	maxAttachments = 10
+	// maxAttachmentIDLength — максимальная длина идентификатора вложения в символах.
+	maxAttachmentIDLength = 64
+)
+
+// Validator проверяет сообщения клиентов после разбора: тип сообщения, обязательные
+// для типа поля, длину текста и отсутствие полей, которые заполняет только сервер.
+type Validator struct {
+	maxTextLength int
+}
+
+type Options struct {
+	// MaxTextLength — максимальная длина текста сообщения в символах. 0 — без ограничения.
+	MaxTextLength int
+}
+
+// New создает Validator с указанными ограничениями.
+func New(options Options) *Validator {
+	return &Validator{
+		maxTextLength: options.MaxTextLength,
+	}
+}
+
+// Validate проверяет сообщение клиента и возвращает *msg.ValidationError со всеми
+// неверными полями или nil, если сообщение корректно.
+//
+// Правила:
+//   - type — один из типов сообщений клиента из реестра типов (msg.ClientTypeNames);
+//   - text — обязателен, кроме сообщений с вложениями, в кодировке UTF-8 и не длиннее MaxTextLength символов;
+//   - conversation — если задан, не длиннее 128 символов и без пробельных и управляющих символов;
+//   - attachments — только в сообщениях с данными в беседу, не больше 10; у каждого вложения
+//     задан только id, остальные поля заполняет сервер;
+//   - id, sent_at, errors и previews заполняет только сервер, клиент не может их передавать.
+func (v *Validator) Validate(message msg.Message) error {
+	var fields []msg.FieldError
+	add := func(field, code, text string) {
+		fields = append(fields, msg.FieldError{Field: field, Code: code, Message: text})
+	}
+
+	if !message.Type.IsClient() {
+		add("type", msg.CodeInvalidValue, "неизвестный тип сообщения, допустимые: "+strings.Join(msg.ClientTypeNames(), ", "))
+	}
+
+	switch {
+	case message.Text == "" && len(message.Attachments) == 0:
+		add("text", msg.CodeRequired, "поле обязательно")
+	case !utf8.ValidString(message.Text):
+		add("text", msg.CodeInvalidValue, "текст должен быть в кодировке UTF-8")
+	case v.maxTextLength > 0 && utf8.RuneCountInString(message.Text) > v.maxTextLength:
+		add("text", msg.CodeTooLong, fmt.Sprintf("текст длиннее %d символов", v.maxTextLength))
+	}
+
+	if message.Conversation != "" {
+		if utf8.RuneCountInString(message.Conversation) > maxConversationLength {
+			add("conversation", msg.CodeTooLong, fmt.Sprintf("идентификатор беседы длиннее %d символов", maxConversationLength))
+		} else if !validIdentifier(message.Conversation) {
+			add("conversation", msg.CodeInvalidValue, "идентификатор беседы не может содержать пробельные и управляющие символы")
+		}
+	}
+
+	if len(message.Attachments) > 0 {
+		switch {
+		case message.Type != msg.DataMessage:
+			add("attachments", msg.CodeInvalidValue, "вложения допустимы только в сообщениях с данными")
+		case message.Conversation == "":
+			add("attachments", msg.CodeInvalidValue, "вложения можно отправить только в беседу")
+		case len(message.Attachments) > maxAttachments:
+			add("attachments", msg.CodeTooLong, fmt.Sprintf("больше %d вложений", maxAttachments))
+		}
+		for i, attachment := range message.Attachments {
+			field := fmt.Sprintf("attachments[%d]", i)
+			switch {
+			case attachment.ID == "":
+				add(field+".id", msg.CodeRequired, "поле обязательно")
+			case utf8.RuneCountInString(attachment.ID) > maxAttachmentIDLength || !validIdentifier(attachment.ID):
+				add(field+".id", msg.CodeInvalidValue, "некорректный идентификатор вложения")
+			}
+			if attachment.Name != "" || attachment.ContentType != "" || attachment.Size != 0 || attachment.URL != "" ||
+				attachment.Width != 0 || attachment.Height != 0 || attachment.Placeholder != "" ||
+				len(attachment.Thumbnails) > 0 {
+				add(field, msg.CodeForbidden, "описание вложения заполняет сервер, передается только id")
+			}
+		}
+	}
+
+	if message.ID != "" {
+		add("id", msg.CodeForbidden, "идентификатор присваивает сервер")
+	}
+	if message.SentAt != 0 {
+		add("sent_at", msg.CodeForbidden, "время отправки присваивает сервер")
+	}
+	if len(message.Errors) > 0 {
+		add("errors", msg.CodeForbidden, "поле заполняет только сервер")
+	}
+	if len(message.Previews) > 0 {
+		add("previews", msg.CodeForbidden, "превью ссылок загружает сервер")
+	}
+
+	if len(fields) > 0 {
+		return &msg.ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// validIdentifier сообщает, состоит ли идентификатор из допустимых символов UTF-8
+// без пробельных и управляющих символов.
+func validIdentifier(id string) bool {
+	if !utf8.ValidString(id) {
+		return false
+	}
+	for _, r := range id {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
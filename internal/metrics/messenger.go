@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultBuckets — границы корзин гистограммы длительностей в секундах,
+// рассчитанные на обработку сообщения от десятков микросекунд до секунд.
+var DefaultBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// Значения метки transport.
+const (
+	TransportWebSocket = "websocket"
+	TransportFallback  = "fallback"
+	TransportREST      = "rest"
+)
+
+// Значения метки result загрузок страниц для превью ссылок.
+const (
+	LinkPreviewOK      = "ok"
+	LinkPreviewFailed  = "failed"
+	LinkPreviewBlocked = "blocked"
+)
+
+// Значения метки reason отклоненных апгрейдов.
+const (
+	RejectReasonOrigin    = "origin"
+	RejectReasonAuth      = "auth"
+	RejectReasonHandshake = "handshake"
+	RejectReasonDraining  = "draining"
+	RejectReasonCapacity  = "capacity"
+	RejectReasonIPLimit   = "ip_limit"
+	RejectReasonUserLimit = "user_limit"
+)
+
+var (
+	ActiveConnections = factory.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "messenger_active_connections",
+			Help: "Число открытых клиентских соединений.",
+		},
+		[]string{"transport"},
+	)
+	UpgradesAccepted = factory.NewCounter(prometheus.CounterOpts{
+		Name: "messenger_ws_upgrades_accepted_total",
+		Help: "Число успешных апгрейдов до WebSocket.",
+	})
+	UpgradesRejected = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messenger_ws_upgrades_rejected_total",
+			Help: "Число отклоненных запросов на апгрейд до WebSocket по причинам.",
+		},
+		[]string{"reason"},
+	)
+	MessagesReceived = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messenger_messages_received_total",
+			Help: "Число полученных от клиентов сообщений по типам.",
+		},
+		[]string{"transport", "type"},
+	)
+	MessagesSent = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messenger_messages_sent_total",
+			Help: "Число отправленных клиентам сообщений по типам.",
+		},
+		[]string{"transport", "type"},
+	)
+	MessagesInvalid = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messenger_messages_invalid_total",
+			Help: "Число отклоненных некорректных сообщений клиентов по кодам ошибок проверки.",
+		},
+		[]string{"transport", "code"},
+	)
+	ProcessingDuration = factory.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "messenger_message_processing_seconds",
+			Help:    "Длительность обработки сообщения в ProcessMessage.",
+			Buckets: DefaultBuckets,
+		},
+		[]string{"type"},
+	)
+	SendErrors = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messenger_send_errors_total",
+			Help: "Число ошибок отправки сообщений клиентам.",
+		},
+		[]string{"transport"},
+	)
+	CloseCodes = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messenger_ws_close_codes_total",
+			Help: "Число закрытий WebSocket-соединений по кодам закрытия.",
+		},
+		[]string{"code"},
+	)
+	RateLimited = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messenger_rate_limited_total",
+			Help: "Число сообщений клиентов, отклоненных ограничением частоты, по областям лимитов.",
+		},
+		[]string{"transport", "scope"},
+	)
+	RateLimitCloses = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messenger_rate_limit_closes_total",
+			Help: "Число соединений, закрытых за постоянное превышение лимитов частоты сообщений.",
+		},
+		[]string{"transport"},
+	)
+	ClusterNodes = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "messenger_cluster_nodes",
+		Help: "Число узлов кластера в каталоге подключений, включая текущий.",
+	})
+	ClusterLeasesExpired = factory.NewCounter(prometheus.CounterOpts{
+		Name: "messenger_cluster_leases_expired_total",
+		Help: "Число узлов, исключенных из каталога подключений по истечении аренды.",
+	})
+	BusMessagesDropped = factory.NewCounter(prometheus.CounterOpts{
+		Name: "messenger_bus_messages_dropped_total",
+		Help: "Число сообщений шины Redis, отброшенных из-за заполненной очереди подписчика.",
+	})
+	LinkPreviewFetches = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messenger_link_preview_fetches_total",
+			Help: "Число загрузок страниц для превью ссылок по результатам.",
+		},
+		[]string{"result"},
+	)
+	LinkPreviewsDropped = factory.NewCounter(prometheus.CounterOpts{
+		Name: "messenger_link_previews_dropped_total",
+		Help: "Число сообщений, пропущенных из-за заполненной очереди загрузки превью.",
+	})
+	TLSHandshakeFailures = factory.NewCounter(prometheus.CounterOpts{
+		Name: "messenger_tls_handshake_failures_total",
+		Help: "Число неудачных TLS-рукопожатий.",
+	})
+)
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default — реестр метрик сервера, отдаваемый эндпоинтом /metrics. Кроме метрик
+// мессенджера в нем зарегистрированы стандартные метрики среды выполнения Go и процесса.
+var Default = prometheus.NewRegistry()
+
+// factory создает метрики мессенджера и регистрирует их в реестре Default.
+var factory = promauto.With(Default)
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler возвращает HTTP-обработчик, отдающий метрики реестра Default в формате
+// экспозиции Prometheus. Ошибки сбора метрик записываются в журнал logger.
+func Handler(logger *slog.Logger) http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{
+		ErrorLog:      slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
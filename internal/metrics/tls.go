@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+)
+
+// tlsHandshakeError — префикс записи журнала http.Server о неудачном TLS-рукопожатии.
+var tlsHandshakeError = []byte("http: TLS handshake error")
+
+// tlsErrorWriter пропускает записи журнала http.Server в out и считает
+// среди них сообщения о неудачных TLS-рукопожатиях.
+type tlsErrorWriter struct {
+	out io.Writer
+}
+
+// NewTLSErrorWriter возвращает io.Writer для http.Server.ErrorLog, который
+// учитывает неудачные TLS-рукопожатия в TLSHandshakeFailures. Сам http.Server
+// сообщает о таких ошибках только записью в журнал.
+func NewTLSErrorWriter(out io.Writer) io.Writer {
+	return &tlsErrorWriter{out: out}
+}
+
+func (w *tlsErrorWriter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, tlsHandshakeError) {
+		TLSHandshakeFailures.Inc()
+	}
+	return w.out.Write(p)
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"messenger/internal/ws/traffic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	payloadBytesDesc = prometheus.NewDesc(
+		"messenger_ws_payload_bytes_total",
+		"Размер полезной нагрузки сообщений закрытых WebSocket-соединений до сжатия.",
+		[]string{"direction"}, nil,
+	)
+	wireBytesDesc = prometheus.NewDesc(
+		"messenger_ws_wire_bytes_total",
+		"Число байт закрытых WebSocket-соединений, переданных по сети.",
+		[]string{"direction"}, nil,
+	)
+)
+
+// trafficCollector отдает счетчики трафика WebSocket-соединений, которые уже
+// накапливаются в traffic.Total, считывая их в момент запроса метрик.
+type trafficCollector struct{}
+
+func init() {
+	Default.MustRegister(trafficCollector{})
+}
+
+func (trafficCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- payloadBytesDesc
+	descs <- wireBytesDesc
+}
+
+func (trafficCollector) Collect(metrics chan<- prometheus.Metric) {
+	snapshot := traffic.Total.Snapshot()
+	collectDirections(metrics, payloadBytesDesc, snapshot.PayloadRead, snapshot.PayloadWritten)
+	collectDirections(metrics, wireBytesDesc, snapshot.WireRead, snapshot.WireWritten)
+}
+
+func collectDirections(metrics chan<- prometheus.Metric, desc *prometheus.Desc, read, written uint64) {
+	metrics <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(read), "read")
+	metrics <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(written), "written")
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// Rule — параметры корзины токенов: Rate токенов в секунду и емкость Burst.
+// Каждое сообщение расходует один токен.
+type Rule struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultKey — ключ правила в Rules, применяемого к типам сообщений без собственного правила.
+const DefaultKey = "default"
+
+// InvalidKey — ключ правила в Rules для некорректных сообщений, не прошедших проверку.
+// Если правила с этим ключом нет, к ним применяется правило по умолчанию.
+const InvalidKey = "invalid"
+
+// Rules — правила лимитов по именам типов сообщений клиента (msg.ClientTypeNames),
+// DefaultKey и InvalidKey.
+type Rules map[string]Rule
+
+// lookup возвращает правило для типа сообщения typeName или правило по умолчанию.
+func (r Rules) lookup(typeName string) (Rule, bool) {
+	if rule, ok := r[typeName]; ok {
+		return rule, true
+	}
+	rule, ok := r[DefaultKey]
+	return rule, ok
+}
+
+// bucket — корзина токенов. Не потокобезопасна: доступ синхронизирует владелец.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill пополняет корзину по правилу rule на момент now. Новая корзина заполнена полностью.
+func (b *bucket) refill(rule Rule, now time.Time) {
+	if b.last.IsZero() {
+		b.tokens = float64(rule.Burst)
+	} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(float64(rule.Burst), b.tokens+elapsed*rule.Rate)
+	}
+	b.last = now
+}
+
+// full сообщает, пополнилась бы корзина полностью к моменту now. Полные корзины
+// не отличаются от новых, поэтому их можно удалять.
+func (b *bucket) full(rule Rule, now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*rule.Rate >= float64(rule.Burst)
+}
+
+// buckets — корзины по ключу (пользователь, IP или соединение) и имени типа сообщения.
+type buckets map[string]map[string]*bucket
+
+func (bs buckets) get(key, typeName string) *bucket {
+	byType, ok := bs[key]
+	if !ok {
+		byType = make(map[string]*bucket)
+		bs[key] = byType
+	}
+	b, ok := byType[typeName]
+	if !ok {
+		b = &bucket{}
+		byType[typeName] = b
+	}
+	return b
+}
+
+// sweep удаляет корзины, которые к моменту now пополнились бы полностью.
+func (bs buckets) sweep(rules Rules, now time.Time) {
+	for key, byType := range bs {
+		for typeName, b := range byType {
+			rule, _ := rules.lookup(typeName)
+			if b.full(rule, now) {
+				delete(byType, typeName)
+			}
+		}
+		if len(byType) == 0 {
+			delete(bs, key)
+		}
+	}
+}
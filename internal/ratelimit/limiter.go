@@ -0,0 +1,185 @@
+package ratelimit
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// sweepInterval — период удаления неиспользуемых корзин пользователей и IP-адресов.
+const sweepInterval = time.Minute
+
+// Области действия лимитов.
+const (
+	ScopeConnection = "connection"
+	ScopeUser       = "user"
+	ScopeIP         = "ip"
+)
+
+// Decision — результат проверки лимитов для одного сообщения.
+type Decision int
+
+const (
+	// Allowed — сообщение укладывается во все лимиты.
+	Allowed Decision = iota
+	// Throttled — сообщение превышает лимит и должно быть отклонено.
+	Throttled
+	// Abusive — клиент превышает лимиты постоянно, соединение нужно закрыть.
+	Abusive
+)
+
+type Options struct {
+	// Connection — лимиты одного соединения.
+	Connection Rules
+	// User — лимиты пользователя, общие для всех его соединений.
+	User Rules
+	// IP — лимиты IP-адреса, общие для всех соединений с него. Адрес берется
+	// из адреса TCP-соединения (см. ClientIP), поэтому за обратным прокси все клиенты
+	// делят лимиты адреса прокси.
+	IP Rules
+	// MaxViolations — число отклоненных сообщений за ViolationWindow,
+	// после которого соединение закрывается.
+	MaxViolations int
+	// ViolationWindow — окно подсчета отклоненных сообщений.
+	ViolationWindow time.Duration
+	// Now возвращает текущее время. Если не задано, используется time.Now.
+	Now func() time.Time
+}
+
+// Limiter ограничивает частоту сообщений клиентов корзинами токенов на уровне
+// соединения, пользователя и IP-адреса, отдельно для каждого типа сообщений.
+// Корзины пользователей и IP-адресов общие для всех соединений.
+type Limiter struct {
+	mu        sync.Mutex
+	options   Options
+	users     buckets
+	ips       buckets
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// New создает Limiter с указанными правилами.
+func New(options Options) *Limiter {
+	now := options.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &Limiter{
+		options:   options,
+		users:     make(buckets),
+		ips:       make(buckets),
+		now:       now,
+		lastSweep: now(),
+	}
+}
+
+// NewConnection создает ограничитель одного соединения пользователя userID с адреса ip.
+// Ограничитель соединения не потокобезопасен и предназначен для цикла обработки
+// сообщений этого соединения. Для nil Limiter (ограничение выключено) возвращает nil,
+// который пропускает все сообщения.
+func (l *Limiter) NewConnection(userID, ip string) *Connection {
+	if l == nil {
+		return nil
+	}
+	return &Connection{
+		limiter: l,
+		userID:  userID,
+		ip:      ip,
+		own:     make(buckets),
+	}
+}
+
+// Connection — ограничитель сообщений одного соединения.
+type Connection struct {
+	limiter     *Limiter
+	userID      string
+	ip          string
+	own         buckets
+	violations  int
+	windowStart time.Time
+}
+
+// Allow проверяет, можно ли обработать сообщение типа typeName. Токен расходуется
+// только если сообщение укладывается в лимиты всех областей. Возвращает область,
+// лимит которой превышен, если сообщение отклонено.
+func (c *Connection) Allow(typeName string) (Decision, string) {
+	if c == nil {
+		return Allowed, ""
+	}
+	now := c.limiter.now()
+	scope := c.limiter.take(c, typeName, now)
+	if scope == "" {
+		return Allowed, ""
+	}
+
+	if now.Sub(c.windowStart) > c.limiter.options.ViolationWindow {
+		c.windowStart = now
+		c.violations = 0
+	}
+	c.violations++
+	if c.limiter.options.MaxViolations > 0 && c.violations >= c.limiter.options.MaxViolations {
+		return Abusive, scope
+	}
+	return Throttled, scope
+}
+
+type scopedBucket struct {
+	scope  string
+	rule   Rule
+	bucket *bucket
+}
+
+// take пополняет корзины соединения, пользователя и IP-адреса и, если во всех есть
+// токен, расходует по одному. Возвращает область первой пустой корзины или пустую строку.
+func (l *Limiter) take(c *Connection, typeName string, now time.Time) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	var selected []scopedBucket
+	if rule, ok := l.options.Connection.lookup(typeName); ok {
+		selected = append(selected, scopedBucket{ScopeConnection, rule, c.own.get("", typeName)})
+	}
+	if rule, ok := l.options.User.lookup(typeName); ok && c.userID != "" {
+		selected = append(selected, scopedBucket{ScopeUser, rule, l.users.get(c.userID, typeName)})
+	}
+	if rule, ok := l.options.IP.lookup(typeName); ok && c.ip != "" {
+		selected = append(selected, scopedBucket{ScopeIP, rule, l.ips.get(c.ip, typeName)})
+	}
+
+	for _, sb := range selected {
+		sb.bucket.refill(sb.rule, now)
+		if sb.bucket.tokens < 1 {
+			return sb.scope
+		}
+	}
+	for _, sb := range selected {
+		sb.bucket.tokens--
+	}
+	return ""
+}
+
+// sweep периодически удаляет полные корзины пользователей и IP-адресов,
+// чтобы память не росла с числом когда-либо подключавшихся клиентов.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	l.users.sweep(l.options.User, now)
+	l.ips.sweep(l.options.IP, now)
+}
+
+// ClientIP возвращает IP-адрес из адреса клиента вида host:port (http.Request.RemoteAddr).
+// Если порт не указан, адрес возвращается без изменений. Заголовки X-Forwarded-For
+// и Forwarded не учитываются: их может подделать любой клиент. Если сервер работает
+// за обратным прокси, лимиты и ограничения соединений по IP-адресу относятся к адресу
+// прокси, и их следует отключить или настроить на прокси.
+func ClientIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
@@ -0,0 +1,231 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock — управляемый тестом источник времени для Options.Now.
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// expectAllowed проверяет, что n сообщений типа typeName подряд укладываются в лимиты.
+func expectAllowed(t *testing.T, c *Connection, typeName string, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		if decision, scope := c.Allow(typeName); decision != Allowed {
+			t.Fatalf("Сообщение %d типа %s отклонено лимитом %s", i+1, typeName, scope)
+		}
+	}
+}
+
+// expectThrottled проверяет, что сообщение типа typeName отклоняется лимитом области scope.
+func expectThrottled(t *testing.T, c *Connection, typeName, scope string) {
+	t.Helper()
+
+	if decision, got := c.Allow(typeName); decision != Throttled || got != scope {
+		t.Fatalf("Получено решение %d области %q, ожидалось отклонение лимитом %s", decision, got, scope)
+	}
+}
+
+func TestBucketRefillAndBurst(t *testing.T) {
+	clock := newFakeClock()
+	limiter := New(Options{
+		Connection: Rules{DefaultKey: {Rate: 2, Burst: 3}},
+		Now:        clock.Now,
+	})
+	conn := limiter.NewConnection("alice", "10.0.0.1")
+
+	// Новая корзина заполнена полностью: проходит Burst сообщений подряд.
+	expectAllowed(t, conn, "data", 3)
+	expectThrottled(t, conn, "data", ScopeConnection)
+
+	// За полсекунды при Rate 2 появляется один токен.
+	clock.Advance(500 * time.Millisecond)
+	expectAllowed(t, conn, "data", 1)
+	expectThrottled(t, conn, "data", ScopeConnection)
+
+	// Корзина не пополняется сверх Burst, сколько бы времени ни прошло.
+	clock.Advance(time.Hour)
+	expectAllowed(t, conn, "data", 3)
+	expectThrottled(t, conn, "data", ScopeConnection)
+}
+
+func TestRulesByMessageType(t *testing.T) {
+	clock := newFakeClock()
+	limiter := New(Options{
+		Connection: Rules{
+			DefaultKey: {Rate: 1, Burst: 1},
+			"data":     {Rate: 1, Burst: 3},
+		},
+		Now: clock.Now,
+	})
+	conn := limiter.NewConnection("alice", "10.0.0.1")
+
+	// У каждого типа своя корзина; типы без правила используют правило по умолчанию,
+	// некорректные сообщения без правила invalid — тоже.
+	expectAllowed(t, conn, "data", 3)
+	expectThrottled(t, conn, "data", ScopeConnection)
+	expectAllowed(t, conn, "info", 1)
+	expectThrottled(t, conn, "info", ScopeConnection)
+	expectAllowed(t, conn, InvalidKey, 1)
+	expectThrottled(t, conn, InvalidKey, ScopeConnection)
+}
+
+func TestScopes(t *testing.T) {
+	rule := Rules{DefaultKey: {Rate: 1, Burst: 2}}
+
+	t.Run("connection", func(t *testing.T) {
+		limiter := New(Options{Connection: rule, Now: newFakeClock().Now})
+		first := limiter.NewConnection("alice", "10.0.0.1")
+		second := limiter.NewConnection("alice", "10.0.0.1")
+
+		// Лимит соединения не делится с другими соединениями того же пользователя и адреса.
+		expectAllowed(t, first, "data", 2)
+		expectThrottled(t, first, "data", ScopeConnection)
+		expectAllowed(t, second, "data", 2)
+	})
+
+	t.Run("user", func(t *testing.T) {
+		limiter := New(Options{User: rule, Now: newFakeClock().Now})
+		first := limiter.NewConnection("alice", "10.0.0.1")
+		second := limiter.NewConnection("alice", "10.0.0.2")
+		other := limiter.NewConnection("bob", "10.0.0.1")
+
+		// Соединения пользователя с разных адресов делят одну корзину.
+		expectAllowed(t, first, "data", 1)
+		expectAllowed(t, second, "data", 1)
+		expectThrottled(t, first, "data", ScopeUser)
+		expectThrottled(t, second, "data", ScopeUser)
+		expectAllowed(t, other, "data", 2)
+	})
+
+	t.Run("ip", func(t *testing.T) {
+		limiter := New(Options{IP: rule, Now: newFakeClock().Now})
+		first := limiter.NewConnection("alice", "10.0.0.1")
+		second := limiter.NewConnection("bob", "10.0.0.1")
+		other := limiter.NewConnection("alice", "10.0.0.2")
+
+		// Соединения разных пользователей с одного адреса делят одну корзину.
+		expectAllowed(t, first, "data", 1)
+		expectAllowed(t, second, "data", 1)
+		expectThrottled(t, first, "data", ScopeIP)
+		expectThrottled(t, second, "data", ScopeIP)
+		expectAllowed(t, other, "data", 2)
+	})
+
+	t.Run("token taken only when all scopes allow", func(t *testing.T) {
+		limiter := New(Options{
+			Connection: Rules{DefaultKey: {Rate: 1, Burst: 5}},
+			User:       Rules{DefaultKey: {Rate: 1, Burst: 1}},
+			Now:        newFakeClock().Now,
+		})
+		first := limiter.NewConnection("alice", "10.0.0.1")
+		other := limiter.NewConnection("bob", "10.0.0.1")
+
+		expectAllowed(t, first, "data", 1)
+		expectThrottled(t, first, "data", ScopeUser)
+		expectThrottled(t, first, "data", ScopeUser)
+
+		// Отклоненные сообщения не расходуют токены соединения: после снятия лимита
+		// пользователя проходят оставшиеся четыре.
+		limiter.options.User = nil
+		expectAllowed(t, first, "data", 4)
+		expectAllowed(t, other, "data", 5)
+	})
+}
+
+func TestViolations(t *testing.T) {
+	clock := newFakeClock()
+	limiter := New(Options{
+		Connection:      Rules{DefaultKey: {Rate: 1, Burst: 1}},
+		MaxViolations:   3,
+		ViolationWindow: 10 * time.Second,
+		Now:             clock.Now,
+	})
+	conn := limiter.NewConnection("alice", "10.0.0.1")
+
+	expectAllowed(t, conn, "data", 1)
+	expectThrottled(t, conn, "data", ScopeConnection)
+	expectThrottled(t, conn, "data", ScopeConnection)
+
+	// Нарушения за пределами окна не накапливаются.
+	clock.Advance(11 * time.Second)
+	expectAllowed(t, conn, "data", 1)
+	expectThrottled(t, conn, "data", ScopeConnection)
+	expectThrottled(t, conn, "data", ScopeConnection)
+	if decision, scope := conn.Allow("data"); decision != Abusive || scope != ScopeConnection {
+		t.Fatalf("Получено решение %d области %q, ожидалось закрытие соединения", decision, scope)
+	}
+}
+
+func TestIdleBucketsEvicted(t *testing.T) {
+	clock := newFakeClock()
+	limiter := New(Options{
+		User: Rules{DefaultKey: {Rate: 1, Burst: 1}},
+		IP:   Rules{"data": {Rate: 0.001, Burst: 1}},
+		Now:  clock.Now,
+	})
+	alice := limiter.NewConnection("alice", "10.0.0.1")
+	bob := limiter.NewConnection("bob", "10.0.0.2")
+
+	expectAllowed(t, alice, "data", 1)
+	expectAllowed(t, bob, "info", 1)
+	if len(limiter.users) != 2 || len(limiter.ips) != 1 {
+		t.Fatalf("Корзин пользователей %d, IP-адресов %d, ожидалось 2 и 1", len(limiter.users), len(limiter.ips))
+	}
+
+	// До истечения sweepInterval корзины не удаляются, даже если уже пополнились.
+	clock.Advance(sweepInterval / 2)
+	expectAllowed(t, bob, "info", 1)
+	if len(limiter.users) != 2 {
+		t.Fatalf("Корзин пользователей %d, ожидалось 2", len(limiter.users))
+	}
+
+	// Корзина пользователя alice пополнилась и удаляется; корзина IP-адреса при Rate 0.001
+	// за полторы минуты не пополняется и остается.
+	clock.Advance(sweepInterval)
+	expectAllowed(t, bob, "info", 1)
+	if _, ok := limiter.users["alice"]; ok {
+		t.Fatal("Корзина неактивного пользователя не удалена")
+	}
+	if _, ok := limiter.ips["10.0.0.1"]; !ok {
+		t.Fatal("Неполная корзина IP-адреса удалена")
+	}
+
+	// Удаленная корзина создается заново полной.
+	expectAllowed(t, alice, "info", 1)
+}
+
+func TestClientIP(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.1:5000":    "10.0.0.1",
+		"[2001:db8::1]:80": "2001:db8::1",
+		"10.0.0.1":         "10.0.0.1",
+	}
+	for remoteAddr, want := range tests {
+		if got := ClientIP(remoteAddr); got != want {
+			t.Errorf("ClientIP(%q) = %q, ожидалось %q", remoteAddr, got, want)
+		}
+	}
+}
+
+func TestNilLimiterAllowsEverything(t *testing.T) {
+	var limiter *Limiter
+	conn := limiter.NewConnection("alice", "10.0.0.1")
+	expectAllowed(t, conn, "data", 100)
+}
@@ -0,0 +1,441 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"log/slog"
+	authinterfaces "messenger/internal/auth/interfaces"
+	authmodels "messenger/internal/auth/models"
+	"messenger/internal/logging"
+	"messenger/internal/messaging/interfaces"
+	"messenger/internal/messaging/store"
+	"messenger/internal/messaging/validation"
+	"messenger/internal/metrics"
+	serverinterfaces "messenger/internal/server/interfaces"
+	"messenger/internal/tracing"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxRequestBodySize — максимальный размер тела запроса REST API.
+	maxRequestBodySize = 64 << 10
+	// defaultHistoryLimit — число сообщений истории, возвращаемых по умолчанию.
+	defaultHistoryLimit = 50
+	// maxHistoryLimit — максимальное число сообщений истории в одном ответе.
+	maxHistoryLimit = 200
+	// maxPresenceUsers — максимальное число пользователей в одном запросе присутствия.
+	maxPresenceUsers = 100
+	// maxInvitedUsers — максимальное число пользователей в одном приглашении в беседу.
+	maxInvitedUsers = 100
+)
+
+// RESTHandler обслуживает HTTP JSON API для сервисов, которым не нужно
+// держать WebSocket-соединение:
+//   - POST {prefix}/conversations/{id}/messages — отправка сообщения в беседу (текст и вложения);
+//   - POST {prefix}/conversations/{id}/members  — приглашение пользователей в беседу;
+//   - GET  {prefix}/conversations               — список бесед пользователя;
+//   - GET  {prefix}/conversations/{id}/messages — история беседы (параметры limit и before);
+//   - POST {prefix}/users/{id}/messages         — личное сообщение пользователю;
+//   - GET  {prefix}/presence                    — подключены ли пользователи (параметры user);
+//   - GET  {prefix}/me                          — личность клиента.
+//
+// Клиенты аутентифицируются так же, как WebSocket-клиенты, сообщения проверяются тем же
+// Validator, а отправленные в беседы сообщения проходят через тот же MessageProcessor:
+// сохраняются и доставляются участникам беседы. Участниками беседы становятся ее создатель
+// (отправитель первого сообщения) и приглашенные участниками пользователи; писать в беседу
+// и читать ее историю могут только они. Сервисы (auth.users[].service) пишут в любую
+// беседу, не становясь ее участниками.
+// Личные сообщения не сохраняются и доставляются только подключенным соединениям получателя.
+type RESTHandler struct {
+	authenticator    authinterfaces.Authenticator
+	messageProcessor interfaces.MessageProcessor
+	store            interfaces.ConversationStore
+	router           interfaces.MessageRouter
+	directory        interfaces.Directory
+	validator        *validation.Validator
+	logger           *slog.Logger
+}
+
+type Options struct {
+	Authenticator    authinterfaces.Authenticator
+	MessageProcessor interfaces.MessageProcessor
+	Store            interfaces.ConversationStore
+	// Router доставляет личные сообщения. Если не задан, эндпоинт личных сообщений не регистрируется.
+	Router interfaces.MessageRouter
+	// Directory — каталог подключений кластера. Если не задан, эндпоинт присутствия не регистрируется.
+	Directory interfaces.Directory
+	// Validator проверяет сообщения клиента по тем же правилам, что и сообщения WebSocket-клиентов.
+	// Если не задан, проверяются правила без ограничения длины текста.
+	Validator *validation.Validator
+	// Logger — логгер обработчика. Если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+func New(options Options) *RESTHandler {
+	validator := options.Validator
+	if validator == nil {
+		validator = validation.New(validation.Options{})
+	}
+	rh := &RESTHandler{
+		authenticator:    options.Authenticator,
+		messageProcessor: options.MessageProcessor,
+		store:            options.Store,
+		router:           options.Router,
+		directory:        options.Directory,
+		validator:        validator,
+	}
+	rh.logger = logging.Component(options.Logger, rh.Tag())
+	return rh
+}
+
+// Tag возвращает строковый идентификатор для RESTHandler.
+func (*RESTHandler) Tag() string {
+	return "REST_HANDLER"
+}
+
+// Register регистрирует обработчики REST API в группе маршрутов.
+func (rh *RESTHandler) Register(routes serverinterfaces.Routes) {
+	routes.HandleFunc("POST /conversations/{id}/messages", rh.authenticated(rh.handleSendMessage))
+	routes.HandleFunc("POST /conversations/{id}/members", rh.authenticated(rh.handleInvite))
+	routes.HandleFunc("GET /conversations", rh.authenticated(rh.handleListConversations))
+	routes.HandleFunc("GET /conversations/{id}/messages", rh.authenticated(rh.handleHistory))
+	if rh.router != nil {
+		routes.HandleFunc("POST /users/{id}/messages", rh.authenticated(rh.handleSendDirect))
+	}
+	if rh.directory != nil {
+		routes.HandleFunc("GET /presence", rh.authenticated(rh.handlePresence))
+	}
+	routes.HandleFunc("GET /me", rh.authenticated(rh.handleMe))
+}
+
+type sendMessageRequest struct {
+	Text string `json:"text"`
+}
+
+type sendConversationMessageRequest struct {
+	Text string `json:"text"`
+	// Attachments — идентификаторы загруженных вложений.
+	Attachments []string `json:"attachments"`
+}
+
+type inviteRequest struct {
+	// Users — идентификаторы приглашаемых пользователей.
+	Users []string `json:"users"`
+}
+
+type meResponse struct {
+	User      string `json:"user"`
+	Anonymous bool   `json:"anonymous"`
+}
+
+type messageResponse struct {
+	ID           string           `json:"id"`
+	Conversation string           `json:"conversation"`
+	From         string           `json:"from"`
+	Text         string           `json:"text"`
+	SentAt       int64            `json:"sent_at"`
+	Attachments  []msg.Attachment `json:"attachments,omitempty"`
+	// Previews — превью ссылок из текста, если сервер уже успел их загрузить.
+	Previews []msg.LinkPreview `json:"previews,omitempty"`
+}
+
+type directResponse struct {
+	To string `json:"to"`
+	// Online — был ли получатель подключен в момент отправки.
+	Online bool `json:"online"`
+}
+
+type presenceResponse struct {
+	User   string `json:"user"`
+	Online bool   `json:"online"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// authenticated аутентифицирует запрос и передает личность клиента обработчику.
+// Неаутентифицированным клиентам отвечает 401.
+func (rh *RESTHandler) authenticated(
+	next func(w http.ResponseWriter, r *http.Request, identity authmodels.Identity),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, err := rh.authenticator.Authenticate(r)
+		if err != nil {
+			rh.writeError(w, http.StatusUnauthorized, "Требуется аутентификация")
+			return
+		}
+		next(w, r, identity)
+	}
+}
+
+// requestLogger возвращает логгер запроса с адресом клиента и идентификатором пользователя.
+func (rh *RESTHandler) requestLogger(r *http.Request, identity authmodels.Identity) *slog.Logger {
+	return rh.logger.With(
+		slog.String(logging.KeyRemoteAddr, r.RemoteAddr),
+		slog.String(logging.KeyUserID, identity.UserID),
+	)
+}
+
+// handleSendMessage отправляет сообщение с данными в беседу от имени клиента.
+// Сообщение может содержать вложения, загруженные клиентом; тогда текст необязателен.
+// Некорректное сообщение отклоняется с кодом 400 и списком неверных полей.
+// Если клиент передал заголовок traceparent, обработка сообщения продолжает его трассу.
+func (rh *RESTHandler) handleSendMessage(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	var request sendConversationMessageRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err := decoder.Decode(&request); err != nil {
+		rh.writeError(w, http.StatusBadRequest, "Некорректное тело запроса")
+		return
+	}
+
+	message := msg.NewDataMessage(request.Text)
+	message.Conversation = r.PathValue("id")
+	for _, attachmentID := range request.Attachments {
+		message.Attachments = append(message.Attachments, msg.Attachment{ID: attachmentID})
+	}
+	if !rh.validate(w, r, identity, message) {
+		return
+	}
+	message.From = identity.UserID
+	tracing.Inject(tracing.ExtractHTTP(r), &message)
+
+	responseMessage, err := rh.messageProcessor.ProcessMessage(message)
+	if err != nil {
+		rh.requestLogger(r, identity).Error("Ошибка при обработке сообщения",
+			logging.MessageType(message.Type), slog.Any("error", err))
+		rh.writeError(w, http.StatusInternalServerError, "Не удалось отправить сообщение")
+		return
+	}
+	if len(responseMessage.Errors) > 0 {
+		validationErr := &msg.ValidationError{Fields: responseMessage.Errors}
+		status := http.StatusBadRequest
+		if slices.ContainsFunc(responseMessage.Errors, func(field msg.FieldError) bool {
+			return field.Code == msg.CodeForbidden && field.Field == "conversation"
+		}) {
+			status = http.StatusForbidden
+		}
+		rh.writeError(w, status, validationErr.Error())
+		return
+	}
+
+	rh.writeJSON(w, http.StatusCreated, messageResponse{
+		ID:           responseMessage.ID,
+		Conversation: message.Conversation,
+		From:         message.From,
+		Text:         message.Text,
+		SentAt:       responseMessage.SentAt,
+		Attachments:  responseMessage.Attachments,
+	})
+}
+
+// validate проверяет сообщение клиента валидатором. Если сообщение некорректно,
+// отвечает кодом 400 со списком неверных полей и возвращает false.
+func (rh *RESTHandler) validate(w http.ResponseWriter, r *http.Request, identity authmodels.Identity, message msg.Message) bool {
+	err := rh.validator.Validate(message)
+	var validationErr *msg.ValidationError
+	if !errors.As(err, &validationErr) {
+		return true
+	}
+	metrics.MessagesInvalid.WithLabelValues(metrics.TransportREST, validationErr.Fields[0].Code).Inc()
+	rh.requestLogger(r, identity).Info("Получено некорректное сообщение", slog.Any("error", err))
+	rh.writeError(w, http.StatusBadRequest, validationErr.Error())
+	return false
+}
+
+// handleInvite добавляет пользователей из тела запроса в участники беседы от имени клиента.
+// Приглашать могут только участники беседы; остальным, в том числе если беседы нет,
+// отвечает 403. Подключенные к узлу приглашенные сразу начинают получать сообщения беседы.
+func (rh *RESTHandler) handleInvite(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	var request inviteRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err := decoder.Decode(&request); err != nil {
+		rh.writeError(w, http.StatusBadRequest, "Некорректное тело запроса")
+		return
+	}
+	if len(request.Users) == 0 || slices.Contains(request.Users, "") {
+		rh.writeError(w, http.StatusBadRequest, "Поле users обязательно")
+		return
+	}
+	if len(request.Users) > maxInvitedUsers {
+		rh.writeError(w, http.StatusBadRequest, "Слишком много пользователей в запросе")
+		return
+	}
+
+	conversationID := r.PathValue("id")
+	conversation, err := rh.store.Invite(conversationID, identity.UserID, request.Users)
+	if errors.Is(err, store.ErrNotMember) {
+		rh.writeError(w, http.StatusForbidden, "Нет доступа к беседе")
+		return
+	}
+	if err != nil {
+		rh.requestLogger(r, identity).Error("Ошибка приглашения в беседу",
+			slog.String("conversation", conversationID), slog.Any("error", err))
+		rh.writeError(w, http.StatusInternalServerError, "Не удалось пригласить пользователей")
+		return
+	}
+	if rh.router != nil {
+		for _, userID := range request.Users {
+			rh.router.Join(userID, conversationID)
+		}
+	}
+
+	rh.writeJSON(w, http.StatusOK, conversation)
+}
+
+// handleListConversations возвращает беседы, участником которых является клиент.
+func (rh *RESTHandler) handleListConversations(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	conversations, err := rh.store.Conversations(identity.UserID)
+	if err != nil {
+		rh.requestLogger(r, identity).Error("Ошибка получения списка бесед", slog.Any("error", err))
+		rh.writeError(w, http.StatusInternalServerError, "Не удалось получить список бесед")
+		return
+	}
+
+	rh.writeJSON(w, http.StatusOK, conversations)
+}
+
+// handleHistory возвращает историю беседы. Историю может читать только участник беседы.
+func (rh *RESTHandler) handleHistory(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	conversationID := r.PathValue("id")
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		rh.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	members, err := rh.store.Members(conversationID)
+	if err != nil {
+		rh.requestLogger(r, identity).Error("Ошибка получения участников беседы",
+			slog.String("conversation", conversationID), slog.Any("error", err))
+		rh.writeError(w, http.StatusInternalServerError, "Не удалось получить историю")
+		return
+	}
+	if !slices.Contains(members, identity.UserID) {
+		rh.writeError(w, http.StatusForbidden, "Нет доступа к беседе")
+		return
+	}
+
+	history, err := rh.store.History(conversationID, r.URL.Query().Get("before"), limit)
+	if errors.Is(err, store.ErrMessageNotFound) {
+		rh.writeError(w, http.StatusBadRequest, "Сообщение before не найдено")
+		return
+	}
+	if err != nil {
+		rh.requestLogger(r, identity).Error("Ошибка получения истории",
+			slog.String("conversation", conversationID), slog.Any("error", err))
+		rh.writeError(w, http.StatusInternalServerError, "Не удалось получить историю")
+		return
+	}
+
+	messages := make([]messageResponse, 0, len(history))
+	for _, message := range history {
+		messages = append(messages, messageResponse{
+			ID:           message.ID,
+			Conversation: message.Conversation,
+			From:         message.From,
+			Text:         message.Text,
+			SentAt:       message.SentAt,
+			Attachments:  message.Attachments,
+			Previews:     message.Previews,
+		})
+	}
+
+	rh.writeJSON(w, http.StatusOK, messages)
+}
+
+// handleSendDirect отправляет личное сообщение с данными пользователю из пути запроса
+// от имени клиента. Сообщение доставляется всем подключенным соединениям получателя
+// на всех узлах и не сохраняется: если получатель не подключен, сообщение теряется,
+// а в ответе online равно false.
+func (rh *RESTHandler) handleSendDirect(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	var request sendMessageRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err := decoder.Decode(&request); err != nil {
+		rh.writeError(w, http.StatusBadRequest, "Некорректное тело запроса")
+		return
+	}
+
+	recipient := r.PathValue("id")
+	message := msg.NewDataMessage(request.Text)
+	if !rh.validate(w, r, identity, message) {
+		return
+	}
+	message.From = identity.UserID
+	message.SentAt = time.Now().UnixMilli()
+	tracing.Inject(tracing.ExtractHTTP(r), &message)
+
+	rh.router.Deliver(message, []string{recipient})
+
+	rh.writeJSON(w, http.StatusAccepted, directResponse{
+		To:     recipient,
+		Online: rh.online(recipient),
+	})
+}
+
+// handlePresence сообщает, подключены ли пользователи из параметров user к какому-либо
+// узлу кластера.
+func (rh *RESTHandler) handlePresence(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	users := r.URL.Query()["user"]
+	if len(users) == 0 {
+		rh.writeError(w, http.StatusBadRequest, "Параметр user обязателен")
+		return
+	}
+	if len(users) > maxPresenceUsers {
+		rh.writeError(w, http.StatusBadRequest, "Слишком много пользователей в запросе")
+		return
+	}
+
+	presence := make([]presenceResponse, 0, len(users))
+	for _, userID := range users {
+		presence = append(presence, presenceResponse{User: userID, Online: rh.online(userID)})
+	}
+	rh.writeJSON(w, http.StatusOK, presence)
+}
+
+// handleMe возвращает личность клиента: по ней клиенты узнают свой идентификатор,
+// например чтобы их пригласили в беседу.
+func (rh *RESTHandler) handleMe(w http.ResponseWriter, r *http.Request, identity authmodels.Identity) {
+	rh.writeJSON(w, http.StatusOK, meResponse{User: identity.UserID, Anonymous: identity.Anonymous})
+}
+
+// online сообщает, подключен ли пользователь по каталогу подключений. Без каталога
+// состояние неизвестно, и возвращается false.
+func (rh *RESTHandler) online(userID string) bool {
+	if rh.directory == nil {
+		return false
+	}
+	nodeIDs, _ := rh.directory.Lookup(userID)
+	return len(nodeIDs) > 0
+}
+
+// parseLimit разбирает параметр limit. Пустое значение означает лимит по умолчанию,
+// значения больше maxHistoryLimit ограничиваются им.
+func parseLimit(value string) (int, error) {
+	if value == "" {
+		return defaultHistoryLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("limit должен быть положительным числом")
+	}
+	return min(limit, maxHistoryLimit), nil
+}
+
+func (rh *RESTHandler) writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		rh.logger.Warn("Ошибка записи ответа", slog.Any("error", err))
+	}
+}
+
+func (rh *RESTHandler) writeError(w http.ResponseWriter, status int, message string) {
+	rh.writeJSON(w, status, errorResponse{Error: message})
+}
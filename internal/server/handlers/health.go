@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"messenger/internal/health"
+	"net/http"
+)
+
+// Healthz сообщает, что процесс жив и обслуживает HTTP-запросы.
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "ok\n")
+}
+
+// Readyz возвращает обработчик, сообщающий, готов ли сервер принимать трафик.
+// Отвечает 200, если пройдены все проверки реестра readiness, иначе 503 — в том числе
+// во время остановки сервера. В теле ответа возвращаются результаты всех проверок.
+func Readyz(readiness *health.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := readiness.Check(r.Context())
+
+		status := http.StatusOK
+		if !report.Ready {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// NotFound отвечает 404 на запросы к незарегистрированным путям.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "Не найдено", http.StatusNotFound)
+}
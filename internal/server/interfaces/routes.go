@@ -0,0 +1,10 @@
+package interfaces
+
+import (
+	"net/http"
+)
+
+type Routes interface {
+	Handle(pattern string, handler http.Handler)
+	HandleFunc(pattern string, handler http.HandlerFunc)
+}
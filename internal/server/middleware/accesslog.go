@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"messenger/internal/logging"
+	"net"
+	"net/http"
+	"time"
+)
+
+// AccessLog логирует метод, путь, код ответа, длительность и адрес клиента каждого запроса.
+// Если logger не задан, используется slog.Default().
+func AccessLog(logger *slog.Logger) func(http.Handler) http.Handler {
+	logger = logging.OrDefault(logger)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(recorder, r)
+
+			logger.Info("Запрос обработан",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", recorder.status),
+				slog.Duration("duration", time.Since(start)),
+				slog.String(logging.KeyRemoteAddr, r.RemoteAddr),
+			)
+		})
+	}
+}
+
+// statusRecorder запоминает код ответа обработчика. Реализует http.Hijacker
+// и http.Flusher, чтобы не ломать WebSocket и SSE.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("ResponseWriter не поддерживает Hijack")
+	}
+	sr.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	authinterfaces "messenger/internal/auth/interfaces"
+	"net/http"
+	"slices"
+)
+
+// RequireAdmin пропускает только аутентифицированных пользователей из списка adminUsers.
+// Неаутентифицированным клиентам отвечает 401, остальным — 403. Анонимные
+// пользователи (при выключенной аутентификации) администраторами не считаются.
+func RequireAdmin(authenticator authinterfaces.Authenticator, adminUsers []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, err := authenticator.Authenticate(r)
+			if err != nil {
+				http.Error(w, "Требуется аутентификация", http.StatusUnauthorized)
+				return
+			}
+			if identity.Anonymous || !slices.Contains(adminUsers, identity.UserID) {
+				http.Error(w, "Доступ запрещен", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
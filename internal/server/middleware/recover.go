@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"messenger/internal/logging"
+	"net"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover перехватывает панику обработчика, логирует ее со стеком вызовов
+// и отвечает клиенту 500, не давая одному запросу уронить соединение молча.
+// Если обработчик уже начал ответ или захватил соединение (hijack), записать
+// ответ невозможно, поэтому паника только логируется. Если logger не задан,
+// используется slog.Default().
+func Recover(logger *slog.Logger) func(http.Handler) http.Handler {
+	logger = logging.OrDefault(logger)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tracker := &responseTracker{ResponseWriter: w}
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					if recovered == http.ErrAbortHandler {
+						panic(recovered)
+					}
+					logger.Error("Паника при обработке запроса",
+						slog.String("method", r.Method),
+						slog.String("path", r.URL.Path),
+						slog.String(logging.KeyRemoteAddr, r.RemoteAddr),
+						slog.Bool("hijacked", tracker.hijacked),
+						slog.Bool("response_started", tracker.started),
+						slog.Any("panic", recovered),
+						slog.String("stack", string(debug.Stack())),
+					)
+					if !tracker.hijacked && !tracker.started {
+						http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+					}
+				}
+			}()
+			next.ServeHTTP(tracker, r)
+		})
+	}
+}
+
+// responseTracker отслеживает, начал ли обработчик ответ и захватил ли соединение,
+// чтобы Recover не писал поверх начатого ответа и в захваченное соединение.
+// Реализует http.Hijacker и http.Flusher, чтобы не ломать WebSocket и SSE.
+type responseTracker struct {
+	http.ResponseWriter
+	started  bool
+	hijacked bool
+}
+
+func (rt *responseTracker) WriteHeader(status int) {
+	rt.started = true
+	rt.ResponseWriter.WriteHeader(status)
+}
+
+func (rt *responseTracker) Write(data []byte) (int, error) {
+	rt.started = true
+	return rt.ResponseWriter.Write(data)
+}
+
+func (rt *responseTracker) Flush() {
+	if flusher, ok := rt.ResponseWriter.(http.Flusher); ok {
+		rt.started = true
+		flusher.Flush()
+	}
+}
+
+func (rt *responseTracker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rt.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("ResponseWriter не поддерживает Hijack")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		rt.hijacked = true
+	}
+	return conn, rw, err
+}
+
+func (rt *responseTracker) Unwrap() http.ResponseWriter {
+	return rt.ResponseWriter
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// discardLogger возвращает журнал, отбрасывающий записи.
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		body    string
+	}{
+		{
+			name:    "before response",
+			handler: func(http.ResponseWriter, *http.Request) { panic("сбой") },
+			status:  http.StatusInternalServerError,
+			body:    "Внутренняя ошибка сервера\n",
+		},
+		{
+			name: "after header",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("сбой")
+			},
+			status: http.StatusAccepted,
+		},
+		{
+			name: "after body",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				io.WriteString(w, "частичный ответ")
+				panic("сбой")
+			},
+			status: http.StatusOK,
+			body:   "частичный ответ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			Recover(discardLogger())(tt.handler).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if recorder.Code != tt.status || recorder.Body.String() != tt.body {
+				t.Fatalf("Получен ответ %d %q, ожидался %d %q", recorder.Code, recorder.Body.String(), tt.status, tt.body)
+			}
+		})
+	}
+}
+
+func TestRecoverHijacked(t *testing.T) {
+	handler := Recover(discardLogger())(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Ошибка захвата соединения: %v", err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+		rw.Flush()
+		panic("сбой")
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Ошибка подключения: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+
+	// После паники в захваченное соединение ничего не дописывается.
+	data, err := io.ReadAll(bufio.NewReader(conn))
+	if err != nil {
+		t.Fatalf("Ошибка чтения ответа: %v", err)
+	}
+	if got := string(data); got != "HTTP/1.1 101 Switching Protocols\r\n\r\n" {
+		t.Fatalf("Получен ответ %q, ожидался только 101", got)
+	}
+}
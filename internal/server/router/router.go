@@ -0,0 +1,84 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware оборачивает обработчик дополнительной логикой (логирование,
+// восстановление после паники, проверка доступа и т.д.).
+type Middleware func(http.Handler) http.Handler
+
+// Router распределяет HTTP-запросы по группам маршрутов. У каждой группы
+// свой префикс пути и свой набор промежуточных обработчиков. Запросы к
+// незарегистрированным путям получают ответ 404.
+type Router struct {
+	mux      *http.ServeMux
+	notFound http.Handler
+}
+
+// New создает Router. Обработчик notFound вызывается для запросов,
+// не соответствующих ни одному маршруту.
+func New(notFound http.Handler) *Router {
+	router := &Router{
+		mux:      http.NewServeMux(),
+		notFound: notFound,
+	}
+	router.mux.Handle("/", notFound)
+	return router
+}
+
+// Group создает группу маршрутов с префиксом prefix и промежуточными обработчиками
+// middlewares. Промежуточные обработчики применяются в порядке перечисления:
+// первый из них получает запрос первым.
+func (rt *Router) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		router:      rt,
+		prefix:      strings.TrimSuffix(prefix, "/"),
+		middlewares: middlewares,
+	}
+}
+
+// ServeHTTP реализует http.Handler.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
+
+// Group — группа маршрутов с общим префиксом и набором промежуточных обработчиков.
+type Group struct {
+	router      *Router
+	prefix      string
+	middlewares []Middleware
+}
+
+// Handle регистрирует обработчик по шаблону pattern относительно префикса группы.
+// Шаблон имеет формат http.ServeMux и может содержать метод: "GET /items/{id}".
+func (g *Group) Handle(pattern string, handler http.Handler) {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		method, path = "", pattern
+	}
+
+	fullPattern := g.prefix + path
+	if fullPattern == "" {
+		fullPattern = "/"
+	}
+	if method != "" {
+		fullPattern = method + " " + fullPattern
+	}
+
+	g.router.mux.Handle(fullPattern, g.wrap(handler))
+}
+
+// HandleFunc регистрирует функцию-обработчик по шаблону pattern относительно префикса группы.
+func (g *Group) HandleFunc(pattern string, handler http.HandlerFunc) {
+	g.Handle(pattern, handler)
+}
+
+// wrap применяет промежуточные обработчики группы к обработчику.
+func (g *Group) wrap(handler http.Handler) http.Handler {
+	for i := len(g.middlewares) - 1; i >= 0; i-- {
+		handler = g.middlewares[i](handler)
+	}
+	return handler
+}
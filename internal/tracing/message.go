@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName — имя библиотеки инструментирования в создаваемых спанах.
+const instrumentationName = "messenger"
+
+// traceParentKey — ключ заголовка W3C Trace Context, значение которого
+// передается в поле TraceParent сообщения.
+const traceParentKey = "traceparent"
+
+// Атрибуты спанов обработки сообщений.
+const (
+	AttrMessageType  = attribute.Key("messenger.message.type")
+	AttrMessageID    = attribute.Key("messenger.message.id")
+	AttrConversation = attribute.Key("messenger.conversation")
+	AttrTransport    = attribute.Key("messenger.transport")
+	AttrUserID       = attribute.Key("messenger.user.id")
+)
+
+// Атрибуты спана апгрейда WebSocket-соединения.
+const (
+	AttrClientAddress = attribute.Key("client.address")
+	AttrSubprotocol   = attribute.Key("messenger.subprotocol")
+	AttrRejectReason  = attribute.Key("messenger.reject_reason")
+)
+
+// Tracer возвращает трассировщик мессенджера из глобального TracerProvider.
+// До вызова Setup и при выключенном экспорте спаны не записываются.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Extract возвращает контекст с родительским спаном из поля TraceParent сообщения.
+// Если поле пустое или некорректное, возвращается ctx без изменений.
+func Extract(ctx context.Context, message msg.Message) context.Context {
+	if message.TraceParent == "" {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{traceParentKey: message.TraceParent}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// Inject записывает текущий спан ctx в поле TraceParent сообщения. Если в ctx
+// нет корректного спана, поле не меняется.
+func Inject(ctx context.Context, message *msg.Message) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if traceParent := carrier.Get(traceParentKey); traceParent != "" {
+		message.TraceParent = traceParent
+	}
+}
+
+// StartMessageSpan начинает спан этапа обработки сообщения. Родителем спана
+// становится спан из поля TraceParent сообщения, а сам новый спан записывается
+// в это поле, поэтому следующие этапы (в том числе доставка другим пользователям)
+// попадают в ту же трассу.
+//
+// Параметры:
+//   - message: Обрабатываемое сообщение; его поле TraceParent обновляется.
+//   - name: Имя спана.
+//   - opts: Дополнительные параметры спана (атрибуты, вид, время начала).
+//
+// Возвращает:
+//   - context.Context: Контекст с новым спаном.
+//   - trace.Span: Новый спан; его нужно завершить вызовом End.
+func StartMessageSpan(
+	message *msg.Message,
+	name string,
+	opts ...trace.SpanStartOption,
+) (context.Context, trace.Span) {
+	ctx := Extract(context.Background(), *message)
+	opts = append(opts, trace.WithAttributes(messageAttributes(*message)...))
+	ctx, span := Tracer().Start(ctx, name, opts...)
+	Inject(ctx, message)
+	return ctx, span
+}
+
+// RecordError отмечает спан как завершившийся ошибкой err. Если err равен nil, ничего не делает.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+func messageAttributes(message msg.Message) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{AttrMessageType.String(message.Type.String())}
+	if message.ID != "" {
+		attrs = append(attrs, AttrMessageID.String(message.ID))
+	}
+	if message.Conversation != "" {
+		attrs = append(attrs, AttrConversation.String(message.Conversation))
+	}
+	if message.From != "" {
+		attrs = append(attrs, AttrUserID.String(message.From))
+	}
+	return attrs
+}
+
+// ExtractHTTP возвращает контекст с родительским спаном из заголовков HTTP-запроса
+// (traceparent), если клиент их передал.
+func ExtractHTTP(r *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+}
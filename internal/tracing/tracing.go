@@ -0,0 +1,129 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Способы экспорта спанов.
+const (
+	// ExporterNone выключает экспорт: спаны не записываются, но контекст трассировки
+	// по-прежнему передается в сообщениях.
+	ExporterNone = "none"
+	// ExporterStdout пишет спаны в стандартный вывод в формате JSON.
+	ExporterStdout = "stdout"
+	// ExporterFile пишет спаны в файл в формате JSON, по одному спану на строку.
+	ExporterFile = "file"
+	// ExporterOTLP отправляет спаны коллектору по протоколу OTLP/HTTP.
+	ExporterOTLP = "otlp"
+)
+
+type Options struct {
+	// Exporter — способ экспорта спанов: none, stdout, file или otlp.
+	Exporter string
+	// File — путь к файлу для экспорта file.
+	File string
+	// Endpoint — URL коллектора OTLP/HTTP, например http://localhost:4318.
+	Endpoint string
+	// ServiceName — значение атрибута service.name ресурса.
+	ServiceName string
+	// SampleRatio — доля записываемых трасс (от 0 до 1). Доля действует и для трасс,
+	// начатых клиентом: флаг sampled из traceparent клиента не учитывается (см. newSampler).
+	SampleRatio float64
+}
+
+// Setup настраивает глобальные TracerProvider и пропагатор W3C Trace Context.
+// Пропагатор настраивается всегда, даже при выключенном экспорте, чтобы контекст
+// трассировки клиентов передавался дальше без изменений.
+//
+// Возвращает функцию, которая выгружает накопленные спаны и освобождает ресурсы
+// экспортера; ее нужно вызвать при остановке приложения.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if options.Exporter == "" || options.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closer, err := newExporter(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceResource, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", options.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания ресурса трассировки: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(serviceResource),
+		sdktrace.WithSampler(newSampler(options.SampleRatio)),
+	)
+	otel.SetTracerProvider(provider)
+
+	shutdown := func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}
+	return shutdown, nil
+}
+
+// newSampler возвращает сэмплер, который записывает долю ratio трасс. Решение о записи
+// трассы принимает сервер по ее идентификатору, даже если трассу начал клиент: иначе
+// клиент флагом sampled в traceparent заставлял бы сервер записывать все свои сообщения.
+// Решение по идентификатору одинаково для всех спанов трассы, в том числе на разных
+// узлах, поэтому трасса записывается целиком или не записывается вовсе.
+func newSampler(ratio float64) sdktrace.Sampler {
+	serverSampler := sdktrace.TraceIDRatioBased(ratio)
+	return sdktrace.ParentBased(serverSampler,
+		sdktrace.WithRemoteParentSampled(serverSampler),
+		sdktrace.WithRemoteParentNotSampled(serverSampler),
+	)
+}
+
+// newExporter создает экспортер спанов. Для экспорта в файл также возвращает файл,
+// который нужно закрыть после остановки TracerProvider.
+func newExporter(ctx context.Context, options Options) (sdktrace.SpanExporter, io.Closer, error) {
+	switch options.Exporter {
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, nil, err
+	case ExporterFile:
+		file, err := os.OpenFile(options.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка открытия файла трассировки: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return exporter, file, nil
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(options.Endpoint))
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка создания экспортера OTLP: %w", err)
+		}
+		return exporter, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("неизвестный экспортер трассировки: %s", options.Exporter)
+	}
+}
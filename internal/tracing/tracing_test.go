@@ -0,0 +1,149 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Трассы клиента с флагом sampled и без него.
+const (
+	sampledParent    = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	notSampledParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+	clientTraceID    = "4bf92f3577b34da6a3ce929d0e0e4736"
+)
+
+// useRecorder настраивает глобальные TracerProvider с сэмплером newSampler(ratio)
+// и пропагатор, как Setup, и возвращает записанные спаны. Прежние глобальные
+// настройки восстанавливаются по завершении теста.
+func useRecorder(t *testing.T, ratio float64) *tracetest.SpanRecorder {
+	t.Helper()
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithSampler(newSampler(ratio)),
+	))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestSamplerIgnoresClientDecision(t *testing.T) {
+	tests := []struct {
+		name        string
+		ratio       float64
+		traceParent string
+		sampled     bool
+	}{
+		{"sampled client, ratio 0", 0, sampledParent, false},
+		{"not sampled client, ratio 1", 1, notSampledParent, true},
+		{"sampled client, ratio 1", 1, sampledParent, true},
+		{"new trace, ratio 0", 0, "", false},
+		{"new trace, ratio 1", 1, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := useRecorder(t, tt.ratio)
+
+			message := msg.Message{Type: msg.DataMessage, TraceParent: tt.traceParent}
+			_, span := StartMessageSpan(&message, "message.receive")
+			span.End()
+
+			wantSpans, wantFlags := 0, "-00"
+			if tt.sampled {
+				wantSpans, wantFlags = 1, "-01"
+			}
+			if got := len(recorder.Ended()); got != wantSpans {
+				t.Fatalf("Записано спанов: %d, ожидалось %d", got, wantSpans)
+			}
+			// Решение сервера передается дальше в поле TraceParent.
+			if !strings.HasSuffix(message.TraceParent, wantFlags) {
+				t.Fatalf("Флаги трассы в TraceParent %q не соответствуют решению сервера", message.TraceParent)
+			}
+		})
+	}
+}
+
+func TestSamplerKeepsTraceDecision(t *testing.T) {
+	recorder := useRecorder(t, 0.5)
+
+	// Этапы обработки одного сообщения попадают в трассу целиком или не попадают вовсе.
+	for i := 0; i < 20; i++ {
+		message := msg.Message{Type: msg.DataMessage}
+		_, receive := StartMessageSpan(&message, "message.receive")
+		receive.End()
+		_, process := StartMessageSpan(&message, "message.process")
+		process.End()
+
+		if receive.SpanContext().IsSampled() != process.SpanContext().IsSampled() {
+			t.Fatalf("Этапы трассы %s записаны частично", receive.SpanContext().TraceID())
+		}
+	}
+	if len(recorder.Ended())%2 != 0 {
+		t.Fatalf("Записано нечетное число спанов: %d", len(recorder.Ended()))
+	}
+}
+
+func TestStartMessageSpanContinuesTrace(t *testing.T) {
+	recorder := useRecorder(t, 1)
+
+	message := msg.Message{Type: msg.DataMessage, Conversation: "room-1", From: "alice", TraceParent: sampledParent}
+	_, receive := StartMessageSpan(&message, "message.receive")
+	receive.End()
+	_, process := StartMessageSpan(&message, "message.process")
+	process.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Записано спанов: %d, ожидалось 2", len(spans))
+	}
+	if spans[0].Parent().SpanID().String() != "00f067aa0ba902b7" || !spans[0].Parent().IsRemote() {
+		t.Fatalf("Родитель первого этапа %v, ожидался спан клиента", spans[0].Parent())
+	}
+	if spans[1].Parent().SpanID() != spans[0].SpanContext().SpanID() {
+		t.Fatalf("Родитель второго этапа %v, ожидался спан message.receive", spans[1].Parent())
+	}
+	for _, span := range spans {
+		if span.SpanContext().TraceID().String() != clientTraceID {
+			t.Fatalf("Спан %s в трассе %s, ожидалась трасса клиента", span.Name(), span.SpanContext().TraceID())
+		}
+	}
+	if want := "00-" + clientTraceID + "-" + spans[1].SpanContext().SpanID().String() + "-01"; message.TraceParent != want {
+		t.Fatalf("TraceParent %q, ожидалось %q", message.TraceParent, want)
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	useRecorder(t, 1)
+
+	// Некорректный traceparent не продолжает трассу.
+	if span := trace.SpanContextFromContext(Extract(context.Background(), msg.Message{TraceParent: "мусор"})); span.IsValid() {
+		t.Fatalf("Из некорректного traceparent получен спан %v", span)
+	}
+
+	// Без спана в контексте поле не меняется.
+	message := msg.Message{TraceParent: "исходное"}
+	Inject(context.Background(), &message)
+	if message.TraceParent != "исходное" {
+		t.Fatalf("TraceParent изменен на %q", message.TraceParent)
+	}
+
+	Inject(Extract(context.Background(), msg.Message{TraceParent: sampledParent}), &message)
+	if message.TraceParent != sampledParent {
+		t.Fatalf("TraceParent %q, ожидалось %q", message.TraceParent, sampledParent)
+	}
+}
@@ -0,0 +1,152 @@
+package connections
+
+import (
+	"context"
+	"errors"
+	"github.com/1ight181/messenger/pkg/protocol"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// closeTimeout — время на отправку close-фрейма одному соединению.
+const closeTimeout = time.Second
+
+// ErrDraining означает, что сервер останавливается и новые соединения не принимаются.
+var ErrDraining = errors.New("сервер останавливается")
+
+// Connection — открытое WebSocket-соединение, которое можно закрыть при остановке сервера.
+type Connection interface {
+	SendCloseMessage(code int, text string, timeout time.Duration) error
+	Close() error
+}
+
+// Registry отслеживает открытые WebSocket-соединения, чтобы корректно закрыть их
+// при остановке сервера. http.Server.Shutdown не затрагивает захваченные (hijacked)
+// соединения, поэтому без реестра клиенты просто обрывались бы.
+type Registry struct {
+	mu             sync.Mutex
+	connections    map[*entry]struct{}
+	draining       bool
+	empty          chan struct{}
+	reconnectDelay time.Duration
+}
+
+type entry struct {
+	connection Connection
+}
+
+type Options struct {
+	// ReconnectDelay — верхняя граница задержки переподключения, которую сервер
+	// подсказывает клиентам при остановке. Каждому соединению назначается случайная
+	// задержка от 0 до ReconnectDelay, чтобы клиенты не переподключались одновременно.
+	ReconnectDelay time.Duration
+}
+
+// NewRegistry создает пустой реестр соединений.
+func NewRegistry(options Options) *Registry {
+	return &Registry{
+		connections:    make(map[*entry]struct{}),
+		reconnectDelay: options.ReconnectDelay,
+	}
+}
+
+// Add регистрирует соединение. Возвращает функцию, снимающую регистрацию при
+// закрытии соединения, и false, если сервер уже останавливается и новые
+// соединения не принимаются.
+func (r *Registry) Add(connection Connection) (func(), bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.draining {
+		return nil, false
+	}
+
+	e := &entry{connection: connection}
+	r.connections[e] = struct{}{}
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		delete(r.connections, e)
+		if len(r.connections) == 0 && r.empty != nil {
+			close(r.empty)
+			r.empty = nil
+		}
+	}, true
+}
+
+// Draining сообщает, останавливается ли сервер. Во время остановки новые
+// апгрейды не принимаются.
+func (r *Registry) Draining() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.draining
+}
+
+// Count возвращает число открытых соединений.
+func (r *Registry) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.connections)
+}
+
+// Drain прекращает прием новых соединений и отправляет каждому открытому соединению
+// close-фрейм с кодом CloseServiceRestart и подсказкой о переподключении.
+// Затем ждет, пока клиенты закроют соединения, но не дольше отмены ctx, и
+// принудительно закрывает оставшиеся соединения.
+//
+// Возвращает число принудительно закрытых соединений.
+func (r *Registry) Drain(ctx context.Context) int {
+	r.mu.Lock()
+	r.draining = true
+	empty := make(chan struct{})
+	if len(r.connections) == 0 {
+		close(empty)
+	} else {
+		r.empty = empty
+	}
+	live := r.snapshot()
+	r.mu.Unlock()
+
+	for _, connection := range live {
+		connection.SendCloseMessage(websocket.CloseServiceRestart, r.reconnectHint(), closeTimeout)
+	}
+
+	select {
+	case <-empty:
+		return 0
+	case <-ctx.Done():
+	}
+
+	r.mu.Lock()
+	remaining := r.snapshot()
+	r.mu.Unlock()
+
+	for _, connection := range remaining {
+		connection.Close()
+	}
+	return len(remaining)
+}
+
+func (r *Registry) snapshot() []Connection {
+	live := make([]Connection, 0, len(r.connections))
+	for e := range r.connections {
+		live = append(live, e.connection)
+	}
+	return live
+}
+
+// reconnectHint возвращает причину close-фрейма со случайной задержкой переподключения.
+func (r *Registry) reconnectHint() string {
+	var delay time.Duration
+	if r.reconnectDelay > 0 {
+		delay = rand.N(r.reconnectDelay + 1)
+	}
+	return protocol.ReconnectHint(delay)
+}
@@ -1,21 +1,49 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	msg "messenger/internal/messaging/models/message"
+	"github.com/1ight181/messenger/pkg/protocol"
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"log/slog"
+	"math"
+	"messenger/internal/admission"
+	authinterfaces "messenger/internal/auth/interfaces"
+	authmodels "messenger/internal/auth/models"
+	"messenger/internal/logging"
+	msginterfaces "messenger/internal/messaging/interfaces"
+	"messenger/internal/metrics"
+	"messenger/internal/ratelimit"
+	"messenger/internal/tracing"
+	"messenger/internal/ws/connections"
 	"messenger/internal/ws/interfaces"
+	"messenger/internal/ws/traffic"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type WebSocketHandler struct {
 	upgrader         websocket.Upgrader
+	checkOrigin      func(*http.Request) bool
 	messageSender    interfaces.WebSocketSender
 	messageReceiver  interfaces.WebSocketReceiver
 	messageProcessor interfaces.WebSocketProcessor
+	authenticator    authinterfaces.Authenticator
+	router           msginterfaces.MessageRouter
+	connections      *connections.Registry
+	admission        *admission.Controller
+	limiter          *ratelimit.Limiter
+	limits           *ratelimit.Connection
+	identity         authmodels.Identity
+	traffic          *traffic.Counters
+	connLogger       *slog.Logger
+	logger           *slog.Logger
 }
 
 func New(
@@ -23,13 +51,38 @@ func New(
 	messageSender interfaces.WebSocketSender,
 	messageReceiver interfaces.WebSocketReceiver,
 	messageProcessor interfaces.WebSocketProcessor,
+	authenticator authinterfaces.Authenticator,
+	router msginterfaces.MessageRouter,
+	connections *connections.Registry,
+	admission *admission.Controller,
+	limiter *ratelimit.Limiter,
+	logger *slog.Logger,
 ) *WebSocketHandler {
-	return &WebSocketHandler{
+	wsh := &WebSocketHandler{
 		upgrader:         upgrader,
 		messageSender:    messageSender,
 		messageReceiver:  messageReceiver,
 		messageProcessor: messageProcessor,
+		authenticator:    authenticator,
+		router:           router,
+		connections:      connections,
+		admission:        admission,
+		limiter:          limiter,
+		traffic:          &traffic.Counters{},
 	}
+	// Origin проверяется в HandleWebSocket до аутентификации, поэтому при апгрейде
+	// проверка не повторяется: иначе отказ оценивался бы и записывался в журнал дважды.
+	if upgrader.CheckOrigin != nil {
+		wsh.checkOrigin = upgrader.CheckOrigin
+		wsh.upgrader.CheckOrigin = originChecked
+	}
+	wsh.setConnLogger(logging.OrDefault(logger))
+	return wsh
+}
+
+// originChecked заменяет CheckOrigin апгрейдера: Origin к моменту апгрейда уже проверен.
+func originChecked(*http.Request) bool {
+	return true
 }
 
 // Tag возвращает строковый идентификатор для WebSocketHandler.
@@ -46,53 +99,292 @@ func (*WebSocketHandler) Tag() string {
 //   - r: HTTP запрос, содержащий запрос на апгрейд до WebSocket.
 //
 // Поведение:
+//   - Во время остановки сервера не принимает новые соединения и возвращает ошибку HTTP 503.
+//   - Проверяет Origin до апгрейда; при неудаче возвращает ошибку HTTP 403.
+//   - Аутентифицирует клиента до апгрейда; при неудаче возвращает ошибку HTTP 401.
+//   - Проверяет ограничения числа соединений до апгрейда: при превышении общего ограничения
+//     сервера возвращает ошибку HTTP 503, при превышении ограничения IP-адреса или
+//     пользователя — HTTP 429; в обоих случаях с заголовком Retry-After.
 //   - Пытается апгрейдить HTTP соединение до WebSocket соединения.
 //   - Если апгрейд не удался, возвращает ошибку HTTP 500 и логирует детали ошибки.
-//   - Если апгрейд успешен, запускает цикл обработки сообщений и гарантирует закрытие соединения по завершении.
+//   - Если апгрейд успешен, регистрирует соединение в реестре открытых соединений, который
+//     закрывает его при остановке сервера, и в Router для доставки сообщений других
+//     пользователей, запускает цикл обработки сообщений и гарантирует закрытие соединения по завершении.
+//   - По завершении соединения логирует статистику трафика и добавляет ее в общие счетчики.
+//   - Принятые и отклоненные (с причиной) апгрейды и число открытых соединений учитываются в метриках.
+//   - Все записи журнала соединения содержат его идентификатор, адрес клиента и, после
+//     аутентификации, идентификатор пользователя.
+//   - Проверки и апгрейд записываются в спан websocket.upgrade, продолжающий трассу
+//     из заголовка traceparent запроса, если клиент его передал.
 func (wsh *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	wsh.setConnLogger(wsh.connLogger.With(
+		slog.String(logging.KeyConnectionID, logging.NewConnectionID()),
+		slog.String(logging.KeyRemoteAddr, r.RemoteAddr),
+	))
+
+	_, upgradeSpan := tracing.Tracer().Start(tracing.ExtractHTTP(r), "websocket.upgrade",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(tracing.AttrClientAddress.String(r.RemoteAddr)),
+	)
+
+	if wsh.connections.Draining() {
+		metrics.UpgradesRejected.WithLabelValues(metrics.RejectReasonDraining).Inc()
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Сервер останавливается", http.StatusServiceUnavailable)
+		wsh.logger.Info("Апгрейд отклонен: сервер останавливается")
+		rejectUpgradeSpan(upgradeSpan, metrics.RejectReasonDraining, connections.ErrDraining)
+		return
+	}
+
+	// Причину отказа записывает в журнал сама проверка Origin.
+	if wsh.checkOrigin != nil && !wsh.checkOrigin(r) {
+		metrics.UpgradesRejected.WithLabelValues(metrics.RejectReasonOrigin).Inc()
+		http.Error(w, "Недопустимый источник запроса", http.StatusForbidden)
+		rejectUpgradeSpan(upgradeSpan, metrics.RejectReasonOrigin, errors.New("недопустимый Origin"))
+		return
+	}
+
+	identity, err := wsh.authenticator.Authenticate(r)
+	if err != nil {
+		metrics.UpgradesRejected.WithLabelValues(metrics.RejectReasonAuth).Inc()
+		http.Error(w, "Требуется аутентификация", http.StatusUnauthorized)
+		wsh.logger.Warn("Ошибка аутентификации", slog.Any("error", err))
+		rejectUpgradeSpan(upgradeSpan, metrics.RejectReasonAuth, err)
+		return
+	}
+	clientIP := ratelimit.ClientIP(r.RemoteAddr)
+	wsh.identity = identity
+	wsh.limits = wsh.limiter.NewConnection(identity.UserID, clientIP)
+	wsh.setConnLogger(wsh.connLogger.With(slog.String(logging.KeyUserID, identity.UserID)))
+	upgradeSpan.SetAttributes(tracing.AttrUserID.String(identity.UserID))
 
-	conn, err := wsh.processConnection(w, r)
+	releaseAdmission, err := wsh.admission.Admit(clientIP, identity.UserID)
 	if err != nil {
+		wsh.rejectAdmission(w, err)
+		rejectUpgradeSpan(upgradeSpan, admissionRejectReason(err), err)
+		return
+	}
+	defer releaseAdmission()
+
+	conn, err := wsh.processConnection(traffic.WrapResponseWriter(w, wsh.traffic), r)
+	if err != nil {
+		metrics.UpgradesRejected.WithLabelValues(metrics.RejectReasonHandshake).Inc()
 		http.Error(w, "Не удалось установить WebSocket соединение", http.StatusInternalServerError)
-		log.Printf(wsh.Tag(), "Ошибка при апгрейде соединения:", err, "IP:", r.RemoteAddr)
+		wsh.logger.Warn("Ошибка при апгрейде соединения", slog.Any("error", err))
+		rejectUpgradeSpan(upgradeSpan, metrics.RejectReasonHandshake, err)
 		return
 	}
+	wsh.logger.Info("Соединение установлено", slog.String("subprotocol", conn.Subprotocol()))
+	upgradeSpan.SetAttributes(tracing.AttrSubprotocol.String(conn.Subprotocol()))
+	upgradeSpan.End()
 
+	metrics.UpgradesAccepted.Inc()
+	activeConnections := metrics.ActiveConnections.WithLabelValues(metrics.TransportWebSocket)
+	activeConnections.Inc()
+	defer activeConnections.Dec()
+
+	defer wsh.reportTraffic()
 	defer conn.Close()
+
+	removeConnection, ok := wsh.connections.Add(&liveConnection{sender: wsh.messageSender, conn: conn})
+	if !ok {
+		wsh.logger.Info("Соединение закрыто: сервер останавливается")
+		wsh.messageSender.SendCloseMessage(websocket.CloseServiceRestart, protocol.ReconnectHint(0), time.Second)
+		return
+	}
+	defer removeConnection()
+
+	unregister := wsh.router.Register(identity.UserID, wsh.messageSender)
+	defer unregister()
+
 	wsh.handleMessageLoop()
 }
 
+// liveConnection позволяет реестру открытых соединений закрыть соединение при остановке
+// сервера: close-фрейм отправляется через отправителя, а принудительное закрытие
+// прерывает чтение в цикле обработки сообщений.
+type liveConnection struct {
+	sender interfaces.WebSocketSender
+	conn   *websocket.Conn
+}
+
+func (lc *liveConnection) SendCloseMessage(code int, text string, timeout time.Duration) error {
+	return lc.sender.SendCloseMessage(code, text, timeout)
+}
+
+func (lc *liveConnection) Close() error {
+	return lc.conn.Close()
+}
+
+// rejectAdmission отвечает клиенту, которому отказано в соединении ограничениями числа
+// соединений: 503 при заполненном сервере, 429 при превышении ограничения IP-адреса или
+// пользователя. Заголовок Retry-After подсказывает, когда повторить попытку.
+func (wsh *WebSocketHandler) rejectAdmission(w http.ResponseWriter, err error) {
+	reason := admissionRejectReason(err)
+	metrics.UpgradesRejected.WithLabelValues(reason).Inc()
+
+	retryAfter := max(1, int(math.Ceil(wsh.admission.RetryAfter().Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+	status := http.StatusTooManyRequests
+	if errors.Is(err, admission.ErrCapacity) {
+		status = http.StatusServiceUnavailable
+	}
+	http.Error(w, "Слишком много соединений", status)
+	wsh.logger.Warn("Апгрейд отклонен: превышено ограничение числа соединений",
+		slog.String("reason", reason), slog.Any("error", err))
+}
+
+// admissionRejectReason возвращает значение метки reason для ошибки допуска соединения.
+func admissionRejectReason(err error) string {
+	switch {
+	case errors.Is(err, admission.ErrIPLimit):
+		return metrics.RejectReasonIPLimit
+	case errors.Is(err, admission.ErrUserLimit):
+		return metrics.RejectReasonUserLimit
+	default:
+		return metrics.RejectReasonCapacity
+	}
+}
+
+// rejectUpgradeSpan завершает спан апгрейда ошибкой с указанием причины отказа.
+func rejectUpgradeSpan(span trace.Span, reason string, err error) {
+	span.SetAttributes(tracing.AttrRejectReason.String(reason))
+	tracing.RecordError(span, err)
+	span.End()
+}
+
+// setConnLogger запоминает логгер с полями соединения, который передается отправителю,
+// получателю и обработчику сообщений, и логгер самого обработчика с полем component.
+func (wsh *WebSocketHandler) setConnLogger(connLogger *slog.Logger) {
+	wsh.connLogger = connLogger
+	wsh.logger = logging.Component(connLogger, wsh.Tag())
+}
+
+// reportTraffic логирует объем полезной нагрузки и фактически переданных по сети байт
+// за время жизни соединения и переносит их в общие счетчики traffic.Total.
+// Отношение сетевых байт к полезной нагрузке показывает, окупается ли сжатие.
+func (wsh *WebSocketHandler) reportTraffic() {
+	snapshot := wsh.traffic.Snapshot()
+	traffic.Total.Merge(snapshot)
+
+	wsh.logger.Info("Трафик соединения",
+		slog.Uint64("payload_written", snapshot.PayloadWritten),
+		slog.Uint64("wire_written", snapshot.WireWritten),
+		slog.Float64("write_ratio", snapshot.WriteRatio()),
+		slog.Uint64("payload_read", snapshot.PayloadRead),
+		slog.Uint64("wire_read", snapshot.WireRead),
+		slog.Float64("read_ratio", snapshot.ReadRatio()),
+	)
+}
+
 // handleMessageLoop выполняет непрерывную обработку входящих WebSocket сообщений в цикле.
 // Он выполняет следующие шаги:
-// 1. Получает сообщение с использованием messageReceiver.
-// 2. Обрабатывает полученное сообщение с использованием messageProcessor.
-// 3. Отправляет обработанное сообщение-ответ с использованием messageSender.
+// 1. Получает сообщение с использованием messageReceiver и помечает его отправителем —
+// аутентифицированным пользователем соединения (значение from от клиента не принимается).
+// На некорректное сообщение клиенту отправляется сообщение об ошибке со списком неверных
+// полей, и цикл продолжается; некорректные сообщения учитываются лимитами частоты
+// под ключом ratelimit.InvalidKey, поэтому поток таких сообщений тоже закрывает
+// соединение. Сообщение больше максимального размера завершает соединение с кодом 1009
+// (message too big).
+// 2. Проверяет лимиты частоты сообщений соединения, пользователя и IP-адреса: сообщение сверх
+// лимита не обрабатывается, клиенту отправляется сообщение об ошибке, а при постоянном
+// превышении лимитов соединение закрывается с кодом 1008 (policy violation).
+// 3. Обрабатывает полученное сообщение с использованием messageProcessor.
+// 4. Отправляет обработанное сообщение-ответ с использованием messageSender.
 //
 // Если на любом этапе (получение, обработка или отправка) возникает ошибка,
-// метод обрабатывает её с помощью handleError и завершает цикл.
+// метод обрабатывает её с помощью handleError и завершает цикл. Записи об ошибках
+// обработки и отправки содержат тип сообщения.
 //
 // Этот метод предназначен для работы до тех пор, пока не произойдет ошибка.
 func (wsh *WebSocketHandler) handleMessageLoop() {
 	for {
 		message, err := wsh.messageReceiver.ReceiveMessage()
+		var validationErr *msg.ValidationError
+		if errors.As(err, &validationErr) {
+			allowed, closed := wsh.allow(ratelimit.InvalidKey)
+			if closed {
+				break
+			}
+			if !allowed {
+				continue
+			}
+			if err := wsh.messageSender.SendMessage(validationErr.Response()); err != nil {
+				wsh.handleError(err, "Ошибка при формировании ответа")
+				break
+			}
+			continue
+		}
+		if errors.Is(err, websocket.ErrReadLimit) {
+			metrics.CloseCodes.WithLabelValues(strconv.Itoa(websocket.CloseMessageTooBig)).Inc()
+			wsh.logger.Warn("Соединение закрыто: превышен максимальный размер сообщения")
+			break
+		}
 		if err != nil {
 			wsh.handleError(err, "Ошибка чтения сообщения")
 			break
 		}
+		message.From = wsh.identity.UserID
+
+		allowed, closed := wsh.allow(message.Type.String())
+		if closed {
+			break
+		}
+		if !allowed {
+			continue
+		}
 
 		responseMessage, err := wsh.messageProcessor.ProcessMessage(message)
 		if err != nil {
-			wsh.handleError(err, "Ошибка при обработке сообщения")
+			wsh.handleError(err, "Ошибка при обработке сообщения", logging.MessageType(message.Type))
 			break
 		}
 
 		if err := wsh.messageSender.SendMessage(responseMessage); err != nil {
-			wsh.handleError(err, "Ошибка при формировании ответа")
+			wsh.handleError(err, "Ошибка при формировании ответа", logging.MessageType(responseMessage.Type))
 			break
 		}
 	}
 }
 
+// rateLimitedText — текст сообщения об ошибке для сообщений сверх лимита частоты.
+const rateLimitedText = "Превышен лимит частоты сообщений"
+
+// allow проверяет лимиты частоты для сообщения типа typeName. Сообщение сверх лимита
+// не обрабатывается, а клиенту отправляется сообщение об ошибке. Возвращает allowed, если
+// сообщение можно обработать, и closed, если соединение нужно завершить: клиент постоянно
+// превышает лимиты или ответ не удалось отправить.
+func (wsh *WebSocketHandler) allow(typeName string) (allowed bool, closed bool) {
+	decision, scope := wsh.limits.Allow(typeName)
+	switch decision {
+	case ratelimit.Abusive:
+		wsh.closeForAbuse(scope, typeName)
+		return false, true
+	case ratelimit.Throttled:
+		metrics.RateLimited.WithLabelValues(metrics.TransportWebSocket, scope).Inc()
+		wsh.logger.Debug("Сообщение отклонено ограничением частоты",
+			slog.String(logging.KeyMessageType, typeName), slog.String("scope", scope))
+		if err := wsh.messageSender.SendMessage(msg.NewErrorResponse(rateLimitedText)); err != nil {
+			wsh.handleError(err, "Ошибка при формировании ответа")
+			return false, true
+		}
+		return false, false
+	default:
+		return true, false
+	}
+}
+
+// closeForAbuse закрывает соединение клиента, постоянно превышающего лимиты частоты
+// сообщений, close-фреймом с кодом 1008 (policy violation).
+func (wsh *WebSocketHandler) closeForAbuse(scope string, typeName string) {
+	metrics.RateLimited.WithLabelValues(metrics.TransportWebSocket, scope).Inc()
+	metrics.RateLimitCloses.WithLabelValues(metrics.TransportWebSocket).Inc()
+	wsh.logger.Warn("Соединение закрыто: постоянное превышение лимита частоты сообщений",
+		slog.String(logging.KeyMessageType, typeName), slog.String("scope", scope))
+	wsh.messageSender.SendCloseMessage(websocket.ClosePolicyViolation, rateLimitedText, time.Second)
+}
+
 // handleError обрабатывает ошибку, отправляя сообщение об ошибке с использованием
 // отправителя сообщений службы WebSocket. Если отправка сообщения об ошибке не удалась,
 // ошибка логируется. Кроме того, логируется исходная ошибка вместе с пользовательским сообщением.
@@ -100,31 +392,34 @@ func (wsh *WebSocketHandler) handleMessageLoop() {
 // Параметры:
 //   - err: Произошедшая ошибка.
 //   - message: Пользовательское сообщение, описывающее контекст ошибки.
-func (wsh *WebSocketHandler) handleError(err error, message string) {
+//   - attrs: Дополнительные поля записи журнала, например тип сообщения.
+func (wsh *WebSocketHandler) handleError(err error, message string, attrs ...slog.Attr) {
 	errorResponse := msg.NewErrorMessage("Ошибка отправки сообщения об ошибке")
 
 	if websocket.IsCloseError(err,
 		websocket.CloseNormalClosure,
 		websocket.CloseGoingAway,
 		websocket.CloseAbnormalClosure,
+		websocket.CloseServiceRestart,
 	) {
 		wsh.handleConnectionClose(err)
 		return
 	}
 
 	if err := wsh.messageSender.SendMessage(errorResponse); err != nil {
-		log.Println(wsh.Tag(), "Ошибка отправки сообщения об ошибке:", err)
+		wsh.logger.Warn("Ошибка отправки сообщения об ошибке", slog.Any("error", err))
 	}
-	log.Println(wsh.Tag(), message, err)
+	wsh.logger.LogAttrs(context.Background(), slog.LevelError, message, append(attrs, slog.Any("error", err))...)
 }
 
 func (wsh *WebSocketHandler) handleConnectionClose(err error) {
 	if closeErr, ok := err.(*websocket.CloseError); ok {
-		log.Printf("Соединение закрыто: код %d, причина: %s\n", closeErr.Code, closeErr.Text)
+		metrics.CloseCodes.WithLabelValues(strconv.Itoa(closeErr.Code)).Inc()
+		wsh.logger.Info("Соединение закрыто", slog.Int("code", closeErr.Code), slog.String("reason", closeErr.Text))
 		wsh.messageSender.SendCloseMessage(closeErr.Code, "Закрытие обработано", 3*time.Second)
 
 	} else {
-		log.Println(wsh.Tag(), "Соединение закрыто:", err)
+		wsh.logger.Info("Соединение закрыто", slog.Any("error", err))
 	}
 }
 
@@ -133,10 +428,20 @@ func (wsh *WebSocketHandler) processConnection(w http.ResponseWriter, r *http.Re
 	if err != nil {
 		return nil, fmt.Errorf("не удалось установить WebSocket соединение: %w", err)
 	}
+	wsh.traffic.ResetWire()
+
+	codec := codecs.BySubprotocol(conn.Subprotocol())
 
 	wsh.messageSender.SetConnection(conn)
+	wsh.messageSender.SetCodec(codec)
+	wsh.messageSender.SetTraffic(wsh.traffic)
 	wsh.messageReceiver.SetConnection(conn)
+	wsh.messageReceiver.SetCodec(codec)
+	wsh.messageReceiver.SetTraffic(wsh.traffic)
 	wsh.messageProcessor.SetConnection(conn)
+	wsh.messageSender.SetLogger(wsh.connLogger)
+	wsh.messageReceiver.SetLogger(wsh.connLogger)
+	wsh.messageProcessor.SetLogger(wsh.connLogger)
 
 	return conn, nil
 }
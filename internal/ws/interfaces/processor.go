@@ -1,6 +1,7 @@
 package interfaces
 
 import (
+	"log/slog"
 	"messenger/internal/messaging/interfaces"
 
 	"github.com/gorilla/websocket"
@@ -9,4 +10,5 @@ import (
 type WebSocketProcessor interface {
 	interfaces.MessageProcessor
 	SetConnection(connection *websocket.Conn)
+	SetLogger(logger *slog.Logger)
 }
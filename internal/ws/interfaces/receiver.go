@@ -1,7 +1,9 @@
 package interfaces
 
 import (
+	"log/slog"
 	"messenger/internal/messaging/interfaces"
+	"messenger/internal/ws/traffic"
 
 	"github.com/gorilla/websocket"
 )
@@ -9,4 +11,7 @@ import (
 type WebSocketReceiver interface {
 	interfaces.MessageReceiver
 	SetConnection(connection *websocket.Conn)
+	SetLogger(logger *slog.Logger)
+	SetCodec(codec interfaces.Codec)
+	SetTraffic(counters *traffic.Counters)
 }
@@ -1,7 +1,9 @@
 package interfaces
 
 import (
+	"log/slog"
 	"messenger/internal/messaging/interfaces"
+	"messenger/internal/ws/traffic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -10,5 +12,8 @@ import (
 type WebSocketSender interface {
 	interfaces.MessageSender
 	SetConnection(connection *websocket.Conn)
+	SetLogger(logger *slog.Logger)
+	SetCodec(codec interfaces.Codec)
+	SetTraffic(counters *traffic.Counters)
 	SendCloseMessage(code int, text string, timeout time.Duration) error
 }
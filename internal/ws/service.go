@@ -2,34 +2,69 @@ package ws
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
+	"messenger/internal/health"
+	"messenger/internal/logging"
+	"messenger/internal/ws/connections"
+	"net"
 	"net/http"
-	"os/signal"
-	"syscall"
+	"sync"
 	"time"
-
-	"os"
 )
 
 // WebsocketService представляет собой службу для обработки WebSocket соединений.
 type WebsocketService struct {
-	server *http.Server
+	server      *http.Server
+	readiness   *health.Registry
+	connections *connections.Registry
+	drainDelay  time.Duration
+	gracePeriod time.Duration
+	closers     []func()
+	logger      *slog.Logger
+}
+
+type Options struct {
+	// Server — HTTP-сервер, обслуживающий WebSocket-эндпоинт и остальные маршруты.
+	Server *http.Server
+	// Readiness — реестр проверок готовности; при остановке сервер снимает готовность.
+	Readiness *health.Registry
+	// Connections — реестр открытых WebSocket-соединений, закрываемых при остановке.
+	Connections *connections.Registry
+	// DrainDelay — время между снятием готовности и остановкой HTTP-сервера.
+	DrainDelay time.Duration
+	// GracePeriod — время на закрытие соединений клиентами и завершение HTTP-запросов,
+	// после которого оставшиеся соединения закрываются принудительно.
+	GracePeriod time.Duration
+	// Closers — функции освобождения ресурсов, которыми пользуются соединения (например,
+	// каталога подключений и шины сообщений). Вызываются по порядку в Shutdown после
+	// закрытия всех соединений и остановки HTTP-сервера.
+	Closers []func()
+	// Logger — логгер службы. Если не задан, используется slog.Default().
+	Logger *slog.Logger
 }
 
 // NewWebsocketService создает новый экземпляр WebsocketService.
-// Принимает HTTP сервер в качестве параметра и возвращает указатель на инициализированный WebsocketService.
+// Принимает параметры службы и возвращает указатель на инициализированный WebsocketService.
 //
 // Параметры:
-//   - server: Экземпляр *http.Server, который будет использоваться WebsocketService.
+//   - options: Структура Options с HTTP-сервером, реестром готовности и настройками остановки.
 //
 // Возвращает:
 //   - Указатель на экземпляр WebsocketService.
 func NewWebsocketService(
-	server *http.Server,
+	options Options,
 ) *WebsocketService {
-	return &WebsocketService{
-		server: server,
+	ws := &WebsocketService{
+		server:      options.Server,
+		readiness:   options.Readiness,
+		connections: options.Connections,
+		drainDelay:  options.DrainDelay,
+		gracePeriod: options.GracePeriod,
+		closers:     options.Closers,
 	}
+	ws.logger = logging.Component(options.Logger, ws.Tag())
+	return ws
 }
 
 // Tag возвращает строковый идентификатор для WebsocketService.
@@ -38,28 +73,67 @@ func (ws *WebsocketService) Tag() string {
 	return "WEBSOCKET"
 }
 
-// StartServer запускает веб-сервер с поддержкой WebSocket и обрабатывает его завершение по сигналу остановки.
-// Сервер запускается в отдельной горутине и слушает указанный адрес с использованием TLS.
-// При получении сигнала завершения (например, SIGTERM или прерывания) инициируется корректное завершение работы сервера с таймаутом.
-// В случае ошибок при запуске или остановке сервера выводятся соответствующие сообщения в лог.
-func (ws *WebsocketService) StartServer() {
-	stopSignal := make(chan os.Signal, 1)
-	signal.Notify(stopSignal, os.Interrupt, syscall.SIGTERM)
+// Server возвращает HTTP-сервер службы. Позволяет запустить его на своем слушателе,
+// например в интеграционных тестах; остановка в этом случае тоже выполняется через Shutdown.
+func (ws *WebsocketService) Server() *http.Server {
+	return ws.server
+}
+
+// Serve обслуживает соединения слушателя listener по TLS с настройками сервера
+// и блокируется до остановки сервера. После Shutdown возвращает nil.
+func (ws *WebsocketService) Serve(listener net.Listener) error {
+	ws.logger.Info("Вебсокет запущен", slog.String("address", listener.Addr().String()))
+	if err := ws.server.ServeTLS(listener, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown корректно останавливает сервер. Сначала сервер снимает
+// готовность (/readyz начинает отвечать 503) и ждет drainDelay, продолжая обслуживать запросы,
+// чтобы балансировщик успел вывести его из ротации. Затем сервер перестает принимать
+// новые соединения и апгрейды, а всем открытым WebSocket-соединениям отправляется
+// close-фрейм CloseServiceRestart с подсказкой о переподключении. Клиентам дается
+// gracePeriod на закрытие соединений и завершение HTTP-запросов; оставшиеся
+// WebSocket-соединения закрываются принудительно. После этого вызываются Closers.
+//
+// Отмена ctx сокращает ожидание: снятие готовности прерывается, а оставшиеся соединения
+// закрываются сразу. Возвращает ошибку остановки HTTP-сервера.
+func (ws *WebsocketService) Shutdown(ctx context.Context) error {
+	ws.logger.Info("Сервер снимает готовность", slog.Duration("drain_delay", ws.drainDelay))
+	if ws.readiness != nil {
+		ws.readiness.StartDraining()
+	}
+	drainTimer := time.NewTimer(ws.drainDelay)
+	select {
+	case <-drainTimer.C:
+	case <-ctx.Done():
+		drainTimer.Stop()
+	}
+
+	ws.logger.Info("Сервер останавливается",
+		slog.Int("connections", ws.connections.Count()),
+		slog.Duration("grace_period", ws.gracePeriod))
+	shutdownCtx, cancel := context.WithTimeout(ctx, ws.gracePeriod)
+	defer cancel()
 
+	var wg sync.WaitGroup
+	wg.Add(1)
 	go func() {
-		log.Printf("Вебсокет запущен на: %s", ws.server.Addr)
-		if err := ws.server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
-			log.Fatal("Ошибка запуска сервера:", err)
+		defer wg.Done()
+		if forced := ws.connections.Drain(shutdownCtx); forced > 0 {
+			ws.logger.Warn("Соединения закрыты принудительно", slog.Int("connections", forced))
 		}
 	}()
 
-	<-stopSignal
-	log.Println("Получен сигнал завершения, сервер останавливается...")
-
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	err := ws.server.Shutdown(shutdownCtx)
+	if err != nil {
+		ws.logger.Error("Ошибка при остановке сервера", slog.Any("error", err))
+	}
+	wg.Wait()
 
-	if err := ws.server.Shutdown(shutdownCtx); err != nil {
-		log.Println("Ошибка при остановке сервера:", err)
+	for _, closer := range ws.closers {
+		closer()
 	}
+	return err
 }
@@ -0,0 +1,64 @@
+package traffic
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// countingConn оборачивает net.Conn и учитывает прочитанные и записанные байты.
+type countingConn struct {
+	net.Conn
+	counters *Counters
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.counters.wireRead.Add(uint64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.counters.wireWritten.Add(uint64(n))
+	return n, err
+}
+
+// NetConn возвращает обернутое соединение.
+func (c *countingConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// countingResponseWriter подменяет соединение, возвращаемое при Hijack,
+// на countingConn, чтобы учитывать байты WebSocket-соединения после апгрейда.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	counters *Counters
+}
+
+// WrapResponseWriter оборачивает http.ResponseWriter так, что соединение,
+// захваченное websocket.Upgrader, учитывает трафик в переданных счетчиках.
+//
+// Для учета входящих байт Upgrader должен иметь ненулевой ReadBufferSize,
+// иначе он читает напрямую из буфера HTTP-сервера в обход обертки.
+func WrapResponseWriter(w http.ResponseWriter, counters *Counters) http.ResponseWriter {
+	return &countingResponseWriter{
+		ResponseWriter: w,
+		counters:       counters,
+	}
+}
+
+func (w *countingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("ResponseWriter не поддерживает Hijack")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &countingConn{Conn: conn, counters: w.counters}, rw, nil
+}
@@ -0,0 +1,80 @@
+package traffic
+
+import (
+	"sync/atomic"
+)
+
+// Counters накапливает объем трафика WebSocket-соединения: размер полезной нагрузки
+// сообщений до сжатия и фактическое число байт, прошедших через сетевое соединение.
+// Сравнение этих величин показывает эффективность сжатия permessage-deflate.
+type Counters struct {
+	payloadRead    atomic.Uint64
+	payloadWritten atomic.Uint64
+	wireRead       atomic.Uint64
+	wireWritten    atomic.Uint64
+}
+
+// Snapshot — снимок значений счетчиков трафика.
+type Snapshot struct {
+	PayloadRead    uint64 `json:"payload_read"`
+	PayloadWritten uint64 `json:"payload_written"`
+	WireRead       uint64 `json:"wire_read"`
+	WireWritten    uint64 `json:"wire_written"`
+}
+
+// Total накапливает трафик всех закрытых соединений процесса.
+var Total Counters
+
+// AddPayloadRead учитывает размер полученного сообщения после распаковки.
+func (c *Counters) AddPayloadRead(n int) {
+	c.payloadRead.Add(uint64(n))
+}
+
+// AddPayloadWritten учитывает размер отправляемого сообщения до сжатия.
+func (c *Counters) AddPayloadWritten(n int) {
+	c.payloadWritten.Add(uint64(n))
+}
+
+// ResetWire обнуляет счетчики сетевых байт. Вызывается после апгрейда,
+// чтобы HTTP-рукопожатие не искажало статистику сжатия.
+func (c *Counters) ResetWire() {
+	c.wireRead.Store(0)
+	c.wireWritten.Store(0)
+}
+
+// Snapshot возвращает текущие значения счетчиков.
+func (c *Counters) Snapshot() Snapshot {
+	return Snapshot{
+		PayloadRead:    c.payloadRead.Load(),
+		PayloadWritten: c.payloadWritten.Load(),
+		WireRead:       c.wireRead.Load(),
+		WireWritten:    c.wireWritten.Load(),
+	}
+}
+
+// Merge добавляет значения снимка к счетчикам. Используется для переноса
+// статистики закрытого соединения в Total.
+func (c *Counters) Merge(snapshot Snapshot) {
+	c.payloadRead.Add(snapshot.PayloadRead)
+	c.payloadWritten.Add(snapshot.PayloadWritten)
+	c.wireRead.Add(snapshot.WireRead)
+	c.wireWritten.Add(snapshot.WireWritten)
+}
+
+// WriteRatio возвращает отношение отправленных по сети байт к размеру
+// полезной нагрузки. Значение меньше 1 означает, что сжатие экономит трафик.
+func (s Snapshot) WriteRatio() float64 {
+	if s.PayloadWritten == 0 {
+		return 0
+	}
+	return float64(s.WireWritten) / float64(s.PayloadWritten)
+}
+
+// ReadRatio возвращает отношение полученных по сети байт к размеру
+// распакованной полезной нагрузки.
+func (s Snapshot) ReadRatio() float64 {
+	if s.PayloadRead == 0 {
+		return 0
+	}
+	return float64(s.WireRead) / float64(s.PayloadRead)
+}
@@ -0,0 +1,263 @@
+package loaders
+
+import (
+	"fmt"
+	"log/slog"
+	"messenger/internal/logging"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Политики обработки запросов без заголовка Origin.
+const (
+	// MissingOriginReject — запросы без Origin отклоняются.
+	MissingOriginReject = "reject"
+	// MissingOriginAllow — запросы без Origin допускаются. Браузеры всегда передают
+	// Origin при апгрейде, его нет только у нативных приложений и серверных клиентов.
+	MissingOriginAllow = "allow"
+)
+
+// regexRulePrefix — префикс правила, задающего регулярное выражение.
+const regexRulePrefix = "re:"
+
+// AnyOrigin — разрешающее правило, с которым совпадает любой корректный Origin.
+const AnyOrigin = "*"
+
+// originRule — правило сравнения Origin. Поддерживаются правила вида:
+//   - "*" — любой корректный Origin;
+//   - "https://app.example.com" — точное совпадение схемы, хоста и порта;
+//   - "app.example.com" — точное совпадение хоста при любой схеме и порте
+//     ("localhost:3000" — хоста и порта при любой схеме);
+//   - "https://*.example.com" или "*.example.com" — любой поддомен example.com
+//     (сам example.com не совпадает);
+//   - "re:^https://[a-z]+\.example\.com$" — регулярное выражение, которое должно
+//     совпасть с нормализованным Origin (scheme://host[:port]) целиком.
+type originRule struct {
+	raw      string
+	scheme   string
+	host     string
+	port     string
+	anyPort  bool
+	wildcard bool
+	any      bool
+	regex    *regexp.Regexp
+}
+
+// parseOriginRule разбирает правило сравнения Origin.
+func parseOriginRule(raw string) (originRule, error) {
+	rule := originRule{raw: raw}
+
+	if raw == AnyOrigin {
+		rule.any = true
+		return rule, nil
+	}
+	if pattern, ok := strings.CutPrefix(raw, regexRulePrefix); ok {
+		regex, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return originRule{}, fmt.Errorf("некорректное регулярное выражение в правиле %q: %w", raw, err)
+		}
+		rule.regex = regex
+		return rule, nil
+	}
+
+	hostPort := raw
+	if scheme, rest, ok := strings.Cut(raw, "://"); ok {
+		rule.scheme = strings.ToLower(scheme)
+		if rule.scheme != "http" && rule.scheme != "https" {
+			return originRule{}, fmt.Errorf("схема правила %q должна быть http или https", raw)
+		}
+		hostPort = rest
+	}
+	if hostPort == "" || strings.ContainsAny(hostPort, "/?#") {
+		return originRule{}, fmt.Errorf("правило %q должно содержать только схему, хост и порт", raw)
+	}
+
+	host, port := splitHostPort(hostPort)
+	if wildcardHost, ok := strings.CutPrefix(host, "*."); ok {
+		rule.wildcard = true
+		host = wildcardHost
+	}
+	if host == "" || strings.Contains(host, "*") {
+		return originRule{}, fmt.Errorf("в правиле %q допускается только шаблон поддоменов вида *.example.com", raw)
+	}
+	rule.host = strings.ToLower(host)
+	if rule.scheme != "" {
+		rule.port = normalizePort(rule.scheme, port)
+	} else {
+		rule.port = port
+		rule.anyPort = port == ""
+	}
+	return rule, nil
+}
+
+// matches сообщает, совпадает ли нормализованный Origin с правилом.
+func (rule originRule) matches(origin normalizedOrigin) bool {
+	if rule.any {
+		return true
+	}
+	if rule.regex != nil {
+		return rule.regex.MatchString(origin.String())
+	}
+	if rule.scheme != "" && rule.scheme != origin.scheme {
+		return false
+	}
+	if !rule.anyPort && rule.port != origin.port {
+		return false
+	}
+	if rule.wildcard {
+		return strings.HasSuffix(origin.host, "."+rule.host)
+	}
+	return origin.host == rule.host
+}
+
+// normalizedOrigin — Origin, разобранный на схему, хост и порт в нижнем регистре
+// без порта по умолчанию.
+type normalizedOrigin struct {
+	scheme string
+	host   string
+	port   string
+}
+
+func (o normalizedOrigin) String() string {
+	if o.port == "" {
+		return o.scheme + "://" + o.host
+	}
+	return o.scheme + "://" + net.JoinHostPort(o.host, o.port)
+}
+
+// parseOrigin разбирает заголовок Origin. Допускаются только http- и https-источники
+// без пути, запроса и фрагмента; значение "null" (например, из sandbox-iframe) отклоняется.
+func parseOrigin(origin string) (normalizedOrigin, error) {
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return normalizedOrigin{}, err
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return normalizedOrigin{}, fmt.Errorf("неподдерживаемая схема %q", parsed.Scheme)
+	}
+	if parsed.Hostname() == "" || parsed.User != nil || (parsed.Path != "" && parsed.Path != "/") ||
+		parsed.RawQuery != "" || parsed.Fragment != "" {
+		return normalizedOrigin{}, fmt.Errorf("Origin должен содержать только схему, хост и порт")
+	}
+	return normalizedOrigin{
+		scheme: scheme,
+		host:   strings.ToLower(parsed.Hostname()),
+		port:   normalizePort(scheme, parsed.Port()),
+	}, nil
+}
+
+// splitHostPort отделяет порт от хоста, если он указан.
+func splitHostPort(hostPort string) (string, string) {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return strings.Trim(hostPort, "[]"), ""
+	}
+	return host, port
+}
+
+// normalizePort убирает порт по умолчанию для схемы.
+func normalizePort(scheme, port string) string {
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		return ""
+	}
+	return port
+}
+
+// OriginPolicy решает, допустим ли запрос на апгрейд с данным заголовком Origin.
+// Origin отклоняется, если совпадает с любым запрещающим правилом, и допускается,
+// только если совпал хотя бы с одним разрешающим правилом. Без разрешающих правил
+// отклоняется любой Origin: разрешить все источники можно только явным правилом AnyOrigin.
+// Каждое отклонение записывается в журнал с указанием сработавшего правила.
+type OriginPolicy struct {
+	allowed       []originRule
+	blocked       []originRule
+	missingOrigin string
+	logger        *slog.Logger
+}
+
+type OriginOptions struct {
+	// Allowed — разрешающие правила. Если не заданы, отклоняется любой Origin;
+	// правило AnyOrigin ("*") допускает любой корректный Origin, не совпавший
+	// с запрещающими правилами.
+	Allowed []string
+	// Blocked — запрещающие правила.
+	Blocked []string
+	// MissingOrigin — политика для запросов без Origin: MissingOriginReject (по умолчанию)
+	// или MissingOriginAllow.
+	MissingOrigin string
+	// Logger — логгер политики. Если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+// NewOriginPolicy создает политику проверки Origin. Возвращает ошибку, если
+// какое-либо правило или политика для запросов без Origin некорректны.
+func NewOriginPolicy(options OriginOptions) (*OriginPolicy, error) {
+	policy := &OriginPolicy{
+		missingOrigin: options.MissingOrigin,
+		logger:        logging.Component(options.Logger, "ORIGIN_POLICY"),
+	}
+	switch policy.missingOrigin {
+	case "":
+		policy.missingOrigin = MissingOriginReject
+	case MissingOriginReject, MissingOriginAllow:
+	default:
+		return nil, fmt.Errorf("неизвестная политика для запросов без Origin %q", options.MissingOrigin)
+	}
+
+	for _, raw := range options.Allowed {
+		rule, err := parseOriginRule(raw)
+		if err != nil {
+			return nil, err
+		}
+		policy.allowed = append(policy.allowed, rule)
+	}
+	for _, raw := range options.Blocked {
+		rule, err := parseOriginRule(raw)
+		if err != nil {
+			return nil, err
+		}
+		policy.blocked = append(policy.blocked, rule)
+	}
+	return policy, nil
+}
+
+// Check проверяет заголовок Origin запроса. Подходит для websocket.Upgrader.CheckOrigin.
+func (p *OriginPolicy) Check(r *http.Request) bool {
+	logger := p.logger.With(slog.String(logging.KeyRemoteAddr, r.RemoteAddr))
+
+	header := r.Header.Get("Origin")
+	if header == "" {
+		if p.missingOrigin == MissingOriginAllow {
+			return true
+		}
+		logger.Warn("Origin отклонен: заголовок отсутствует", slog.String("policy", p.missingOrigin))
+		return false
+	}
+
+	origin, err := parseOrigin(header)
+	if err != nil {
+		logger.Warn("Origin отклонен: некорректный заголовок", slog.String("origin", header), slog.Any("error", err))
+		return false
+	}
+
+	for _, rule := range p.blocked {
+		if rule.matches(origin) {
+			logger.Warn("Origin отклонен запрещающим правилом",
+				slog.String("origin", header), slog.String("rule", rule.raw))
+			return false
+		}
+	}
+
+	for _, rule := range p.allowed {
+		if rule.matches(origin) {
+			logger.Debug("Origin разрешен", slog.String("origin", header), slog.String("rule", rule.raw))
+			return true
+		}
+	}
+	logger.Warn("Origin отклонен: не совпал ни с одним разрешающим правилом", slog.String("origin", header))
+	return false
+}
@@ -1,59 +1,43 @@
 package loaders
 
 import (
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
 	"net/http"
-	"strings"
 
 	"github.com/gorilla/websocket"
 )
 
+// bufferSize — размер буферов чтения и записи WebSocket-соединения.
+const bufferSize = 4096
+
 // NewUpgrader создает и возвращает новый websocket.Upgrader с пользовательской
 // функцией CheckOrigin. Функция CheckOrigin определяет, разрешен ли запрос
 // на подключение websocket на основе заголовка Origin запроса.
+// В Subprotocols передаются подпротоколы поддерживаемых кодеков, чтобы клиент мог
+// согласовать формат сообщений через заголовок Sec-WebSocket-Protocol.
+//
+// Размеры буферов задаются явно: при нулевом ReadBufferSize Upgrader читает из буфера
+// HTTP-сервера в обход захваченного соединения, и учет входящего трафика становится невозможен.
 //
 // Параметры:
 //   - debug: Если true, разрешены все origins.
-//   - validOrigin: Конкретный origin, который разрешен, если debug равен false.
+//   - originPolicy: Политика проверки Origin, если debug равен false.
+//   - enableCompression: Если true, с клиентами согласуется сжатие permessage-deflate.
 //
 // Возвращает:
 //
 //	websocket.Upgrader, настроенный с пользовательской логикой CheckOrigin.
-func NewUpgrader(debug bool, invalidOrigins []string) websocket.Upgrader {
+func NewUpgrader(debug bool, originPolicy *OriginPolicy, enableCompression bool) websocket.Upgrader {
 	return websocket.Upgrader{
+		ReadBufferSize:    bufferSize,
+		WriteBufferSize:   bufferSize,
+		EnableCompression: enableCompression,
+		Subprotocols:      codecs.Subprotocols(),
 		CheckOrigin: func(r *http.Request) bool {
 			if debug {
 				return true
 			}
-			origin := r.Header.Get("Origin")
-			return isAllowedOrigin(origin, invalidOrigins)
+			return originPolicy.Check(r)
 		},
 	}
 }
-
-// isAllowedOrigin проверяет, разрешен ли данный origin на основе списка запрещенных origin'ов.
-// Возвращает false, если origin пустой или содержит любой из запрещенных подстрок,
-// указанных в срезе invalidOrigins. Если срез invalidOrigins пуст, все origin'ы считаются разрешенными.
-//
-// Параметры:
-//   - origin: Строка origin для проверки.
-//   - invalidOrigins: Срез строк, представляющих подстроки origin'ов, которые запрещены.
-//
-// Возвращает:
-//   - bool: True, если origin разрешен, false в противном случае.
-func isAllowedOrigin(origin string, invalidOrigins []string) bool {
-	if len(origin) == 0 {
-		return false
-	}
-
-	if len(invalidOrigins) == 0 {
-		return true
-	}
-
-	for _, blocked := range invalidOrigins {
-		if strings.Contains(origin, blocked) {
-			return false
-		}
-	}
-
-	return true
-}
@@ -0,0 +1,437 @@
+// Package client — Go-клиент протокола мессенджера.
+//
+// Клиент подключается к WebSocket-эндпоинту сервера по TLS, аутентифицируется токеном
+// доступа, согласует кодек (JSON, MessagePack или Protobuf) и поддерживает соединение:
+// отправляет ping-фреймы, обнаруживает зависшее соединение и переподключается
+// с экспоненциальной задержкой, соблюдая подсказки сервера о задержке. После
+// переподключения клиент догружает через REST API сообщения бесед, пропущенные
+// во время разрыва.
+//
+// Входящие сообщения доступны через канал Messages или обратный вызов Options.OnMessage.
+// Ответы сервера на отправленные сообщения возвращают методы Send, SendData, SendInfo
+// и SendError: сервер обрабатывает сообщения соединения по порядку, поэтому ответы
+// сопоставляются с запросами в порядке отправки.
+//
+// Пакет входит в отдельный модуль github.com/1ight181/messenger/pkg вместе с общими частями протокола
+// (пакеты protocol, protocol/message, protocol/codecs) и не зависит от кода сервера.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/1ight181/messenger/pkg/protocol"
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	// ErrClosed возвращается после вызова Close.
+	ErrClosed = errors.New("клиент закрыт")
+	// ErrConnectionLost возвращается ожидающим ответа вызовам, если соединение
+	// разорвалось до получения ответа. Сообщение могло быть обработано сервером.
+	ErrConnectionLost = errors.New("соединение с сервером разорвано")
+	// ErrUnauthorized возвращается, если сервер отклонил токен доступа.
+	ErrUnauthorized = errors.New("сервер отклонил токен доступа")
+)
+
+// HandshakeError — отказ сервера в подключении по HTTP (например, превышен лимит подключений).
+type HandshakeError struct {
+	StatusCode int
+	// RetryAfter — задержка из заголовка Retry-After; 0, если заголовка нет.
+	RetryAfter time.Duration
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("сервер отклонил подключение: HTTP %d", e.StatusCode)
+}
+
+// Client — клиент мессенджера с автоматическим переподключением. Методы безопасны
+// для вызова из нескольких горутин.
+type Client struct {
+	*RESTClient
+
+	options Options
+	dialer  *websocket.Dialer
+	header  http.Header
+	logger  *slog.Logger
+
+	// writeMu упорядочивает запись сообщений и постановку ожидающих ответа вызовов в очередь.
+	writeMu sync.Mutex
+
+	mu        sync.Mutex
+	conn      *websocket.Conn
+	codec     codecs.Codec
+	connected chan struct{}
+	pending   []*call
+	err       error
+
+	resume   *resumeState
+	messages chan Message
+
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// Dial подключается к серверу и запускает обслуживание соединения. Ошибка первого
+// подключения возвращается сразу; последующие разрывы обрабатываются переподключением.
+func Dial(ctx context.Context, options Options) (*Client, error) {
+	if options.URL == "" {
+		return nil, errors.New("не задан адрес сервера")
+	}
+	options = options.withDefaults()
+
+	header := http.Header{}
+	if options.Token != "" {
+		header.Set("Authorization", "Bearer "+options.Token)
+	}
+	if options.Origin != "" {
+		header.Set("Origin", options.Origin)
+	}
+
+	c := &Client{
+		RESTClient: NewREST(options),
+		options:    options,
+		dialer: &websocket.Dialer{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   options.TLSConfig,
+			HandshakeTimeout:  options.HandshakeTimeout,
+			Subprotocols:      []string{options.Codec},
+			EnableCompression: true,
+		},
+		header:    header,
+		logger:    componentLogger(options.Logger),
+		connected: make(chan struct{}),
+		resume:    newResumeState(),
+		messages:  make(chan Message, options.BufferSize),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	go c.run(conn)
+
+	return c, nil
+}
+
+// Messages возвращает канал входящих сообщений. Канал закрывается после остановки
+// клиента. Если канал не читать, после заполнения буфера останавливается чтение
+// соединения, и оно будет разорвано по таймауту. Не используется, если задан Options.OnMessage.
+func (c *Client) Messages() <-chan Message {
+	return c.messages
+}
+
+// Connected сообщает, установлено ли сейчас соединение с сервером.
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.conn != nil
+}
+
+// Err возвращает причину остановки клиента: ErrClosed после Close, ErrUnauthorized,
+// если сервер перестал принимать токен, или ошибку разрыва при выключенном
+// переподключении. Пока клиент работает, возвращает nil.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// Done возвращает канал, закрываемый после остановки клиента.
+func (c *Client) Done() <-chan struct{} {
+	return c.stopped
+}
+
+// Close закрывает соединение close-фреймом с кодом 1000 и останавливает клиент.
+// Повторные вызовы безопасны.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		if c.err == nil {
+			c.err = ErrClosed
+		}
+		conn := c.conn
+		c.mu.Unlock()
+
+		close(c.done)
+
+		if conn != nil {
+			closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(c.options.WriteTimeout))
+			conn.Close()
+		}
+	})
+	<-c.stopped
+	return nil
+}
+
+// dial подключается к серверу и настраивает соединение.
+func (c *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	conn, response, err := c.dialer.DialContext(ctx, c.options.URL, c.header)
+	if err != nil {
+		if response != nil {
+			return nil, handshakeError(response)
+		}
+		return nil, err
+	}
+
+	readTimeout := c.options.HeartbeatInterval + c.options.PongTimeout
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+	return conn, nil
+}
+
+// handshakeError преобразует HTTP-ответ сервера на рукопожатие в ошибку.
+func handshakeError(response *http.Response) error {
+	if response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden {
+		return ErrUnauthorized
+	}
+	handshakeErr := &HandshakeError{StatusCode: response.StatusCode}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		handshakeErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return handshakeErr
+}
+
+// run обслуживает соединение и переподключается после разрывов до остановки клиента.
+func (c *Client) run(conn *websocket.Conn) {
+	defer close(c.stopped)
+	defer close(c.messages)
+
+	for reconnected := false; ; reconnected = true {
+		missed := c.resume.snapshot()
+		stopHeartbeat := c.attach(conn)
+		if reconnected {
+			c.resumeMissed(missed)
+		}
+
+		err := c.readLoop(conn)
+		stopHeartbeat()
+		c.detach(conn, err)
+
+		if conn = c.reconnect(err); conn == nil {
+			return
+		}
+	}
+}
+
+// attach делает соединение текущим и запускает отправку ping-фреймов.
+// Возвращает функцию, останавливающую отправку.
+func (c *Client) attach(conn *websocket.Conn) func() {
+	c.mu.Lock()
+	c.conn = conn
+	c.codec = codecs.BySubprotocol(conn.Subprotocol())
+	close(c.connected)
+	c.mu.Unlock()
+
+	c.logger.Info("Соединение с сервером установлено", slog.String("subprotocol", conn.Subprotocol()))
+	if c.options.OnConnect != nil {
+		c.options.OnConnect()
+	}
+
+	stop := make(chan struct{})
+	go c.heartbeat(conn, stop)
+	return func() { close(stop) }
+}
+
+// detach снимает разорванное соединение и завершает ожидающие ответа вызовы ошибкой ErrConnectionLost.
+func (c *Client) detach(conn *websocket.Conn, err error) {
+	conn.Close()
+
+	c.mu.Lock()
+	c.conn = nil
+	c.connected = make(chan struct{})
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	for _, pendingCall := range pending {
+		pendingCall.reply <- result{err: ErrConnectionLost}
+	}
+
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	c.logger.Warn("Соединение с сервером разорвано", slog.Any("error", err))
+	if c.options.OnDisconnect != nil {
+		c.options.OnDisconnect(err)
+	}
+}
+
+// heartbeat периодически отправляет ping-фреймы. Сервер отвечает pong-фреймами,
+// которые продлевают таймаут чтения соединения.
+func (c *Client) heartbeat(conn *websocket.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(c.options.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.options.WriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readLoop читает сообщения соединения до его разрыва и возвращает причину разрыва.
+func (c *Client) readLoop(conn *websocket.Conn) error {
+	readTimeout := c.options.HeartbeatInterval + c.options.PongTimeout
+
+	c.mu.Lock()
+	codec := c.codec
+	c.mu.Unlock()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		message, err := codec.Decode(data)
+		if err != nil {
+			c.logger.Warn("Некорректное сообщение сервера", slog.Any("error", err))
+			continue
+		}
+		c.dispatch(message)
+	}
+}
+
+// dispatch передает ответ сервера первому ожидающему вызову, а остальные сообщения —
+// получателю входящих сообщений. Сообщения с данными от других пользователей
+// и события изменения сообщений не бывают ответами; ответы без ожидающих вызовов
+// считаются входящими сообщениями.
+func (c *Client) dispatch(message Message) {
+	if message.Type != DataMessage && message.Type != MessageUpdated {
+		c.mu.Lock()
+		var pendingCall *call
+		if len(c.pending) > 0 {
+			pendingCall = c.pending[0]
+			c.pending = c.pending[1:]
+		}
+		c.mu.Unlock()
+
+		if pendingCall != nil {
+			pendingCall.reply <- result{response: message}
+			return
+		}
+	}
+	c.deliver(message)
+}
+
+// deliver передает входящее сообщение в OnMessage или канал Messages.
+// Повторно полученные сообщения с данными пропускаются.
+func (c *Client) deliver(message Message) {
+	if !c.resume.track(message) {
+		return
+	}
+	if c.options.OnMessage != nil {
+		c.options.OnMessage(message)
+		return
+	}
+	select {
+	case c.messages <- message:
+	case <-c.done:
+	}
+}
+
+// reconnect ждет задержку и переподключается к серверу. Возвращает nil, если клиент
+// закрыт, переподключение выключено или сервер отклонил токен доступа.
+func (c *Client) reconnect(cause error) *websocket.Conn {
+	if c.options.DisableReconnect {
+		c.stop(cause)
+		return nil
+	}
+
+	delay, hinted := reconnectHint(cause)
+	for attempt := 0; ; attempt++ {
+		if !hinted {
+			delay = c.backoff(attempt)
+		}
+		hinted = false
+
+		select {
+		case <-c.done:
+			return nil
+		case <-time.After(delay):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.options.HandshakeTimeout)
+		conn, err := c.dial(ctx)
+		cancel()
+		if err == nil {
+			return conn
+		}
+
+		if errors.Is(err, ErrUnauthorized) {
+			c.logger.Error("Переподключение остановлено: сервер отклонил токен доступа")
+			c.stop(err)
+			return nil
+		}
+		var handshakeErr *HandshakeError
+		if errors.As(err, &handshakeErr) && handshakeErr.RetryAfter > 0 {
+			delay, hinted = handshakeErr.RetryAfter, true
+		}
+		c.logger.Warn("Ошибка переподключения к серверу", slog.Int("attempt", attempt+1), slog.Any("error", err))
+	}
+}
+
+// stop запоминает причину остановки клиента, если клиент не закрыт явно.
+func (c *Client) stop(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// backoff возвращает задержку перед попыткой переподключения attempt (с нуля):
+// экспоненциальный рост от ReconnectMinDelay до ReconnectMaxDelay со случайным
+// разбросом, чтобы клиенты не переподключались одновременно.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.options.ReconnectMaxDelay
+	if attempt < 30 {
+		delay = min(c.options.ReconnectMinDelay<<attempt, c.options.ReconnectMaxDelay)
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// reconnectHint извлекает задержку переподключения из close-фрейма сервера.
+func reconnectHint(err error) (time.Duration, bool) {
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) {
+		return 0, false
+	}
+	return protocol.ParseReconnectHint(closeErr.Text)
+}
+
+// componentLogger возвращает логгер клиента с полем component.
+func componentLogger(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return logger.With("component", "MESSENGER_CLIENT")
+}
@@ -0,0 +1,318 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/1ight181/messenger/pkg/protocol"
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
+
+	"github.com/gorilla/websocket"
+)
+
+// testTimeout — время ожидания событий в тестах.
+const testTimeout = 5 * time.Second
+
+// fakeServer — сервер мессенджера для тестов клиента: принимает WebSocket-соединения
+// на /ws и отдает историю бесед на /api/conversations/{id}/messages. Соединения
+// передаются тесту через канал conns, и тест сам ведет обмен по ним.
+type fakeServer struct {
+	t      *testing.T
+	server *httptest.Server
+	conns  chan *serverConn
+
+	mu      sync.Mutex
+	history map[string][]Message
+}
+
+// serverConn — серверная сторона соединения клиента.
+type serverConn struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func startFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	fs := &fakeServer{t: t, conns: make(chan *serverConn, 4), history: make(map[string][]Message)}
+	upgrader := websocket.Upgrader{Subprotocols: codecs.Subprotocols()}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		fs.conns <- &serverConn{t: t, conn: conn}
+	})
+	mux.HandleFunc("GET /api/conversations/{id}/messages", func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		history := fs.history[r.PathValue("id")]
+		fs.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(history)
+	})
+	fs.server = httptest.NewTLSServer(mux)
+	t.Cleanup(fs.server.Close)
+	return fs
+}
+
+// setHistory задает историю беседы conversation.
+func (fs *fakeServer) setHistory(conversation string, messages ...Message) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.history[conversation] = messages
+}
+
+// dial подключает клиента к серверу с быстрыми таймаутами тестов.
+func (fs *fakeServer) dial(t *testing.T, options Options) *Client {
+	t.Helper()
+
+	options.URL = "wss" + strings.TrimPrefix(fs.server.URL, "https") + "/ws"
+	options.RESTURL = fs.server.URL + "/api"
+	options.TLSConfig = fs.server.Client().Transport.(*http.Transport).TLSClientConfig
+	options.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	if options.ReconnectMinDelay == 0 {
+		options.ReconnectMinDelay = 10 * time.Millisecond
+		options.ReconnectMaxDelay = 10 * time.Millisecond
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	c, err := Dial(ctx, options)
+	if err != nil {
+		t.Fatalf("Ошибка подключения клиента: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// accept ждет следующее соединение клиента.
+func (fs *fakeServer) accept() *serverConn {
+	fs.t.Helper()
+
+	select {
+	case conn := <-fs.conns:
+		fs.t.Cleanup(func() { conn.conn.Close() })
+		return conn
+	case <-time.After(testTimeout):
+		fs.t.Fatal("Клиент не подключился")
+		return nil
+	}
+}
+
+// write отправляет клиенту сообщение. Вызывается и из горутин теста, поэтому
+// ошибка отмечается без остановки теста.
+func (sc *serverConn) write(message Message) {
+	sc.t.Helper()
+
+	data, err := codecs.JSONCodec{}.Encode(message)
+	if err == nil {
+		err = sc.conn.WriteMessage(websocket.TextMessage, data)
+	}
+	if err != nil {
+		sc.t.Errorf("Ошибка отправки сообщения клиенту: %v", err)
+	}
+}
+
+// read ждет сообщение клиента. Вызывается из горутины теста: ошибка чтения
+// возвращается, а не завершает тест.
+func (sc *serverConn) read() (Message, error) {
+	sc.conn.SetReadDeadline(time.Now().Add(testTimeout))
+	_, data, err := sc.conn.ReadMessage()
+	if err != nil {
+		return Message{}, err
+	}
+	return codecs.JSONCodec{}.Decode(data)
+}
+
+// closeWithHint закрывает соединение close-фреймом 1012 с подсказкой о переподключении.
+func (sc *serverConn) closeWithHint(delay time.Duration) {
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseServiceRestart, protocol.ReconnectHint(delay))
+	sc.conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+	sc.conn.Close()
+}
+
+// expectMessage ждет входящее сообщение клиента.
+func expectMessage(t *testing.T, c *Client) Message {
+	t.Helper()
+
+	select {
+	case message := <-c.Messages():
+		return message
+	case <-time.After(testTimeout):
+		t.Fatal("Входящее сообщение не получено")
+		return Message{}
+	}
+}
+
+func dataMessage(id, conversation, text string) Message {
+	return Message{Type: DataMessage, ID: id, Conversation: conversation, From: "bob", Text: text, SentAt: 1}
+}
+
+func TestClientReconnectsWithServerHint(t *testing.T) {
+	fs := startFakeServer(t)
+	// Без подсказки сервера клиент ждал бы переподключения не меньше получаса.
+	c := fs.dial(t, Options{ReconnectMinDelay: time.Hour, ReconnectMaxDelay: time.Hour})
+	first := fs.accept()
+
+	// Вызов, ожидающий ответа, завершается ошибкой, если соединение разорвалось до ответа.
+	sendErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		defer cancel()
+		_, err := c.SendInfo(ctx, "до разрыва")
+		sendErr <- err
+	}()
+	if _, err := first.read(); err != nil {
+		t.Fatalf("Сервер не получил сообщение: %v", err)
+	}
+	first.closeWithHint(10 * time.Millisecond)
+	if err := <-sendErr; !errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("Ожидающий ответа вызов вернул %v, ожидалась ErrConnectionLost", err)
+	}
+
+	// Клиент переподключается через указанную сервером задержку, и отправка продолжается.
+	second := fs.accept()
+	go func() {
+		if request, err := second.read(); err == nil {
+			second.write(Message{Type: InfoResponse, Text: request.Text})
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	response, err := c.SendInfo(ctx, "после переподключения")
+	if err != nil || response.Text != "после переподключения" {
+		t.Fatalf("Отправка после переподключения вернула %+v, %v", response, err)
+	}
+}
+
+func TestClientResumesMissedMessagesOnce(t *testing.T) {
+	fs := startFakeServer(t)
+	c := fs.dial(t, Options{})
+	first := fs.accept()
+
+	first.write(dataMessage("m1", "room-1", "до разрыва"))
+	if message := expectMessage(t, c); message.ID != "m1" {
+		t.Fatalf("Получено сообщение %+v, ожидалось m1", message)
+	}
+
+	// Пока клиент отключен, в беседу приходят m2 и m3.
+	fs.setHistory("room-1",
+		dataMessage("m1", "room-1", "до разрыва"),
+		dataMessage("m2", "room-1", "во время разрыва"),
+		dataMessage("m3", "room-1", "во время разрыва"))
+	first.closeWithHint(0)
+
+	// После переподключения сервер повторно присылает уже полученные сообщения:
+	// клиент передает каждое сообщение только один раз.
+	second := fs.accept()
+	second.write(dataMessage("m1", "room-1", "повтор"))
+	second.write(dataMessage("m3", "room-1", "повтор"))
+	second.write(dataMessage("m4", "room-1", "после переподключения"))
+
+	var ids []string
+	for range 3 {
+		ids = append(ids, expectMessage(t, c).ID)
+	}
+	if fmt.Sprint(ids) != "[m2 m3 m4]" {
+		t.Fatalf("Получены сообщения %v, ожидались [m2 m3 m4]", ids)
+	}
+	select {
+	case message := <-c.Messages():
+		t.Fatalf("Получено лишнее сообщение %+v", message)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestClientMatchesRepliesInOrder(t *testing.T) {
+	fs := startFakeServer(t)
+	c := fs.dial(t, Options{})
+	conn := fs.accept()
+
+	const requests = 12
+
+	// Сервер отвечает на сообщения по порядку, а перед каждым ответом присылает сообщение
+	// другого участника и событие изменения сообщения: они не должны считаться ответами.
+	go func() {
+		for i := range requests {
+			request, err := conn.read()
+			if err != nil {
+				return
+			}
+			conn.write(dataMessage(fmt.Sprintf("push-%d", i), "room-2", "от другого участника"))
+			conn.write(Message{Type: MessageUpdated, ID: fmt.Sprintf("push-%d", i), Conversation: "room-2"})
+
+			response := Message{Text: request.Text}
+			switch request.Type {
+			case InfoMessage:
+				response.Type = InfoResponse
+			case ErrorMessage:
+				response.Type = ErrorResponse
+			case DataMessage:
+				response.Type = DataResponse
+				response.ID = "stored-" + request.Text
+				response.Conversation = request.Conversation
+			}
+			conn.write(response)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+			defer cancel()
+
+			text := fmt.Sprintf("запрос-%d", i)
+			var response Message
+			var err error
+			var expected MessageType
+			switch i % 3 {
+			case 0:
+				response, err = c.SendInfo(ctx, text)
+				expected = InfoResponse
+			case 1:
+				response, err = c.SendError(ctx, text)
+				expected = ErrorResponse
+			default:
+				response, err = c.SendData(ctx, "room-1", text)
+				expected = DataResponse
+			}
+			if err != nil || response.Type != expected || response.Text != text {
+				errs <- fmt.Errorf("%s: получен ответ %+v, %v; ожидался %s", text, response, err, expected)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	updates := 0
+	for range 2 * requests {
+		if message := expectMessage(t, c); message.Type == MessageUpdated {
+			updates++
+		} else if message.Type != DataMessage || message.Conversation != "room-2" {
+			t.Fatalf("Получено входящее сообщение %+v", message)
+		}
+	}
+	if updates != requests {
+		t.Fatalf("Получено %d событий изменения, ожидалось %d", updates, requests)
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"github.com/1ight181/messenger/pkg/protocol/codecs"
+	conv "github.com/1ight181/messenger/pkg/protocol/conversation"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+)
+
+// Message — сообщение протокола мессенджера.
+type Message = msg.Message
+
+// MessageType — тип сообщения; по сети передается строковым именем.
+type MessageType = msg.MessageType
+
+// FieldError — ошибка проверки поля сообщения, которую сервер возвращает в ответе.
+type FieldError = msg.FieldError
+
+// Attachment — ссылка на вложение сообщения с данными.
+type Attachment = msg.Attachment
+
+// LinkPreview — превью ссылки из текста сообщения с данными.
+type LinkPreview = msg.LinkPreview
+
+// Conversation — беседа из списка бесед пользователя.
+type Conversation = conv.Conversation
+
+// Типы сообщений протокола.
+const (
+	ErrorMessage    = msg.ErrorMessage
+	InfoMessage     = msg.InfoMessage
+	DataMessage     = msg.DataMessage
+	ErrorResponse   = msg.ErrorResponse
+	InfoResponse    = msg.InfoResponse
+	DataResponse    = msg.DataResponse
+	UnknownResponse = msg.UnknownResponse
+	// MessageUpdated — событие изменения сообщения с данными, например после загрузки
+	// превью ссылок. Приходит в канал Messages с прежними ID и временем отправки.
+	MessageUpdated = msg.MessageUpdated
+	Unknown        = msg.Unknown
+)
+
+// Подпротоколы кодеков, которые можно указать в Options.Codec.
+const (
+	CodecJSON    = codecs.JSONSubprotocol
+	CodecMsgpack = codecs.MsgpackSubprotocol
+	CodecProto   = codecs.ProtoSubprotocol
+)
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"time"
+)
+
+// Значения Options по умолчанию.
+const (
+	defaultHandshakeTimeout  = 10 * time.Second
+	defaultWriteTimeout      = 10 * time.Second
+	defaultHeartbeatInterval = 30 * time.Second
+	defaultPongTimeout       = 10 * time.Second
+	defaultReconnectMinDelay = 500 * time.Millisecond
+	defaultReconnectMaxDelay = 30 * time.Second
+	defaultBufferSize        = 64
+)
+
+type Options struct {
+	// URL — адрес WebSocket-эндпоинта сервера, например wss://chat.example.com:8443/ws.
+	URL string
+	// Token — токен доступа. Передается в заголовке "Authorization: Bearer <токен>"
+	// при подключении и в запросах к REST API.
+	Token string
+	// TLSConfig — настройки TLS (корневые сертификаты, ServerName). Если не задан,
+	// используются системные корневые сертификаты.
+	TLSConfig *tls.Config
+	// Codec — подпротокол кодека: CodecJSON (по умолчанию), CodecMsgpack или CodecProto.
+	Codec string
+	// Origin — значение заголовка Origin. Пустая строка — заголовок не передается,
+	// как у нативных приложений.
+	Origin string
+	// RESTURL — базовый адрес REST API сервера, например https://chat.example.com:8443/api.
+	// Нужен для History, Conversations и догрузки сообщений, пропущенных во время
+	// переподключения. Пустая строка — REST API не используется.
+	RESTURL string
+
+	// HandshakeTimeout — таймаут подключения и рукопожатия. По умолчанию 10s.
+	HandshakeTimeout time.Duration
+	// WriteTimeout — таймаут записи сообщения в соединение. По умолчанию 10s.
+	WriteTimeout time.Duration
+	// HeartbeatInterval — период отправки ping-фреймов. По умолчанию 30s.
+	HeartbeatInterval time.Duration
+	// PongTimeout — сколько ждать данных от сервера сверх HeartbeatInterval, прежде
+	// чем считать соединение разорванным. По умолчанию 10s.
+	PongTimeout time.Duration
+	// ReconnectMinDelay и ReconnectMaxDelay — границы экспоненциальной задержки
+	// переподключения. По умолчанию 500ms и 30s. Подсказка сервера о задержке
+	// переподключения в close-фрейме и заголовок Retry-After имеют приоритет.
+	ReconnectMinDelay time.Duration
+	ReconnectMaxDelay time.Duration
+	// DisableReconnect выключает автоматическое переподключение: после разрыва
+	// соединения клиент останавливается, а Err возвращает причину разрыва.
+	DisableReconnect bool
+
+	// OnMessage вызывается для каждого входящего сообщения в горутине чтения.
+	// Если задан, сообщения не попадают в канал Messages.
+	OnMessage func(message Message)
+	// OnConnect вызывается после каждого успешного подключения, в том числе переподключения.
+	OnConnect func()
+	// OnDisconnect вызывается при разрыве соединения с его причиной.
+	OnDisconnect func(err error)
+	// BufferSize — емкость канала Messages. По умолчанию 64.
+	BufferSize int
+
+	// Logger — логгер клиента. Если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+// withDefaults возвращает копию настроек с заполненными значениями по умолчанию.
+func (o Options) withDefaults() Options {
+	if o.Codec == "" {
+		o.Codec = CodecJSON
+	}
+	if o.HandshakeTimeout == 0 {
+		o.HandshakeTimeout = defaultHandshakeTimeout
+	}
+	if o.WriteTimeout == 0 {
+		o.WriteTimeout = defaultWriteTimeout
+	}
+	if o.HeartbeatInterval == 0 {
+		o.HeartbeatInterval = defaultHeartbeatInterval
+	}
+	if o.PongTimeout == 0 {
+		o.PongTimeout = defaultPongTimeout
+	}
+	if o.ReconnectMinDelay == 0 {
+		o.ReconnectMinDelay = defaultReconnectMinDelay
+	}
+	if o.ReconnectMaxDelay == 0 {
+		o.ReconnectMaxDelay = defaultReconnectMaxDelay
+	}
+	if o.BufferSize == 0 {
+		o.BufferSize = defaultBufferSize
+	}
+	return o
+}
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrRESTUnavailable возвращается методами RESTClient, если не задан Options.RESTURL.
+var ErrRESTUnavailable = errors.New("не задан адрес REST API")
+
+// APIError — ответ REST API с ошибкой.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("REST API: HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// RESTClient — клиент REST API сервера: история, список бесед и приглашения в них,
+// личные сообщения и присутствие пользователей. Входит в Client; для команд, которым не нужно
+// WebSocket-соединение, создается отдельно через NewREST.
+type RESTClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewREST создает клиента REST API по настройкам Options.RESTURL, Options.Token
+// и Options.TLSConfig. Соединение WebSocket не открывается.
+func NewREST(options Options) *RESTClient {
+	return &RESTClient{
+		baseURL: strings.TrimSuffix(options.RESTURL, "/"),
+		token:   options.Token,
+		http: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: options.TLSConfig,
+			},
+		},
+	}
+}
+
+// Conversations возвращает беседы, участником которых является пользователь.
+func (rc *RESTClient) Conversations(ctx context.Context) ([]Conversation, error) {
+	var conversations []Conversation
+	if err := rc.getJSON(ctx, "/conversations", &conversations); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// History возвращает до limit последних сообщений беседы в хронологическом порядке,
+// предшествующих сообщению before (пустая строка — самые последние). limit <= 0 —
+// значение сервера по умолчанию.
+func (rc *RESTClient) History(ctx context.Context, conversation, before string, limit int) ([]Message, error) {
+	query := url.Values{}
+	if before != "" {
+		query.Set("before", before)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/conversations/" + url.PathEscape(conversation) + "/messages"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var messages []Message
+	if err := rc.getJSON(ctx, path, &messages); err != nil {
+		return nil, err
+	}
+	// REST API не передает тип: история состоит из сообщений с данными.
+	for i := range messages {
+		messages[i].Type = DataMessage
+	}
+	return messages, nil
+}
+
+// SendRoom отправляет сообщение с данными в беседу conversation через REST API и возвращает
+// сохраненное сообщение. Первое сообщение создает беседу, и отправитель становится ее
+// участником; в существующую беседу могут писать только ее участники.
+func (rc *RESTClient) SendRoom(ctx context.Context, conversation, text string) (Message, error) {
+	request := struct {
+		Text string `json:"text"`
+	}{Text: text}
+	var message Message
+	err := rc.doJSON(ctx, http.MethodPost, "/conversations/"+url.PathEscape(conversation)+"/messages", request, &message)
+	message.Type = DataMessage
+	return message, err
+}
+
+// Invite приглашает пользователей userIDs в беседу conversation через REST API и возвращает
+// беседу с новым списком участников. Приглашать могут только участники беседы.
+func (rc *RESTClient) Invite(ctx context.Context, conversation string, userIDs ...string) (Conversation, error) {
+	request := struct {
+		Users []string `json:"users"`
+	}{Users: userIDs}
+	var updated Conversation
+	err := rc.doJSON(ctx, http.MethodPost, "/conversations/"+url.PathEscape(conversation)+"/members", request, &updated)
+	return updated, err
+}
+
+// Me возвращает идентификатор пользователя, от имени которого работает клиент.
+func (rc *RESTClient) Me(ctx context.Context) (string, error) {
+	var response struct {
+		User string `json:"user"`
+	}
+	if err := rc.getJSON(ctx, "/me", &response); err != nil {
+		return "", err
+	}
+	return response.User, nil
+}
+
+// SendDirect отправляет личное сообщение пользователю userID через REST API.
+// Сообщение не сохраняется на сервере и доставляется только подключенным соединениям
+// получателя; возвращает, был ли получатель подключен в момент отправки.
+func (rc *RESTClient) SendDirect(ctx context.Context, userID, text string) (bool, error) {
+	request := struct {
+		Text string `json:"text"`
+	}{Text: text}
+	var response struct {
+		Online bool `json:"online"`
+	}
+	err := rc.doJSON(ctx, http.MethodPost, "/users/"+url.PathEscape(userID)+"/messages", request, &response)
+	return response.Online, err
+}
+
+// Presence сообщает через REST API, подключены ли пользователи к серверу.
+func (rc *RESTClient) Presence(ctx context.Context, userIDs ...string) (map[string]bool, error) {
+	query := url.Values{"user": userIDs}
+
+	var response []struct {
+		User   string `json:"user"`
+		Online bool   `json:"online"`
+	}
+	if err := rc.getJSON(ctx, "/presence?"+query.Encode(), &response); err != nil {
+		return nil, err
+	}
+
+	presence := make(map[string]bool, len(response))
+	for _, entry := range response {
+		presence[entry.User] = entry.Online
+	}
+	return presence, nil
+}
+
+// getJSON выполняет аутентифицированный GET-запрос к REST API и разбирает ответ в value.
+func (rc *RESTClient) getJSON(ctx context.Context, path string, value any) error {
+	return rc.doJSON(ctx, http.MethodGet, path, nil, value)
+}
+
+// doJSON выполняет аутентифицированный запрос к REST API с телом body в JSON
+// (nil — без тела) и разбирает успешный ответ в value.
+func (rc *RESTClient) doJSON(ctx context.Context, method, path string, body, value any) error {
+	if rc.baseURL == "" {
+		return ErrRESTUnavailable
+	}
+
+	var requestBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		requestBody = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, rc.baseURL+path, requestBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if rc.token != "" {
+		request.Header.Set("Authorization", "Bearer "+rc.token)
+	}
+
+	response, err := rc.http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(response.Body).Decode(&body)
+		return &APIError{StatusCode: response.StatusCode, Message: body.Error}
+	}
+	return json.NewDecoder(response.Body).Decode(value)
+}
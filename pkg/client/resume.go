@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+const (
+	// resumeHistoryLimit — сколько последних сообщений беседы запрашивается при
+	// догрузке пропущенных сообщений; совпадает с максимумом REST API.
+	resumeHistoryLimit = 200
+	// seenLimit — сколько последних идентификаторов сообщений клиент помнит,
+	// чтобы не передавать повторно сообщения, полученные и вживую, и из истории.
+	seenLimit = 1024
+)
+
+// resumeMissed догружает из истории сообщения бесед, пришедшие во время разрыва
+// соединения, и передает их получателю входящих сообщений. missed — последние
+// полученные сообщения бесед до разрыва. Без REST API догрузка не выполняется.
+// В отличие от доставки вживую, догрузка возвращает и сообщения, отправленные
+// тем же пользователем с других устройств или через REST API: история не отличает
+// их от сообщений других участников.
+func (c *Client) resumeMissed(missed map[string]string) {
+	if c.options.RESTURL == "" || len(missed) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.options.HandshakeTimeout)
+	defer cancel()
+
+	for conversation, lastID := range missed {
+		history, err := c.History(ctx, conversation, "", resumeHistoryLimit)
+		if err != nil {
+			c.logger.Warn("Ошибка догрузки пропущенных сообщений",
+				slog.String("conversation", conversation), slog.Any("error", err))
+			continue
+		}
+		// Если последнего полученного сообщения нет в истории, пропущено больше
+		// resumeHistoryLimit сообщений, и передается вся полученная история.
+		start := slices.IndexFunc(history, func(message Message) bool { return message.ID == lastID }) + 1
+		for _, message := range history[start:] {
+			c.deliver(message)
+		}
+	}
+}
+
+// resumeState запоминает последние полученные сообщения бесед для догрузки
+// пропущенных сообщений после переподключения.
+type resumeState struct {
+	mu       sync.Mutex
+	lastSeen map[string]string
+	seen     map[string]struct{}
+	order    []string
+}
+
+func newResumeState() *resumeState {
+	return &resumeState{
+		lastSeen: make(map[string]string),
+		seen:     make(map[string]struct{}),
+	}
+}
+
+// track запоминает входящее сообщение беседы. Возвращает false, если сообщение
+// с таким идентификатором уже было получено. События изменения сообщений
+// (MessageUpdated) повторяют идентификатор сообщения и пропускаются всегда.
+func (rs *resumeState) track(message Message) bool {
+	if message.Type != DataMessage || message.ID == "" || message.Conversation == "" {
+		return true
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if _, ok := rs.seen[message.ID]; ok {
+		return false
+	}
+	rs.remember(message)
+	return true
+}
+
+// sent запоминает сообщение, отправленное клиентом, по ответу сервера: оно
+// не должно вернуться клиенту при догрузке истории.
+func (rs *resumeState) sent(response Message) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.remember(response)
+}
+
+// remember вызывается под mu.
+func (rs *resumeState) remember(message Message) {
+	rs.lastSeen[message.Conversation] = message.ID
+	rs.seen[message.ID] = struct{}{}
+	rs.order = append(rs.order, message.ID)
+	if len(rs.order) > seenLimit {
+		delete(rs.seen, rs.order[0])
+		rs.order = rs.order[1:]
+	}
+}
+
+// snapshot возвращает последние полученные сообщения всех бесед.
+func (rs *resumeState) snapshot() map[string]string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	missed := make(map[string]string, len(rs.lastSeen))
+	for conversation, lastID := range rs.lastSeen {
+		missed[conversation] = lastID
+	}
+	return missed
+}
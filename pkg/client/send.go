@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+)
+
+// call — отправленное сообщение, ожидающее ответа сервера.
+type call struct {
+	reply chan result
+}
+
+type result struct {
+	response Message
+	err      error
+}
+
+// ResponseError — отказ сервера обработать сообщение: ошибка проверки полей,
+// превышение лимита частоты, неизвестный тип или ошибка обработки.
+type ResponseError struct {
+	Response Message
+}
+
+func (e *ResponseError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Response.Text)
+	for i, fieldError := range e.Response.Errors {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(fieldError.Field + ": " + fieldError.Message)
+	}
+	return b.String()
+}
+
+// Send отправляет сообщение и ждет ответа сервера. Если соединение сейчас разорвано,
+// ждет переподключения. Ответ, означающий отказ сервера, возвращается как *ResponseError
+// вместе с самим ответом.
+//
+// Если ctx отменен после отправки, сообщение может быть обработано сервером.
+func (c *Client) Send(ctx context.Context, message Message) (Message, error) {
+	pendingCall, err := c.write(ctx, message)
+	if err != nil {
+		return Message{}, err
+	}
+
+	select {
+	case res := <-pendingCall.reply:
+		if res.err != nil {
+			return Message{}, res.err
+		}
+		if isFailure(message.Type, res.response) {
+			return res.response, &ResponseError{Response: res.response}
+		}
+		return res.response, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	case <-c.done:
+		return Message{}, ErrClosed
+	}
+}
+
+// SendData отправляет текст в беседу conversation. Ответ содержит идентификатор
+// и время сохранения сообщения. Участники беседы, подключенные к серверу, получат
+// сообщение с типом DataMessage.
+func (c *Client) SendData(ctx context.Context, conversation, text string) (Message, error) {
+	message := msg.NewDataMessage(text)
+	message.Conversation = conversation
+
+	response, err := c.Send(ctx, message)
+	if err == nil && response.ID != "" {
+		c.resume.sent(response)
+	}
+	return response, err
+}
+
+// SendInfo отправляет информационное сообщение серверу.
+func (c *Client) SendInfo(ctx context.Context, text string) (Message, error) {
+	return c.Send(ctx, msg.NewInfoMessage(text))
+}
+
+// SendError сообщает серверу об ошибке на стороне клиента.
+func (c *Client) SendError(ctx context.Context, text string) (Message, error) {
+	return c.Send(ctx, msg.NewErrorMessage(text))
+}
+
+// write кодирует и отправляет сообщение, ставя вызов в очередь ожидания ответа.
+func (c *Client) write(ctx context.Context, message Message) (*call, error) {
+	for {
+		c.mu.Lock()
+		conn, codec, connected, stopErr := c.conn, c.codec, c.connected, c.err
+		c.mu.Unlock()
+
+		if stopErr != nil {
+			return nil, stopErr
+		}
+		if conn == nil {
+			select {
+			case <-connected:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-c.stopped:
+				return nil, c.stoppedErr()
+			}
+		}
+
+		data, err := codec.Encode(message)
+		if err != nil {
+			return nil, err
+		}
+
+		pendingCall := &call{reply: make(chan result, 1)}
+
+		c.writeMu.Lock()
+		c.mu.Lock()
+		if c.conn != conn {
+			// Соединение сменилось, пока сообщение кодировалось.
+			c.mu.Unlock()
+			c.writeMu.Unlock()
+			continue
+		}
+		c.pending = append(c.pending, pendingCall)
+		c.mu.Unlock()
+
+		conn.SetWriteDeadline(time.Now().Add(c.options.WriteTimeout))
+		err = conn.WriteMessage(codec.FrameType(), data)
+		c.writeMu.Unlock()
+
+		if err != nil {
+			// Разорванное соединение обнаружит цикл чтения; ожидающий вызов
+			// завершится ошибкой ErrConnectionLost.
+			conn.Close()
+			return nil, err
+		}
+		return pendingCall, nil
+	}
+}
+
+// stoppedErr возвращает причину остановки клиента.
+func (c *Client) stoppedErr() error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+	return ErrClosed
+}
+
+// isFailure сообщает, означает ли ответ сервера отказ обработать сообщение запроса.
+func isFailure(request MessageType, response Message) bool {
+	switch {
+	case len(response.Errors) > 0:
+		return true
+	case response.Type == UnknownResponse, response.Type == ErrorMessage:
+		return true
+	case response.Type == ErrorResponse:
+		return request != ErrorMessage
+	default:
+		return false
+	}
+}
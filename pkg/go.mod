@@ -0,0 +1,11 @@
+module github.com/1ight181/messenger/pkg
+
+go 1.23.3
+
+require (
+	github.com/gorilla/websocket v1.5.3
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	google.golang.org/protobuf v1.36.5
+)
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
@@ -0,0 +1,17 @@
+package codecs
+
+import (
+	message "github.com/1ight181/messenger/pkg/protocol/message"
+)
+
+// Codec кодирует сообщения протокола в WebSocket-фреймы согласованного подпротокола
+// и разбирает их.
+type Codec interface {
+	Subprotocol() string
+	FrameType() int
+	Encode(message message.Message) ([]byte, error)
+	Decode(data []byte) (message.Message, error)
+	// DecodeStrict разбирает сообщение клиента, отклоняя поля вне схемы.
+	// Ошибки возвращаются как *message.ValidationError.
+	DecodeStrict(data []byte) (message.Message, error)
+}
@@ -0,0 +1,299 @@
+package codecs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// fullMessage возвращает сообщение, в котором заполнены все поля, включая вложенные.
+func fullMessage(messageType msg.MessageType) msg.Message {
+	return msg.Message{
+		ID:           "m-1",
+		Type:         messageType,
+		Text:         "привет, https://example.com",
+		Conversation: "room-1",
+		From:         "alice",
+		SentAt:       1700000000123,
+		TraceParent:  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		Errors: []msg.FieldError{
+			{Field: "text", Code: msg.CodeTooLong, Message: "слишком длинный текст"},
+			{Code: msg.CodeMalformed, Message: "некорректное сообщение"},
+		},
+		Attachments: []msg.Attachment{
+			{
+				ID:          "a-1",
+				Name:        "photo.png",
+				ContentType: "image/png",
+				Size:        1 << 20,
+				URL:         "/api/attachments/a-1",
+				Width:       1920,
+				Height:      1080,
+				Placeholder: "LEHV6nWB2yk8pyo0adR*.7kCMdnj",
+				Thumbnails: []msg.Thumbnail{
+					{Width: 320, Height: 180, ContentType: "image/jpeg", URL: "/api/attachments/a-1/thumbnails/320"},
+					{Width: 64, Height: 36, ContentType: "image/jpeg", URL: "/api/attachments/a-1/thumbnails/64"},
+				},
+			},
+			{ID: "a-2"},
+		},
+		Previews: []msg.LinkPreview{
+			{
+				URL:         "https://example.com",
+				Title:       "Пример",
+				Description: "Описание страницы",
+				Image:       "https://example.com/og.png",
+				SiteName:    "Example",
+			},
+		},
+	}
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	messages := map[string]msg.Message{
+		"full":    fullMessage(msg.DataMessage),
+		"empty":   {Type: msg.InfoMessage},
+		"unknown": fullMessage(msg.Unknown),
+		"text":    {Type: msg.DataResponse, Text: "ответ", Conversation: "room-1"},
+	}
+
+	for _, codec := range supported {
+		for name, message := range messages {
+			t.Run(codec.Subprotocol()+"/"+name, func(t *testing.T) {
+				data, err := codec.Encode(message)
+				if err != nil {
+					t.Fatalf("Ошибка кодирования: %v", err)
+				}
+
+				decoded, err := codec.Decode(data)
+				if err != nil {
+					t.Fatalf("Ошибка декодирования: %v", err)
+				}
+				if !reflect.DeepEqual(decoded, message) {
+					t.Fatalf("Сообщение изменилось при передаче:\nполучено  %+v\nожидалось %+v", decoded, message)
+				}
+
+				strict, err := codec.DecodeStrict(data)
+				if err != nil {
+					t.Fatalf("Ошибка строгого декодирования: %v", err)
+				}
+				if !reflect.DeepEqual(strict, message) {
+					t.Fatalf("Сообщение изменилось при строгом декодировании:\nполучено  %+v\nожидалось %+v", strict, message)
+				}
+			})
+		}
+	}
+}
+
+func TestCodecsUnregisteredTypeDecodesAsUnknown(t *testing.T) {
+	for _, codec := range supported {
+		t.Run(codec.Subprotocol(), func(t *testing.T) {
+			data, err := codec.Encode(msg.Message{Type: msg.DataMessage, Text: "привет"})
+			if err != nil {
+				t.Fatalf("Ошибка кодирования: %v", err)
+			}
+			// Подменяем имя типа на незарегистрированное той же длины, чтобы не пересчитывать
+			// длины в бинарных форматах.
+			data = []byte(strings.Replace(string(data), "data", "zzzz", 1))
+
+			decoded, err := codec.Decode(data)
+			if err != nil {
+				t.Fatalf("Ошибка декодирования: %v", err)
+			}
+			if decoded.Type != msg.Unknown || decoded.Text != "привет" {
+				t.Fatalf("Получено %+v, ожидался тип unknown и исходный текст", decoded)
+			}
+		})
+	}
+}
+
+func TestProtoDecodeStrict(t *testing.T) {
+	valid, err := ProtoCodec{}.Encode(msg.Message{Type: msg.DataMessage, Text: "привет"})
+	if err != nil {
+		t.Fatalf("Ошибка кодирования: %v", err)
+	}
+	withAttachment, err := ProtoCodec{}.Encode(msg.Message{
+		Type:        msg.DataMessage,
+		Attachments: []msg.Attachment{{ID: "a-1"}},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка кодирования: %v", err)
+	}
+
+	// Вложение с неизвестным полем 15 в поле attachments (9).
+	var attachment []byte
+	attachment = protowire.AppendTag(attachment, 1, protowire.BytesType)
+	attachment = protowire.AppendString(attachment, "a-1")
+	attachment = protowire.AppendTag(attachment, 15, protowire.VarintType)
+	attachment = protowire.AppendVarint(attachment, 1)
+	unknownNested := protowire.AppendTag(append([]byte(nil), valid...), 9, protowire.BytesType)
+	unknownNested = protowire.AppendBytes(unknownNested, attachment)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		field string
+		code  string
+	}{
+		{
+			name:  "unknown field",
+			data:  protowire.AppendVarint(protowire.AppendTag(append([]byte(nil), valid...), 42, protowire.VarintType), 1),
+			field: "#42",
+			code:  msg.CodeUnknownField,
+		},
+		{
+			name:  "invalid wire type",
+			data:  protowire.AppendVarint(protowire.AppendTag(append([]byte(nil), valid...), 2, protowire.VarintType), 1),
+			field: "text",
+			code:  msg.CodeInvalidType,
+		},
+		{
+			name:  "unknown nested field",
+			data:  unknownNested,
+			field: "attachments",
+			code:  msg.CodeUnknownField,
+		},
+		{
+			name: "truncated",
+			data: withAttachment[:len(withAttachment)-1],
+			code: msg.CodeMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ProtoCodec{}.DecodeStrict(tt.data)
+			var validationErr *msg.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Ожидалась ошибка проверки, получено %v", err)
+			}
+			got := validationErr.Fields[0]
+			if got.Field != tt.field || got.Code != tt.code {
+				t.Fatalf("Получена ошибка %+v, ожидалось поле %q с кодом %q", got, tt.field, tt.code)
+			}
+		})
+	}
+
+	// Без строгой проверки неизвестные поля пропускаются.
+	message, err := ProtoCodec{}.Decode(unknownNested)
+	if err != nil {
+		t.Fatalf("Ошибка декодирования: %v", err)
+	}
+	if message.Text != "привет" || len(message.Attachments) != 1 || message.Attachments[0].ID != "a-1" {
+		t.Fatalf("Получено %+v, ожидались текст и вложение a-1", message)
+	}
+}
+
+func TestDecodeStrictRejectsUnknownFields(t *testing.T) {
+	tests := []struct {
+		codec Codec
+		data  func(t *testing.T) []byte
+	}{
+		{JSONCodec{}, func(*testing.T) []byte {
+			return []byte(`{"type":"data","text":"привет","color":"red"}`)
+		}},
+		{MsgpackCodec{}, func(t *testing.T) []byte {
+			data, err := MsgpackCodec{}.Encode(msg.Message{Type: msg.DataMessage, Text: "привет"})
+			if err != nil {
+				t.Fatalf("Ошибка кодирования: %v", err)
+			}
+			// Увеличиваем число элементов fixmap и дописываем пару "color": "red".
+			data[0]++
+			return append(data, 0xa5, 'c', 'o', 'l', 'o', 'r', 0xa3, 'r', 'e', 'd')
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.codec.Subprotocol(), func(t *testing.T) {
+			data := tt.data(t)
+			if _, err := tt.codec.Decode(data); err != nil {
+				t.Fatalf("Decode не должен отклонять неизвестные поля: %v", err)
+			}
+
+			_, err := tt.codec.DecodeStrict(data)
+			var validationErr *msg.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Ожидалась ошибка проверки, получено %v", err)
+			}
+			if got := validationErr.Fields[0]; got.Field != "color" || got.Code != msg.CodeUnknownField {
+				t.Fatalf("Получена ошибка %+v, ожидалось неизвестное поле color", got)
+			}
+		})
+	}
+}
+
+func TestBySubprotocol(t *testing.T) {
+	tests := []struct {
+		subprotocol string
+		want        Codec
+	}{
+		{JSONSubprotocol, JSONCodec{}},
+		{MsgpackSubprotocol, MsgpackCodec{}},
+		{ProtoSubprotocol, ProtoCodec{}},
+		{"", JSONCodec{}},
+		{"messenger.xml", JSONCodec{}},
+	}
+
+	for _, tt := range tests {
+		if got := BySubprotocol(tt.subprotocol); got != tt.want {
+			t.Errorf("BySubprotocol(%q) = %T, ожидался %T", tt.subprotocol, got, tt.want)
+		}
+	}
+}
+
+func TestSubprotocolNegotiation(t *testing.T) {
+	upgrader := websocket.Upgrader{Subprotocols: Subprotocols()}
+	negotiated := make(chan Codec, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		negotiated <- BySubprotocol(conn.Subprotocol())
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		offered []string
+		want    Codec
+	}{
+		{"none", nil, JSONCodec{}},
+		{"json", []string{JSONSubprotocol}, JSONCodec{}},
+		{"msgpack", []string{MsgpackSubprotocol}, MsgpackCodec{}},
+		{"proto", []string{ProtoSubprotocol}, ProtoCodec{}},
+		// При нескольких поддерживаемых подпротоколах выбор определяет порядок supported.
+		{"server preference", []string{ProtoSubprotocol, JSONSubprotocol}, JSONCodec{}},
+		{"server preference binary", []string{ProtoSubprotocol, MsgpackSubprotocol}, MsgpackCodec{}},
+		{"unsupported first", []string{"messenger.xml", MsgpackSubprotocol}, MsgpackCodec{}},
+		{"unsupported only", []string{"messenger.xml"}, JSONCodec{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer := websocket.Dialer{Subprotocols: tt.offered}
+			conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+			if err != nil {
+				t.Fatalf("Ошибка подключения: %v", err)
+			}
+			defer conn.Close()
+
+			codec := <-negotiated
+			if codec != tt.want {
+				t.Fatalf("Согласован кодек %T, ожидался %T", codec, tt.want)
+			}
+			if got := BySubprotocol(conn.Subprotocol()); got != tt.want {
+				t.Fatalf("Клиент выбрал кодек %T по подпротоколу %q, ожидался %T", got, conn.Subprotocol(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package codecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"io"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// JSONSubprotocol — имя подпротокола WebSocket для JSON-кодека.
+const JSONSubprotocol = "messenger.json"
+
+// JSONCodec кодирует сообщения в JSON и передает их текстовыми фреймами.
+// Используется по умолчанию, если клиент не запросил другой подпротокол.
+type JSONCodec struct{}
+
+// Subprotocol возвращает имя подпротокола, под которым кодек согласуется с клиентом.
+func (JSONCodec) Subprotocol() string {
+	return JSONSubprotocol
+}
+
+// FrameType возвращает тип WebSocket-фрейма, которым передаются закодированные сообщения.
+func (JSONCodec) FrameType() int {
+	return websocket.TextMessage
+}
+
+// Encode сериализует сообщение в JSON.
+func (JSONCodec) Encode(message msg.Message) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+// Decode десериализует сообщение из JSON.
+func (JSONCodec) Decode(data []byte) (msg.Message, error) {
+	var message msg.Message
+	if err := json.Unmarshal(data, &message); err != nil {
+		return msg.Message{}, err
+	}
+	return message, nil
+}
+
+// DecodeStrict десериализует сообщение клиента из JSON. В отличие от Decode,
+// неизвестные поля и данные после JSON-объекта считаются ошибкой.
+// Ошибки возвращаются как *msg.ValidationError.
+func (JSONCodec) DecodeStrict(data []byte) (msg.Message, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var message msg.Message
+	if err := decoder.Decode(&message); err != nil {
+		return msg.Message{}, jsonValidationError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return msg.Message{}, msg.NewValidationError("", msg.CodeMalformed, "данные после JSON-объекта")
+	}
+	return message, nil
+}
+
+// jsonUnknownFieldPrefix — начало текста ошибки encoding/json о неизвестном поле.
+const jsonUnknownFieldPrefix = "json: unknown field "
+
+// jsonValidationError преобразует ошибку encoding/json в *msg.ValidationError с именем неверного поля.
+func jsonValidationError(err error) *msg.ValidationError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return msg.NewValidationError(typeErr.Field, msg.CodeInvalidType,
+			"ожидается значение типа "+typeErr.Type.String()+", получено "+typeErr.Value)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), jsonUnknownFieldPrefix); ok {
+		return msg.NewValidationError(strings.Trim(field, `"`), msg.CodeUnknownField, "неизвестное поле")
+	}
+	return msg.NewValidationError("", msg.CodeMalformed, "некорректный JSON: "+err.Error())
+}
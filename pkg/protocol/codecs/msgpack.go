@@ -0,0 +1,69 @@
+package codecs
+
+import (
+	"bytes"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MsgpackSubprotocol — имя подпротокола WebSocket для MessagePack-кодека.
+const MsgpackSubprotocol = "messenger.msgpack"
+
+// MsgpackCodec кодирует сообщения в MessagePack и передает их бинарными фреймами.
+// Имена полей совпадают с JSON-представлением (используются json-теги структуры),
+// поэтому схема сообщения едина для всех кодеков.
+type MsgpackCodec struct{}
+
+// Subprotocol возвращает имя подпротокола, под которым кодек согласуется с клиентом.
+func (MsgpackCodec) Subprotocol() string {
+	return MsgpackSubprotocol
+}
+
+// FrameType возвращает тип WebSocket-фрейма, которым передаются закодированные сообщения.
+func (MsgpackCodec) FrameType() int {
+	return websocket.BinaryMessage
+}
+
+// Encode сериализует сообщение в MessagePack.
+func (MsgpackCodec) Encode(message msg.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(message); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode десериализует сообщение из MessagePack.
+func (MsgpackCodec) Decode(data []byte) (msg.Message, error) {
+	var message msg.Message
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	if err := decoder.Decode(&message); err != nil {
+		return msg.Message{}, err
+	}
+	return message, nil
+}
+
+// DecodeStrict десериализует сообщение клиента из MessagePack. В отличие от Decode,
+// неизвестные поля считаются ошибкой. Ошибки возвращаются как *msg.ValidationError.
+func (MsgpackCodec) DecodeStrict(data []byte) (msg.Message, error) {
+	var message msg.Message
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	decoder.DisallowUnknownFields(true)
+	if err := decoder.Decode(&message); err != nil {
+		if field, ok := strings.CutPrefix(err.Error(), msgpackUnknownFieldPrefix); ok {
+			return msg.Message{}, msg.NewValidationError(strings.Trim(field, `"`), msg.CodeUnknownField, "неизвестное поле")
+		}
+		return msg.Message{}, msg.NewValidationError("", msg.CodeMalformed, "некорректный MessagePack: "+err.Error())
+	}
+	return message, nil
+}
+
+// msgpackUnknownFieldPrefix — начало текста ошибки msgpack о неизвестном поле.
+const msgpackUnknownFieldPrefix = "msgpack: unknown field "
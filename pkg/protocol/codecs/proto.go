@@ -0,0 +1,212 @@
+package codecs
+
+import (
+	"errors"
+	"fmt"
+	msg "github.com/1ight181/messenger/pkg/protocol/message"
+	"github.com/1ight181/messenger/pkg/protocol/messengerpb"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ProtoSubprotocol — имя подпротокола WebSocket для Protobuf-кодека.
+const ProtoSubprotocol = "messenger.proto"
+
+// ProtoCodec кодирует сообщения в формат Protobuf по схеме api/proto/messenger.proto
+// и передает их бинарными фреймами. Сообщения сериализуются типами пакета messengerpb,
+// сгенерированными по этой схеме. Неизвестные поля при декодировании пропускаются,
+// что позволяет расширять схему без поломки старых клиентов; сообщения клиентов
+// разбираются DecodeStrict, который их отклоняет.
+type ProtoCodec struct{}
+
+// Subprotocol возвращает имя подпротокола, под которым кодек согласуется с клиентом.
+func (ProtoCodec) Subprotocol() string {
+	return ProtoSubprotocol
+}
+
+// FrameType возвращает тип WebSocket-фрейма, которым передаются закодированные сообщения.
+func (ProtoCodec) FrameType() int {
+	return websocket.BinaryMessage
+}
+
+// Encode сериализует сообщение в Protobuf.
+func (ProtoCodec) Encode(message msg.Message) ([]byte, error) {
+	return proto.Marshal(toProto(message))
+}
+
+// Decode десериализует сообщение из Protobuf.
+func (ProtoCodec) Decode(data []byte) (msg.Message, error) {
+	var message messengerpb.Message
+	if err := proto.Unmarshal(data, &message); err != nil {
+		return msg.Message{}, fmt.Errorf("некорректное сообщение protobuf: %w", err)
+	}
+	return fromProto(&message), nil
+}
+
+// DecodeStrict десериализует сообщение клиента из Protobuf. В отличие от Decode,
+// неизвестные поля и поля с неверным типом кодирования считаются ошибкой.
+// Ошибки возвращаются как *msg.ValidationError.
+func (ProtoCodec) DecodeStrict(data []byte) (msg.Message, error) {
+	var message messengerpb.Message
+	if err := proto.Unmarshal(data, &message); err != nil {
+		return msg.Message{}, msg.NewValidationError("", msg.CodeMalformed,
+			fmt.Sprintf("некорректное сообщение protobuf: %v", err))
+	}
+	if err := checkProtoFields(message.ProtoReflect(), ""); err != nil {
+		var validationErr *msg.ValidationError
+		if errors.As(err, &validationErr) {
+			return msg.Message{}, err
+		}
+		return msg.Message{}, msg.NewValidationError("", msg.CodeMalformed, err.Error())
+	}
+	return fromProto(&message), nil
+}
+
+// checkProtoFields проверяет, что в сообщении m и вложенных в него сообщениях нет
+// неизвестных полей: сгенерированный код сохраняет как неизвестные и поля с неверным
+// типом кодирования. field — имя поля сообщения верхнего уровня, в котором находится m,
+// или пустая строка для самого сообщения; ошибки вложенных сообщений относятся к нему.
+func checkProtoFields(m protoreflect.Message, field string) error {
+	if unknown := m.GetUnknown(); len(unknown) > 0 {
+		number, _, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return fmt.Errorf("некорректный тег protobuf: %w", protowire.ParseError(n))
+		}
+		if field != "" {
+			return msg.NewValidationError(field, msg.CodeUnknownField,
+				fmt.Sprintf("неизвестное поле protobuf %d", number))
+		}
+		if known := m.Descriptor().Fields().ByNumber(number); known != nil {
+			return msg.NewValidationError(string(known.Name()), msg.CodeInvalidType,
+				"неверный тип кодирования поля")
+		}
+		return msg.NewValidationError(fmt.Sprintf("#%d", number), msg.CodeUnknownField,
+			fmt.Sprintf("неизвестное поле protobuf %d", number))
+	}
+
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if !fd.IsList() || fd.Message() == nil {
+			return true
+		}
+		name := field
+		if name == "" {
+			name = string(fd.Name())
+		}
+		list := value.List()
+		for i := 0; i < list.Len(); i++ {
+			if err = checkProtoFields(list.Get(i).Message(), name); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+	return err
+}
+
+// toProto преобразует сообщение в сгенерированный тип messengerpb.Message.
+func toProto(message msg.Message) *messengerpb.Message {
+	pb := &messengerpb.Message{
+		Type:         message.Type.String(),
+		Text:         message.Text,
+		Id:           message.ID,
+		Conversation: message.Conversation,
+		From:         message.From,
+		SentAt:       message.SentAt,
+		Traceparent:  message.TraceParent,
+	}
+	for _, fieldError := range message.Errors {
+		pb.Errors = append(pb.Errors, &messengerpb.FieldError{
+			Field:   fieldError.Field,
+			Code:    fieldError.Code,
+			Message: fieldError.Message,
+		})
+	}
+	for _, attachment := range message.Attachments {
+		pbAttachment := &messengerpb.Attachment{
+			Id:          attachment.ID,
+			Name:        attachment.Name,
+			ContentType: attachment.ContentType,
+			Size:        attachment.Size,
+			Url:         attachment.URL,
+			Width:       int32(attachment.Width),
+			Height:      int32(attachment.Height),
+			Placeholder: attachment.Placeholder,
+		}
+		for _, thumbnail := range attachment.Thumbnails {
+			pbAttachment.Thumbnails = append(pbAttachment.Thumbnails, &messengerpb.Thumbnail{
+				Width:       int32(thumbnail.Width),
+				Height:      int32(thumbnail.Height),
+				ContentType: thumbnail.ContentType,
+				Url:         thumbnail.URL,
+			})
+		}
+		pb.Attachments = append(pb.Attachments, pbAttachment)
+	}
+	for _, preview := range message.Previews {
+		pb.Previews = append(pb.Previews, &messengerpb.LinkPreview{
+			Url:         preview.URL,
+			Title:       preview.Title,
+			Description: preview.Description,
+			Image:       preview.Image,
+			SiteName:    preview.SiteName,
+		})
+	}
+	return pb
+}
+
+// fromProto преобразует сгенерированный тип messengerpb.Message в сообщение.
+// Имя типа, не зарегистрированное в реестре типов, становится msg.Unknown.
+func fromProto(pb *messengerpb.Message) msg.Message {
+	messageType, _ := msg.Lookup(pb.GetType())
+	message := msg.Message{
+		ID:           pb.GetId(),
+		Type:         messageType,
+		Text:         pb.GetText(),
+		Conversation: pb.GetConversation(),
+		From:         pb.GetFrom(),
+		SentAt:       pb.GetSentAt(),
+		TraceParent:  pb.GetTraceparent(),
+	}
+	for _, fieldError := range pb.GetErrors() {
+		message.Errors = append(message.Errors, msg.FieldError{
+			Field:   fieldError.GetField(),
+			Code:    fieldError.GetCode(),
+			Message: fieldError.GetMessage(),
+		})
+	}
+	for _, pbAttachment := range pb.GetAttachments() {
+		attachment := msg.Attachment{
+			ID:          pbAttachment.GetId(),
+			Name:        pbAttachment.GetName(),
+			ContentType: pbAttachment.GetContentType(),
+			Size:        pbAttachment.GetSize(),
+			URL:         pbAttachment.GetUrl(),
+			Width:       int(pbAttachment.GetWidth()),
+			Height:      int(pbAttachment.GetHeight()),
+			Placeholder: pbAttachment.GetPlaceholder(),
+		}
+		for _, thumbnail := range pbAttachment.GetThumbnails() {
+			attachment.Thumbnails = append(attachment.Thumbnails, msg.Thumbnail{
+				Width:       int(thumbnail.GetWidth()),
+				Height:      int(thumbnail.GetHeight()),
+				ContentType: thumbnail.GetContentType(),
+				URL:         thumbnail.GetUrl(),
+			})
+		}
+		message.Attachments = append(message.Attachments, attachment)
+	}
+	for _, preview := range pb.GetPreviews() {
+		message.Previews = append(message.Previews, msg.LinkPreview{
+			URL:         preview.GetUrl(),
+			Title:       preview.GetTitle(),
+			Description: preview.GetDescription(),
+			Image:       preview.GetImage(),
+			SiteName:    preview.GetSiteName(),
+		})
+	}
+	return message
+}
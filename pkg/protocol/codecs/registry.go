@@ -0,0 +1,35 @@
+package codecs
+
+// supported перечисляет поддерживаемые кодеки в порядке предпочтения сервера.
+// JSON стоит первым, поэтому остается кодеком по умолчанию.
+var supported = []Codec{
+	JSONCodec{},
+	MsgpackCodec{},
+	ProtoCodec{},
+}
+
+// Default возвращает кодек, используемый при отсутствии согласованного подпротокола.
+func Default() Codec {
+	return JSONCodec{}
+}
+
+// Subprotocols возвращает имена подпротоколов всех поддерживаемых кодеков
+// для передачи в websocket.Upgrader.
+func Subprotocols() []string {
+	subprotocols := make([]string, 0, len(supported))
+	for _, codec := range supported {
+		subprotocols = append(subprotocols, codec.Subprotocol())
+	}
+	return subprotocols
+}
+
+// BySubprotocol возвращает кодек по имени согласованного подпротокола.
+// Если подпротокол пуст или неизвестен, возвращается кодек по умолчанию.
+func BySubprotocol(subprotocol string) Codec {
+	for _, codec := range supported {
+		if codec.Subprotocol() == subprotocol {
+			return codec
+		}
+	}
+	return Default()
+}
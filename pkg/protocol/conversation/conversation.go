@@ -0,0 +1,7 @@
+package conversation
+
+type Conversation struct {
+	ID            string   `json:"id"`
+	Members       []string `json:"members"`
+	LastMessageAt int64    `json:"last_message_at,omitempty"`
+}
@@ -0,0 +1,62 @@
+package message
+
+type Message struct {
+	ID           string      `json:"id,omitempty"`
+	Type         MessageType `json:"type"`
+	Text         string      `json:"text"`
+	Conversation string      `json:"conversation,omitempty"`
+	From         string      `json:"from,omitempty"`
+	SentAt       int64       `json:"sent_at,omitempty"`
+	// TraceParent — контекст трассировки в формате заголовка W3C traceparent.
+	// Связывает этапы обработки сообщения, в том числе его доставку другим пользователям, в одну трассу.
+	TraceParent string `json:"traceparent,omitempty"`
+	// Errors — ошибки проверки полей сообщения клиента, на которое отвечает сервер.
+	Errors []FieldError `json:"errors,omitempty"`
+	// Attachments — вложения сообщения с данными.
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Previews — превью ссылок из текста сообщения. Сервер загружает их после отправки
+	// сообщения и присылает участникам беседы в событии message_updated.
+	Previews []LinkPreview `json:"previews,omitempty"`
+}
+
+// LinkPreview — превью ссылки: метаданные страницы, на которую она ведет.
+type LinkPreview struct {
+	// URL — ссылка из текста сообщения.
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Image — абсолютный URL изображения страницы (og:image).
+	Image    string `json:"image,omitempty"`
+	SiteName string `json:"site_name,omitempty"`
+}
+
+// Attachment — ссылка на загруженное вложение. Клиент передает только ID вложения,
+// остальные поля заполняет сервер при обработке сообщения.
+type Attachment struct {
+	ID string `json:"id"`
+	// Name — имя файла, указанное при загрузке.
+	Name string `json:"name,omitempty"`
+	// ContentType — тип содержимого, определенный сервером по данным файла.
+	ContentType string `json:"content_type,omitempty"`
+	// Size — размер файла в байтах.
+	Size int64 `json:"size,omitempty"`
+	// URL — путь для скачивания вложения; запрос аутентифицируется так же, как REST API.
+	URL string `json:"url,omitempty"`
+	// Width и Height — размеры изображения в пикселях с учетом его ориентации.
+	// Заполняются только для изображений.
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// Placeholder — BlurHash изображения для показа размытой заглушки до загрузки миниатюры.
+	Placeholder string `json:"placeholder,omitempty"`
+	// Thumbnails — уменьшенные копии изображения от большей к меньшей.
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty"`
+}
+
+// Thumbnail — уменьшенная копия изображения из вложения.
+type Thumbnail struct {
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"content_type"`
+	// URL — путь для скачивания миниатюры; аутентифицируется так же, как URL вложения.
+	URL string `json:"url"`
+}
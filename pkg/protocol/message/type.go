@@ -0,0 +1,222 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+type MessageType int
+
+const (
+	ErrorMessage MessageType = iota
+	InfoMessage
+	DataMessage
+
+	ErrorResponse
+	InfoResponse
+	DataResponse
+	UnknownResponse
+
+	// MessageUpdated — событие изменения сохраненного сообщения, например после загрузки
+	// превью ссылок. Содержит сообщение целиком с прежними ID и временем отправки.
+	MessageUpdated
+)
+
+// Unknown — тип сообщения с именем, не зарегистрированным в реестре типов.
+// Передается по сети как "unknown" и не совпадает ни с одним зарегистрированным типом.
+const Unknown MessageType = -1
+
+// unknownName — имя типа Unknown.
+const unknownName = "unknown"
+
+// typeNamePattern — допустимый формат имени типа сообщения.
+var typeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_.]*$`)
+
+// registry — реестр типов сообщений: значение MessageType — индекс имени в names.
+// Встроенные типы регистрируются при инициализации пакета, остальные — подсистемами
+// при запуске через Register и RegisterClient. В client отмечены типы, которые
+// могут отправлять клиенты.
+var registry = struct {
+	mu     sync.RWMutex
+	names  []string
+	byName map[string]MessageType
+	client []MessageType
+}{
+	names: []string{
+		ErrorMessage:    "error",
+		InfoMessage:     "info",
+		DataMessage:     "data",
+		ErrorResponse:   "error_response",
+		InfoResponse:    "info_response",
+		DataResponse:    "data_response",
+		UnknownResponse: "unknown_response",
+		MessageUpdated:  "message_updated",
+	},
+	client: []MessageType{ErrorMessage, InfoMessage, DataMessage},
+}
+
+func init() {
+	registry.byName = make(map[string]MessageType, len(registry.names))
+	for i, name := range registry.names {
+		registry.byName[name] = MessageType(i)
+	}
+}
+
+// Register регистрирует новый тип сообщения с уникальным именем name и возвращает его значение.
+// Имя передается по сети и должно состоять из строчных латинских букв, цифр, "_" и "."
+// и начинаться с буквы. Предназначен для вызова подсистемами при запуске сервера.
+//
+// Возвращает:
+//   - MessageType: Значение зарегистрированного типа.
+//   - error: Ошибка, если имя некорректно, зарезервировано или уже зарегистрировано.
+func Register(name string) (MessageType, error) {
+	return register(name, false)
+}
+
+// RegisterClient регистрирует тип сообщения как Register и отмечает его как тип,
+// который могут отправлять клиенты: сообщения этого типа проходят проверку сервера,
+// а имя типа можно использовать как ключ правил ограничения частоты.
+func RegisterClient(name string) (MessageType, error) {
+	return register(name, true)
+}
+
+func register(name string, client bool) (MessageType, error) {
+	if !typeNamePattern.MatchString(name) {
+		return Unknown, fmt.Errorf("некорректное имя типа сообщения %q", name)
+	}
+	if name == unknownName {
+		return Unknown, fmt.Errorf("имя типа сообщения %q зарезервировано", name)
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.byName[name]; ok {
+		return Unknown, fmt.Errorf("тип сообщения %q уже зарегистрирован", name)
+	}
+	messageType := MessageType(len(registry.names))
+	registry.names = append(registry.names, name)
+	registry.byName[name] = messageType
+	if client {
+		registry.client = append(registry.client, messageType)
+	}
+	return messageType, nil
+}
+
+// MustRegister регистрирует тип сообщения как Register и паникует при ошибке.
+// Предназначен для инициализации переменных пакетов.
+func MustRegister(name string) MessageType {
+	messageType, err := Register(name)
+	if err != nil {
+		panic(err)
+	}
+	return messageType
+}
+
+// MustRegisterClient регистрирует тип сообщения клиента как RegisterClient и паникует
+// при ошибке. Предназначен для инициализации переменных пакетов.
+func MustRegisterClient(name string) MessageType {
+	messageType, err := RegisterClient(name)
+	if err != nil {
+		panic(err)
+	}
+	return messageType
+}
+
+// ClientTypeNames возвращает имена типов сообщений, которые могут отправлять клиенты,
+// в порядке регистрации: встроенные error, info, data и типы, зарегистрированные
+// через RegisterClient.
+func ClientTypeNames() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	names := make([]string, len(registry.client))
+	for i, messageType := range registry.client {
+		names[i] = registry.names[messageType]
+	}
+	return names
+}
+
+// Lookup возвращает тип сообщения по имени. Если имя не зарегистрировано,
+// возвращает Unknown и false.
+func Lookup(name string) (MessageType, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	messageType, ok := registry.byName[name]
+	if !ok {
+		return Unknown, false
+	}
+	return messageType, true
+}
+
+// IsClient сообщает, могут ли клиенты отправлять сообщения этого типа.
+func (mt MessageType) IsClient() bool {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	return slices.Contains(registry.client, mt)
+}
+
+// String возвращает имя типа сообщения, под которым он передается по сети.
+// Для незарегистрированных значений, в том числе Unknown, возвращает "unknown".
+func (mt MessageType) String() string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	if mt < 0 || int(mt) >= len(registry.names) {
+		return unknownName
+	}
+	return registry.names[mt]
+}
+
+// MarshalJSON реализует интерфейс json.Marshaler для типа MessageType.
+// Он преобразует значение MessageType в строковое представление JSON,
+// вызывая метод String и оборачивая результат в двойные кавычки.
+func (mt MessageType) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + mt.String() + `"`), nil
+}
+
+// MarshalText реализует интерфейс encoding.TextMarshaler для типа MessageType.
+// Используется бинарными кодеками, чтобы тип сообщения передавался тем же
+// строковым именем, что и в JSON.
+func (mt MessageType) MarshalText() ([]byte, error) {
+	return []byte(mt.String()), nil
+}
+
+// UnmarshalJSON реализует пользовательский JSON-демаршалер для типа MessageType.
+// Он интерпретирует JSON-данные как строку и сопоставляет её с зарегистрированным
+// типом сообщения. Если имя не зарегистрировано, присваивается Unknown.
+//
+// Параметры:
+//   - b: Срез байтов, содержащий JSON-данные.
+//
+// Возвращает:
+//   - Ошибку, если входные данные не могут быть демаршалированы в строку, или nil,
+//     если демаршалирование и сопоставление выполнены успешно.
+func (mt *MessageType) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		// encoding/json не указывает поле в ошибках json.Unmarshaler,
+		// а тип сообщения передается в поле type.
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			typeErr.Field = "type"
+		}
+		return err
+	}
+
+	return mt.UnmarshalText([]byte(str))
+}
+
+// UnmarshalText реализует интерфейс encoding.TextUnmarshaler для типа MessageType.
+// Сопоставляет имя с зарегистрированным типом сообщения; незарегистрированные имена
+// преобразуются в Unknown.
+func (mt *MessageType) UnmarshalText(text []byte) error {
+	*mt, _ = Lookup(string(text))
+	return nil
+}
@@ -0,0 +1,63 @@
+package message
+
+import (
+	"strings"
+)
+
+// Коды ошибок проверки полей сообщения.
+const (
+	// CodeMalformed — сообщение не удалось разобрать.
+	CodeMalformed = "malformed"
+	// CodeUnknownField — поле не входит в схему сообщения.
+	CodeUnknownField = "unknown_field"
+	// CodeInvalidType — значение поля имеет неверный тип.
+	CodeInvalidType = "invalid_type"
+	// CodeRequired — обязательное поле не заполнено.
+	CodeRequired = "required"
+	// CodeTooLong — значение поля длиннее допустимого.
+	CodeTooLong = "too_long"
+	// CodeInvalidValue — значение поля недопустимо.
+	CodeInvalidValue = "invalid_value"
+	// CodeForbidden — поле заполняется только сервером.
+	CodeForbidden = "forbidden"
+)
+
+// FieldError описывает ошибку проверки одного поля сообщения клиента.
+// Field — имя поля в JSON-представлении сообщения; пустое, если ошибка относится
+// к сообщению целиком.
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError — ошибка разбора или проверки сообщения клиента со списком
+// неверных полей. Клиенту возвращается сообщение об ошибке с этим списком,
+// а соединение остается открытым.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// NewValidationError создает ValidationError с одной ошибкой поля.
+func NewValidationError(field, code, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Code: code, Message: message}}}
+}
+
+func (ve *ValidationError) Error() string {
+	parts := make([]string, 0, len(ve.Fields))
+	for _, field := range ve.Fields {
+		if field.Field == "" {
+			parts = append(parts, field.Message)
+			continue
+		}
+		parts = append(parts, field.Field+": "+field.Message)
+	}
+	return "некорректное сообщение: " + strings.Join(parts, "; ")
+}
+
+// Response возвращает ответ с типом ErrorResponse и списком неверных полей для отправки клиенту.
+func (ve *ValidationError) Response() Message {
+	response := NewErrorResponse("Некорректное сообщение")
+	response.Errors = ve.Fields
+	return response
+}
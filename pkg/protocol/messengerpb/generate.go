@@ -0,0 +1,6 @@
+// Package messengerpb содержит типы Protobuf, сгенерированные protoc-gen-go по схеме
+// api/proto/messenger.proto. Кодек codecs.ProtoCodec преобразует их в msg.Message
+// и обратно; после изменения схемы типы перегенерируются командой go generate.
+package messengerpb
+
+//go:generate protoc --proto_path=../../../api/proto --go_out=. --go_opt=paths=source_relative messenger.proto
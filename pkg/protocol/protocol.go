@@ -0,0 +1,35 @@
+// Package protocol — общие для сервера и клиентов части протокола мессенджера.
+// Модель сообщений находится в пакете message, беседы — в пакете conversation,
+// кодеки подпротоколов WebSocket — в пакете codecs. Сам пакет описывает соглашения
+// о close-фреймах.
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reconnectHintPrefix — префикс подсказки о переподключении в причине close-фрейма.
+const reconnectHintPrefix = "reconnect_after_ms="
+
+// ReconnectHint формирует причину close-фрейма, подсказывающую клиенту,
+// через сколько переподключаться, в формате "reconnect_after_ms=<миллисекунды>".
+func ReconnectHint(delay time.Duration) string {
+	return fmt.Sprintf("%s%d", reconnectHintPrefix, delay.Milliseconds())
+}
+
+// ParseReconnectHint извлекает задержку переподключения из причины close-фрейма.
+// Возвращает false, если причина не содержит подсказки.
+func ParseReconnectHint(text string) (time.Duration, bool) {
+	value, ok := strings.CutPrefix(text, reconnectHintPrefix)
+	if !ok {
+		return 0, false
+	}
+	milliseconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || milliseconds < 0 {
+		return 0, false
+	}
+	return time.Duration(milliseconds) * time.Millisecond, true
+}